@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/importer"
+)
+
+// deleteBatch previews what deleting a batch would change, asks for
+// confirmation and then deletes it
+func deleteBatch(ctx context.Context, db *sql.DB, batchID int64, skipConfirm bool) {
+	imp := importer.NewImporter(db)
+
+	batch, preview, err := imp.PreviewDeleteBatch(ctx, batchID)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	fmt.Printf("Batch %d - imported %s\n", batch.ID, batch.ImportedAt.Format("2006-01-02 15:04:05"))
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("Jobs file:     %s (%d rows)\n", batch.JobReportFilename, batch.RowCountJobs)
+	fmt.Printf("Invoices file: %s (%d rows)\n", batch.InvoiceReportFilename, batch.RowCountInvoices)
+	if batch.EstimateReportFilename.Valid {
+		fmt.Printf("Estimates file: %s (%d rows)\n", batch.EstimateReportFilename.String, batch.RowCountEstimates)
+	}
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+	fmt.Printf("Jobs:          %d deleted, %d restored to their previous version\n", preview.JobsDeleted, preview.JobsRestored)
+	fmt.Printf("Invoices:      %d deleted, %d restored to their previous version\n", preview.InvoicesDeleted, preview.InvoicesRestored)
+	if preview.EstimatesDeleted > 0 || preview.EstimatesRestored > 0 {
+		fmt.Printf("Estimates:     %d deleted, %d restored to their previous version\n", preview.EstimatesDeleted, preview.EstimatesRestored)
+	}
+	if preview.JobsKept > 0 || preview.InvoicesKept > 0 || preview.EstimatesKept > 0 {
+		fmt.Printf("⚠️  %d jobs, %d invoices and %d estimates were overwritten before snapshots were kept and will stay as they are\n",
+			preview.JobsKept, preview.InvoicesKept, preview.EstimatesKept)
+	}
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+
+	if !skipConfirm && !confirm(fmt.Sprintf("Delete batch %d? This cannot be undone. [y/N]: ", batchID)) {
+		fmt.Println("Cancelled")
+		return
+	}
+
+	result, err := imp.DeleteBatch(ctx, batchID)
+	if err != nil {
+		fmt.Printf("❌ Delete failed: %v\n", err)
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("✅ Batch deleted!")
+	fmt.Println()
+	fmt.Printf("Jobs:                 %d deleted, %d restored, %d kept\n", result.JobsDeleted, result.JobsRestored, result.JobsKept)
+	fmt.Printf("Invoices:             %d deleted, %d restored, %d kept\n", result.InvoicesDeleted, result.InvoicesRestored, result.InvoicesKept)
+	fmt.Printf("Estimates:            %d deleted, %d restored, %d kept\n", result.EstimatesDeleted, result.EstimatesRestored, result.EstimatesKept)
+	fmt.Printf("Customers deleted:    %d\n", result.CustomersDeleted)
+	fmt.Printf("Locations deleted:    %d\n", result.LocationsDeleted)
+	fmt.Printf("Metrics recalculated: %d jobs, %d technicians\n", result.JobMetricsCalculated, result.TechMetricsCalculated)
+	fmt.Printf("Duration:             %v\n", result.Duration.Round(time.Millisecond))
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
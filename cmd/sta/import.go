@@ -3,23 +3,124 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/datsun80zx/sta.git/internal/db"
 	"github.com/datsun80zx/sta.git/internal/importer"
+	"github.com/datsun80zx/sta.git/internal/parser"
 )
 
-func runImport(ctx context.Context, db *sql.DB, jobsPath, invoicesPath string) {
-	fmt.Println("Starting import...")
+// importOptions holds the flags shared by the import subcommands
+type importOptions struct {
+	dryRun   bool
+	lenient  bool
+	rowByRow bool
+	mapping  *parser.ColumnMapping
+	sheet    string
+	dates    parser.DateFormat
+}
+
+// parseImportFlags extracts import flags from args and returns the rest
+func parseImportFlags(args []string) (importOptions, []string) {
+	var opts importOptions
+	var remainingArgs []string
+	var dateLayout, timezone string
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dry-run":
+			opts.dryRun = true
+		case args[i] == "--lenient":
+			opts.lenient = true
+		case args[i] == "--row-by-row":
+			opts.rowByRow = true
+		case args[i] == "--mapping" && i+1 < len(args):
+			mapping, err := parser.LoadColumnMapping(args[i+1])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.mapping = mapping
+			i++
+		case args[i] == "--sheet" && i+1 < len(args):
+			opts.sheet = args[i+1]
+			i++
+		case args[i] == "--date-format" && i+1 < len(args):
+			dateLayout = args[i+1]
+			i++
+		case args[i] == "--timezone" && i+1 < len(args):
+			timezone = args[i+1]
+			i++
+		default:
+			remainingArgs = append(remainingArgs, args[i])
+		}
+	}
+
+	// Flags override the mapping file's date settings
+	if opts.mapping != nil {
+		if dateLayout == "" {
+			dateLayout = opts.mapping.DateFormat
+		}
+		if timezone == "" {
+			timezone = opts.mapping.Timezone
+		}
+	}
+	dates, err := parser.NewDateFormat(dateLayout, timezone)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.dates = dates
+
+	return opts, remainingArgs
+}
+
+// newImporter creates an importer configured from the import flags
+func newImporter(db *sql.DB, opts importOptions) *importer.Importer {
+	imp := importer.NewImporter(db)
+	imp.DryRun = opts.dryRun
+	imp.Lenient = opts.lenient
+	imp.RowByRow = opts.rowByRow
+	imp.Mapping = opts.mapping
+	imp.Sheet = opts.sheet
+	imp.DateFormat = opts.dates
+	return imp
+}
+
+func runImport(ctx context.Context, db *sql.DB, jobsPath, invoicesPath, estimatesPath string, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Starting import...")
+	}
 	fmt.Printf("  Jobs file:     %s\n", jobsPath)
 	fmt.Printf("  Invoices file: %s\n", invoicesPath)
+	if estimatesPath != "" {
+		fmt.Printf("  Estimates file: %s\n", estimatesPath)
+	}
 	fmt.Println()
 
-	imp := importer.NewImporter(db)
+	imp := newImporter(db, opts)
 
-	result, err := imp.ImportFiles(ctx, jobsPath, invoicesPath)
+	result, err := imp.ImportFiles(ctx, jobsPath, invoicesPath, estimatesPath)
 	if err != nil {
 		fmt.Printf("❌ Import failed: %v\n", err)
+		var importErr *importer.ImportError
+		if errors.As(err, &importErr) {
+			if importErr.Row > 0 {
+				fmt.Printf("   Step: %s (row %d)\n", importErr.Step, importErr.Row)
+			} else {
+				fmt.Printf("   Step: %s\n", importErr.Step)
+			}
+			if importErr.BatchID > 0 {
+				fmt.Printf("   Recorded as failed batch %d (see: sta list)\n", importErr.BatchID)
+			}
+		}
 		return
 	}
 
@@ -29,29 +130,608 @@ func runImport(ctx context.Context, db *sql.DB, jobsPath, invoicesPath string) {
 		return
 	}
 
+	if result.DryRun != nil {
+		printDryRun(result, opts.dates)
+		return
+	}
+
 	fmt.Println("✅ Import successful!")
 	fmt.Println()
 	fmt.Printf("Batch ID:           %d\n", result.BatchID)
-	fmt.Printf("Jobs imported:      %d\n", result.JobsImported)
-	fmt.Printf("Invoices imported:  %d\n", result.InvoicesImported)
+	fmt.Printf("Jobs:               %d new, %d updated, %d unchanged\n",
+		result.JobsInserted, result.JobsUpdated, result.JobsUnchanged)
+	fmt.Printf("Invoices:           %d new, %d updated, %d unchanged\n",
+		result.InvoicesInserted, result.InvoicesUpdated, result.InvoicesUnchanged)
+	if result.InvoicesLinked > 0 {
+		fmt.Printf("Invoices linked:    %d (to jobs from earlier imports)\n", result.InvoicesLinked)
+	}
 	if result.InvoicesSkipped > 0 {
 		fmt.Printf("Invoices skipped:   %d (no matching job)\n", result.InvoicesSkipped)
 	}
-	fmt.Printf("Customers upserted: %d\n", result.CustomersUpserted)
-	// fmt.Printf("Metrics calculated: %d\n", result.MetricsCalculated)
+	if estimatesPath != "" {
+		fmt.Printf("Estimates:          %d new, %d updated, %d unchanged\n",
+			result.EstimatesInserted, result.EstimatesUpdated, result.EstimatesUnchanged)
+	}
+	if result.EstimatesSkipped > 0 {
+		fmt.Printf("Estimates skipped:  %d (no matching job)\n", result.EstimatesSkipped)
+	}
+	if result.RowsRejected > 0 {
+		fmt.Printf("Rows rejected:      %d (sta import errors %d)\n", result.RowsRejected, result.BatchID)
+	}
+	fmt.Printf("Customers:          %d new, %d updated, %d unchanged\n",
+		result.CustomersInserted, result.CustomersUpdated, result.CustomersUnchanged)
+	fmt.Printf("Locations:          %d new, %d updated, %d unchanged\n",
+		result.LocationsInserted, result.LocationsUpdated, result.LocationsUnchanged)
+	fmt.Printf("Metrics calculated: %d (changed jobs only)\n", result.JobMetricsCalculated)
+	fmt.Printf("Job versions:       %d recorded (sta job history <job-id>)\n", result.JobVersionsRecorded)
 	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
 
+	printBadDates(result.BadDates, opts.dates)
+
 	if result.ValidationResult != nil && len(result.ValidationResult.Warnings) > 0 {
 		fmt.Println()
 		fmt.Println("⚠️  Warnings:")
 		for _, warning := range result.ValidationResult.Warnings {
 			fmt.Printf("   - %s\n", warning)
 		}
+		if result.ValidationResult.IssueCount() > 0 {
+			fmt.Printf("   Details: sta import quality %d\n", result.BatchID)
+		}
 	}
 
+	printUnknownCampaigns(result.UnknownCampaigns)
+
 	fmt.Println()
 	fmt.Println("💡 Next steps:")
 	fmt.Println("   sta report job-types     # View profitability by job type")
 	fmt.Println("   sta report campaigns     # View profitability by campaign")
 	fmt.Println("   sta report customers     # View top customers by profit")
 }
+
+// runTimesheetImport imports a Timesheet/Payroll report and prints the labor it added
+func runTimesheetImport(ctx context.Context, db *sql.DB, path string, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting timesheet dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Starting timesheet import...")
+	}
+	fmt.Printf("  Timesheet file: %s\n", path)
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.ImportTimesheets(ctx, path)
+	if err != nil {
+		fmt.Printf("❌ Import failed: %v\n", err)
+		return
+	}
+
+	if result.AlreadyImported {
+		fmt.Println("ℹ️  This timesheet has already been imported")
+		fmt.Printf("   Timesheet import ID: %d\n", result.ImportID)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("🔍 Dry run complete - no changes were saved")
+	} else {
+		fmt.Println("✅ Import successful!")
+	}
+	fmt.Println()
+	if !result.DryRun {
+		fmt.Printf("Timesheet import ID: %d\n", result.ImportID)
+	}
+	fmt.Printf("Entries:            %d (%d replaced from earlier timesheets)\n", result.EntriesImported, result.EntriesReplaced)
+	fmt.Printf("Hours:              %s\n", result.TotalHours.StringFixed(2))
+	fmt.Printf("Labor cost:         %s\n", formatCurrency(result.TotalLaborCost.InexactFloat64()))
+	fmt.Printf("Jobs matched:       %d\n", result.JobsMatched)
+	fmt.Printf("Metrics calculated: %d jobs, %d technicians\n", result.JobMetricsCalculated, result.TechMetricsCalculated)
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	if len(result.MissingJobIDs) > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d job(s) in the timesheet haven't been imported yet; their labor\n", len(result.MissingJobIDs))
+		fmt.Println("   counts once they are")
+	}
+	printDryRunList("Jobs not imported yet", result.MissingJobIDs)
+	printDryRunList("New technicians", result.NewTechnicians)
+	printBadDates(result.BadDates, opts.dates)
+
+	fmt.Println()
+	if result.DryRun {
+		fmt.Println("💡 Run the same command without --dry-run to import")
+	} else {
+		fmt.Println("💡 Next steps:")
+		fmt.Println("   sta report summary --after-labor        # Profit after technician labor")
+		fmt.Println("   sta report red-flags jobs --after-labor # Jobs losing money once labor is counted")
+	}
+}
+
+// runSpendImport imports a marketing spend file and prints what it stored
+func runSpendImport(ctx context.Context, db *sql.DB, path string, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting spend dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Starting spend import...")
+	}
+	fmt.Printf("  Spend file: %s\n", path)
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.ImportSpend(ctx, path)
+	if err != nil {
+		fmt.Printf("❌ Import failed: %v\n", err)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("🔍 Dry run complete - no changes were saved")
+	} else {
+		fmt.Println("✅ Import successful!")
+	}
+	fmt.Println()
+	fmt.Printf("Rows read:          %d\n", result.RowsRead)
+	fmt.Printf("Campaign months:    %d new, %d updated, %d unchanged\n",
+		result.MonthsInserted, result.MonthsUpdated, result.MonthsUnchanged)
+	fmt.Printf("Campaigns:          %d\n", result.Campaigns)
+	fmt.Printf("Total spend:        %s\n", formatCurrency(result.TotalAmount.InexactFloat64()))
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	if len(result.UnmatchedCampaigns) > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d campaign(s) don't match any imported job; their spend shows with no jobs\n", len(result.UnmatchedCampaigns))
+	}
+	printDryRunList("Campaigns without jobs", result.UnmatchedCampaigns)
+
+	fmt.Println()
+	if result.DryRun {
+		fmt.Println("💡 Run the same command without --dry-run to import")
+	} else {
+		fmt.Println("💡 Next steps:")
+		fmt.Println("   sta report campaigns     # View spend and ROI by campaign")
+	}
+}
+
+// runCampaignImport imports a campaign list and reports campaign IDs that
+// are still unknown
+func runCampaignImport(ctx context.Context, db *sql.DB, path string, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting campaign dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Starting campaign import...")
+	}
+	fmt.Printf("  Campaign file: %s\n", path)
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.ImportCampaigns(ctx, path)
+	if err != nil {
+		fmt.Printf("❌ Import failed: %v\n", err)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("🔍 Dry run complete - no changes were saved")
+	} else {
+		fmt.Println("✅ Import successful!")
+	}
+	fmt.Println()
+	fmt.Printf("Rows read:          %d\n", result.RowsRead)
+	fmt.Printf("Campaigns:          %d new, %d updated, %d unchanged\n",
+		result.CampaignsInserted, result.CampaignsUpdated, result.CampaignsUnchanged)
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	printUnknownCampaigns(result.UnknownCampaigns)
+	printBadDates(result.BadDates, opts.dates)
+
+	fmt.Println()
+	if result.DryRun {
+		fmt.Println("💡 Run the same command without --dry-run to import")
+	} else {
+		fmt.Println("💡 Next steps:")
+		fmt.Println("   sta report campaigns     # View profitability by campaign")
+	}
+}
+
+func runRosterImport(ctx context.Context, db *sql.DB, path string, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting roster dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Starting roster import...")
+	}
+	fmt.Printf("  Roster file: %s\n", path)
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.ImportRoster(ctx, path)
+	if err != nil {
+		fmt.Printf("❌ Import failed: %v\n", err)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("🔍 Dry run complete - no changes were saved")
+	} else {
+		fmt.Println("✅ Import successful!")
+	}
+	fmt.Println()
+	fmt.Printf("Rows read:          %d\n", result.RowsRead)
+	fmt.Printf("Technicians:        %d updated, %d unchanged\n", result.TechniciansUpdated, result.TechniciansUnchanged)
+	fmt.Printf("Terminated:         %d\n", result.Terminated)
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	if len(result.NewTechnicians) > 0 {
+		fmt.Println()
+		fmt.Printf("🆕 %d technicians on the roster haven't been seen on a job yet:\n", len(result.NewTechnicians))
+		for _, name := range result.NewTechnicians {
+			fmt.Printf("   %s\n", name)
+		}
+	}
+
+	if len(result.NameConflicts) > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d roster names belong to a different technician:\n", len(result.NameConflicts))
+		for _, conflict := range result.NameConflicts {
+			fmt.Printf("   %s\n", conflict)
+		}
+		fmt.Println("   Combine them with: sta technicians merge <from> <into>")
+	}
+	printBadDates(result.BadDates, opts.dates)
+
+	fmt.Println()
+	if result.DryRun {
+		fmt.Println("💡 Run the same command without --dry-run to import")
+	} else {
+		fmt.Println("💡 Next steps:")
+		fmt.Println("   sta report technicians teams                  # Roll up by team")
+		fmt.Println("   sta report technicians --exclude-terminated   # Current technicians only")
+	}
+}
+
+// dryRunListLimit caps how many IDs/names are printed per section
+const dryRunListLimit = 20
+
+// printDryRun prints everything a dry-run import found
+func printDryRun(result *importer.ImportResult, dates parser.DateFormat) {
+	report := result.DryRun
+
+	fmt.Println("🔍 Dry run complete - no changes were saved")
+	fmt.Println()
+	fmt.Printf("Jobs:               %d new, %d updated, %d unchanged\n",
+		result.JobsInserted, result.JobsUpdated, result.JobsUnchanged)
+	fmt.Printf("Invoices:           %d new, %d updated, %d unchanged\n",
+		result.InvoicesInserted, result.InvoicesUpdated, result.InvoicesUnchanged)
+	if result.InvoicesLinked > 0 {
+		fmt.Printf("Invoices linked:    %d (to jobs from earlier imports)\n", result.InvoicesLinked)
+	}
+	fmt.Printf("Invoices w/o job:   %d (would be skipped)\n", result.InvoicesSkipped)
+	if result.EstimatesInserted+result.EstimatesUpdated+result.EstimatesUnchanged+result.EstimatesSkipped > 0 {
+		fmt.Printf("Estimates:          %d new, %d updated, %d unchanged\n",
+			result.EstimatesInserted, result.EstimatesUpdated, result.EstimatesUnchanged)
+		fmt.Printf("Estimates w/o job:  %d (would be skipped)\n", result.EstimatesSkipped)
+	}
+	if result.RowsRejected > 0 {
+		fmt.Printf("Rows rejected:      %d (would be quarantined)\n", result.RowsRejected)
+	}
+	fmt.Printf("Customers:          %d new, %d updated, %d unchanged\n",
+		result.CustomersInserted, result.CustomersUpdated, result.CustomersUnchanged)
+	fmt.Printf("Locations:          %d new, %d updated, %d unchanged\n",
+		result.LocationsInserted, result.LocationsUpdated, result.LocationsUnchanged)
+	fmt.Printf("New technicians:    %d\n", len(report.NewTechnicians))
+	fmt.Printf("Metrics to update:  %d jobs\n", result.JobMetricsCalculated)
+	fmt.Printf("Job versions:       %d (would be recorded)\n", result.JobVersionsRecorded)
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	printDryRunList("Updated jobs", report.UpdatedJobIDs)
+	printDryRunList("Jobs missing for invoices/estimates", report.MissingJobIDs)
+	printDryRunList("New customers", formatIDs(report.NewCustomerIDs))
+	printDryRunList("Updated customers", formatIDs(report.UpdatedCustomerIDs))
+	printDryRunList("New technicians", report.NewTechnicians)
+
+	if result.ValidationResult != nil && len(result.ValidationResult.Warnings) > 0 {
+		fmt.Println()
+		fmt.Println("⚠️  Warnings:")
+		for _, warning := range result.ValidationResult.Warnings {
+			fmt.Printf("   - %s\n", warning)
+		}
+	}
+
+	printUnknownCampaigns(result.UnknownCampaigns)
+	printBadDates(result.BadDates, dates)
+
+	fmt.Println()
+	fmt.Println("💡 Run the same command without --dry-run to import")
+}
+
+// printUnknownCampaigns lists campaign IDs on jobs that have no name in the
+// campaigns table, so reports would show the bare ID
+func printUnknownCampaigns(unknown []db.GetUnknownCampaignIDsRow) {
+	if len(unknown) == 0 {
+		return
+	}
+
+	items := make([]string, len(unknown))
+	for idx, c := range unknown {
+		items[idx] = fmt.Sprintf("%s (%d jobs)", c.CampaignID, c.JobCount)
+	}
+
+	fmt.Println()
+	fmt.Printf("⚠️  %d campaign ID(s) have no name; reports show the ID until you run: sta import campaigns FILE\n", len(unknown))
+	printDryRunList("Unknown campaign IDs", items)
+}
+
+// printDryRunList prints a titled list, truncated to dryRunListLimit entries
+func printDryRunList(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s (%d):\n", title, len(items))
+	for idx, item := range items {
+		if idx == dryRunListLimit {
+			fmt.Printf("   ... and %d more\n", len(items)-dryRunListLimit)
+			break
+		}
+		fmt.Printf("   - %s\n", item)
+	}
+}
+
+func formatIDs(ids []int64) []string {
+	out := make([]string, len(ids))
+	for idx, id := range ids {
+		out[idx] = fmt.Sprintf("%d", id)
+	}
+	return out
+}
+
+// listRejectedRows prints a batch's rejected rows that still need fixing
+func listRejectedRows(ctx context.Context, db *sql.DB, batchID int64) {
+	imp := importer.NewImporter(db)
+
+	rows, err := imp.ListRejectedRows(ctx, batchID)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	if len(rows) == 0 {
+		fmt.Printf("✅ Batch %d has no rejected rows\n", batchID)
+		return
+	}
+
+	fmt.Printf("Rejected Rows - Batch %d\n", batchID)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-9s  %6s  %-12s  %-20s  %-20s  %s\n",
+		"Report", "Row", "Key", "Column", "Value", "Error")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+
+	for _, row := range rows {
+		key := "-"
+		if row.RecordKey.Valid {
+			key = row.RecordKey.String
+		}
+		fmt.Printf("%-9s  %6d  %-12s  %-20s  %-20s  %s\n",
+			row.ReportType,
+			row.RowNumber,
+			truncate(key, 12),
+			truncate(row.ColumnName, 20),
+			truncate(row.RawValue, 20),
+			row.ErrorMessage,
+		)
+	}
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("Total: %d rejected row(s)\n", len(rows))
+	fmt.Println()
+	fmt.Println("💡 Fix these rows in a copy of the original export, then run:")
+	fmt.Printf("   sta import retry %d --fixed fixed.csv\n", batchID)
+}
+
+// dataQualityLabels describes each data quality check for people
+var dataQualityLabels = map[string]string{
+	importer.CheckJobWithoutInvoices:        "Job has no invoices",
+	importer.CheckInvoiceCustomerMismatch:   "Invoice customer differs from job",
+	importer.CheckInvoiceLocationMismatch:   "Invoice location differs from job",
+	importer.CheckInvoiceJobTypeMismatch:    "Invoice job type differs from job",
+	importer.CheckInvoiceCostsTotalMismatch: "Costs total isn't the sum of its costs",
+	importer.CheckJobSubtotalMismatch:       "Job subtotal is off from invoice totals",
+}
+
+// listDataQualityIssues prints the invoices and jobs that failed a batch's
+// data quality checks, grouped by check
+func listDataQualityIssues(ctx context.Context, db *sql.DB, batchID int64) {
+	imp := importer.NewImporter(db)
+
+	issues, err := imp.ListDataQualityIssues(ctx, batchID)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("✅ Batch %d has no data quality issues\n", batchID)
+		return
+	}
+
+	fmt.Printf("Data Quality Issues - Batch %d\n", batchID)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+
+	// Issues come back ordered by check
+	var checks []string
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.CheckName]++
+		if counts[issue.CheckName] == 1 {
+			check := issue.CheckName
+			checks = append(checks, check)
+			label := dataQualityLabels[check]
+			if label == "" {
+				label = check
+			}
+			fmt.Println()
+			fmt.Println(label)
+			fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+			fmt.Printf("%-12s  %-12s  %-24s  %-24s\n", "Job", "Invoice", "Expected", "Actual")
+		}
+		invoiceID, expected, actual := "-", "-", "-"
+		if issue.InvoiceID.Valid {
+			invoiceID = issue.InvoiceID.String
+		}
+		if issue.Expected.Valid {
+			expected = issue.Expected.String
+		}
+		if issue.Actual.Valid {
+			actual = issue.Actual.String
+		}
+		fmt.Printf("%-12s  %-12s  %-24s  %-24s\n",
+			truncate(issue.JobID, 12), truncate(invoiceID, 12), truncate(expected, 24), truncate(actual, 24))
+	}
+
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("Total: %d issue(s)\n", len(issues))
+	for _, name := range checks {
+		label := dataQualityLabels[name]
+		if label == "" {
+			label = name
+		}
+		fmt.Printf("  %-42s %d\n", label+":", counts[name])
+	}
+}
+
+// retryRejectedRows re-ingests a corrected file for a batch's rejected rows
+func retryRejectedRows(ctx context.Context, db *sql.DB, batchID int64, fixedPath string, opts importOptions) {
+	fmt.Printf("Retrying rejected rows for batch %d...\n", batchID)
+	fmt.Printf("  Fixed file: %s\n", fixedPath)
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.RetryRejected(ctx, batchID, fixedPath)
+	if err != nil {
+		fmt.Printf("❌ Retry failed: %v\n", err)
+		return
+	}
+
+	fmt.Println("✅ Retry complete!")
+	fmt.Println()
+	fmt.Printf("Report:             %s\n", result.ReportType)
+	fmt.Printf("Rows read:          %d\n", result.RowsRead)
+	fmt.Printf("Rows resolved:      %d\n", result.RowsResolved)
+	switch result.ReportType {
+	case parser.ReportJobs:
+		fmt.Printf("Jobs:               %d new, %d updated\n", result.JobsInserted, result.JobsUpdated)
+	case parser.ReportEstimates:
+		fmt.Printf("Estimates:          %d new, %d updated\n", result.EstimatesInserted, result.EstimatesUpdated)
+		if result.EstimatesSkipped > 0 {
+			fmt.Printf("Estimates skipped:  %d (no matching job)\n", result.EstimatesSkipped)
+		}
+	default:
+		fmt.Printf("Invoices:           %d new, %d updated\n", result.InvoicesInserted, result.InvoicesUpdated)
+		if result.InvoicesSkipped > 0 {
+			fmt.Printf("Invoices skipped:   %d (no matching job)\n", result.InvoicesSkipped)
+		}
+	}
+	fmt.Printf("Metrics calculated: %d\n", result.JobMetricsCalculated)
+	fmt.Printf("Duration:           %v\n", result.Duration.Round(time.Millisecond))
+
+	if result.RowsRejected > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d rows still failed to parse (sta import errors %d)\n", result.RowsRejected, batchID)
+	}
+	printBadDates(result.BadDates, opts.dates)
+}
+
+// printBadDates lists the date columns with values that didn't match the
+// date format. Those values were left empty rather than failing their rows.
+func printBadDates(badDates map[string]int, dates parser.DateFormat) {
+	if len(badDates) == 0 {
+		return
+	}
+
+	columns := make([]string, 0, len(badDates))
+	total := 0
+	for column, count := range badDates {
+		columns = append(columns, column)
+		total += count
+	}
+	sort.Strings(columns)
+
+	fmt.Println()
+	fmt.Printf("⚠️  %d date value(s) don't match %s and were left empty:\n", total, dates)
+	for _, column := range columns {
+		fmt.Printf("   - %s: %d\n", column, badDates[column])
+	}
+	fmt.Println("   Set the layout with --date-format (e.g. 02/01/2006 for day first) and --timezone")
+}
+
+// inspectColumns prints how each header in a file maps onto row fields
+func inspectColumns(path string, opts importOptions) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	csvParser := parser.NewCSVParser()
+	csvParser.Mapping = opts.mapping
+
+	var p parser.Parser = csvParser
+	if parser.IsXLSX(path) {
+		p = &parser.XLSXParser{CSVParser: *csvParser, Sheet: opts.sheet}
+	}
+
+	report, err := p.Inspect(file)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	fmt.Printf("Column Mapping - %s (%s report)\n", filepath.Base(path), report.ReportType)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-36s  %s\n", "Header", "Field")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+
+	for _, col := range report.Columns {
+		field := "(ignored)"
+		if col.Field != "" {
+			field = col.Field
+		}
+		note := ""
+		if col.Required {
+			note = "required"
+		}
+		fmt.Printf("%-36s  %-28s  %s\n", truncate(col.Header, 36), field, note)
+	}
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+
+	if len(report.Missing) > 0 {
+		fmt.Println()
+		fmt.Println("Fields with no matching header:")
+		for _, col := range report.Missing {
+			if col.Required {
+				fmt.Printf("   ❌ %s (required)\n", col.Field)
+			} else {
+				fmt.Printf("   ⚠️  %s (will be empty)\n", col.Field)
+			}
+		}
+	}
+
+	fmt.Println()
+	if missing := report.MissingRequired(); len(missing) > 0 {
+		fmt.Printf("❌ %d required column(s) missing - import would fail\n", len(missing))
+		fmt.Println("💡 Add aliases for them in a mapping file and pass --mapping FILE")
+	} else {
+		fmt.Println("✅ All required columns found")
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// showJobHistory lists every version of a job kept in job_versions, oldest
+// first, with what changed from one import to the next
+func showJobHistory(ctx context.Context, db *sql.DB, jobID string) {
+	query := `
+		SELECT
+			v.valid_from_batch_id,
+			b.imported_at,
+			v.status,
+			v.primary_technician,
+			v.jobs_subtotal,
+			v.revenue,
+			v.total_costs,
+			v.gross_profit,
+			v.gross_margin_pct
+		FROM job_versions v
+		JOIN import_batches b ON b.id = v.valid_from_batch_id
+		WHERE v.job_id = $1
+		ORDER BY v.id
+	`
+
+	rows, err := db.QueryContext(ctx, query, jobID)
+	if err != nil {
+		fmt.Printf("Error reading job history: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type JobVersion struct {
+		BatchID    int64
+		ImportedAt time.Time
+		Status     string
+		Technician sql.NullString
+		Subtotal   sql.NullFloat64
+		Revenue    float64
+		Costs      float64
+		Profit     float64
+		MarginPct  sql.NullFloat64
+	}
+
+	var versions []JobVersion
+	for rows.Next() {
+		var v JobVersion
+		err := rows.Scan(
+			&v.BatchID,
+			&v.ImportedAt,
+			&v.Status,
+			&v.Technician,
+			&v.Subtotal,
+			&v.Revenue,
+			&v.Costs,
+			&v.Profit,
+			&v.MarginPct,
+		)
+		if err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+		versions = append(versions, v)
+	}
+
+	if len(versions) == 0 {
+		fmt.Printf("Job %s not found\n", jobID)
+		return
+	}
+
+	fmt.Printf("History of Job %s\n", jobID)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-5s  %-16s  %-12s  %11s  %11s  %11s  %11s  %8s  %-20s\n",
+		"Batch", "Imported", "Status", "Subtotal", "Revenue", "Costs", "Profit", "Margin", "Primary Technician")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	for idx, v := range versions {
+		subtotal := "N/A"
+		if v.Subtotal.Valid {
+			subtotal = fmt.Sprintf("$%.2f", v.Subtotal.Float64)
+		}
+		margin := "N/A"
+		if v.MarginPct.Valid {
+			margin = fmt.Sprintf("%.1f%%", v.MarginPct.Float64)
+		}
+		technician := "-"
+		if v.Technician.Valid {
+			technician = v.Technician.String
+			if len(technician) > 20 {
+				technician = technician[:17] + "..."
+			}
+		}
+
+		fmt.Printf("%-5d  %-16s  %-12s  %11s  $%10.2f  $%10.2f  $%10.2f  %8s  %-20s\n",
+			v.BatchID,
+			v.ImportedAt.Format("2006-01-02 15:04"),
+			v.Status,
+			subtotal,
+			v.Revenue,
+			v.Costs,
+			v.Profit,
+			margin,
+			technician,
+		)
+
+		if idx == 0 {
+			continue
+		}
+
+		// Spell out what the batch changed
+		prev := versions[idx-1]
+		var changes []string
+		if v.Status != prev.Status {
+			changes = append(changes, fmt.Sprintf("status %s → %s", prev.Status, v.Status))
+		}
+		if v.Technician != prev.Technician {
+			changes = append(changes, fmt.Sprintf("technician %s → %s", nullStringOr(prev.Technician, "-"), nullStringOr(v.Technician, "-")))
+		}
+		if v.Revenue != prev.Revenue {
+			changes = append(changes, fmt.Sprintf("revenue %+.2f", v.Revenue-prev.Revenue))
+		}
+		if v.Costs != prev.Costs {
+			changes = append(changes, fmt.Sprintf("costs %+.2f", v.Costs-prev.Costs))
+		}
+		if v.MarginPct.Valid && prev.MarginPct.Valid && v.MarginPct.Float64 != prev.MarginPct.Float64 {
+			changes = append(changes, fmt.Sprintf("margin %+.1f pts", v.MarginPct.Float64-prev.MarginPct.Float64))
+		}
+		if len(changes) > 0 {
+			fmt.Printf("       ↳ %s\n", strings.Join(changes, ", "))
+		}
+	}
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════════")
+
+	current := versions[len(versions)-1]
+	fmt.Printf("Total: %d versions since batch %d; current since batch %d (%s)\n",
+		len(versions), versions[0].BatchID, current.BatchID, current.Status)
+}
+
+// nullStringOr returns s, or fallback when s is NULL
+func nullStringOr(s sql.NullString, fallback string) string {
+	if !s.Valid {
+		return fallback
+	}
+	return s.String
+}
+
+// reportStatusChanges shows what became of jobs that had a given status
+// (Scheduled by default) at some point in a date range, going by the
+// imports that recorded them: how many are now Completed, Canceled, still
+// Scheduled and so on. The range defaults to the last 7 days.
+func reportStatusChanges(ctx context.Context, db *sql.DB, args []string) {
+	status := "Scheduled"
+	var remaining []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--status" && i+1 < len(args) {
+			status = args[i+1]
+			i++
+		} else {
+			remaining = append(remaining, args[i])
+		}
+	}
+
+	fromDate, toDate, _ := parseDateFlags(remaining)
+	if toDate == nil {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		toDate = &today
+	}
+	if fromDate == nil {
+		weekBefore := toDate.AddDate(0, 0, -7)
+		fromDate = &weekBefore
+	}
+
+	// A version counts when it was current at any point in the range: its
+	// batch was imported by the end of the range and it wasn't replaced
+	// before the range started
+	query := `
+		WITH had_status AS (
+			SELECT DISTINCT v.job_id
+			FROM job_versions v
+			JOIN import_batches f ON f.id = v.valid_from_batch_id
+			LEFT JOIN import_batches t ON t.id = v.valid_to_batch_id
+			WHERE v.status = $1
+			AND f.imported_at < $3::date + 1
+			AND (t.id IS NULL OR t.imported_at >= $2::date)
+		)
+		SELECT
+			j.status,
+			COUNT(*) as job_count,
+			COALESCE(SUM(m.revenue), 0)::numeric(12,2) as revenue,
+			COALESCE(SUM(m.gross_profit), 0)::numeric(12,2) as gross_profit
+		FROM had_status h
+		JOIN jobs j ON j.id = h.job_id
+		LEFT JOIN job_metrics m ON m.job_id = j.id
+		GROUP BY j.status
+		ORDER BY job_count DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, status, *fromDate, *toDate)
+	if err != nil {
+		fmt.Printf("Error running report: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type StatusOutcome struct {
+		Status   string
+		JobCount int
+		Revenue  float64
+		Profit   float64
+	}
+
+	var results []StatusOutcome
+	total := 0
+	for rows.Next() {
+		var r StatusOutcome
+		if err := rows.Scan(&r.Status, &r.JobCount, &r.Revenue, &r.Profit); err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+		results = append(results, r)
+		total += r.JobCount
+	}
+
+	fmt.Printf("Where %s Jobs Ended Up\n", status)
+	printDateRange(fromDate, toDate)
+
+	if len(results) == 0 {
+		fmt.Printf("No jobs were %s between %s and %s\n",
+			status, fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+		return
+	}
+
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-20s  %6s  %7s  %12s  %12s\n", "Status Now", "Jobs", "Share", "Revenue", "Profit")
+	fmt.Println("────────────────────────────────────────────────────────────────")
+	for _, r := range results {
+		label := r.Status
+		if r.Status == status {
+			label += " (still)"
+		}
+		fmt.Printf("%-20s  %6d  %6.1f%%  $%11.2f  $%11.2f\n",
+			label,
+			r.JobCount,
+			float64(r.JobCount)/float64(total)*100,
+			r.Revenue,
+			r.Profit,
+		)
+	}
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Printf("Total: %d jobs were %s in the range\n", total, status)
+}
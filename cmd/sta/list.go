@@ -56,6 +56,26 @@ func listImports(ctx context.Context, database *sql.DB) {
 			batch.RowCountInvoices,
 			filename,
 		)
+
+		if batch.EstimateReportFilename.Valid {
+			fmt.Printf("      ↳ estimates: %s (%d rows)\n", batch.EstimateReportFilename.String, batch.RowCountEstimates)
+		}
+
+		// Show where failed attempts stopped
+		if status == "failed" && batch.ErrorMessage.Valid {
+			where := "failed"
+			if batch.FailedStep.Valid {
+				where = batch.FailedStep.String
+			}
+			if batch.FailedRow.Valid {
+				where = fmt.Sprintf("%s, row %d", where, batch.FailedRow.Int32)
+			}
+			message := batch.ErrorMessage.String
+			if len(message) > 60 {
+				message = message[:57] + "..."
+			}
+			fmt.Printf("      ↳ %s: %s\n", where, message)
+		}
 	}
 	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("Total: %d import(s)\n", len(batches))
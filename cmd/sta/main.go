@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
 
 	_ "github.com/lib/pq"
 )
@@ -12,25 +13,88 @@ import (
 const usage = `ServiceTitan Profitability Analysis Tool
 
 Usage:
-  sta import <jobs.csv> <invoices.csv>     Import ServiceTitan reports
+  sta import [--dry-run] [--lenient] [--mapping FILE] [--sheet NAME] [--date-format LAYOUT] [--timezone NAME] <jobs> <invoices> [estimates]
+                                            Import ServiceTitan reports
+  sta import inspect <file> [--mapping FILE] [--sheet NAME]
+                                            Show how each header maps to a field
+  sta import timesheets [--dry-run] <file>  Import a Timesheet/Payroll report for labor costs
+  sta import spend [--dry-run] <file>       Import marketing spend (Campaign, Month, Amount)
+  sta import campaigns [--dry-run] <file>   Import campaign names (Campaign ID, Name, ...)
+  sta import roster [--dry-run] <file>      Import the technician roster (Name, Employee ID, Team, ...)
+  sta import errors <batch-id>              List rows rejected by a lenient import
+  sta import quality <batch-id>             List invoices and jobs that don't agree with each other
+  sta import retry <batch-id> --fixed FILE  Re-ingest corrected rejected rows
+  sta batch delete <batch-id> [--yes]      Undo an import, restoring rows it overwrote
+  sta rebuild [--dry-run] [--mapping FILE]  Re-parse and re-import every batch from its staged raw rows
   sta list                                  List import history
-  sta report summary [--output FILE] [--from DATE] [--to DATE]
+  sta job history <job-id>                  Show how a job changed across imports
+  sta technicians list                      List technicians and the names they've been seen under
+  sta technicians merge <from> <into>       Fold a duplicate technician into another
+  sta technicians rename <tech> <name>      Change a technician's display name
+  sta technicians deactivate <tech>         Leave a technician out of technician reports
+  sta technicians activate <tech>           Bring a deactivated technician back
+  sta report summary [--output FILE] [--from DATE] [--to DATE] [--after-labor]
                                             Generate HTML profitability report
   sta report job-types [--from DATE] [--to DATE]
                                             Show profitability by job type
+  sta report business-units [--from DATE] [--to DATE]
+                                            Show revenue, costs and profit by business unit
   sta report campaigns [--from DATE] [--to DATE]
-                                            Show profitability by campaign
+                                            Show profit, spend and ROI by campaign
   sta report customers [--top N] [--from DATE] [--to DATE]
                                             Show top customers by profit
+  sta report locations [--top N] [--from DATE] [--to DATE]
+                                            Show profit, visits and recall rate per site
+  sta report status-changes [--status NAME] [--from DATE] [--to DATE]
+                                            Show what became of jobs that had a status
+                                            (default Scheduled, last 7 days of imports)
   sta report red-flags <type> [options]     Identify profitability problems
                                             Types: jobs, job-types, customers, high-revenue
-  sta report technicians [type]             Technician performance reports
-                                            Types: overview, sales, conversion, efficiency	
+  sta report technicians [type] [--exclude-terminated]
+                                            Technician performance reports
+                                            Types: overview, sales, conversion, efficiency,
+                                            teams, managers, tenure
 
 Date Filtering:
   --from YYYY-MM-DD    Include jobs completed on or after this date
   --to YYYY-MM-DD      Include jobs completed on or before this date
 
+Import Options:
+  --dry-run            Validate and report what would change without saving
+  --lenient            Quarantine rows that fail to parse and import the rest
+  --mapping FILE       JSON file of header aliases for renamed columns, e.g.
+                       {"jobs": {"JobsSubtotal": ["Subtotal"]}, "invoices": {}}
+  --sheet NAME|N       Worksheet to read from .xlsx files, by name or position
+                       (default: first sheet)
+  --row-by-row         Upsert rows one at a time instead of bulk loading with
+                       COPY (slower; for comparing import durations)
+  --date-format LAYOUT Go layout of date columns, e.g. 02/01/2006 for day-first
+                       dates or "2006-01-02 15:04" (default: M/D/YYYY formats)
+  --timezone NAME      IANA timezone the dates are in, e.g. America/Chicago
+                       (default: UTC). Both can also be set in the mapping file
+                       as "date_format" and "timezone"
+
+Reports may be CSV or XLSX files; the format is chosen by file extension.
+The Estimates report is optional and feeds technician close rates.
+Timesheets are imported on their own; labor cost is hours × (pay rate + burden rate).
+Spend files have one row per campaign and month; Campaign is the ServiceTitan
+campaign ID or name, Month is e.g. 2024-11, and rows for the same month are summed.
+Campaign files are the ServiceTitan Campaigns export or a hand-maintained CSV with
+Campaign ID and Name, plus optional Category, Channel, Start Date and End Date.
+Reports show campaign names from it; unknown IDs are listed after each import.
+Roster files have one row per technician: Name, plus optional Employee ID, Team,
+Manager, Hire Date, Termination Date and Hourly Cost. Rows match technicians by
+Employee ID, then by name; re-importing the roster replaces the earlier details.
+
+Technicians are matched by name ignoring case and extra spaces. <tech> is a
+technician ID or any name they've been seen under; after a merge or rename,
+later imports map the old names onto the same technician.
+
+Profit Options:
+  --after-labor        Subtract timesheet labor from profit (summary and red-flags)
+  --exclude-unreliable Leave out jobs with no costs recorded, invoice totals that
+                       don't match revenue, or no hours (every job-based report)
+
 Output Options:
   --output FILE        Write report to FILE (default: profitability-report-DATE.html)
 
@@ -40,15 +104,39 @@ Database Configuration:
 
 Examples:
   sta import jobs_2024.csv invoices_2024.csv
+  sta import --dry-run jobs_2024.csv invoices_2024.csv
+  sta import jobs_2024.csv invoices_2024.csv estimates_2024.csv
+  sta import --lenient jobs_2024.csv invoices_2024.csv
+  sta import --mapping columns.json custom_jobs.csv invoices_2024.csv
+  sta import --sheet "Report" jobs_2024.xlsx invoices_2024.xlsx
+  sta import --date-format 02/01/2006 --timezone Europe/London jobs_uk.csv invoices_uk.csv
+  sta import inspect custom_jobs.csv --mapping columns.json
+  sta import timesheets payroll_2024-11.csv
+  sta import spend spend_2024.csv
+  sta import campaigns campaigns.csv
+  sta import roster roster.csv
+  sta import errors 12
+  sta import quality 12
+  sta import retry 12 --fixed jobs_fixed.csv
+  sta batch delete 12
+  sta rebuild --dry-run
   sta list
+  sta job history 48213
+  sta technicians merge "Jon Smith" "John Smith"
+  sta technicians deactivate 42
   sta report summary --output q4-report.html --from 2024-10-01 --to 2024-12-31
+  sta report summary --exclude-unreliable
   sta report job-types
   sta report job-types --from 2024-01-01 --to 2024-06-30
+  sta report business-units --from 2024-10-01 --to 2024-12-31
   sta report campaigns --from 2024-07-01
   sta report customers --top 20 --from 2024-01-01
+  sta report locations --top 50
+  sta report status-changes --from 2024-11-04 --to 2024-11-10
   sta report red-flags jobs
   sta report red-flags job-types --margin-threshold 15
   sta report red-flags customers --from 2024-11-01
+  sta report red-flags jobs --after-labor
 `
 
 func main() {
@@ -57,6 +145,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Inspecting a file's headers doesn't need a database
+	if len(os.Args) > 2 && os.Args[1] == "import" && os.Args[2] == "inspect" {
+		handleImportInspect(os.Args[3:])
+		return
+	}
+
 	// Get database URL from environment
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -86,8 +180,16 @@ func main() {
 	switch command {
 	case "import":
 		handleImport(ctx, db, os.Args[2:])
+	case "batch":
+		handleBatch(ctx, db, os.Args[2:])
+	case "rebuild":
+		handleRebuild(ctx, db, os.Args[2:])
 	case "list":
 		handleList(ctx, db)
+	case "job":
+		handleJob(ctx, db, os.Args[2:])
+	case "technicians":
+		handleTechnicians(ctx, db, os.Args[2:])
 	case "report":
 		handleReport(ctx, db, os.Args[2:])
 	case "help", "-h", "--help":
@@ -100,14 +202,46 @@ func main() {
 }
 
 func handleImport(ctx context.Context, db *sql.DB, args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "errors":
+			handleImportErrors(ctx, db, args[1:])
+			return
+		case "quality":
+			handleImportQuality(ctx, db, args[1:])
+			return
+		case "retry":
+			handleImportRetry(ctx, db, args[1:])
+			return
+		case "timesheets":
+			handleImportTimesheets(ctx, db, args[1:])
+			return
+		case "spend":
+			handleImportSpend(ctx, db, args[1:])
+			return
+		case "campaigns":
+			handleImportCampaigns(ctx, db, args[1:])
+			return
+		case "roster":
+			handleImportRoster(ctx, db, args[1:])
+			return
+		}
+	}
+
+	opts, args := parseImportFlags(args)
+
 	if len(args) < 2 {
 		fmt.Println("Error: import requires two arguments")
-		fmt.Println("Usage: sta import <jobs.csv> <invoices.csv>")
+		fmt.Println("Usage: sta import [--dry-run] [--lenient] [--mapping FILE] [--sheet NAME] [--date-format LAYOUT] [--timezone NAME] <jobs> <invoices> [estimates]")
 		os.Exit(1)
 	}
 
 	jobsPath := args[0]
 	invoicesPath := args[1]
+	estimatesPath := ""
+	if len(args) > 2 {
+		estimatesPath = args[2]
+	}
 
 	// Check files exist
 	if _, err := os.Stat(jobsPath); os.IsNotExist(err) {
@@ -118,18 +252,263 @@ func handleImport(ctx context.Context, db *sql.DB, args []string) {
 		fmt.Printf("Error: invoices file not found: %s\n", invoicesPath)
 		os.Exit(1)
 	}
+	if estimatesPath != "" {
+		if _, err := os.Stat(estimatesPath); os.IsNotExist(err) {
+			fmt.Printf("Error: estimates file not found: %s\n", estimatesPath)
+			os.Exit(1)
+		}
+	}
+
+	runImport(ctx, db, jobsPath, invoicesPath, estimatesPath, opts)
+}
+
+func handleBatch(ctx context.Context, db *sql.DB, args []string) {
+	if len(args) < 1 || args[0] != "delete" {
+		fmt.Println("Usage: sta batch delete <batch-id> [--yes]")
+		os.Exit(1)
+	}
+
+	var batchArg string
+	skipConfirm := false
+	for _, arg := range args[1:] {
+		if arg == "--yes" || arg == "-y" {
+			skipConfirm = true
+		} else {
+			batchArg = arg
+		}
+	}
+
+	if batchArg == "" {
+		fmt.Println("Error: batch delete requires a batch ID")
+		fmt.Println("Usage: sta batch delete <batch-id> [--yes]")
+		os.Exit(1)
+	}
+
+	batchID, err := strconv.ParseInt(batchArg, 10, 64)
+	if err != nil {
+		fmt.Printf("Error: invalid batch ID: %s\n", batchArg)
+		os.Exit(1)
+	}
+
+	deleteBatch(ctx, db, batchID, skipConfirm)
+}
+
+func handleImportTimesheets(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+
+	if len(args) < 1 {
+		fmt.Println("Error: import timesheets requires a file")
+		fmt.Println("Usage: sta import timesheets [--dry-run] [--mapping FILE] [--sheet NAME] <file>")
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		fmt.Printf("Error: timesheet file not found: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	runTimesheetImport(ctx, db, args[0], opts)
+}
+
+func handleImportSpend(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+
+	if len(args) < 1 {
+		fmt.Println("Error: import spend requires a file")
+		fmt.Println("Usage: sta import spend [--dry-run] [--mapping FILE] [--sheet NAME] <file>")
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		fmt.Printf("Error: spend file not found: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	runSpendImport(ctx, db, args[0], opts)
+}
+
+func handleImportCampaigns(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+
+	if len(args) < 1 {
+		fmt.Println("Error: import campaigns requires a file")
+		fmt.Println("Usage: sta import campaigns [--dry-run] [--mapping FILE] [--sheet NAME] <file>")
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		fmt.Printf("Error: campaign file not found: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	runCampaignImport(ctx, db, args[0], opts)
+}
+
+func handleImportRoster(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+
+	if len(args) < 1 {
+		fmt.Println("Error: import roster requires a file")
+		fmt.Println("Usage: sta import roster [--dry-run] [--mapping FILE] [--sheet NAME] <file>")
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		fmt.Printf("Error: roster file not found: %s\n", args[0])
+		os.Exit(1)
+	}
 
-	runImport(ctx, db, jobsPath, invoicesPath)
+	runRosterImport(ctx, db, args[0], opts)
+}
+
+func handleImportErrors(ctx context.Context, db *sql.DB, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: import errors requires a batch ID")
+		fmt.Println("Usage: sta import errors <batch-id>")
+		os.Exit(1)
+	}
+
+	batchID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Printf("Error: invalid batch ID: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	listRejectedRows(ctx, db, batchID)
+}
+
+func handleImportQuality(ctx context.Context, db *sql.DB, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: import quality requires a batch ID")
+		fmt.Println("Usage: sta import quality <batch-id>")
+		os.Exit(1)
+	}
+
+	batchID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Printf("Error: invalid batch ID: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	listDataQualityIssues(ctx, db, batchID)
+}
+
+func handleImportRetry(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+
+	var batchArg, fixedPath string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--fixed" && i+1 < len(args) {
+			fixedPath = args[i+1]
+			i++
+		} else {
+			batchArg = args[i]
+		}
+	}
+
+	if batchArg == "" || fixedPath == "" {
+		fmt.Println("Error: import retry requires a batch ID and --fixed file")
+		fmt.Println("Usage: sta import retry <batch-id> --fixed <file.csv>")
+		os.Exit(1)
+	}
+
+	batchID, err := strconv.ParseInt(batchArg, 10, 64)
+	if err != nil {
+		fmt.Printf("Error: invalid batch ID: %s\n", batchArg)
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(fixedPath); os.IsNotExist(err) {
+		fmt.Printf("Error: fixed file not found: %s\n", fixedPath)
+		os.Exit(1)
+	}
+
+	retryRejectedRows(ctx, db, batchID, fixedPath, opts)
+}
+
+func handleImportInspect(args []string) {
+	opts, args := parseImportFlags(args)
+
+	if len(args) < 1 {
+		fmt.Println("Error: import inspect requires a file")
+		fmt.Println("Usage: sta import inspect <file> [--mapping FILE] [--sheet NAME]")
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
+		fmt.Printf("Error: file not found: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	inspectColumns(args[0], opts)
+}
+
+func handleTechnicians(ctx context.Context, db *sql.DB, args []string) {
+	const techniciansUsage = "Usage: sta technicians list | merge <from> <into> | rename <tech> <name> | deactivate <tech> | activate <tech>"
+	if len(args) < 1 {
+		fmt.Println("Error: technicians requires a subcommand")
+		fmt.Println(techniciansUsage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		listTechnicians(ctx, db)
+	case "merge":
+		if len(args) != 3 {
+			fmt.Println("Error: technicians merge requires two technicians")
+			fmt.Println("Usage: sta technicians merge <from> <into>")
+			os.Exit(1)
+		}
+		mergeTechnicians(ctx, db, args[1], args[2])
+	case "rename":
+		if len(args) != 3 {
+			fmt.Println("Error: technicians rename requires a technician and a new name")
+			fmt.Println("Usage: sta technicians rename <tech> <name>")
+			os.Exit(1)
+		}
+		renameTechnician(ctx, db, args[1], args[2])
+	case "deactivate", "activate":
+		if len(args) != 2 {
+			fmt.Printf("Error: technicians %s requires a technician\n", args[0])
+			fmt.Printf("Usage: sta technicians %s <tech>\n", args[0])
+			os.Exit(1)
+		}
+		setTechnicianActive(ctx, db, args[1], args[0] == "activate")
+	default:
+		fmt.Printf("Unknown technicians command: %s\n", args[0])
+		fmt.Println(techniciansUsage)
+		os.Exit(1)
+	}
+}
+
+func handleRebuild(ctx context.Context, db *sql.DB, args []string) {
+	opts, args := parseImportFlags(args)
+	if len(args) > 0 {
+		fmt.Println("Usage: sta rebuild [--dry-run] [--mapping FILE]")
+		os.Exit(1)
+	}
+
+	rebuild(ctx, db, opts)
 }
 
 func handleList(ctx context.Context, db *sql.DB) {
 	listImports(ctx, db)
 }
 
+func handleJob(ctx context.Context, db *sql.DB, args []string) {
+	if len(args) != 2 || args[0] != "history" {
+		fmt.Println("Usage: sta job history <job-id>")
+		os.Exit(1)
+	}
+
+	showJobHistory(ctx, db, args[1])
+}
+
 func handleReport(ctx context.Context, db *sql.DB, args []string) {
 	if len(args) < 1 {
 		fmt.Println("Error: report requires a report type")
-		fmt.Println("Available reports: summary, job-types, campaigns, customers, red-flags")
+		fmt.Println("Available reports: summary, job-types, business-units, campaigns, customers, locations, status-changes, red-flags")
 		os.Exit(1)
 	}
 
@@ -141,17 +520,23 @@ func handleReport(ctx context.Context, db *sql.DB, args []string) {
 		reportSummary(ctx, db, reportArgs)
 	case "job-types":
 		reportJobTypes(ctx, db, reportArgs)
+	case "business-units":
+		reportBusinessUnits(ctx, db, reportArgs)
 	case "campaigns":
 		reportCampaigns(ctx, db, reportArgs)
 	case "customers":
 		reportCustomers(ctx, db, reportArgs)
+	case "locations":
+		reportLocations(ctx, db, reportArgs)
+	case "status-changes":
+		reportStatusChanges(ctx, db, reportArgs)
 	case "red-flags":
 		handleRedFlags(ctx, db, reportArgs)
 	case "technicians":
 		reportTechnicians(ctx, db, reportArgs)
 	default:
 		fmt.Printf("Unknown report type: %s\n", reportType)
-		fmt.Println("Available reports: summary, job-types, campaigns, customers, red-flags")
+		fmt.Println("Available reports: summary, job-types, business-units, campaigns, customers, locations, status-changes, red-flags")
 		os.Exit(1)
 	}
 }
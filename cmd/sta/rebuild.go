@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// rebuild re-imports every batch from its staged raw rows and recalculates
+// all metrics
+func rebuild(ctx context.Context, db *sql.DB, opts importOptions) {
+	if opts.dryRun {
+		fmt.Println("Starting rebuild dry run (nothing will be saved)...")
+	} else {
+		fmt.Println("Rebuilding from staged rows...")
+	}
+	fmt.Println()
+
+	imp := newImporter(db, opts)
+
+	result, err := imp.Rebuild(ctx)
+	if err != nil {
+		fmt.Printf("❌ Rebuild failed: %v\n", err)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("🔍 Dry run complete - no changes were saved")
+	} else {
+		fmt.Println("✅ Rebuild successful!")
+	}
+	fmt.Println()
+	fmt.Printf("Batches rebuilt:      %d\n", result.BatchesRebuilt)
+	fmt.Printf("Rows re-imported:     %d jobs, %d invoices\n", result.JobRows, result.InvoiceRows)
+	fmt.Printf("Metrics recalculated: %d jobs, %d technicians\n", result.JobMetricsCalculated, result.TechMetricsCalculated)
+	fmt.Printf("Duration:             %v\n", result.Duration.Round(time.Millisecond))
+
+	if result.RowsRejected > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d staged rows no longer parse and were skipped\n", result.RowsRejected)
+		fmt.Println("   Run with --dry-run to check parser or mapping changes before saving")
+	}
+	if result.BatchesUnstaged > 0 {
+		fmt.Println()
+		fmt.Printf("⚠️  %d batches were imported before raw rows were staged and were left as they are\n", result.BatchesUnstaged)
+		fmt.Println("   To stage one, delete it with sta batch delete and import its files again")
+	}
+	printBadDates(result.BadDates, opts.dates)
+
+	if result.DryRun {
+		fmt.Println()
+		fmt.Println("💡 Run the same command without --dry-run to rebuild")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+
+	"github.com/datsun80zx/sta.git/internal/report"
 )
 
 // parseMarginThreshold extracts --margin-threshold flag from args
@@ -31,7 +33,7 @@ func parseMarginThreshold(args []string, defaultVal float64) (float64, []string)
 }
 
 // redFlagsJobs shows individual jobs with negative margins
-func redFlagsJobs(ctx context.Context, db *sql.DB, args []string) {
+func redFlagsJobs(ctx context.Context, db *sql.DB, cols report.ProfitColumns, args []string) {
 	fromDate, toDate, _ := parseDateFlags(args)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 0)
 
@@ -41,16 +43,16 @@ func redFlagsJobs(ctx context.Context, db *sql.DB, args []string) {
 			c.customer_name,
 			j.job_type,
 			m.revenue,
-			m.total_costs,
-			m.gross_profit,
-			m.gross_margin_pct,
+			` + cols.Costs + `,
+			` + cols.Profit + `,
+			` + cols.Margin + `,
 			j.job_completion_date
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
 		JOIN customers c ON j.customer_id = c.id
 		WHERE j.status = 'Completed'
-		  AND m.gross_profit < 0` + dateClause + `
-		ORDER BY m.gross_profit ASC
+		  AND ` + cols.Profit + ` < 0` + cols.Filter + dateClause + `
+		ORDER BY ` + cols.Profit + ` ASC
 	`
 
 	rows, err := db.QueryContext(ctx, query, dateArgs...)
@@ -93,11 +95,13 @@ func redFlagsJobs(ctx context.Context, db *sql.DB, args []string) {
 
 	if len(results) == 0 {
 		fmt.Println("✅ No jobs with negative margins found")
+		printProfitBasis(cols)
 		printDateRange(fromDate, toDate)
 		return
 	}
 
 	fmt.Println("🚩 RED FLAG: Jobs with Negative Margins")
+	printProfitBasis(cols)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-12s  %-25s  %-25s  %11s  %11s  %12s  %10s\n",
@@ -139,7 +143,7 @@ func redFlagsJobs(ctx context.Context, db *sql.DB, args []string) {
 }
 
 // redFlagsJobTypes shows job types with average margin below threshold
-func redFlagsJobTypes(ctx context.Context, db *sql.DB, args []string) {
+func redFlagsJobTypes(ctx context.Context, db *sql.DB, cols report.ProfitColumns, args []string) {
 	threshold, remainingArgs := parseMarginThreshold(args, 10.0)
 	fromDate, toDate, _ := parseDateFlags(remainingArgs)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 1) // offset by 1 for threshold param
@@ -149,17 +153,17 @@ func redFlagsJobTypes(ctx context.Context, db *sql.DB, args []string) {
 			j.job_type,
 			COUNT(*) as job_count,
 			AVG(m.revenue)::numeric(12,2) as avg_revenue,
-			AVG(m.total_costs)::numeric(12,2) as avg_costs,
-			AVG(m.gross_profit)::numeric(12,2) as avg_gross_profit,
-			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit
+			AVG(` + cols.Costs + `)::numeric(12,2) as avg_costs,
+			AVG(` + cols.Profit + `)::numeric(12,2) as avg_gross_profit,
+			AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL)::numeric(8,2) as avg_margin_pct,
+			SUM(` + cols.Profit + `)::numeric(12,2) as total_profit
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
 		GROUP BY j.job_type
-		HAVING AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL) < $1
-		   OR AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL) IS NULL
-		ORDER BY AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL) ASC NULLS FIRST
+		HAVING AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL) < $1
+		   OR AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL) IS NULL
+		ORDER BY AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL) ASC NULLS FIRST
 	`
 
 	// Build args: threshold first, then date args
@@ -204,11 +208,13 @@ func redFlagsJobTypes(ctx context.Context, db *sql.DB, args []string) {
 
 	if len(results) == 0 {
 		fmt.Printf("✅ No job types with average margin below %.1f%% found\n", threshold)
+		printProfitBasis(cols)
 		printDateRange(fromDate, toDate)
 		return
 	}
 
 	fmt.Printf("🚩 RED FLAG: Job Types with Average Margin Below %.1f%%\n", threshold)
+	printProfitBasis(cols)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-35s  %6s  %11s  %11s  %9s  %13s\n",
@@ -253,7 +259,7 @@ func redFlagsJobTypes(ctx context.Context, db *sql.DB, args []string) {
 }
 
 // redFlagsCustomers shows customers with negative total margin
-func redFlagsCustomers(ctx context.Context, db *sql.DB, args []string) {
+func redFlagsCustomers(ctx context.Context, db *sql.DB, cols report.ProfitColumns, args []string) {
 	fromDate, toDate, _ := parseDateFlags(args)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 0)
 
@@ -264,17 +270,17 @@ func redFlagsCustomers(ctx context.Context, db *sql.DB, args []string) {
 			c.customer_type,
 			COUNT(j.id) as job_count,
 			SUM(m.revenue)::numeric(12,2) as total_revenue,
-			SUM(m.total_costs)::numeric(12,2) as total_costs,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit,
+			SUM(` + cols.Costs + `)::numeric(12,2) as total_costs,
+			SUM(` + cols.Profit + `)::numeric(12,2) as total_profit,
 			MIN(j.job_completion_date) as first_job,
 			MAX(j.job_completion_date) as last_job
 		FROM customers c
 		JOIN jobs j ON c.id = j.customer_id
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
 		GROUP BY c.id, c.customer_name, c.customer_type
-		HAVING SUM(m.gross_profit) < 0
-		ORDER BY SUM(m.gross_profit) ASC
+		HAVING SUM(` + cols.Profit + `) < 0
+		ORDER BY SUM(` + cols.Profit + `) ASC
 	`
 
 	rows, err := db.QueryContext(ctx, query, dateArgs...)
@@ -319,11 +325,13 @@ func redFlagsCustomers(ctx context.Context, db *sql.DB, args []string) {
 
 	if len(results) == 0 {
 		fmt.Println("✅ No customers with negative total margin found")
+		printProfitBasis(cols)
 		printDateRange(fromDate, toDate)
 		return
 	}
 
 	fmt.Println("🚩 RED FLAG: Customers with Negative Total Margin")
+	printProfitBasis(cols)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-30s  %6s  %12s  %12s  %12s  %10s  %10s\n",
@@ -369,7 +377,7 @@ func redFlagsCustomers(ctx context.Context, db *sql.DB, args []string) {
 }
 
 // redFlagsHighRevenue shows jobs with high revenue but low margin
-func redFlagsHighRevenue(ctx context.Context, db *sql.DB, args []string) {
+func redFlagsHighRevenue(ctx context.Context, db *sql.DB, cols report.ProfitColumns, args []string) {
 	marginThreshold, remainingArgs := parseMarginThreshold(args, 15.0)
 	fromDate, toDate, _ := parseDateFlags(remainingArgs)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 2) // offset by 2 for revenue and margin params
@@ -382,16 +390,16 @@ func redFlagsHighRevenue(ctx context.Context, db *sql.DB, args []string) {
 			c.customer_name,
 			j.job_type,
 			m.revenue,
-			m.total_costs,
-			m.gross_profit,
-			m.gross_margin_pct,
+			` + cols.Costs + `,
+			` + cols.Profit + `,
+			` + cols.Margin + `,
 			j.job_completion_date
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
 		JOIN customers c ON j.customer_id = c.id
 		WHERE j.status = 'Completed'
 		  AND m.revenue > $1
-		  AND (m.gross_margin_pct < $2 OR m.gross_margin_pct IS NULL)` + dateClause + `
+		  AND (` + cols.Margin + ` < $2 OR ` + cols.Margin + ` IS NULL)` + cols.Filter + dateClause + `
 		ORDER BY m.revenue DESC
 	`
 
@@ -440,12 +448,14 @@ func redFlagsHighRevenue(ctx context.Context, db *sql.DB, args []string) {
 	if len(results) == 0 {
 		fmt.Printf("✅ No high-revenue jobs (>$%.0f) with margin below %.1f%% found\n",
 			revenueThreshold, marginThreshold)
+		printProfitBasis(cols)
 		printDateRange(fromDate, toDate)
 		return
 	}
 
 	fmt.Printf("🚩 RED FLAG: High Revenue Jobs (>$%.0f) with Margin Below %.1f%%\n",
 		revenueThreshold, marginThreshold)
+	printProfitBasis(cols)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-12s  %-25s  %-20s  %11s  %11s  %9s  %10s\n",
@@ -501,6 +511,15 @@ func redFlagsHighRevenue(ctx context.Context, db *sql.DB, args []string) {
 	fmt.Println("\n💡 You're busy but not maximizing profit on these large jobs - review pricing")
 }
 
+// printProfitBasis notes when profit includes timesheet labor or jobs with
+// unreliable data are left out
+func printProfitBasis(cols report.ProfitColumns) {
+	if cols.AfterLabor {
+		fmt.Println("Profit: after technician labor from timesheets")
+	}
+	printUnreliableNote(cols.Filter != "")
+}
+
 // handleRedFlags routes to the appropriate red flag subcommand
 func handleRedFlags(ctx context.Context, db *sql.DB, args []string) {
 	if len(args) < 1 {
@@ -509,17 +528,20 @@ func handleRedFlags(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	subcommand := args[0]
-	subArgs := args[1:]
+	afterLabor, subArgs := parseAfterLaborFlag(args[1:])
+	excludeUnreliable, subArgs := parseExcludeUnreliableFlag(subArgs)
+	cols := report.ProfitColumnsFor(afterLabor)
+	cols.Filter = report.UnreliableFilter(excludeUnreliable)
 
 	switch subcommand {
 	case "jobs":
-		redFlagsJobs(ctx, db, subArgs)
+		redFlagsJobs(ctx, db, cols, subArgs)
 	case "job-types":
-		redFlagsJobTypes(ctx, db, subArgs)
+		redFlagsJobTypes(ctx, db, cols, subArgs)
 	case "customers":
-		redFlagsCustomers(ctx, db, subArgs)
+		redFlagsCustomers(ctx, db, cols, subArgs)
 	case "high-revenue":
-		redFlagsHighRevenue(ctx, db, subArgs)
+		redFlagsHighRevenue(ctx, db, cols, subArgs)
 	case "help", "-h", "--help":
 		printRedFlagsUsage()
 	default:
@@ -544,10 +566,13 @@ Options:
   --from YYYY-MM-DD        Filter jobs completed on or after date
   --to YYYY-MM-DD          Filter jobs completed on or before date
   --margin-threshold N     Set margin % threshold (default: 10 for job-types, 15 for high-revenue)
+  --after-labor            Subtract technician labor from imported timesheets
+  --exclude-unreliable     Leave out jobs with no costs, invoice mismatches or missing hours
 
 Examples:
   sta report red-flags jobs
   sta report red-flags job-types --margin-threshold 15
   sta report red-flags customers --from 2024-11-01
-  sta report red-flags high-revenue --from 2024-11-01 --to 2025-03-31`)
+  sta report red-flags high-revenue --from 2024-11-01 --to 2025-03-31
+  sta report red-flags jobs --after-labor`)
 }
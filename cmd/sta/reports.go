@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"time"
+
+	"github.com/datsun80zx/sta.git/internal/report"
 )
 
 // parseDateFlags extracts --from and --to flags from args
@@ -89,6 +91,7 @@ func printDateRange(fromDate, toDate *time.Time) {
 }
 
 func reportJobTypes(ctx context.Context, db *sql.DB, args []string) {
+	excludeUnreliable, args := parseExcludeUnreliableFlag(args)
 	fromDate, toDate, _ := parseDateFlags(args)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 0)
 
@@ -103,7 +106,7 @@ func reportJobTypes(ctx context.Context, db *sql.DB, args []string) {
 			SUM(m.gross_profit)::numeric(12,2) as total_profit
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + report.UnreliableFilter(excludeUnreliable) + dateClause + `
 		GROUP BY j.job_type
 		ORDER BY total_profit DESC
 	`
@@ -150,6 +153,7 @@ func reportJobTypes(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	fmt.Println("Profitability by Job Type")
+	printUnreliableNote(excludeUnreliable)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-30s  %6s  %12s  %12s  %12s  %9s  %14s\n",
@@ -193,63 +197,16 @@ func reportJobTypes(ctx context.Context, db *sql.DB, args []string) {
 }
 
 func reportCampaigns(ctx context.Context, db *sql.DB, args []string) {
+	excludeUnreliable, args := parseExcludeUnreliableFlag(args)
 	fromDate, toDate, _ := parseDateFlags(args)
-	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 0)
-
-	query := `
-		SELECT 
-			COALESCE(j.campaign_name, 'Unknown') as campaign_name,
-			COALESCE(j.campaign_category, 'Uncategorized') as campaign_category,
-			COUNT(*) as job_count,
-			AVG(m.revenue)::numeric(12,2) as avg_revenue,
-			AVG(m.total_costs)::numeric(12,2) as avg_costs,
-			AVG(m.gross_profit)::numeric(12,2) as avg_gross_profit,
-			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit
-		FROM jobs j
-		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
-		GROUP BY j.campaign_name, j.campaign_category
-		ORDER BY total_profit DESC
-	`
+	cols := report.ProfitColumnsFor(false)
+	cols.Filter = report.UnreliableFilter(excludeUnreliable)
 
-	rows, err := db.QueryContext(ctx, query, dateArgs...)
+	results, err := report.LoadCampaigns(ctx, db, cols, fromDate, toDate)
 	if err != nil {
 		fmt.Printf("Error running report: %v\n", err)
 		return
 	}
-	defer rows.Close()
-
-	type CampaignStats struct {
-		CampaignName     string
-		CampaignCategory string
-		JobCount         int
-		AvgRevenue       float64
-		AvgCosts         float64
-		AvgProfit        float64
-		AvgMarginPct     sql.NullFloat64
-		TotalProfit      float64
-	}
-
-	var results []CampaignStats
-	for rows.Next() {
-		var r CampaignStats
-		err := rows.Scan(
-			&r.CampaignName,
-			&r.CampaignCategory,
-			&r.JobCount,
-			&r.AvgRevenue,
-			&r.AvgCosts,
-			&r.AvgProfit,
-			&r.AvgMarginPct,
-			&r.TotalProfit,
-		)
-		if err != nil {
-			fmt.Printf("Error reading results: %v\n", err)
-			return
-		}
-		results = append(results, r)
-	}
 
 	if len(results) == 0 {
 		fmt.Println("No completed jobs with campaign data found")
@@ -257,12 +214,18 @@ func reportCampaigns(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	fmt.Println("Profitability by Campaign")
+	printUnreliableNote(excludeUnreliable)
 	printDateRange(fromDate, toDate)
-	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════")
-	fmt.Printf("%-25s  %-20s  %6s  %11s  %11s  %9s  %13s\n",
-		"Campaign", "Category", "Jobs", "Avg Profit", "Margin %", "Total Profit", "Avg Revenue")
-	fmt.Println("────────────────────────────────────────────────────────────────────────────────────────────────────────")
+	fmt.Println("═══════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-25s  %-20s  %6s  %11s  %9s  %13s  %11s  %11s  %11s  %8s  %6s\n",
+		"Campaign", "Category", "Jobs", "Avg Profit", "Margin %", "Total Profit", "Spend", "Cost/Job", "Cost/Cust", "ROI", "ROAS")
+	fmt.Println("───────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────────")
 
+	totalJobs := 0
+	totalProfit := 0.0
+	totalRevenue := 0.0
+	totalSpend := 0.0
+	totalNewCustomers := 0
 	for _, r := range results {
 		campaign := r.CampaignName
 		if len(campaign) > 25 {
@@ -274,34 +237,75 @@ func reportCampaigns(ctx context.Context, db *sql.DB, args []string) {
 		}
 
 		marginStr := "N/A"
-		if r.AvgMarginPct.Valid {
-			marginStr = fmt.Sprintf("%7.1f%%", r.AvgMarginPct.Float64)
+		if r.AvgMarginPct != nil {
+			marginStr = fmt.Sprintf("%7.1f%%", *r.AvgMarginPct)
 		}
 
-		fmt.Printf("%-25s  %-20s  %6d  $%10.2f  %8s  $%12.2f  $%10.2f\n",
+		fmt.Printf("%-25s  %-20s  %6d  $%10.2f  %9s  $%12.2f  $%10.2f  %11s  %11s  %8s  %6s\n",
 			campaign,
 			category,
 			r.JobCount,
 			r.AvgProfit,
 			marginStr,
 			r.TotalProfit,
-			r.AvgRevenue,
+			r.Spend,
+			formatOptionalCurrency(r.CostPerJob),
+			formatOptionalCurrency(r.CostPerCustomer),
+			formatOptionalPercent(r.ROIPct),
+			formatOptionalRatio(r.ROAS),
 		)
-	}
-	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════")
 
-	totalJobs := 0
-	totalProfit := 0.0
-	for _, r := range results {
 		totalJobs += r.JobCount
 		totalProfit += r.TotalProfit
+		totalRevenue += r.TotalRevenue
+		totalSpend += r.Spend
+		totalNewCustomers += r.NewCustomers
 	}
+	fmt.Println("═══════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════════")
 
 	fmt.Printf("Total: %d campaigns, %d completed jobs, $%.2f total profit\n",
 		len(results), totalJobs, totalProfit)
+	if totalSpend > 0 {
+		ret := report.CalculateCampaignReturn(totalSpend, totalRevenue, totalProfit, totalJobs, totalNewCustomers)
+		fmt.Printf("Spend: $%.2f, %s per job, %s per new customer, ROI %s, ROAS %s\n",
+			totalSpend,
+			formatOptionalCurrency(ret.CostPerJob),
+			formatOptionalCurrency(ret.CostPerCustomer),
+			formatOptionalPercent(ret.ROIPct),
+			formatOptionalRatio(ret.ROAS),
+		)
+		fmt.Println("💡 Spend covers every month the date range touches")
+	} else {
+		fmt.Println("💡 Import marketing spend (sta import spend FILE) to see cost per job and ROI")
+	}
+}
+
+// formatOptionalCurrency formats an optional amount, or N/A when nil
+func formatOptionalCurrency(amount *float64) string {
+	if amount == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("$%.2f", *amount)
+}
+
+// formatOptionalPercent formats an optional percentage, or N/A when nil
+func formatOptionalPercent(pct *float64) string {
+	if pct == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f%%", *pct)
+}
+
+// formatOptionalRatio formats an optional multiple such as ROAS, or N/A when nil
+func formatOptionalRatio(ratio *float64) string {
+	if ratio == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1fx", *ratio)
 }
 
 func reportCustomers(ctx context.Context, db *sql.DB, args []string) {
+	excludeUnreliable, args := parseExcludeUnreliableFlag(args)
 	fromDate, toDate, remainingArgs := parseDateFlags(args)
 	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 1) // offset by 1 for LIMIT param
 
@@ -330,7 +334,7 @@ func reportCustomers(ctx context.Context, db *sql.DB, args []string) {
 		FROM customers c
 		JOIN jobs j ON c.id = j.customer_id
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + report.UnreliableFilter(excludeUnreliable) + dateClause + `
 		GROUP BY c.id, c.customer_name, c.customer_type, c.location_zip
 		ORDER BY total_profit DESC
 		LIMIT $1
@@ -386,6 +390,7 @@ func reportCustomers(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	fmt.Printf("Top %d Customers by Profit\n", limit)
+	printUnreliableNote(excludeUnreliable)
 	printDateRange(fromDate, toDate)
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════")
 	fmt.Printf("%-35s  %6s  %11s  %11s  %9s  %13s\n",
@@ -440,3 +445,296 @@ func reportCustomers(ctx context.Context, db *sql.DB, args []string) {
 		fmt.Printf("Average customer lifetime value: $%.2f\n", avgLifetimeValue)
 	}
 }
+
+func reportLocations(ctx context.Context, db *sql.DB, args []string) {
+	excludeUnreliable, args := parseExcludeUnreliableFlag(args)
+	fromDate, toDate, remainingArgs := parseDateFlags(args)
+	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 1) // offset by 1 for LIMIT param
+
+	limit := 25 // default
+
+	// Parse --top flag from remaining args
+	for i, arg := range remainingArgs {
+		if arg == "--top" && i+1 < len(remainingArgs) {
+			if n, err := strconv.Atoi(remainingArgs[i+1]); err == nil {
+				limit = n
+			}
+		}
+	}
+
+	// Recalls and other $0 visits have no job_metrics row, so metrics are
+	// left-joined to keep them in the visit count
+	query := `
+		SELECT 
+			l.id as location_id,
+			c.customer_name,
+			l.city,
+			l.state,
+			l.zip,
+			COUNT(j.id) as visit_count,
+			COUNT(j.id) FILTER (WHERE j.is_recall) as recall_count,
+			COALESCE(SUM(m.revenue), 0)::numeric(12,2) as total_revenue,
+			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
+			COALESCE(SUM(m.gross_profit), 0)::numeric(12,2) as total_profit
+		FROM locations l
+		JOIN customers c ON c.id = l.customer_id
+		JOIN jobs j ON j.location_id = l.id
+		LEFT JOIN job_metrics m ON j.id = m.job_id
+		WHERE j.status = 'Completed'` + report.UnreliableFilter(excludeUnreliable) + dateClause + `
+		GROUP BY l.id, c.customer_name, l.city, l.state, l.zip
+		ORDER BY total_profit DESC
+		LIMIT $1
+	`
+
+	// Build args: LIMIT first, then date args
+	queryArgs := []interface{}{limit}
+	queryArgs = append(queryArgs, dateArgs...)
+
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		fmt.Printf("Error running report: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type LocationStats struct {
+		LocationID   int64
+		CustomerName string
+		City         sql.NullString
+		State        sql.NullString
+		Zip          sql.NullString
+		VisitCount   int
+		RecallCount  int
+		TotalRevenue float64
+		AvgMarginPct sql.NullFloat64
+		TotalProfit  float64
+	}
+
+	var results []LocationStats
+	for rows.Next() {
+		var r LocationStats
+		err := rows.Scan(
+			&r.LocationID,
+			&r.CustomerName,
+			&r.City,
+			&r.State,
+			&r.Zip,
+			&r.VisitCount,
+			&r.RecallCount,
+			&r.TotalRevenue,
+			&r.AvgMarginPct,
+			&r.TotalProfit,
+		)
+		if err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+		results = append(results, r)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No locations with completed jobs found")
+		fmt.Println("💡 Locations come from the Location ID column of the Jobs report")
+		return
+	}
+
+	fmt.Printf("Top %d Locations by Profit\n", limit)
+	printUnreliableNote(excludeUnreliable)
+	printDateRange(fromDate, toDate)
+	fmt.Println("═════════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-10s  %-30s  %-24s  %6s  %8s  %9s  %13s\n",
+		"Location", "Customer", "Address", "Visits", "Recall %", "Margin %", "Total Profit")
+	fmt.Println("─────────────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	totalVisits := 0
+	totalRecalls := 0
+	totalProfit := 0.0
+	for i, r := range results {
+		name := r.CustomerName
+		if len(name) > 30 {
+			name = name[:27] + "..."
+		}
+
+		address := formatLocationAddress(r.City, r.State, r.Zip)
+		if len(address) > 24 {
+			address = address[:21] + "..."
+		}
+
+		recallPct := 0.0
+		if r.VisitCount > 0 {
+			recallPct = float64(r.RecallCount) / float64(r.VisitCount) * 100
+		}
+
+		marginStr := "N/A"
+		if r.AvgMarginPct.Valid {
+			marginStr = fmt.Sprintf("%7.1f%%", r.AvgMarginPct.Float64)
+		}
+
+		fmt.Printf("%-10d  %-30s  %-24s  %6d  %7.1f%%  %9s  $%12.2f\n",
+			r.LocationID,
+			name,
+			address,
+			r.VisitCount,
+			recallPct,
+			marginStr,
+			r.TotalProfit,
+		)
+
+		// Add separator every 10 rows for readability
+		if (i+1)%10 == 0 && i+1 < len(results) {
+			fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+		}
+
+		totalVisits += r.VisitCount
+		totalRecalls += r.RecallCount
+		totalProfit += r.TotalProfit
+	}
+	fmt.Println("═════════════════════════════════════════════════════════════════════════════════════════════════════════════")
+
+	fmt.Printf("Showing top %d locations, %d visits, $%.2f total profit\n",
+		len(results), totalVisits, totalProfit)
+	if totalVisits > 0 {
+		fmt.Printf("Recall rate: %.1f%% (%d of %d visits)\n",
+			float64(totalRecalls)/float64(totalVisits)*100, totalRecalls, totalVisits)
+	}
+}
+
+// formatLocationAddress joins a location's city, state and zip, skipping
+// parts that weren't exported
+func formatLocationAddress(city, state, zip sql.NullString) string {
+	address := city.String
+	if state.Valid && state.String != "" {
+		if address != "" {
+			address += ", "
+		}
+		address += state.String
+	}
+	if zip.Valid && zip.String != "" {
+		if address != "" {
+			address += " "
+		}
+		address += zip.String
+	}
+	if address == "" {
+		return "Unknown"
+	}
+	return address
+}
+
+func reportBusinessUnits(ctx context.Context, db *sql.DB, args []string) {
+	excludeUnreliable, args := parseExcludeUnreliableFlag(args)
+	fromDate, toDate, _ := parseDateFlags(args)
+	dateClause, dateArgs := buildDateFilter(fromDate, toDate, 0)
+
+	// Jobs imported before business unit IDs were stored only have the name,
+	// so those are grouped by name instead
+	query := `
+		SELECT 
+			COALESCE(bu.name, MAX(j.business_unit), 'Unassigned') as business_unit,
+			COUNT(*) as job_count,
+			SUM(m.revenue)::numeric(12,2) as revenue,
+			SUM(m.total_costs)::numeric(12,2) as costs,
+			SUM(m.gross_profit)::numeric(12,2) as gross_profit,
+			(SUM(m.gross_profit) / NULLIF(SUM(m.revenue), 0) * 100)::numeric(8,2) as margin_pct,
+			AVG(m.revenue)::numeric(12,2) as avg_ticket
+		FROM jobs j
+		JOIN job_metrics m ON j.id = m.job_id
+		LEFT JOIN business_units bu ON bu.id = j.business_unit_id
+		WHERE j.status = 'Completed'` + report.UnreliableFilter(excludeUnreliable) + dateClause + `
+		GROUP BY j.business_unit_id, bu.name, CASE WHEN j.business_unit_id IS NULL THEN j.business_unit END
+		ORDER BY gross_profit DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, dateArgs...)
+	if err != nil {
+		fmt.Printf("Error running report: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type BusinessUnitStats struct {
+		BusinessUnit string
+		JobCount     int
+		Revenue      float64
+		Costs        float64
+		GrossProfit  float64
+		MarginPct    sql.NullFloat64
+		AvgTicket    float64
+	}
+
+	var results []BusinessUnitStats
+	for rows.Next() {
+		var r BusinessUnitStats
+		err := rows.Scan(
+			&r.BusinessUnit,
+			&r.JobCount,
+			&r.Revenue,
+			&r.Costs,
+			&r.GrossProfit,
+			&r.MarginPct,
+			&r.AvgTicket,
+		)
+		if err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+		results = append(results, r)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No completed jobs found")
+		return
+	}
+
+	fmt.Println("Business Unit P&L")
+	printUnreliableNote(excludeUnreliable)
+	printDateRange(fromDate, toDate)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-25s  %6s  %13s  %13s  %13s  %8s  %11s\n",
+		"Business Unit", "Jobs", "Revenue", "Costs", "Gross Profit", "Margin", "Avg Ticket")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	totalJobs := 0
+	totalRevenue := 0.0
+	totalCosts := 0.0
+	totalProfit := 0.0
+	for _, r := range results {
+		unit := r.BusinessUnit
+		if len(unit) > 25 {
+			unit = unit[:22] + "..."
+		}
+
+		marginStr := "N/A"
+		if r.MarginPct.Valid {
+			marginStr = fmt.Sprintf("%.1f%%", r.MarginPct.Float64)
+		}
+
+		fmt.Printf("%-25s  %6d  $%12.2f  $%12.2f  $%12.2f  %8s  $%10.2f\n",
+			unit,
+			r.JobCount,
+			r.Revenue,
+			r.Costs,
+			r.GrossProfit,
+			marginStr,
+			r.AvgTicket,
+		)
+
+		totalJobs += r.JobCount
+		totalRevenue += r.Revenue
+		totalCosts += r.Costs
+		totalProfit += r.GrossProfit
+	}
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	totalMargin := "N/A"
+	avgTicket := 0.0
+	if totalRevenue != 0 {
+		totalMargin = fmt.Sprintf("%.1f%%", totalProfit/totalRevenue*100)
+	}
+	if totalJobs > 0 {
+		avgTicket = totalRevenue / float64(totalJobs)
+	}
+	fmt.Printf("%-25s  %6d  $%12.2f  $%12.2f  $%12.2f  %8s  $%10.2f\n",
+		"Total", totalJobs, totalRevenue, totalCosts, totalProfit, totalMargin, avgTicket)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════════")
+}
@@ -34,8 +34,49 @@ func parseOutputFlag(args []string) (string, []string) {
 	return output, remainingArgs
 }
 
+// parseAfterLaborFlag extracts --after-labor from args
+func parseAfterLaborFlag(args []string) (bool, []string) {
+	afterLabor := false
+	var remainingArgs []string
+
+	for _, arg := range args {
+		if arg == "--after-labor" {
+			afterLabor = true
+		} else {
+			remainingArgs = append(remainingArgs, arg)
+		}
+	}
+
+	return afterLabor, remainingArgs
+}
+
+// parseExcludeUnreliableFlag extracts --exclude-unreliable from args
+func parseExcludeUnreliableFlag(args []string) (bool, []string) {
+	exclude := false
+	var remainingArgs []string
+
+	for _, arg := range args {
+		if arg == "--exclude-unreliable" {
+			exclude = true
+		} else {
+			remainingArgs = append(remainingArgs, arg)
+		}
+	}
+
+	return exclude, remainingArgs
+}
+
+// printUnreliableNote notes when jobs with unreliable data are left out
+func printUnreliableNote(exclude bool) {
+	if exclude {
+		fmt.Println("Excluding jobs with unreliable data (no costs, invoice mismatch, hours missing)")
+	}
+}
+
 func reportSummary(ctx context.Context, db *sql.DB, args []string) {
 	output, remainingArgs := parseOutputFlag(args)
+	afterLabor, remainingArgs := parseAfterLaborFlag(remainingArgs)
+	excludeUnreliable, remainingArgs := parseExcludeUnreliableFlag(remainingArgs)
 	fromDate, toDate, _ := parseDateFlags(remainingArgs)
 
 	// Default output filename if not specified
@@ -65,10 +106,16 @@ func reportSummary(ctx context.Context, db *sql.DB, args []string) {
 		}
 		fmt.Println()
 	}
+	if afterLabor {
+		fmt.Println("  Profit: after technician labor")
+	}
+	if excludeUnreliable {
+		fmt.Println("  Excluding jobs with unreliable data")
+	}
 	fmt.Println()
 
 	// Generate report data
-	summary, err := report.GenerateSummary(ctx, db, fromDate, toDate)
+	summary, err := report.GenerateSummary(ctx, db, fromDate, toDate, afterLabor, excludeUnreliable)
 	if err != nil {
 		fmt.Printf("❌ Error generating report: %v\n", err)
 		return
@@ -105,6 +152,22 @@ func reportSummary(ctx context.Context, db *sql.DB, args []string) {
 	if summary.JobsWithLoss > 0 {
 		fmt.Printf("   • ⚠️  %d jobs with losses totaling %s\n", summary.JobsWithLoss, formatCurrency(-summary.TotalLoss))
 	}
+	if afterLabor {
+		fmt.Printf("   • %s technician labor from timesheets\n", formatCurrency(summary.LaborCosts))
+		if summary.JobsWithoutLabor > 0 {
+			fmt.Printf("   • ⚠️  %d jobs have no timesheet entries, so their labor isn't counted\n", summary.JobsWithoutLabor)
+		}
+	}
+	if summary.UnreliableJobs > 0 {
+		if excludeUnreliable {
+			fmt.Printf("   • %d jobs with unreliable data excluded:\n", summary.UnreliableJobs)
+		} else {
+			fmt.Printf("   • ⚠️  %d jobs have unreliable data (--exclude-unreliable leaves them out):\n", summary.UnreliableJobs)
+		}
+		for _, r := range summary.UnreliableReasons {
+			fmt.Printf("       %s: %d\n", r.Label, r.JobCount)
+		}
+	}
 	fmt.Println()
 	fmt.Println("💡 Open the HTML file in your browser and print to PDF (Cmd+P / Ctrl+P)")
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/importer"
+)
+
+// listTechnicians prints every technician with their aliases and job counts
+func listTechnicians(ctx context.Context, db *sql.DB) {
+	imp := importer.NewImporter(db)
+
+	techs, err := imp.ListTechnicians(ctx)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	if len(techs) == 0 {
+		fmt.Println("No technicians found")
+		fmt.Println()
+		fmt.Println("💡 Technicians are created when jobs are imported:")
+		fmt.Println("   sta import jobs.csv invoices.csv")
+		return
+	}
+
+	fmt.Println("Technicians")
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-5s  %-22s  %-15s  %-10s  %5s  %-10s  %-10s\n",
+		"ID", "Name", "Team", "Status", "Jobs", "First Seen", "Last Seen")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
+
+	today := time.Now()
+	for _, t := range techs {
+		status := "active"
+		if t.TerminationDate.Valid && !t.TerminationDate.Time.After(today) {
+			status = "terminated"
+		} else if !t.Active {
+			status = "inactive"
+		}
+		team := "-"
+		if t.Team.Valid {
+			team = t.Team.String
+		}
+		firstSeen, lastSeen := "-", "-"
+		if t.FirstSeenDate.Valid {
+			firstSeen = t.FirstSeenDate.Time.Format("2006-01-02")
+		}
+		if t.LastSeenDate.Valid {
+			lastSeen = t.LastSeenDate.Time.Format("2006-01-02")
+		}
+
+		fmt.Printf("%-5d  %-22s  %-15s  %-10s  %5d  %-10s  %-10s\n",
+			t.ID, truncate(t.Name, 22), truncate(team, 15), status, t.JobCount, firstSeen, lastSeen)
+		if t.Aliases != "" {
+			fmt.Printf("%-5s  also seen as: %s\n", "", t.Aliases)
+		}
+	}
+
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("Total: %d technicians\n", len(techs))
+}
+
+// mergeTechnicians folds the technician from into into
+func mergeTechnicians(ctx context.Context, db *sql.DB, from, into string) {
+	imp := importer.NewImporter(db)
+
+	result, err := imp.MergeTechnicians(ctx, from, into)
+	if err != nil {
+		fmt.Printf("❌ Merge failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✅ Merged %q (ID %d) into %q (ID %d)\n", result.From.Name, result.From.ID, result.Into.Name, result.Into.ID)
+	fmt.Println()
+	fmt.Printf("Job links moved:      %d\n", result.JobLinksMoved)
+	fmt.Printf("Metrics recalculated: %d technicians\n", result.TechMetricsCalculated)
+	fmt.Printf("Duration:             %v\n", result.Duration.Round(time.Millisecond))
+	fmt.Println()
+	fmt.Printf("💡 Later imports will map %q onto %q\n", result.From.Name, result.Into.Name)
+}
+
+// renameTechnician changes a technician's display name
+func renameTechnician(ctx context.Context, db *sql.DB, ref, name string) {
+	imp := importer.NewImporter(db)
+
+	tech, err := imp.RenameTechnician(ctx, ref, name)
+	if err != nil {
+		fmt.Printf("❌ Rename failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✅ Technician %d is now %q\n", tech.ID, tech.Name)
+}
+
+// setTechnicianActive activates or deactivates a technician
+func setTechnicianActive(ctx context.Context, db *sql.DB, ref string, active bool) {
+	imp := importer.NewImporter(db)
+
+	tech, err := imp.SetTechnicianActive(ctx, ref, active)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	if active {
+		fmt.Printf("✅ %s (ID %d) is active and back in technician reports\n", tech.Name, tech.ID)
+	} else {
+		fmt.Printf("✅ %s (ID %d) is inactive and left out of technician reports\n", tech.Name, tech.ID)
+	}
+}
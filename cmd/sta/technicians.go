@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,11 +17,12 @@ func reportTechnicians(ctx context.Context, db *sql.DB, args []string) {
 	// Check for --html flag first
 	htmlOutput, args := parseHTMLFlag(args)
 	outputFile, args := parseOutputFlag(args)
+	excludeTerminated, args := parseExcludeTerminatedFlag(args)
 	fromDate, toDate, remainingArgs := parseDateFlags(args)
 
 	// If HTML output requested, generate HTML report
 	if htmlOutput || outputFile != "" {
-		generateTechnicianHTML(ctx, db, fromDate, toDate, outputFile)
+		generateTechnicianHTML(ctx, db, fromDate, toDate, excludeTerminated, outputFile)
 		return
 	}
 
@@ -32,13 +34,19 @@ func reportTechnicians(ctx context.Context, db *sql.DB, args []string) {
 
 	switch subcommand {
 	case "overview", "":
-		reportTechnicianOverview(ctx, db)
+		reportTechnicianOverview(ctx, db, excludeTerminated)
 	case "sales":
-		reportTechnicianSales(ctx, db)
+		reportTechnicianSales(ctx, db, excludeTerminated)
 	case "conversion":
-		reportTechnicianConversion(ctx, db)
+		reportTechnicianConversion(ctx, db, excludeTerminated)
 	case "efficiency":
-		reportTechnicianEfficiency(ctx, db)
+		reportTechnicianEfficiency(ctx, db, excludeTerminated)
+	case "teams":
+		reportTechnicianGroups(ctx, db, "team", excludeTerminated)
+	case "managers":
+		reportTechnicianGroups(ctx, db, "manager", excludeTerminated)
+	case "tenure":
+		reportTechnicianTenure(ctx, db, excludeTerminated)
 	case "help":
 		printTechnicianUsage()
 	default:
@@ -63,7 +71,31 @@ func parseHTMLFlag(args []string) (bool, []string) {
 	return htmlOutput, remainingArgs
 }
 
-func generateTechnicianHTML(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, outputFile string) {
+// parseExcludeTerminatedFlag extracts --exclude-terminated from args
+func parseExcludeTerminatedFlag(args []string) (bool, []string) {
+	var remainingArgs []string
+	excludeTerminated := false
+
+	for _, arg := range args {
+		if arg == "--exclude-terminated" {
+			excludeTerminated = true
+		} else {
+			remainingArgs = append(remainingArgs, arg)
+		}
+	}
+
+	return excludeTerminated, remainingArgs
+}
+
+// terminatedFilter leaves out technicians terminated on or before today
+func terminatedFilter(excludeTerminated bool) string {
+	if !excludeTerminated {
+		return ""
+	}
+	return " AND (t.termination_date IS NULL OR t.termination_date > CURRENT_DATE)"
+}
+
+func generateTechnicianHTML(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, excludeTerminated bool, outputFile string) {
 	// Default output filename if not specified
 	if outputFile == "" {
 		timestamp := time.Now().Format("2006-01-02")
@@ -91,10 +123,13 @@ func generateTechnicianHTML(ctx context.Context, db *sql.DB, fromDate, toDate *t
 		}
 		fmt.Println()
 	}
+	if excludeTerminated {
+		fmt.Println("  Terminated technicians excluded")
+	}
 	fmt.Println()
 
 	// Generate report data
-	techReport, err := report.GenerateTechnicianReport(ctx, db, fromDate, toDate)
+	techReport, err := report.GenerateTechnicianReport(ctx, db, fromDate, toDate, excludeTerminated)
 	if err != nil {
 		fmt.Printf("❌ Error generating report: %v\n", err)
 		return
@@ -132,6 +167,9 @@ func generateTechnicianHTML(ctx context.Context, db *sql.DB, fromDate, toDate *t
 	if len(techReport.MonthlyTrends) > 0 {
 		fmt.Printf("   • %d months of trend data\n", len(techReport.MonthlyTrends))
 	}
+	if techReport.HasRoster {
+		fmt.Printf("   • %d teams, %d managers\n", len(techReport.Teams), len(techReport.Managers))
+	}
 	fmt.Println()
 	fmt.Println("💡 Open the HTML file in your browser and print to PDF (Ctrl+P)")
 }
@@ -146,8 +184,14 @@ Usage:
 Report Types (console output):
   overview     All KPIs for each technician (default)
   sales        Ranked by average sale amount
-  conversion   Ranked by conversion rate (min 5 opportunities)
+  conversion   Ranked by conversion rate (min 5 opportunities), with estimate close rates
   efficiency   Ranked by average hours per job (lower is better)
+  teams        Rolled up by team (needs a roster import)
+  managers     Rolled up by manager (needs a roster import)
+  tenure       Sales and profit per month employed (needs hire dates)
+
+Options:
+  --exclude-terminated  Leave out technicians terminated on or before today
 
 HTML Report Options:
   --html                Generate HTML report instead of console output
@@ -158,12 +202,13 @@ HTML Report Options:
 Examples:
   sta report technicians
   sta report technicians sales
+  sta report technicians teams --exclude-terminated
   sta report technicians --html
   sta report technicians --html --output q4-techs.html
   sta report technicians --html --from 2024-10-01 --to 2024-12-31`)
 }
 
-func reportTechnicianOverview(ctx context.Context, db *sql.DB) {
+func reportTechnicianOverview(ctx context.Context, db *sql.DB, excludeTerminated bool) {
 	query := `
 		SELECT 
 			t.name,
@@ -176,7 +221,7 @@ func reportTechnicianOverview(ctx context.Context, db *sql.DB) {
 			tm.total_gross_profit
 		FROM technicians t
 		JOIN technician_metrics tm ON t.id = tm.technician_id
-		WHERE (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)
+		WHERE t.active AND (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)` + terminatedFilter(excludeTerminated) + `
 		ORDER BY COALESCE(tm.total_gross_profit, 0) DESC
 	`
 
@@ -276,7 +321,7 @@ func reportTechnicianOverview(ctx context.Context, db *sql.DB) {
 	fmt.Printf("Total: %d technicians\n", len(results))
 }
 
-func reportTechnicianSales(ctx context.Context, db *sql.DB) {
+func reportTechnicianSales(ctx context.Context, db *sql.DB, excludeTerminated bool) {
 	query := `
 		SELECT 
 			t.name,
@@ -287,7 +332,7 @@ func reportTechnicianSales(ctx context.Context, db *sql.DB) {
 			tm.total_gross_profit
 		FROM technicians t
 		JOIN technician_metrics tm ON t.id = tm.technician_id
-		WHERE tm.jobs_sold > 0
+		WHERE t.active AND tm.jobs_sold > 0` + terminatedFilter(excludeTerminated) + `
 		ORDER BY tm.avg_sale DESC
 	`
 
@@ -376,17 +421,20 @@ func reportTechnicianSales(ctx context.Context, db *sql.DB) {
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════")
 }
 
-func reportTechnicianConversion(ctx context.Context, db *sql.DB) {
+func reportTechnicianConversion(ctx context.Context, db *sql.DB, excludeTerminated bool) {
 	query := `
 		SELECT 
 			t.name,
 			tm.opportunities,
 			tm.conversions,
 			tm.conversion_rate,
-			tm.avg_sale
+			tm.avg_sale,
+			tm.close_rate,
+			tm.avg_estimate_value,
+			tm.avg_days_to_sold
 		FROM technicians t
 		JOIN technician_metrics tm ON t.id = tm.technician_id
-		WHERE tm.opportunities >= 5
+		WHERE t.active AND tm.opportunities >= 5` + terminatedFilter(excludeTerminated) + `
 		ORDER BY tm.conversion_rate DESC NULLS LAST
 	`
 
@@ -403,6 +451,9 @@ func reportTechnicianConversion(ctx context.Context, db *sql.DB) {
 		Conversions    int
 		ConversionRate sql.NullFloat64
 		AvgSale        sql.NullFloat64
+		CloseRate      sql.NullFloat64 // From the Estimates report
+		AvgEstimate    sql.NullFloat64
+		AvgDaysToSold  sql.NullFloat64
 	}
 
 	var results []TechConversion
@@ -414,6 +465,9 @@ func reportTechnicianConversion(ctx context.Context, db *sql.DB) {
 			&r.Conversions,
 			&r.ConversionRate,
 			&r.AvgSale,
+			&r.CloseRate,
+			&r.AvgEstimate,
+			&r.AvgDaysToSold,
 		)
 		if err != nil {
 			fmt.Printf("Error reading results: %v\n", err)
@@ -428,10 +482,10 @@ func reportTechnicianConversion(ctx context.Context, db *sql.DB) {
 	}
 
 	fmt.Println("Technician Conversion Rates (Min 5 Opportunities)")
-	fmt.Println("════════════════════════════════════════════════════════════════════════════════")
-	fmt.Printf("%-25s  %12s  %11s  %12s  %12s\n",
-		"Technician", "Opportunities", "Conversions", "Conv Rate", "Avg Sale")
-	fmt.Println("────────────────────────────────────────────────────────────────────────────────")
+	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-25s  %12s  %11s  %12s  %12s  %10s  %12s  %9s\n",
+		"Technician", "Opportunities", "Conversions", "Conv Rate", "Avg Sale", "Close Rate", "Avg Estimate", "Days→Sold")
+	fmt.Println("────────────────────────────────────────────────────────────────────────────────────────────────────────────────────")
 
 	for i, r := range results {
 		name := r.Name
@@ -449,25 +503,44 @@ func reportTechnicianConversion(ctx context.Context, db *sql.DB) {
 			avgSale = fmt.Sprintf("$%11.2f", r.AvgSale.Float64)
 		}
 
+		closeRate := "N/A"
+		if r.CloseRate.Valid {
+			closeRate = fmt.Sprintf("%8.1f%%", r.CloseRate.Float64)
+		}
+
+		avgEstimate := "N/A"
+		if r.AvgEstimate.Valid {
+			avgEstimate = fmt.Sprintf("$%11.2f", r.AvgEstimate.Float64)
+		}
+
+		daysToSold := "N/A"
+		if r.AvgDaysToSold.Valid {
+			daysToSold = fmt.Sprintf("%9.1f", r.AvgDaysToSold.Float64)
+		}
+
 		rank := "   "
 		if i < 3 {
 			medals := []string{"🥇 ", "🥈 ", "🥉 "}
 			rank = medals[i]
 		}
 
-		fmt.Printf("%s%-22s  %12d  %11d  %12s  %12s\n",
+		fmt.Printf("%s%-22s  %12d  %11d  %12s  %12s  %10s  %12s  %9s\n",
 			rank,
 			name,
 			r.Opportunities,
 			r.Conversions,
 			convRate,
 			avgSale,
+			closeRate,
+			avgEstimate,
+			daysToSold,
 		)
 	}
-	fmt.Println("════════════════════════════════════════════════════════════════════════════════")
+	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Println("Close rate = sold / (sold + dismissed) estimates; needs an Estimates report import")
 }
 
-func reportTechnicianEfficiency(ctx context.Context, db *sql.DB) {
+func reportTechnicianEfficiency(ctx context.Context, db *sql.DB, excludeTerminated bool) {
 	query := `
 		SELECT 
 			t.name,
@@ -477,7 +550,7 @@ func reportTechnicianEfficiency(ctx context.Context, db *sql.DB) {
 			tm.avg_estimates_per_job
 		FROM technicians t
 		JOIN technician_metrics tm ON t.id = tm.technician_id
-		WHERE tm.jobs_serviced > 0
+		WHERE t.active AND tm.jobs_serviced > 0` + terminatedFilter(excludeTerminated) + `
 		ORDER BY tm.avg_hours_per_job ASC NULLS LAST
 	`
 
@@ -562,3 +635,231 @@ func reportTechnicianEfficiency(ctx context.Context, db *sql.DB) {
 	}
 	fmt.Println("════════════════════════════════════════════════════════════════════════════════")
 }
+
+// reportTechnicianGroups rolls technician metrics up by team or manager.
+// column is the technicians column to group by.
+func reportTechnicianGroups(ctx context.Context, db *sql.DB, column string, excludeTerminated bool) {
+	query := fmt.Sprintf(`
+		SELECT 
+			COALESCE(t.%[1]s, '(none)') as group_name,
+			COUNT(*) as technicians,
+			SUM(tm.jobs_sold) as jobs_sold,
+			SUM(tm.jobs_serviced) as jobs_serviced,
+			SUM(tm.total_sales) as total_sales,
+			SUM(COALESCE(tm.total_gross_profit, 0)) as total_gross_profit,
+			CASE WHEN SUM(tm.opportunities) > 0
+				THEN SUM(tm.conversions)::float / SUM(tm.opportunities) * 100
+			END as conversion_rate,
+			SUM(tm.total_sales) FILTER (WHERE t.hire_date < CURRENT_DATE)
+				/ NULLIF(SUM((LEAST(COALESCE(t.termination_date, CURRENT_DATE), CURRENT_DATE) - t.hire_date) / 30.44)
+					FILTER (WHERE t.hire_date < CURRENT_DATE), 0) as sales_per_tech_month
+		FROM technicians t
+		JOIN technician_metrics tm ON t.id = tm.technician_id
+		WHERE t.active AND (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)`+terminatedFilter(excludeTerminated)+`
+		GROUP BY COALESCE(t.%[1]s, '(none)')
+		ORDER BY total_sales DESC
+	`, column)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		fmt.Printf("Error running report: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type TechGroup struct {
+		Name              string
+		Technicians       int
+		JobsSold          int
+		JobsServiced      int
+		TotalSales        float64
+		TotalGrossProfit  float64
+		ConversionRate    sql.NullFloat64
+		SalesPerTechMonth sql.NullFloat64
+	}
+
+	var results []TechGroup
+	for rows.Next() {
+		var r TechGroup
+		err := rows.Scan(
+			&r.Name,
+			&r.Technicians,
+			&r.JobsSold,
+			&r.JobsServiced,
+			&r.TotalSales,
+			&r.TotalGrossProfit,
+			&r.ConversionRate,
+			&r.SalesPerTechMonth,
+		)
+		if err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+		results = append(results, r)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No technician data found")
+		fmt.Println("Run 'sta import' with data that includes technician information")
+		return
+	}
+
+	title := "Team"
+	if column == "manager" {
+		title = "Manager"
+	}
+
+	fmt.Printf("Technician Performance by %s\n", title)
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-25s  %5s  %6s  %8s  %8s  %14s  %14s  %12s\n",
+		title, "Techs", "Sold", "Serviced", "Conv %", "Total Sales", "Total Profit", "Sales/Month")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	for _, r := range results {
+		name := r.Name
+		if len(name) > 25 {
+			name = name[:22] + "..."
+		}
+
+		convRate := "N/A"
+		if r.ConversionRate.Valid {
+			convRate = fmt.Sprintf("%7.1f%%", r.ConversionRate.Float64)
+		}
+
+		salesPerMonth := "N/A"
+		if r.SalesPerTechMonth.Valid {
+			salesPerMonth = fmt.Sprintf("$%11.2f", r.SalesPerTechMonth.Float64)
+		}
+
+		fmt.Printf("%-25s  %5d  %6d  %8d  %8s  $%13.2f  $%13.2f  %12s\n",
+			name,
+			r.Technicians,
+			r.JobsSold,
+			r.JobsServiced,
+			convRate,
+			r.TotalSales,
+			r.TotalGrossProfit,
+			salesPerMonth,
+		)
+	}
+	fmt.Println("══════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Println("Sales/Month = sales per month employed, over technicians with a hire date")
+}
+
+// reportTechnicianTenure ranks technicians by sales per month employed, so
+// new hires can be compared with veterans
+func reportTechnicianTenure(ctx context.Context, db *sql.DB, excludeTerminated bool) {
+	query := `
+		SELECT 
+			t.name,
+			COALESCE(t.team, ''),
+			t.hire_date,
+			t.termination_date,
+			tm.jobs_serviced,
+			tm.total_sales,
+			COALESCE(tm.total_gross_profit, 0)
+		FROM technicians t
+		JOIN technician_metrics tm ON t.id = tm.technician_id
+		WHERE t.active AND t.hire_date IS NOT NULL
+		  AND (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)` + terminatedFilter(excludeTerminated) + `
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		fmt.Printf("Error running report: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	type TechTenure struct {
+		Name             string
+		Team             string
+		HireDate         time.Time
+		Months           float64
+		JobsServiced     int
+		TotalSales       float64
+		TotalGrossProfit float64
+		SalesPerMonth    float64
+		ProfitPerMonth   float64
+	}
+
+	now := time.Now()
+	var results []TechTenure
+	for rows.Next() {
+		var r TechTenure
+		var terminationDate sql.NullTime
+		err := rows.Scan(
+			&r.Name,
+			&r.Team,
+			&r.HireDate,
+			&terminationDate,
+			&r.JobsServiced,
+			&r.TotalSales,
+			&r.TotalGrossProfit,
+		)
+		if err != nil {
+			fmt.Printf("Error reading results: %v\n", err)
+			return
+		}
+
+		var terminated *time.Time
+		if terminationDate.Valid {
+			terminated = &terminationDate.Time
+		}
+		months := report.MonthsEmployed(&r.HireDate, terminated, nil, nil, now)
+		if months == nil {
+			continue
+		}
+		r.Months = *months
+		r.SalesPerMonth = r.TotalSales / r.Months
+		r.ProfitPerMonth = r.TotalGrossProfit / r.Months
+		results = append(results, r)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No technicians with hire dates found")
+		fmt.Println("Import a roster with hire dates: sta import roster roster.csv")
+		return
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SalesPerMonth > results[j].SalesPerMonth
+	})
+
+	fmt.Println("Technician Performance by Tenure (Ranked by Sales per Month Employed)")
+	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Printf("%-25s  %-15s  %-10s  %7s  %8s  %14s  %12s  %12s\n",
+		"Technician", "Team", "Hired", "Months", "Serviced", "Total Sales", "Sales/Month", "Profit/Month")
+	fmt.Println("────────────────────────────────────────────────────────────────────────────────────────────────────")
+
+	for i, r := range results {
+		name := r.Name
+		if len(name) > 22 {
+			name = name[:19] + "..."
+		}
+		team := r.Team
+		if len(team) > 15 {
+			team = team[:12] + "..."
+		}
+
+		rank := "   "
+		if i < 3 {
+			medals := []string{"🥇 ", "🥈 ", "🥉 "}
+			rank = medals[i]
+		}
+
+		fmt.Printf("%s%-22s  %-15s  %-10s  %7.1f  %8d  $%13.2f  $%11.2f  $%11.2f\n",
+			rank,
+			name,
+			team,
+			r.HireDate.Format("2006-01-02"),
+			r.Months,
+			r.JobsServiced,
+			r.TotalSales,
+			r.SalesPerMonth,
+			r.ProfitPerMonth,
+		)
+	}
+	fmt.Println("════════════════════════════════════════════════════════════════════════════════════════════════════")
+	fmt.Println("Months employed run from the hire date to the termination date or today")
+}
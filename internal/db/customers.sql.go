@@ -8,10 +8,53 @@ package db
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+const deleteCustomersWithoutJobs = `-- name: DeleteCustomersWithoutJobs :execrows
+DELETE FROM customers c
+WHERE c.id = ANY($1::bigint[])
+AND NOT EXISTS (SELECT 1 FROM jobs j WHERE j.customer_id = c.id)
+`
+
+func (q *Queries) DeleteCustomersWithoutJobs(ctx context.Context, customerIds []int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCustomersWithoutJobs, pq.Array(customerIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllCustomerIDs = `-- name: GetAllCustomerIDs :many
+SELECT id FROM customers
+`
+
+func (q *Queries) GetAllCustomerIDs(ctx context.Context) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getAllCustomerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCustomer = `-- name: GetCustomer :one
-SELECT id, customer_name, customer_type, customer_city, customer_state, customer_zip, location_city, location_state, location_zip, first_job_date, last_job_date, created_at, updated_at FROM customers WHERE id = $1
+SELECT id, customer_name, customer_type, customer_city, customer_state, customer_zip, location_city, location_state, location_zip, first_job_date, last_job_date, created_at, updated_at, job_count, lifetime_revenue FROM customers WHERE id = $1
 `
 
 func (q *Queries) GetCustomer(ctx context.Context, id int64) (Customer, error) {
@@ -31,17 +74,57 @@ func (q *Queries) GetCustomer(ctx context.Context, id int64) (Customer, error) {
 		&i.LastJobDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.JobCount,
+		&i.LifetimeRevenue,
 	)
 	return i, err
 }
 
+const refreshCustomerRollups = `-- name: RefreshCustomerRollups :exec
+UPDATE customers c SET
+    first_job_date = d.first_job_date,
+    last_job_date = d.last_job_date,
+    job_count = d.job_count,
+    lifetime_revenue = d.lifetime_revenue,
+    updated_at = NOW()
+FROM (
+    SELECT
+        t.id AS customer_id,
+        MIN(j.job_completion_date) AS first_job_date,
+        MAX(j.job_completion_date) AS last_job_date,
+        COUNT(j.id)::integer AS job_count,
+        COALESCE(SUM(m.revenue), 0)::numeric(12,2) AS lifetime_revenue
+    FROM customers t
+    LEFT JOIN jobs j ON j.customer_id = t.id
+    LEFT JOIN job_metrics m ON m.job_id = j.id
+    WHERE t.id = ANY($1::bigint[])
+    OR t.id IN (SELECT customer_id FROM jobs WHERE id = ANY($2::text[]))
+    GROUP BY t.id
+) d
+WHERE c.id = d.customer_id
+AND (c.first_job_date, c.last_job_date, c.job_count, c.lifetime_revenue)
+    IS DISTINCT FROM (d.first_job_date, d.last_job_date, d.job_count, d.lifetime_revenue)
+`
+
+type RefreshCustomerRollupsParams struct {
+	CustomerIds []int64  `json:"customer_ids"`
+	JobIds      []string `json:"job_ids"`
+}
+
+// Recomputes job dates, job count and lifetime revenue from the jobs that
+// remain, for the given customers and the customers of the given jobs.
+// Customers left without jobs are zeroed.
+func (q *Queries) RefreshCustomerRollups(ctx context.Context, arg RefreshCustomerRollupsParams) error {
+	_, err := q.db.ExecContext(ctx, refreshCustomerRollups, pq.Array(arg.CustomerIds), pq.Array(arg.JobIds))
+	return err
+}
+
 const upsertCustomer = `-- name: UpsertCustomer :one
 INSERT INTO customers (
     id, customer_name, customer_type,
     customer_city, customer_state, customer_zip,
-    location_city, location_state, location_zip,
-    first_job_date, last_job_date
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    location_city, location_state, location_zip
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 ON CONFLICT (id) DO UPDATE SET
     customer_name = EXCLUDED.customer_name,
     customer_type = EXCLUDED.customer_type,
@@ -51,10 +134,17 @@ ON CONFLICT (id) DO UPDATE SET
     location_city = EXCLUDED.location_city,
     location_state = EXCLUDED.location_state,
     location_zip = EXCLUDED.location_zip,
-    last_job_date = GREATEST(customers.last_job_date, EXCLUDED.last_job_date),
-    first_job_date = LEAST(customers.first_job_date, EXCLUDED.first_job_date),
     updated_at = NOW()
-RETURNING id, customer_name, customer_type, customer_city, customer_state, customer_zip, location_city, location_state, location_zip, first_job_date, last_job_date, created_at, updated_at
+WHERE (
+    customers.customer_name, customers.customer_type,
+    customers.customer_city, customers.customer_state, customers.customer_zip,
+    customers.location_city, customers.location_state, customers.location_zip
+) IS DISTINCT FROM (
+    EXCLUDED.customer_name, EXCLUDED.customer_type,
+    EXCLUDED.customer_city, EXCLUDED.customer_state, EXCLUDED.customer_zip,
+    EXCLUDED.location_city, EXCLUDED.location_state, EXCLUDED.location_zip
+)
+RETURNING (xmax = 0)::boolean AS inserted
 `
 
 type UpsertCustomerParams struct {
@@ -67,11 +157,11 @@ type UpsertCustomerParams struct {
 	LocationCity  sql.NullString `json:"location_city"`
 	LocationState sql.NullString `json:"location_state"`
 	LocationZip   sql.NullString `json:"location_zip"`
-	FirstJobDate  sql.NullTime   `json:"first_job_date"`
-	LastJobDate   sql.NullTime   `json:"last_job_date"`
 }
 
-func (q *Queries) UpsertCustomer(ctx context.Context, arg UpsertCustomerParams) (Customer, error) {
+// Job dates, job count and lifetime revenue are rollups of the customer's
+// jobs, kept up to date by RefreshCustomerRollups instead.
+func (q *Queries) UpsertCustomer(ctx context.Context, arg UpsertCustomerParams) (bool, error) {
 	row := q.db.QueryRowContext(ctx, upsertCustomer,
 		arg.ID,
 		arg.CustomerName,
@@ -82,24 +172,8 @@ func (q *Queries) UpsertCustomer(ctx context.Context, arg UpsertCustomerParams)
 		arg.LocationCity,
 		arg.LocationState,
 		arg.LocationZip,
-		arg.FirstJobDate,
-		arg.LastJobDate,
 	)
-	var i Customer
-	err := row.Scan(
-		&i.ID,
-		&i.CustomerName,
-		&i.CustomerType,
-		&i.CustomerCity,
-		&i.CustomerState,
-		&i.CustomerZip,
-		&i.LocationCity,
-		&i.LocationState,
-		&i.LocationZip,
-		&i.FirstJobDate,
-		&i.LastJobDate,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
 }
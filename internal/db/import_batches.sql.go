@@ -10,35 +10,88 @@ import (
 	"database/sql"
 )
 
+const createFailedImportBatch = `-- name: CreateFailedImportBatch :one
+INSERT INTO import_batches (
+    job_report_filename,
+    invoice_report_filename,
+    estimate_report_filename,
+    job_report_hash,
+    invoice_report_hash,
+    estimate_report_hash,
+    status,
+    error_message,
+    failed_step,
+    failed_row
+) VALUES ($1, $2, $3, $4, $5, $6, 'failed', $7, $8, $9)
+RETURNING id
+`
+
+type CreateFailedImportBatchParams struct {
+	JobReportFilename      string         `json:"job_report_filename"`
+	InvoiceReportFilename  string         `json:"invoice_report_filename"`
+	EstimateReportFilename sql.NullString `json:"estimate_report_filename"`
+	JobReportHash          string         `json:"job_report_hash"`
+	InvoiceReportHash      string         `json:"invoice_report_hash"`
+	EstimateReportHash     sql.NullString `json:"estimate_report_hash"`
+	ErrorMessage           sql.NullString `json:"error_message"`
+	FailedStep             sql.NullString `json:"failed_step"`
+	FailedRow              sql.NullInt32  `json:"failed_row"`
+}
+
+// Records a failed import attempt. Runs outside the import transaction so
+// the record survives its rollback.
+func (q *Queries) CreateFailedImportBatch(ctx context.Context, arg CreateFailedImportBatchParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createFailedImportBatch,
+		arg.JobReportFilename,
+		arg.InvoiceReportFilename,
+		arg.EstimateReportFilename,
+		arg.JobReportHash,
+		arg.InvoiceReportHash,
+		arg.EstimateReportHash,
+		arg.ErrorMessage,
+		arg.FailedStep,
+		arg.FailedRow,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createImportBatch = `-- name: CreateImportBatch :one
 INSERT INTO import_batches (
     job_report_filename,
     invoice_report_filename,
+    estimate_report_filename,
     job_report_hash,
     invoice_report_hash,
+    estimate_report_hash,
     row_count_jobs,
     row_count_invoices,
     status
-) VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at, failed_step, failed_row, estimate_report_filename, estimate_report_hash, row_count_estimates
 `
 
 type CreateImportBatchParams struct {
-	JobReportFilename     string `json:"job_report_filename"`
-	InvoiceReportFilename string `json:"invoice_report_filename"`
-	JobReportHash         string `json:"job_report_hash"`
-	InvoiceReportHash     string `json:"invoice_report_hash"`
-	RowCountJobs          int32  `json:"row_count_jobs"`
-	RowCountInvoices      int32  `json:"row_count_invoices"`
-	Status                string `json:"status"`
+	JobReportFilename      string         `json:"job_report_filename"`
+	InvoiceReportFilename  string         `json:"invoice_report_filename"`
+	EstimateReportFilename sql.NullString `json:"estimate_report_filename"`
+	JobReportHash          string         `json:"job_report_hash"`
+	InvoiceReportHash      string         `json:"invoice_report_hash"`
+	EstimateReportHash     sql.NullString `json:"estimate_report_hash"`
+	RowCountJobs           int32          `json:"row_count_jobs"`
+	RowCountInvoices       int32          `json:"row_count_invoices"`
+	Status                 string         `json:"status"`
 }
 
 func (q *Queries) CreateImportBatch(ctx context.Context, arg CreateImportBatchParams) (ImportBatch, error) {
 	row := q.db.QueryRowContext(ctx, createImportBatch,
 		arg.JobReportFilename,
 		arg.InvoiceReportFilename,
+		arg.EstimateReportFilename,
 		arg.JobReportHash,
 		arg.InvoiceReportHash,
+		arg.EstimateReportHash,
 		arg.RowCountJobs,
 		arg.RowCountInvoices,
 		arg.Status,
@@ -56,23 +109,138 @@ func (q *Queries) CreateImportBatch(ctx context.Context, arg CreateImportBatchPa
 		&i.Status,
 		&i.ErrorMessage,
 		&i.CreatedAt,
+		&i.FailedStep,
+		&i.FailedRow,
+		&i.EstimateReportFilename,
+		&i.EstimateReportHash,
+		&i.RowCountEstimates,
+	)
+	return i, err
+}
+
+const deleteImportBatch = `-- name: DeleteImportBatch :exec
+DELETE FROM import_batches WHERE id = $1
+`
+
+func (q *Queries) DeleteImportBatch(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteImportBatch, id)
+	return err
+}
+
+const getBatchDeletePreview = `-- name: GetBatchDeletePreview :one
+SELECT
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND r.has_snapshot)::int AS jobs_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND NOT r.has_snapshot AND r.created)::int AS jobs_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND NOT r.has_snapshot AND NOT r.created)::int AS jobs_kept,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND r.has_snapshot)::int AS invoices_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND NOT r.has_snapshot AND r.created)::int AS invoices_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND NOT r.has_snapshot AND NOT r.created)::int AS invoices_kept,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND r.has_snapshot)::int AS estimates_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND NOT r.has_snapshot AND r.created)::int AS estimates_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND NOT r.has_snapshot AND NOT r.created)::int AS estimates_kept
+FROM (
+    SELECT 'jobs' AS record_type, j.import_batch_id = $1::bigint AS created,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = $1::bigint AND s.record_type = 'jobs' AND s.record_id = j.id
+        ) AS has_snapshot
+    FROM jobs j
+    WHERE j.last_import_batch_id = $1::bigint
+    UNION ALL
+    SELECT 'invoices', i.import_batch_id = $1::bigint,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = $1::bigint AND s.record_type = 'invoices' AND s.record_id = i.id
+        )
+    FROM invoices i
+    WHERE i.last_import_batch_id = $1::bigint
+    UNION ALL
+    SELECT 'estimates', e.import_batch_id = $1::bigint,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = $1::bigint AND s.record_type = 'estimates' AND s.record_id = e.id
+        )
+    FROM estimates e
+    WHERE e.last_import_batch_id = $1::bigint
+) r
+`
+
+type GetBatchDeletePreviewRow struct {
+	JobsRestored      int32 `json:"jobs_restored"`
+	JobsDeleted       int32 `json:"jobs_deleted"`
+	JobsKept          int32 `json:"jobs_kept"`
+	InvoicesRestored  int32 `json:"invoices_restored"`
+	InvoicesDeleted   int32 `json:"invoices_deleted"`
+	InvoicesKept      int32 `json:"invoices_kept"`
+	EstimatesRestored int32 `json:"estimates_restored"`
+	EstimatesDeleted  int32 `json:"estimates_deleted"`
+	EstimatesKept     int32 `json:"estimates_kept"`
+}
+
+// Counts what deleting a batch would do to the rows it last changed: rows
+// with a snapshot are restored, rows it created are deleted, and rows
+// overwritten before snapshots existed are kept as they are.
+func (q *Queries) GetBatchDeletePreview(ctx context.Context, importBatchID int64) (GetBatchDeletePreviewRow, error) {
+	row := q.db.QueryRowContext(ctx, getBatchDeletePreview, importBatchID)
+	var i GetBatchDeletePreviewRow
+	err := row.Scan(
+		&i.JobsRestored,
+		&i.JobsDeleted,
+		&i.JobsKept,
+		&i.InvoicesRestored,
+		&i.InvoicesDeleted,
+		&i.InvoicesKept,
+		&i.EstimatesRestored,
+		&i.EstimatesDeleted,
+		&i.EstimatesKept,
+	)
+	return i, err
+}
+
+const getImportBatch = `-- name: GetImportBatch :one
+SELECT id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at, failed_step, failed_row, estimate_report_filename, estimate_report_hash, row_count_estimates FROM import_batches WHERE id = $1
+`
+
+func (q *Queries) GetImportBatch(ctx context.Context, id int64) (ImportBatch, error) {
+	row := q.db.QueryRowContext(ctx, getImportBatch, id)
+	var i ImportBatch
+	err := row.Scan(
+		&i.ID,
+		&i.JobReportFilename,
+		&i.InvoiceReportFilename,
+		&i.JobReportHash,
+		&i.InvoiceReportHash,
+		&i.ImportedAt,
+		&i.RowCountJobs,
+		&i.RowCountInvoices,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.FailedStep,
+		&i.FailedRow,
+		&i.EstimateReportFilename,
+		&i.EstimateReportHash,
+		&i.RowCountEstimates,
 	)
 	return i, err
 }
 
 const getImportBatchByHashes = `-- name: GetImportBatchByHashes :one
-SELECT id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at FROM import_batches
+SELECT id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at, failed_step, failed_row, estimate_report_filename, estimate_report_hash, row_count_estimates FROM import_batches
 WHERE job_report_hash = $1 AND invoice_report_hash = $2
+AND estimate_report_hash IS NOT DISTINCT FROM $3
+AND status <> 'failed'
 LIMIT 1
 `
 
 type GetImportBatchByHashesParams struct {
-	JobReportHash     string `json:"job_report_hash"`
-	InvoiceReportHash string `json:"invoice_report_hash"`
+	JobReportHash      string         `json:"job_report_hash"`
+	InvoiceReportHash  string         `json:"invoice_report_hash"`
+	EstimateReportHash sql.NullString `json:"estimate_report_hash"`
 }
 
 func (q *Queries) GetImportBatchByHashes(ctx context.Context, arg GetImportBatchByHashesParams) (ImportBatch, error) {
-	row := q.db.QueryRowContext(ctx, getImportBatchByHashes, arg.JobReportHash, arg.InvoiceReportHash)
+	row := q.db.QueryRowContext(ctx, getImportBatchByHashes, arg.JobReportHash, arg.InvoiceReportHash, arg.EstimateReportHash)
 	var i ImportBatch
 	err := row.Scan(
 		&i.ID,
@@ -86,12 +254,17 @@ func (q *Queries) GetImportBatchByHashes(ctx context.Context, arg GetImportBatch
 		&i.Status,
 		&i.ErrorMessage,
 		&i.CreatedAt,
+		&i.FailedStep,
+		&i.FailedRow,
+		&i.EstimateReportFilename,
+		&i.EstimateReportHash,
+		&i.RowCountEstimates,
 	)
 	return i, err
 }
 
 const listImportBatches = `-- name: ListImportBatches :many
-SELECT id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at FROM import_batches
+SELECT id, job_report_filename, invoice_report_filename, job_report_hash, invoice_report_hash, imported_at, row_count_jobs, row_count_invoices, status, error_message, created_at, failed_step, failed_row, estimate_report_filename, estimate_report_hash, row_count_estimates FROM import_batches
 ORDER BY imported_at DESC
 LIMIT $1
 `
@@ -117,6 +290,11 @@ func (q *Queries) ListImportBatches(ctx context.Context, limit int32) ([]ImportB
 			&i.Status,
 			&i.ErrorMessage,
 			&i.CreatedAt,
+			&i.FailedStep,
+			&i.FailedRow,
+			&i.EstimateReportFilename,
+			&i.EstimateReportHash,
+			&i.RowCountEstimates,
 		); err != nil {
 			return nil, err
 		}
@@ -131,6 +309,29 @@ func (q *Queries) ListImportBatches(ctx context.Context, limit int32) ([]ImportB
 	return items, nil
 }
 
+const updateImportBatchRowCounts = `-- name: UpdateImportBatchRowCounts :exec
+UPDATE import_batches
+SET row_count_jobs = $2, row_count_invoices = $3, row_count_estimates = $4
+WHERE id = $1
+`
+
+type UpdateImportBatchRowCountsParams struct {
+	ID                int64 `json:"id"`
+	RowCountJobs      int32 `json:"row_count_jobs"`
+	RowCountInvoices  int32 `json:"row_count_invoices"`
+	RowCountEstimates int32 `json:"row_count_estimates"`
+}
+
+func (q *Queries) UpdateImportBatchRowCounts(ctx context.Context, arg UpdateImportBatchRowCountsParams) error {
+	_, err := q.db.ExecContext(ctx, updateImportBatchRowCounts,
+		arg.ID,
+		arg.RowCountJobs,
+		arg.RowCountInvoices,
+		arg.RowCountEstimates,
+	)
+	return err
+}
+
 const updateImportBatchStatus = `-- name: UpdateImportBatchStatus :exec
 UPDATE import_batches
 SET status = $2, error_message = $3
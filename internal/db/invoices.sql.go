@@ -13,114 +13,52 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-const createInvoice = `-- name: CreateInvoice :one
-INSERT INTO invoices (
-    id, job_id, import_batch_id,
-    invoice_date, invoice_status, invoice_type, invoice_summary,
-    total, balance, payments,
-    material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total,
-    material_retail, material_markup, equipment_retail, equipment_markup,
-    labor, labor_pay, labor_burden, total_labor_costs,
-    income, discount_total, is_adjustment
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
-)
-RETURNING id, job_id, import_batch_id, invoice_date, invoice_status, invoice_type, invoice_summary, total, balance, payments, material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total, material_retail, material_markup, equipment_retail, equipment_markup, labor, labor_pay, labor_burden, total_labor_costs, income, discount_total, is_adjustment, created_at
+const deleteInvoicesCreatedByBatch = `-- name: DeleteInvoicesCreatedByBatch :execrows
+DELETE FROM invoices
+WHERE import_batch_id = $1 AND last_import_batch_id = $1
 `
 
-type CreateInvoiceParams struct {
-	ID                 string          `json:"id"`
-	JobID              string          `json:"job_id"`
-	ImportBatchID      int64           `json:"import_batch_id"`
-	InvoiceDate        time.Time       `json:"invoice_date"`
-	InvoiceStatus      sql.NullString  `json:"invoice_status"`
-	InvoiceType        sql.NullString  `json:"invoice_type"`
-	InvoiceSummary     sql.NullString  `json:"invoice_summary"`
-	Total              decimal.Decimal `json:"total"`
-	Balance            decimal.Decimal `json:"balance"`
-	Payments           decimal.Decimal `json:"payments"`
-	MaterialCosts      decimal.Decimal `json:"material_costs"`
-	EquipmentCosts     decimal.Decimal `json:"equipment_costs"`
-	PurchaseOrderCosts decimal.Decimal `json:"purchase_order_costs"`
-	ReturnCosts        decimal.Decimal `json:"return_costs"`
-	CostsTotal         decimal.Decimal `json:"costs_total"`
-	MaterialRetail     decimal.Decimal `json:"material_retail"`
-	MaterialMarkup     decimal.Decimal `json:"material_markup"`
-	EquipmentRetail    decimal.Decimal `json:"equipment_retail"`
-	EquipmentMarkup    decimal.Decimal `json:"equipment_markup"`
-	Labor              decimal.Decimal `json:"labor"`
-	LaborPay           decimal.Decimal `json:"labor_pay"`
-	LaborBurden        decimal.Decimal `json:"labor_burden"`
-	TotalLaborCosts    decimal.Decimal `json:"total_labor_costs"`
-	Income             decimal.Decimal `json:"income"`
-	DiscountTotal      decimal.Decimal `json:"discount_total"`
-	IsAdjustment       bool            `json:"is_adjustment"`
+func (q *Queries) DeleteInvoicesCreatedByBatch(ctx context.Context, importBatchID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteInvoicesCreatedByBatch, importBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (q *Queries) CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error) {
-	row := q.db.QueryRowContext(ctx, createInvoice,
-		arg.ID,
-		arg.JobID,
-		arg.ImportBatchID,
-		arg.InvoiceDate,
-		arg.InvoiceStatus,
-		arg.InvoiceType,
-		arg.InvoiceSummary,
-		arg.Total,
-		arg.Balance,
-		arg.Payments,
-		arg.MaterialCosts,
-		arg.EquipmentCosts,
-		arg.PurchaseOrderCosts,
-		arg.ReturnCosts,
-		arg.CostsTotal,
-		arg.MaterialRetail,
-		arg.MaterialMarkup,
-		arg.EquipmentRetail,
-		arg.EquipmentMarkup,
-		arg.Labor,
-		arg.LaborPay,
-		arg.LaborBurden,
-		arg.TotalLaborCosts,
-		arg.Income,
-		arg.DiscountTotal,
-		arg.IsAdjustment,
-	)
-	var i Invoice
-	err := row.Scan(
-		&i.ID,
-		&i.JobID,
-		&i.ImportBatchID,
-		&i.InvoiceDate,
-		&i.InvoiceStatus,
-		&i.InvoiceType,
-		&i.InvoiceSummary,
-		&i.Total,
-		&i.Balance,
-		&i.Payments,
-		&i.MaterialCosts,
-		&i.EquipmentCosts,
-		&i.PurchaseOrderCosts,
-		&i.ReturnCosts,
-		&i.CostsTotal,
-		&i.MaterialRetail,
-		&i.MaterialMarkup,
-		&i.EquipmentRetail,
-		&i.EquipmentMarkup,
-		&i.Labor,
-		&i.LaborPay,
-		&i.LaborBurden,
-		&i.TotalLaborCosts,
-		&i.Income,
-		&i.DiscountTotal,
-		&i.IsAdjustment,
-		&i.CreatedAt,
-	)
-	return i, err
+const getInvoiceJobIDsForBatch = `-- name: GetInvoiceJobIDsForBatch :many
+SELECT job_id FROM invoices WHERE last_import_batch_id = $1
+UNION
+SELECT previous->>'job_id' FROM batch_snapshots
+WHERE import_batch_id = $1 AND record_type = 'invoices'
+`
+
+// Jobs of invoices last changed by a batch, before and after the change.
+func (q *Queries) GetInvoiceJobIDsForBatch(ctx context.Context, lastImportBatchID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getInvoiceJobIDsForBatch, lastImportBatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var jobID string
+		if err := rows.Scan(&jobID); err != nil {
+			return nil, err
+		}
+		items = append(items, jobID)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getInvoicesForJob = `-- name: GetInvoicesForJob :many
-SELECT id, job_id, import_batch_id, invoice_date, invoice_status, invoice_type, invoice_summary, total, balance, payments, material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total, material_retail, material_markup, equipment_retail, equipment_markup, labor, labor_pay, labor_burden, total_labor_costs, income, discount_total, is_adjustment, created_at FROM invoices
+SELECT id, job_id, import_batch_id, invoice_date, invoice_status, invoice_type, invoice_summary, total, balance, payments, material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total, material_retail, material_markup, equipment_retail, equipment_markup, labor, labor_pay, labor_burden, total_labor_costs, income, discount_total, is_adjustment, created_at, last_import_batch_id, updated_at, customer_id, location_id, project_number, business_unit_id, payment_types, payment_term, pricebook_price, is_dispatch_service_fee_only, is_prevailing_wage FROM invoices
 WHERE job_id = $1
 ORDER BY invoice_date DESC
 `
@@ -162,6 +100,17 @@ func (q *Queries) GetInvoicesForJob(ctx context.Context, jobID string) ([]Invoic
 			&i.DiscountTotal,
 			&i.IsAdjustment,
 			&i.CreatedAt,
+			&i.LastImportBatchID,
+			&i.UpdatedAt,
+			&i.CustomerID,
+			&i.LocationID,
+			&i.ProjectNumber,
+			&i.BusinessUnitID,
+			&i.PaymentTypes,
+			&i.PaymentTerm,
+			&i.PricebookPrice,
+			&i.IsDispatchServiceFeeOnly,
+			&i.IsPrevailingWage,
 		); err != nil {
 			return nil, err
 		}
@@ -175,3 +124,185 @@ func (q *Queries) GetInvoicesForJob(ctx context.Context, jobID string) ([]Invoic
 	}
 	return items, nil
 }
+
+const reassignInvoicesImportBatch = `-- name: ReassignInvoicesImportBatch :exec
+UPDATE invoices SET import_batch_id = last_import_batch_id
+WHERE import_batch_id = $1
+`
+
+// Invoices first imported by a batch but changed since count as imported
+// by the batch that last changed them.
+func (q *Queries) ReassignInvoicesImportBatch(ctx context.Context, importBatchID int64) error {
+	_, err := q.db.ExecContext(ctx, reassignInvoicesImportBatch, importBatchID)
+	return err
+}
+
+const releaseInvoicesFromBatch = `-- name: ReleaseInvoicesFromBatch :execrows
+UPDATE invoices SET last_import_batch_id = import_batch_id
+WHERE last_import_batch_id = $1 AND import_batch_id <> $1
+`
+
+// Invoices still marked as changed by a batch after restoring snapshots were
+// overwritten before snapshots existed; they keep their current values.
+func (q *Queries) ReleaseInvoicesFromBatch(ctx context.Context, lastImportBatchID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, releaseInvoicesFromBatch, lastImportBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertInvoice = `-- name: UpsertInvoice :one
+INSERT INTO invoices (
+    id, job_id, import_batch_id, last_import_batch_id,
+    invoice_date, invoice_status, invoice_type, invoice_summary,
+    total, balance, payments,
+    material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total,
+    material_retail, material_markup, equipment_retail, equipment_markup,
+    labor, labor_pay, labor_burden, total_labor_costs,
+    income, discount_total, is_adjustment,
+    customer_id, location_id, project_number, business_unit_id, payment_types, payment_term,
+    pricebook_price, is_dispatch_service_fee_only, is_prevailing_wage, job_type
+) VALUES (
+    $1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26,
+    $27, $28, $29, $30, $31, $32, $33, $34, $35, $36
+)
+ON CONFLICT (id) DO UPDATE SET
+    job_id = EXCLUDED.job_id,
+    invoice_date = EXCLUDED.invoice_date,
+    invoice_status = EXCLUDED.invoice_status,
+    invoice_type = EXCLUDED.invoice_type,
+    invoice_summary = EXCLUDED.invoice_summary,
+    total = EXCLUDED.total,
+    balance = EXCLUDED.balance,
+    payments = EXCLUDED.payments,
+    material_costs = EXCLUDED.material_costs,
+    equipment_costs = EXCLUDED.equipment_costs,
+    purchase_order_costs = EXCLUDED.purchase_order_costs,
+    return_costs = EXCLUDED.return_costs,
+    costs_total = EXCLUDED.costs_total,
+    material_retail = EXCLUDED.material_retail,
+    material_markup = EXCLUDED.material_markup,
+    equipment_retail = EXCLUDED.equipment_retail,
+    equipment_markup = EXCLUDED.equipment_markup,
+    labor = EXCLUDED.labor,
+    labor_pay = EXCLUDED.labor_pay,
+    labor_burden = EXCLUDED.labor_burden,
+    total_labor_costs = EXCLUDED.total_labor_costs,
+    income = EXCLUDED.income,
+    discount_total = EXCLUDED.discount_total,
+    is_adjustment = EXCLUDED.is_adjustment,
+    customer_id = EXCLUDED.customer_id,
+    location_id = EXCLUDED.location_id,
+    project_number = EXCLUDED.project_number,
+    business_unit_id = EXCLUDED.business_unit_id,
+    payment_types = EXCLUDED.payment_types,
+    payment_term = EXCLUDED.payment_term,
+    pricebook_price = EXCLUDED.pricebook_price,
+    is_dispatch_service_fee_only = EXCLUDED.is_dispatch_service_fee_only,
+    is_prevailing_wage = EXCLUDED.is_prevailing_wage,
+    job_type = EXCLUDED.job_type,
+    last_import_batch_id = EXCLUDED.last_import_batch_id,
+    updated_at = NOW()
+WHERE (
+    invoices.job_id, invoices.invoice_date, invoices.invoice_status, invoices.invoice_type, invoices.invoice_summary, invoices.total,
+    invoices.balance, invoices.payments, invoices.material_costs, invoices.equipment_costs, invoices.purchase_order_costs, invoices.return_costs,
+    invoices.costs_total, invoices.material_retail, invoices.material_markup, invoices.equipment_retail, invoices.equipment_markup, invoices.labor,
+    invoices.labor_pay, invoices.labor_burden, invoices.total_labor_costs, invoices.income, invoices.discount_total, invoices.is_adjustment,
+    invoices.customer_id, invoices.location_id, invoices.project_number, invoices.business_unit_id, invoices.payment_types, invoices.payment_term,
+    invoices.pricebook_price, invoices.is_dispatch_service_fee_only, invoices.is_prevailing_wage, invoices.job_type
+) IS DISTINCT FROM (
+    EXCLUDED.job_id, EXCLUDED.invoice_date, EXCLUDED.invoice_status, EXCLUDED.invoice_type, EXCLUDED.invoice_summary, EXCLUDED.total,
+    EXCLUDED.balance, EXCLUDED.payments, EXCLUDED.material_costs, EXCLUDED.equipment_costs, EXCLUDED.purchase_order_costs, EXCLUDED.return_costs,
+    EXCLUDED.costs_total, EXCLUDED.material_retail, EXCLUDED.material_markup, EXCLUDED.equipment_retail, EXCLUDED.equipment_markup, EXCLUDED.labor,
+    EXCLUDED.labor_pay, EXCLUDED.labor_burden, EXCLUDED.total_labor_costs, EXCLUDED.income, EXCLUDED.discount_total, EXCLUDED.is_adjustment,
+    EXCLUDED.customer_id, EXCLUDED.location_id, EXCLUDED.project_number, EXCLUDED.business_unit_id, EXCLUDED.payment_types, EXCLUDED.payment_term,
+    EXCLUDED.pricebook_price, EXCLUDED.is_dispatch_service_fee_only, EXCLUDED.is_prevailing_wage, EXCLUDED.job_type
+)
+RETURNING (xmax = 0)::boolean AS inserted
+`
+
+type UpsertInvoiceParams struct {
+	ID                       string          `json:"id"`
+	JobID                    string          `json:"job_id"`
+	ImportBatchID            int64           `json:"import_batch_id"`
+	InvoiceDate              time.Time       `json:"invoice_date"`
+	InvoiceStatus            sql.NullString  `json:"invoice_status"`
+	InvoiceType              sql.NullString  `json:"invoice_type"`
+	InvoiceSummary           sql.NullString  `json:"invoice_summary"`
+	Total                    decimal.Decimal `json:"total"`
+	Balance                  decimal.Decimal `json:"balance"`
+	Payments                 decimal.Decimal `json:"payments"`
+	MaterialCosts            decimal.Decimal `json:"material_costs"`
+	EquipmentCosts           decimal.Decimal `json:"equipment_costs"`
+	PurchaseOrderCosts       decimal.Decimal `json:"purchase_order_costs"`
+	ReturnCosts              decimal.Decimal `json:"return_costs"`
+	CostsTotal               decimal.Decimal `json:"costs_total"`
+	MaterialRetail           decimal.Decimal `json:"material_retail"`
+	MaterialMarkup           decimal.Decimal `json:"material_markup"`
+	EquipmentRetail          decimal.Decimal `json:"equipment_retail"`
+	EquipmentMarkup          decimal.Decimal `json:"equipment_markup"`
+	Labor                    decimal.Decimal `json:"labor"`
+	LaborPay                 decimal.Decimal `json:"labor_pay"`
+	LaborBurden              decimal.Decimal `json:"labor_burden"`
+	TotalLaborCosts          decimal.Decimal `json:"total_labor_costs"`
+	Income                   decimal.Decimal `json:"income"`
+	DiscountTotal            decimal.Decimal `json:"discount_total"`
+	IsAdjustment             bool            `json:"is_adjustment"`
+	CustomerID               sql.NullInt64   `json:"customer_id"`
+	LocationID               sql.NullInt64   `json:"location_id"`
+	ProjectNumber            sql.NullInt64   `json:"project_number"`
+	BusinessUnitID           sql.NullInt64   `json:"business_unit_id"`
+	PaymentTypes             sql.NullString  `json:"payment_types"`
+	PaymentTerm              sql.NullString  `json:"payment_term"`
+	PricebookPrice           decimal.Decimal `json:"pricebook_price"`
+	IsDispatchServiceFeeOnly bool            `json:"is_dispatch_service_fee_only"`
+	IsPrevailingWage         bool            `json:"is_prevailing_wage"`
+	JobType                  sql.NullString  `json:"job_type"`
+}
+
+// Inserts a new invoice or updates an existing one from an overlapping export.
+// Rows whose values haven't changed are left alone and return no row.
+func (q *Queries) UpsertInvoice(ctx context.Context, arg UpsertInvoiceParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, upsertInvoice,
+		arg.ID,
+		arg.JobID,
+		arg.ImportBatchID,
+		arg.InvoiceDate,
+		arg.InvoiceStatus,
+		arg.InvoiceType,
+		arg.InvoiceSummary,
+		arg.Total,
+		arg.Balance,
+		arg.Payments,
+		arg.MaterialCosts,
+		arg.EquipmentCosts,
+		arg.PurchaseOrderCosts,
+		arg.ReturnCosts,
+		arg.CostsTotal,
+		arg.MaterialRetail,
+		arg.MaterialMarkup,
+		arg.EquipmentRetail,
+		arg.EquipmentMarkup,
+		arg.Labor,
+		arg.LaborPay,
+		arg.LaborBurden,
+		arg.TotalLaborCosts,
+		arg.Income,
+		arg.DiscountTotal,
+		arg.IsAdjustment,
+		arg.CustomerID,
+		arg.LocationID,
+		arg.ProjectNumber,
+		arg.BusinessUnitID,
+		arg.PaymentTypes,
+		arg.PaymentTerm,
+		arg.PricebookPrice,
+		arg.IsDispatchServiceFeeOnly,
+		arg.IsPrevailingWage,
+		arg.JobType,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
+}
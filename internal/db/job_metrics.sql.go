@@ -7,8 +7,20 @@ package db
 
 import (
 	"context"
+
+	"github.com/lib/pq"
 )
 
+const deleteJobMetricsForJobs = `-- name: DeleteJobMetricsForJobs :exec
+DELETE FROM job_metrics
+WHERE job_id = ANY($1::text[])
+`
+
+func (q *Queries) DeleteJobMetricsForJobs(ctx context.Context, jobIds []string) error {
+	_, err := q.db.ExecContext(ctx, deleteJobMetricsForJobs, pq.Array(jobIds))
+	return err
+}
+
 const getProfitByJobType = `-- name: GetProfitByJobType :many
 
 SELECT 
@@ -9,114 +9,153 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
-const createJob = `-- name: CreateJob :one
-INSERT INTO jobs (
-    id, customer_id, import_batch_id,
-    job_type, business_unit, status,
-    job_creation_date, job_schedule_date, job_completion_date,
-    assigned_technician, sold_by_technician, booked_by,
-    campaign_name, campaign_category, call_campaign,
-    jobs_subtotal, job_total, estimate_sales_subtotal,
-    invoice_id, total_hours_worked, priority, survey_score,
-    estimate_count, is_opportunity, is_converted, primary_technician
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
-)
-RETURNING id, customer_id, import_batch_id, job_type, business_unit, status, job_creation_date, job_schedule_date, job_completion_date, assigned_technician, sold_by_technician, booked_by, campaign_name, campaign_category, call_campaign, jobs_subtotal, job_total, invoice_id, total_hours_worked, priority, survey_score, created_at, estimate_count, is_opportunity, is_converted, primary_technician, estimate_sales_subtotal
+const countInvoicesBlockingBatchDelete = `-- name: CountInvoicesBlockingBatchDelete :one
+SELECT COUNT(*) FROM invoices i
+JOIN jobs j ON j.id = i.job_id
+WHERE j.last_import_batch_id = $1
+AND j.import_batch_id = $1
+AND i.last_import_batch_id <> $1
 `
 
-type CreateJobParams struct {
-	ID                    string          `json:"id"`
-	CustomerID            int64           `json:"customer_id"`
-	ImportBatchID         int64           `json:"import_batch_id"`
-	JobType               string          `json:"job_type"`
-	BusinessUnit          sql.NullString  `json:"business_unit"`
-	Status                string          `json:"status"`
-	JobCreationDate       sql.NullTime    `json:"job_creation_date"`
-	JobScheduleDate       sql.NullTime    `json:"job_schedule_date"`
-	JobCompletionDate     sql.NullTime    `json:"job_completion_date"`
-	AssignedTechnician    sql.NullString  `json:"assigned_technician"`
-	SoldByTechnician      sql.NullString  `json:"sold_by_technician"`
-	BookedBy              sql.NullString  `json:"booked_by"`
-	CampaignName          sql.NullString  `json:"campaign_name"`
-	CampaignCategory      sql.NullString  `json:"campaign_category"`
-	CallCampaign          sql.NullString  `json:"call_campaign"`
-	JobsSubtotal          decimal.Decimal `json:"jobs_subtotal"`
-	JobTotal              decimal.Decimal `json:"job_total"`
-	EstimateSalesSubtotal decimal.Decimal `json:"estimate_sales_subtotal"`
-	InvoiceID             sql.NullString  `json:"invoice_id"`
-	TotalHoursWorked      decimal.Decimal `json:"total_hours_worked"`
-	Priority              sql.NullString  `json:"priority"`
-	SurveyScore           sql.NullInt32   `json:"survey_score"`
-	EstimateCount         sql.NullInt32   `json:"estimate_count"`
-	IsOpportunity         bool            `json:"is_opportunity"`
-	IsConverted           bool            `json:"is_converted"`
-	PrimaryTechnician     sql.NullString  `json:"primary_technician"`
+// Invoices from other batches attached to jobs that deleting the batch would remove.
+func (q *Queries) CountInvoicesBlockingBatchDelete(ctx context.Context, lastImportBatchID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countInvoicesBlockingBatchDelete, lastImportBatchID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
-func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
-	row := q.db.QueryRowContext(ctx, createJob,
-		arg.ID,
-		arg.CustomerID,
-		arg.ImportBatchID,
-		arg.JobType,
-		arg.BusinessUnit,
-		arg.Status,
-		arg.JobCreationDate,
-		arg.JobScheduleDate,
-		arg.JobCompletionDate,
-		arg.AssignedTechnician,
-		arg.SoldByTechnician,
-		arg.BookedBy,
-		arg.CampaignName,
-		arg.CampaignCategory,
-		arg.CallCampaign,
-		arg.JobsSubtotal,
-		arg.JobTotal,
-		arg.EstimateSalesSubtotal,
-		arg.InvoiceID,
-		arg.TotalHoursWorked,
-		arg.Priority,
-		arg.SurveyScore,
-		arg.EstimateCount,
-		arg.IsOpportunity,
-		arg.IsConverted,
-		arg.PrimaryTechnician,
-	)
-	var i Job
-	err := row.Scan(
-		&i.ID,
-		&i.CustomerID,
-		&i.ImportBatchID,
-		&i.JobType,
-		&i.BusinessUnit,
-		&i.Status,
-		&i.JobCreationDate,
-		&i.JobScheduleDate,
-		&i.JobCompletionDate,
-		&i.AssignedTechnician,
-		&i.SoldByTechnician,
-		&i.BookedBy,
-		&i.CampaignName,
-		&i.CampaignCategory,
-		&i.CallCampaign,
-		&i.JobsSubtotal,
-		&i.JobTotal,
-		&i.InvoiceID,
-		&i.TotalHoursWorked,
-		&i.Priority,
-		&i.SurveyScore,
-		&i.CreatedAt,
-		&i.EstimateCount,
-		&i.IsOpportunity,
-		&i.IsConverted,
-		&i.PrimaryTechnician,
-		&i.EstimateSalesSubtotal,
-	)
-	return i, err
+const deleteJobsCreatedByBatch = `-- name: DeleteJobsCreatedByBatch :execrows
+DELETE FROM jobs
+WHERE import_batch_id = $1 AND last_import_batch_id = $1
+`
+
+// Deletes jobs a batch created and no later batch changed. Job metrics and
+// technician links cascade.
+func (q *Queries) DeleteJobsCreatedByBatch(ctx context.Context, importBatchID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteJobsCreatedByBatch, importBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllJobIDs = `-- name: GetAllJobIDs :many
+SELECT id FROM jobs
+`
+
+func (q *Queries) GetAllJobIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getAllJobIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomerIDsForBatch = `-- name: GetCustomerIDsForBatch :many
+SELECT customer_id FROM jobs WHERE last_import_batch_id = $1
+UNION
+SELECT (previous->>'customer_id')::bigint FROM batch_snapshots
+WHERE import_batch_id = $1 AND record_type = 'jobs'
+`
+
+// Customers of jobs last changed by a batch, before and after the change.
+func (q *Queries) GetCustomerIDsForBatch(ctx context.Context, lastImportBatchID int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getCustomerIDsForBatch, lastImportBatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var customerID int64
+		if err := rows.Scan(&customerID); err != nil {
+			return nil, err
+		}
+		items = append(items, customerID)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingJobIDs = `-- name: GetExistingJobIDs :many
+SELECT id FROM jobs
+WHERE id = ANY($1::text[])
+`
+
+func (q *Queries) GetExistingJobIDs(ctx context.Context, jobIds []string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getExistingJobIDs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getJobIDsForBatch = `-- name: GetJobIDsForBatch :many
+SELECT id FROM jobs WHERE last_import_batch_id = $1
+`
+
+// Jobs last inserted or changed by a batch.
+func (q *Queries) GetJobIDsForBatch(ctx context.Context, lastImportBatchID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getJobIDsForBatch, lastImportBatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getJobsForTechnicianProcessing = `-- name: GetJobsForTechnicianProcessing :many
@@ -167,30 +206,41 @@ func (q *Queries) GetJobsForTechnicianProcessing(ctx context.Context, importBatc
 	return items, nil
 }
 
-const getJobsWithoutInvoices = `-- name: GetJobsWithoutInvoices :many
-SELECT j.id, j.job_type, j.customer_id
-FROM jobs j
-LEFT JOIN invoices i ON j.id = i.job_id
-WHERE i.id IS NULL
-AND j.import_batch_id = $1
+const getJobsForTechnicianProcessingByIDs = `-- name: GetJobsForTechnicianProcessingByIDs :many
+SELECT 
+    id, 
+    assigned_technician, 
+    sold_by_technician, 
+    primary_technician,
+    job_completion_date
+FROM jobs
+WHERE id = ANY($1::text[])
 `
 
-type GetJobsWithoutInvoicesRow struct {
-	ID         string `json:"id"`
-	JobType    string `json:"job_type"`
-	CustomerID int64  `json:"customer_id"`
+type GetJobsForTechnicianProcessingByIDsRow struct {
+	ID                 string         `json:"id"`
+	AssignedTechnician sql.NullString `json:"assigned_technician"`
+	SoldByTechnician   sql.NullString `json:"sold_by_technician"`
+	PrimaryTechnician  sql.NullString `json:"primary_technician"`
+	JobCompletionDate  sql.NullTime   `json:"job_completion_date"`
 }
 
-func (q *Queries) GetJobsWithoutInvoices(ctx context.Context, importBatchID int64) ([]GetJobsWithoutInvoicesRow, error) {
-	rows, err := q.db.QueryContext(ctx, getJobsWithoutInvoices, importBatchID)
+func (q *Queries) GetJobsForTechnicianProcessingByIDs(ctx context.Context, jobIds []string) ([]GetJobsForTechnicianProcessingByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getJobsForTechnicianProcessingByIDs, pq.Array(jobIds))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetJobsWithoutInvoicesRow{}
+	items := []GetJobsForTechnicianProcessingByIDsRow{}
 	for rows.Next() {
-		var i GetJobsWithoutInvoicesRow
-		if err := rows.Scan(&i.ID, &i.JobType, &i.CustomerID); err != nil {
+		var i GetJobsForTechnicianProcessingByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AssignedTechnician,
+			&i.SoldByTechnician,
+			&i.PrimaryTechnician,
+			&i.JobCompletionDate,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -203,3 +253,189 @@ func (q *Queries) GetJobsWithoutInvoices(ctx context.Context, importBatchID int6
 	}
 	return items, nil
 }
+
+const reassignJobsImportBatch = `-- name: ReassignJobsImportBatch :exec
+UPDATE jobs SET import_batch_id = last_import_batch_id
+WHERE import_batch_id = $1
+`
+
+// Jobs first imported by a batch but changed since count as imported by
+// the batch that last changed them.
+func (q *Queries) ReassignJobsImportBatch(ctx context.Context, importBatchID int64) error {
+	_, err := q.db.ExecContext(ctx, reassignJobsImportBatch, importBatchID)
+	return err
+}
+
+const releaseJobsFromBatch = `-- name: ReleaseJobsFromBatch :execrows
+UPDATE jobs SET last_import_batch_id = import_batch_id
+WHERE last_import_batch_id = $1 AND import_batch_id <> $1
+`
+
+// Jobs still marked as changed by a batch after restoring snapshots were
+// overwritten before snapshots existed; they keep their current values.
+func (q *Queries) ReleaseJobsFromBatch(ctx context.Context, lastImportBatchID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, releaseJobsFromBatch, lastImportBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertJob = `-- name: UpsertJob :one
+INSERT INTO jobs (
+    id, customer_id, import_batch_id, last_import_batch_id,
+    job_type, business_unit, status,
+    job_creation_date, job_schedule_date, job_completion_date,
+    assigned_technician, sold_by_technician, booked_by,
+    campaign_name, campaign_category, call_campaign,
+    jobs_subtotal, job_total, estimate_sales_subtotal,
+    invoice_id, total_hours_worked, priority, survey_score,
+    estimate_count, is_opportunity, is_converted, primary_technician,
+    location_id, business_unit_id, dispatched_by, summary, member_status, tags,
+    is_warranty, is_recall, is_zero_dollar
+) VALUES (
+    $1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26,
+    $27, $28, $29, $30, $31, $32, $33, $34, $35
+)
+ON CONFLICT (id) DO UPDATE SET
+    customer_id = EXCLUDED.customer_id,
+    job_type = EXCLUDED.job_type,
+    business_unit = EXCLUDED.business_unit,
+    status = EXCLUDED.status,
+    job_creation_date = EXCLUDED.job_creation_date,
+    job_schedule_date = EXCLUDED.job_schedule_date,
+    job_completion_date = EXCLUDED.job_completion_date,
+    assigned_technician = EXCLUDED.assigned_technician,
+    sold_by_technician = EXCLUDED.sold_by_technician,
+    booked_by = EXCLUDED.booked_by,
+    campaign_name = EXCLUDED.campaign_name,
+    campaign_category = EXCLUDED.campaign_category,
+    call_campaign = EXCLUDED.call_campaign,
+    jobs_subtotal = EXCLUDED.jobs_subtotal,
+    job_total = EXCLUDED.job_total,
+    estimate_sales_subtotal = EXCLUDED.estimate_sales_subtotal,
+    invoice_id = EXCLUDED.invoice_id,
+    total_hours_worked = EXCLUDED.total_hours_worked,
+    priority = EXCLUDED.priority,
+    survey_score = EXCLUDED.survey_score,
+    estimate_count = EXCLUDED.estimate_count,
+    is_opportunity = EXCLUDED.is_opportunity,
+    is_converted = EXCLUDED.is_converted,
+    primary_technician = EXCLUDED.primary_technician,
+    location_id = EXCLUDED.location_id,
+    business_unit_id = EXCLUDED.business_unit_id,
+    dispatched_by = EXCLUDED.dispatched_by,
+    summary = EXCLUDED.summary,
+    member_status = EXCLUDED.member_status,
+    tags = EXCLUDED.tags,
+    is_warranty = EXCLUDED.is_warranty,
+    is_recall = EXCLUDED.is_recall,
+    is_zero_dollar = EXCLUDED.is_zero_dollar,
+    last_import_batch_id = EXCLUDED.last_import_batch_id,
+    updated_at = NOW()
+WHERE (
+    jobs.customer_id, jobs.job_type, jobs.business_unit, jobs.status,
+    jobs.job_creation_date, jobs.job_schedule_date, jobs.job_completion_date,
+    jobs.assigned_technician, jobs.sold_by_technician, jobs.booked_by,
+    jobs.campaign_name, jobs.campaign_category, jobs.call_campaign,
+    jobs.jobs_subtotal, jobs.job_total, jobs.estimate_sales_subtotal,
+    jobs.invoice_id, jobs.total_hours_worked, jobs.priority, jobs.survey_score,
+    jobs.estimate_count, jobs.is_opportunity, jobs.is_converted, jobs.primary_technician,
+    jobs.location_id, jobs.business_unit_id, jobs.dispatched_by, jobs.summary, jobs.member_status, jobs.tags,
+    jobs.is_warranty, jobs.is_recall, jobs.is_zero_dollar
+) IS DISTINCT FROM (
+    EXCLUDED.customer_id, EXCLUDED.job_type, EXCLUDED.business_unit, EXCLUDED.status,
+    EXCLUDED.job_creation_date, EXCLUDED.job_schedule_date, EXCLUDED.job_completion_date,
+    EXCLUDED.assigned_technician, EXCLUDED.sold_by_technician, EXCLUDED.booked_by,
+    EXCLUDED.campaign_name, EXCLUDED.campaign_category, EXCLUDED.call_campaign,
+    EXCLUDED.jobs_subtotal, EXCLUDED.job_total, EXCLUDED.estimate_sales_subtotal,
+    EXCLUDED.invoice_id, EXCLUDED.total_hours_worked, EXCLUDED.priority, EXCLUDED.survey_score,
+    EXCLUDED.estimate_count, EXCLUDED.is_opportunity, EXCLUDED.is_converted, EXCLUDED.primary_technician,
+    EXCLUDED.location_id, EXCLUDED.business_unit_id, EXCLUDED.dispatched_by, EXCLUDED.summary, EXCLUDED.member_status, EXCLUDED.tags,
+    EXCLUDED.is_warranty, EXCLUDED.is_recall, EXCLUDED.is_zero_dollar
+)
+RETURNING (xmax = 0)::boolean AS inserted
+`
+
+type UpsertJobParams struct {
+	ID                    string          `json:"id"`
+	CustomerID            int64           `json:"customer_id"`
+	ImportBatchID         int64           `json:"import_batch_id"`
+	JobType               string          `json:"job_type"`
+	BusinessUnit          sql.NullString  `json:"business_unit"`
+	Status                string          `json:"status"`
+	JobCreationDate       sql.NullTime    `json:"job_creation_date"`
+	JobScheduleDate       sql.NullTime    `json:"job_schedule_date"`
+	JobCompletionDate     sql.NullTime    `json:"job_completion_date"`
+	AssignedTechnician    sql.NullString  `json:"assigned_technician"`
+	SoldByTechnician      sql.NullString  `json:"sold_by_technician"`
+	BookedBy              sql.NullString  `json:"booked_by"`
+	CampaignName          sql.NullString  `json:"campaign_name"`
+	CampaignCategory      sql.NullString  `json:"campaign_category"`
+	CallCampaign          sql.NullString  `json:"call_campaign"`
+	JobsSubtotal          decimal.Decimal `json:"jobs_subtotal"`
+	JobTotal              decimal.Decimal `json:"job_total"`
+	EstimateSalesSubtotal decimal.Decimal `json:"estimate_sales_subtotal"`
+	InvoiceID             sql.NullString  `json:"invoice_id"`
+	TotalHoursWorked      decimal.Decimal `json:"total_hours_worked"`
+	Priority              sql.NullString  `json:"priority"`
+	SurveyScore           sql.NullInt32   `json:"survey_score"`
+	EstimateCount         sql.NullInt32   `json:"estimate_count"`
+	IsOpportunity         bool            `json:"is_opportunity"`
+	IsConverted           bool            `json:"is_converted"`
+	PrimaryTechnician     sql.NullString  `json:"primary_technician"`
+	LocationID            sql.NullInt64   `json:"location_id"`
+	BusinessUnitID        sql.NullInt64   `json:"business_unit_id"`
+	DispatchedBy          sql.NullString  `json:"dispatched_by"`
+	Summary               sql.NullString  `json:"summary"`
+	MemberStatus          sql.NullString  `json:"member_status"`
+	Tags                  sql.NullString  `json:"tags"`
+	IsWarranty            bool            `json:"is_warranty"`
+	IsRecall              bool            `json:"is_recall"`
+	IsZeroDollar          bool            `json:"is_zero_dollar"`
+}
+
+// Inserts a new job or updates an existing one from an overlapping export.
+// Rows whose values haven't changed are left alone and return no row.
+func (q *Queries) UpsertJob(ctx context.Context, arg UpsertJobParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, upsertJob,
+		arg.ID,
+		arg.CustomerID,
+		arg.ImportBatchID,
+		arg.JobType,
+		arg.BusinessUnit,
+		arg.Status,
+		arg.JobCreationDate,
+		arg.JobScheduleDate,
+		arg.JobCompletionDate,
+		arg.AssignedTechnician,
+		arg.SoldByTechnician,
+		arg.BookedBy,
+		arg.CampaignName,
+		arg.CampaignCategory,
+		arg.CallCampaign,
+		arg.JobsSubtotal,
+		arg.JobTotal,
+		arg.EstimateSalesSubtotal,
+		arg.InvoiceID,
+		arg.TotalHoursWorked,
+		arg.Priority,
+		arg.SurveyScore,
+		arg.EstimateCount,
+		arg.IsOpportunity,
+		arg.IsConverted,
+		arg.PrimaryTechnician,
+		arg.LocationID,
+		arg.BusinessUnitID,
+		arg.DispatchedBy,
+		arg.Summary,
+		arg.MemberStatus,
+		arg.Tags,
+		arg.IsWarranty,
+		arg.IsRecall,
+		arg.IsZeroDollar,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
+}
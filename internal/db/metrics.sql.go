@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
@@ -136,6 +137,54 @@ func (q *Queries) GetInvoicesForMetrics(ctx context.Context, importBatchID int64
 	return items, nil
 }
 
+const getInvoicesForMetricsByJobIDs = `-- name: GetInvoicesForMetricsByJobIDs :many
+SELECT 
+    id,
+    job_id,
+    total,
+    costs_total,
+    is_adjustment
+FROM invoices
+WHERE job_id = ANY($1::text[])
+`
+
+type GetInvoicesForMetricsByJobIDsRow struct {
+	ID           string          `json:"id"`
+	JobID        string          `json:"job_id"`
+	Total        decimal.Decimal `json:"total"`
+	CostsTotal   decimal.Decimal `json:"costs_total"`
+	IsAdjustment bool            `json:"is_adjustment"`
+}
+
+func (q *Queries) GetInvoicesForMetricsByJobIDs(ctx context.Context, jobIds []string) ([]GetInvoicesForMetricsByJobIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getInvoicesForMetricsByJobIDs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetInvoicesForMetricsByJobIDsRow{}
+	for rows.Next() {
+		var i GetInvoicesForMetricsByJobIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.JobID,
+			&i.Total,
+			&i.CostsTotal,
+			&i.IsAdjustment,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getJobTechniciansForBatch = `-- name: GetJobTechniciansForBatch :many
 SELECT 
     jt.job_id,
@@ -175,6 +224,44 @@ func (q *Queries) GetJobTechniciansForBatch(ctx context.Context, importBatchID i
 	return items, nil
 }
 
+const getJobTechniciansForJobs = `-- name: GetJobTechniciansForJobs :many
+SELECT 
+    job_id,
+    technician_id,
+    role
+FROM job_technicians
+WHERE job_id = ANY($1::text[])
+`
+
+type GetJobTechniciansForJobsRow struct {
+	JobID        string `json:"job_id"`
+	TechnicianID int64  `json:"technician_id"`
+	Role         string `json:"role"`
+}
+
+func (q *Queries) GetJobTechniciansForJobs(ctx context.Context, jobIds []string) ([]GetJobTechniciansForJobsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getJobTechniciansForJobs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetJobTechniciansForJobsRow{}
+	for rows.Next() {
+		var i GetJobTechniciansForJobsRow
+		if err := rows.Scan(&i.JobID, &i.TechnicianID, &i.Role); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getJobsForMetrics = `-- name: GetJobsForMetrics :many
 
 SELECT 
@@ -215,6 +302,51 @@ func (q *Queries) GetJobsForMetrics(ctx context.Context, importBatchID int64) ([
 	return items, nil
 }
 
+const getJobsForMetricsByIDs = `-- name: GetJobsForMetricsByIDs :many
+SELECT 
+    id,
+    status,
+    jobs_subtotal,
+    total_hours_worked
+FROM jobs
+WHERE id = ANY($1::text[])
+`
+
+type GetJobsForMetricsByIDsRow struct {
+	ID               string          `json:"id"`
+	Status           string          `json:"status"`
+	JobsSubtotal     decimal.Decimal `json:"jobs_subtotal"`
+	TotalHoursWorked decimal.Decimal `json:"total_hours_worked"`
+}
+
+func (q *Queries) GetJobsForMetricsByIDs(ctx context.Context, jobIds []string) ([]GetJobsForMetricsByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getJobsForMetricsByIDs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetJobsForMetricsByIDsRow{}
+	for rows.Next() {
+		var i GetJobsForMetricsByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.JobsSubtotal,
+			&i.TotalHoursWorked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getJobsForTechnicianMetrics = `-- name: GetJobsForTechnicianMetrics :many
 SELECT 
     id,
@@ -268,3 +400,54 @@ func (q *Queries) GetJobsForTechnicianMetrics(ctx context.Context, importBatchID
 	}
 	return items, nil
 }
+
+const getJobsForTechnicianMetricsByIDs = `-- name: GetJobsForTechnicianMetricsByIDs :many
+SELECT 
+    id,
+    status,
+    jobs_subtotal,
+    estimate_sales_subtotal,
+    total_hours_worked,
+    COALESCE(estimate_count, 0) as estimate_count
+FROM jobs
+WHERE id = ANY($1::text[])
+`
+
+type GetJobsForTechnicianMetricsByIDsRow struct {
+	ID                    string          `json:"id"`
+	Status                string          `json:"status"`
+	JobsSubtotal          decimal.Decimal `json:"jobs_subtotal"`
+	EstimateSalesSubtotal decimal.Decimal `json:"estimate_sales_subtotal"`
+	TotalHoursWorked      decimal.Decimal `json:"total_hours_worked"`
+	EstimateCount         int32           `json:"estimate_count"`
+}
+
+func (q *Queries) GetJobsForTechnicianMetricsByIDs(ctx context.Context, jobIds []string) ([]GetJobsForTechnicianMetricsByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getJobsForTechnicianMetricsByIDs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetJobsForTechnicianMetricsByIDsRow{}
+	for rows.Next() {
+		var i GetJobsForTechnicianMetricsByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.JobsSubtotal,
+			&i.EstimateSalesSubtotal,
+			&i.TotalHoursWorked,
+			&i.EstimateCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
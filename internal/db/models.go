@@ -6,84 +6,169 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+type BatchSnapshot struct {
+	ImportBatchID int64           `json:"import_batch_id"`
+	RecordType    string          `json:"record_type"`
+	RecordID      string          `json:"record_id"`
+	Previous      json.RawMessage `json:"previous"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type BusinessUnit struct {
+	ID        int64          `json:"id"`
+	Name      sql.NullString `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type Campaign struct {
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	Category       sql.NullString `json:"category"`
+	Channel        sql.NullString `json:"channel"`
+	ActiveFrom     sql.NullTime   `json:"active_from"`
+	ActiveTo       sql.NullTime   `json:"active_to"`
+	SourceFilename sql.NullString `json:"source_filename"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+type CampaignSpend struct {
+	ID             int64          `json:"id"`
+	Campaign       string         `json:"campaign"`
+	Month          time.Time      `json:"month"`
+	Amount         string         `json:"amount"`
+	SourceFilename sql.NullString `json:"source_filename"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
 type Customer struct {
+	ID              int64           `json:"id"`
+	CustomerName    string          `json:"customer_name"`
+	CustomerType    sql.NullString  `json:"customer_type"`
+	CustomerCity    sql.NullString  `json:"customer_city"`
+	CustomerState   sql.NullString  `json:"customer_state"`
+	CustomerZip     sql.NullString  `json:"customer_zip"`
+	LocationCity    sql.NullString  `json:"location_city"`
+	LocationState   sql.NullString  `json:"location_state"`
+	LocationZip     sql.NullString  `json:"location_zip"`
+	FirstJobDate    sql.NullTime    `json:"first_job_date"`
+	LastJobDate     sql.NullTime    `json:"last_job_date"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	JobCount        int32           `json:"job_count"`
+	LifetimeRevenue decimal.Decimal `json:"lifetime_revenue"`
+}
+
+type DataQualityIssue struct {
 	ID            int64          `json:"id"`
-	CustomerName  string         `json:"customer_name"`
-	CustomerType  sql.NullString `json:"customer_type"`
-	CustomerCity  sql.NullString `json:"customer_city"`
-	CustomerState sql.NullString `json:"customer_state"`
-	CustomerZip   sql.NullString `json:"customer_zip"`
-	LocationCity  sql.NullString `json:"location_city"`
-	LocationState sql.NullString `json:"location_state"`
-	LocationZip   sql.NullString `json:"location_zip"`
-	FirstJobDate  sql.NullTime   `json:"first_job_date"`
-	LastJobDate   sql.NullTime   `json:"last_job_date"`
+	ImportBatchID int64          `json:"import_batch_id"`
+	CheckName     string         `json:"check_name"`
+	JobID         string         `json:"job_id"`
+	InvoiceID     sql.NullString `json:"invoice_id"`
+	Expected      sql.NullString `json:"expected"`
+	Actual        sql.NullString `json:"actual"`
 	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type Estimate struct {
+	ID                string          `json:"id"`
+	JobID             string          `json:"job_id"`
+	ImportBatchID     int64           `json:"import_batch_id"`
+	LastImportBatchID int64           `json:"last_import_batch_id"`
+	Name              sql.NullString  `json:"name"`
+	Status            string          `json:"status"`
+	Technician        sql.NullString  `json:"technician"`
+	TechnicianID      sql.NullInt64   `json:"technician_id"`
+	Subtotal          decimal.Decimal `json:"subtotal"`
+	CreatedOn         sql.NullTime    `json:"created_on"`
+	SoldOn            sql.NullTime    `json:"sold_on"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
 type ImportBatch struct {
-	ID                    int64          `json:"id"`
-	JobReportFilename     string         `json:"job_report_filename"`
-	InvoiceReportFilename string         `json:"invoice_report_filename"`
-	JobReportHash         string         `json:"job_report_hash"`
-	InvoiceReportHash     string         `json:"invoice_report_hash"`
-	ImportedAt            time.Time      `json:"imported_at"`
-	RowCountJobs          int32          `json:"row_count_jobs"`
-	RowCountInvoices      int32          `json:"row_count_invoices"`
-	Status                string         `json:"status"`
-	ErrorMessage          sql.NullString `json:"error_message"`
-	CreatedAt             time.Time      `json:"created_at"`
+	ID                     int64          `json:"id"`
+	JobReportFilename      string         `json:"job_report_filename"`
+	InvoiceReportFilename  string         `json:"invoice_report_filename"`
+	JobReportHash          string         `json:"job_report_hash"`
+	InvoiceReportHash      string         `json:"invoice_report_hash"`
+	ImportedAt             time.Time      `json:"imported_at"`
+	RowCountJobs           int32          `json:"row_count_jobs"`
+	RowCountInvoices       int32          `json:"row_count_invoices"`
+	Status                 string         `json:"status"`
+	ErrorMessage           sql.NullString `json:"error_message"`
+	CreatedAt              time.Time      `json:"created_at"`
+	FailedStep             sql.NullString `json:"failed_step"`
+	FailedRow              sql.NullInt32  `json:"failed_row"`
+	EstimateReportFilename sql.NullString `json:"estimate_report_filename"`
+	EstimateReportHash     sql.NullString `json:"estimate_report_hash"`
+	RowCountEstimates      int32          `json:"row_count_estimates"`
 }
 
 type Invoice struct {
-	ID                 string          `json:"id"`
-	JobID              string          `json:"job_id"`
-	ImportBatchID      int64           `json:"import_batch_id"`
-	InvoiceDate        time.Time       `json:"invoice_date"`
-	InvoiceStatus      sql.NullString  `json:"invoice_status"`
-	InvoiceType        sql.NullString  `json:"invoice_type"`
-	InvoiceSummary     sql.NullString  `json:"invoice_summary"`
-	Total              decimal.Decimal `json:"total"`
-	Balance            decimal.Decimal `json:"balance"`
-	Payments           decimal.Decimal `json:"payments"`
-	MaterialCosts      decimal.Decimal `json:"material_costs"`
-	EquipmentCosts     decimal.Decimal `json:"equipment_costs"`
-	PurchaseOrderCosts decimal.Decimal `json:"purchase_order_costs"`
-	ReturnCosts        decimal.Decimal `json:"return_costs"`
-	CostsTotal         decimal.Decimal `json:"costs_total"`
-	MaterialRetail     decimal.Decimal `json:"material_retail"`
-	MaterialMarkup     decimal.Decimal `json:"material_markup"`
-	EquipmentRetail    decimal.Decimal `json:"equipment_retail"`
-	EquipmentMarkup    decimal.Decimal `json:"equipment_markup"`
-	Labor              decimal.Decimal `json:"labor"`
-	LaborPay           decimal.Decimal `json:"labor_pay"`
-	LaborBurden        decimal.Decimal `json:"labor_burden"`
-	TotalLaborCosts    decimal.Decimal `json:"total_labor_costs"`
-	Income             decimal.Decimal `json:"income"`
-	DiscountTotal      decimal.Decimal `json:"discount_total"`
-	IsAdjustment       bool            `json:"is_adjustment"`
-	CreatedAt          time.Time       `json:"created_at"`
+	ID                       string          `json:"id"`
+	JobID                    string          `json:"job_id"`
+	ImportBatchID            int64           `json:"import_batch_id"`
+	InvoiceDate              time.Time       `json:"invoice_date"`
+	InvoiceStatus            sql.NullString  `json:"invoice_status"`
+	InvoiceType              sql.NullString  `json:"invoice_type"`
+	InvoiceSummary           sql.NullString  `json:"invoice_summary"`
+	Total                    decimal.Decimal `json:"total"`
+	Balance                  decimal.Decimal `json:"balance"`
+	Payments                 decimal.Decimal `json:"payments"`
+	MaterialCosts            decimal.Decimal `json:"material_costs"`
+	EquipmentCosts           decimal.Decimal `json:"equipment_costs"`
+	PurchaseOrderCosts       decimal.Decimal `json:"purchase_order_costs"`
+	ReturnCosts              decimal.Decimal `json:"return_costs"`
+	CostsTotal               decimal.Decimal `json:"costs_total"`
+	MaterialRetail           decimal.Decimal `json:"material_retail"`
+	MaterialMarkup           decimal.Decimal `json:"material_markup"`
+	EquipmentRetail          decimal.Decimal `json:"equipment_retail"`
+	EquipmentMarkup          decimal.Decimal `json:"equipment_markup"`
+	Labor                    decimal.Decimal `json:"labor"`
+	LaborPay                 decimal.Decimal `json:"labor_pay"`
+	LaborBurden              decimal.Decimal `json:"labor_burden"`
+	TotalLaborCosts          decimal.Decimal `json:"total_labor_costs"`
+	Income                   decimal.Decimal `json:"income"`
+	DiscountTotal            decimal.Decimal `json:"discount_total"`
+	IsAdjustment             bool            `json:"is_adjustment"`
+	CreatedAt                time.Time       `json:"created_at"`
+	LastImportBatchID        int64           `json:"last_import_batch_id"`
+	UpdatedAt                time.Time       `json:"updated_at"`
+	CustomerID               sql.NullInt64   `json:"customer_id"`
+	LocationID               sql.NullInt64   `json:"location_id"`
+	ProjectNumber            sql.NullInt64   `json:"project_number"`
+	BusinessUnitID           sql.NullInt64   `json:"business_unit_id"`
+	PaymentTypes             sql.NullString  `json:"payment_types"`
+	PaymentTerm              sql.NullString  `json:"payment_term"`
+	PricebookPrice           decimal.Decimal `json:"pricebook_price"`
+	IsDispatchServiceFeeOnly bool            `json:"is_dispatch_service_fee_only"`
+	IsPrevailingWage         bool            `json:"is_prevailing_wage"`
+	JobType                  sql.NullString  `json:"job_type"`
 }
 
 type Job struct {
-	ID                    string          `json:"id"`
-	CustomerID            int64           `json:"customer_id"`
-	ImportBatchID         int64           `json:"import_batch_id"`
-	JobType               string          `json:"job_type"`
-	BusinessUnit          sql.NullString  `json:"business_unit"`
-	Status                string          `json:"status"`
-	JobCreationDate       sql.NullTime    `json:"job_creation_date"`
-	JobScheduleDate       sql.NullTime    `json:"job_schedule_date"`
-	JobCompletionDate     sql.NullTime    `json:"job_completion_date"`
-	AssignedTechnician    sql.NullString  `json:"assigned_technician"`
-	SoldByTechnician      sql.NullString  `json:"sold_by_technician"`
-	BookedBy              sql.NullString  `json:"booked_by"`
+	ID                 string         `json:"id"`
+	CustomerID         int64          `json:"customer_id"`
+	ImportBatchID      int64          `json:"import_batch_id"`
+	JobType            string         `json:"job_type"`
+	BusinessUnit       sql.NullString `json:"business_unit"`
+	Status             string         `json:"status"`
+	JobCreationDate    sql.NullTime   `json:"job_creation_date"`
+	JobScheduleDate    sql.NullTime   `json:"job_schedule_date"`
+	JobCompletionDate  sql.NullTime   `json:"job_completion_date"`
+	AssignedTechnician sql.NullString `json:"assigned_technician"`
+	SoldByTechnician   sql.NullString `json:"sold_by_technician"`
+	BookedBy           sql.NullString `json:"booked_by"`
+	// ServiceTitan Job Campaign ID; the name is in campaigns
 	CampaignName          sql.NullString  `json:"campaign_name"`
 	CampaignCategory      sql.NullString  `json:"campaign_category"`
 	CallCampaign          sql.NullString  `json:"call_campaign"`
@@ -99,17 +184,34 @@ type Job struct {
 	IsConverted           bool            `json:"is_converted"`
 	PrimaryTechnician     sql.NullString  `json:"primary_technician"`
 	EstimateSalesSubtotal decimal.Decimal `json:"estimate_sales_subtotal"`
+	LastImportBatchID     int64           `json:"last_import_batch_id"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+	LocationID            sql.NullInt64   `json:"location_id"`
+	BusinessUnitID        sql.NullInt64   `json:"business_unit_id"`
+	DispatchedBy          sql.NullString  `json:"dispatched_by"`
+	Summary               sql.NullString  `json:"summary"`
+	MemberStatus          sql.NullString  `json:"member_status"`
+	Tags                  sql.NullString  `json:"tags"`
+	IsWarranty            bool            `json:"is_warranty"`
+	IsRecall              bool            `json:"is_recall"`
+	IsZeroDollar          bool            `json:"is_zero_dollar"`
 }
 
 type JobMetric struct {
-	JobID          string          `json:"job_id"`
-	Revenue        string          `json:"revenue"`
-	TotalCosts     string          `json:"total_costs"`
-	GrossProfit    string          `json:"gross_profit"`
-	GrossMarginPct decimal.Decimal `json:"gross_margin_pct"`
-	InvoiceCount   int32           `json:"invoice_count"`
-	HasAdjustment  bool            `json:"has_adjustment"`
-	CalculatedAt   time.Time       `json:"calculated_at"`
+	JobID                 string          `json:"job_id"`
+	Revenue               string          `json:"revenue"`
+	TotalCosts            string          `json:"total_costs"`
+	GrossProfit           string          `json:"gross_profit"`
+	GrossMarginPct        decimal.Decimal `json:"gross_margin_pct"`
+	InvoiceCount          int32           `json:"invoice_count"`
+	HasAdjustment         bool            `json:"has_adjustment"`
+	CalculatedAt          time.Time       `json:"calculated_at"`
+	LaborCosts            string          `json:"labor_costs"`
+	HasLabor              bool            `json:"has_labor"`
+	GrossProfitAfterLabor string          `json:"gross_profit_after_labor"`
+	MarginAfterLaborPct   decimal.Decimal `json:"margin_after_labor_pct"`
+	QualityFlags          []string        `json:"quality_flags"`
+	Reliable              bool            `json:"reliable"`
 }
 
 type JobTechnician struct {
@@ -120,13 +222,86 @@ type JobTechnician struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+type JobVersion struct {
+	ID                 int64           `json:"id"`
+	JobID              string          `json:"job_id"`
+	ValidFromBatchID   int64           `json:"valid_from_batch_id"`
+	ValidToBatchID     sql.NullInt64   `json:"valid_to_batch_id"`
+	Status             string          `json:"status"`
+	JobCompletionDate  sql.NullTime    `json:"job_completion_date"`
+	PrimaryTechnician  sql.NullString  `json:"primary_technician"`
+	AssignedTechnician sql.NullString  `json:"assigned_technician"`
+	SoldByTechnician   sql.NullString  `json:"sold_by_technician"`
+	JobsSubtotal       decimal.Decimal `json:"jobs_subtotal"`
+	Revenue            string          `json:"revenue"`
+	TotalCosts         string          `json:"total_costs"`
+	GrossProfit        string          `json:"gross_profit"`
+	GrossMarginPct     decimal.Decimal `json:"gross_margin_pct"`
+	CreatedAt          time.Time       `json:"created_at"`
+}
+
+type Location struct {
+	ID           int64          `json:"id"`
+	CustomerID   int64          `json:"customer_id"`
+	City         sql.NullString `json:"city"`
+	State        sql.NullString `json:"state"`
+	Zip          sql.NullString `json:"zip"`
+	FirstJobDate sql.NullTime   `json:"first_job_date"`
+	LastJobDate  sql.NullTime   `json:"last_job_date"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type RawInvoiceRow struct {
+	ImportBatchID int64           `json:"import_batch_id"`
+	LineNumber    int32           `json:"line_number"`
+	Record        json.RawMessage `json:"record"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type RawJobRow struct {
+	ImportBatchID int64           `json:"import_batch_id"`
+	LineNumber    int32           `json:"line_number"`
+	Record        json.RawMessage `json:"record"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type RejectedRow struct {
+	ID            int64           `json:"id"`
+	ImportBatchID int64           `json:"import_batch_id"`
+	ReportType    string          `json:"report_type"`
+	RowNumber     int32           `json:"row_number"`
+	ColumnName    string          `json:"column_name"`
+	RawValue      string          `json:"raw_value"`
+	ErrorMessage  string          `json:"error_message"`
+	RecordKey     sql.NullString  `json:"record_key"`
+	RawRecord     json.RawMessage `json:"raw_record"`
+	ResolvedAt    sql.NullTime    `json:"resolved_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
 type Technician struct {
-	ID            int64        `json:"id"`
-	Name          string       `json:"name"`
-	FirstSeenDate sql.NullTime `json:"first_seen_date"`
-	LastSeenDate  sql.NullTime `json:"last_seen_date"`
-	CreatedAt     time.Time    `json:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at"`
+	ID              int64           `json:"id"`
+	Name            string          `json:"name"`
+	FirstSeenDate   sql.NullTime    `json:"first_seen_date"`
+	LastSeenDate    sql.NullTime    `json:"last_seen_date"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Active          bool            `json:"active"`
+	EmployeeID      sql.NullString  `json:"employee_id"`
+	Team            sql.NullString  `json:"team"`
+	Manager         sql.NullString  `json:"manager"`
+	HireDate        sql.NullTime    `json:"hire_date"`
+	TerminationDate sql.NullTime    `json:"termination_date"`
+	HourlyCost      decimal.Decimal `json:"hourly_cost"`
+	RosterFilename  sql.NullString  `json:"roster_filename"`
+}
+
+type TechnicianAlias struct {
+	AliasKey     string    `json:"alias_key"`
+	TechnicianID int64     `json:"technician_id"`
+	Alias        string    `json:"alias"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type TechnicianMetric struct {
@@ -147,4 +322,31 @@ type TechnicianMetric struct {
 	AvgGrossProfit     decimal.Decimal `json:"avg_gross_profit"`
 	AvgMarginPct       decimal.Decimal `json:"avg_margin_pct"`
 	CalculatedAt       time.Time       `json:"calculated_at"`
+	EstimatesSold      int32           `json:"estimates_sold"`
+	EstimatesDismissed int32           `json:"estimates_dismissed"`
+	CloseRate          decimal.Decimal `json:"close_rate"`
+	AvgEstimateValue   decimal.Decimal `json:"avg_estimate_value"`
+	AvgDaysToSold      decimal.Decimal `json:"avg_days_to_sold"`
+}
+
+type TimesheetEntry struct {
+	ID                int64           `json:"id"`
+	TimesheetImportID int64           `json:"timesheet_import_id"`
+	JobID             string          `json:"job_id"`
+	Technician        string          `json:"technician"`
+	TechnicianID      sql.NullInt64   `json:"technician_id"`
+	WorkDate          sql.NullTime    `json:"work_date"`
+	Hours             string          `json:"hours"`
+	PayRate           decimal.Decimal `json:"pay_rate"`
+	BurdenRate        decimal.Decimal `json:"burden_rate"`
+	LaborCost         string          `json:"labor_cost"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+type TimesheetImport struct {
+	ID         int64     `json:"id"`
+	Filename   string    `json:"filename"`
+	FileHash   string    `json:"file_hash"`
+	RowCount   int32     `json:"row_count"`
+	ImportedAt time.Time `json:"imported_at"`
 }
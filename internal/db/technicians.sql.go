@@ -9,9 +9,33 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
+const copyJobTechnicians = `-- name: CopyJobTechnicians :execrows
+INSERT INTO job_technicians (job_id, technician_id, role)
+SELECT job_id, $1, role
+FROM job_technicians
+WHERE technician_id = $2
+ON CONFLICT (job_id, technician_id, role) DO NOTHING
+`
+
+type CopyJobTechniciansParams struct {
+	IntoID int64 `json:"into_id"`
+	FromID int64 `json:"from_id"`
+}
+
+// Links the merge target to every job/role the merged technician has;
+// links the target already has are skipped.
+func (q *Queries) CopyJobTechnicians(ctx context.Context, arg CopyJobTechniciansParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, copyJobTechnicians, arg.IntoID, arg.FromID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createJobTechnician = `-- name: CreateJobTechnician :exec
 INSERT INTO job_technicians (job_id, technician_id, role)
 VALUES ($1, $2, $3)
@@ -29,8 +53,92 @@ func (q *Queries) CreateJobTechnician(ctx context.Context, arg CreateJobTechnici
 	return err
 }
 
+const createTechnicianAlias = `-- name: CreateTechnicianAlias :exec
+INSERT INTO technician_aliases (alias_key, technician_id, alias)
+VALUES ($1, $2, $3)
+ON CONFLICT (alias_key) DO NOTHING
+`
+
+type CreateTechnicianAliasParams struct {
+	AliasKey     string `json:"alias_key"`
+	TechnicianID int64  `json:"technician_id"`
+	Alias        string `json:"alias"`
+}
+
+// Keeps the existing mapping when the alias is already known.
+func (q *Queries) CreateTechnicianAlias(ctx context.Context, arg CreateTechnicianAliasParams) error {
+	_, err := q.db.ExecContext(ctx, createTechnicianAlias, arg.AliasKey, arg.TechnicianID, arg.Alias)
+	return err
+}
+
+const deleteJobTechniciansForJobs = `-- name: DeleteJobTechniciansForJobs :many
+DELETE FROM job_technicians
+WHERE job_id = ANY($1::text[])
+RETURNING technician_id
+`
+
+// Returns the technician of every removed link, whose metrics may change.
+func (q *Queries) DeleteJobTechniciansForJobs(ctx context.Context, jobIds []string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, deleteJobTechniciansForJobs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var technicianID int64
+		if err := rows.Scan(&technicianID); err != nil {
+			return nil, err
+		}
+		items = append(items, technicianID)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteTechnician = `-- name: DeleteTechnician :exec
+DELETE FROM technicians WHERE id = $1
+`
+
+// Job links and metrics cascade with the technician.
+func (q *Queries) DeleteTechnician(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTechnician, id)
+	return err
+}
+
+const getTechnician = `-- name: GetTechnician :one
+SELECT id, name, first_seen_date, last_seen_date, created_at, updated_at, active, employee_id, team, manager, hire_date, termination_date, hourly_cost, roster_filename FROM technicians WHERE id = $1
+`
+
+func (q *Queries) GetTechnician(ctx context.Context, id int64) (Technician, error) {
+	row := q.db.QueryRowContext(ctx, getTechnician, id)
+	var i Technician
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.FirstSeenDate,
+		&i.LastSeenDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Active,
+		&i.EmployeeID,
+		&i.Team,
+		&i.Manager,
+		&i.HireDate,
+		&i.TerminationDate,
+		&i.HourlyCost,
+		&i.RosterFilename,
+	)
+	return i, err
+}
+
 const getTechnicianByName = `-- name: GetTechnicianByName :one
-SELECT id, name, first_seen_date, last_seen_date, created_at, updated_at FROM technicians WHERE name = $1
+SELECT id, name, first_seen_date, last_seen_date, created_at, updated_at, active, employee_id, team, manager, hire_date, termination_date, hourly_cost, roster_filename FROM technicians WHERE name = $1
 `
 
 func (q *Queries) GetTechnicianByName(ctx context.Context, name string) (Technician, error) {
@@ -43,10 +151,73 @@ func (q *Queries) GetTechnicianByName(ctx context.Context, name string) (Technic
 		&i.LastSeenDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Active,
+		&i.EmployeeID,
+		&i.Team,
+		&i.Manager,
+		&i.HireDate,
+		&i.TerminationDate,
+		&i.HourlyCost,
+		&i.RosterFilename,
 	)
 	return i, err
 }
 
+const getTechnicianIDByAlias = `-- name: GetTechnicianIDByAlias :one
+SELECT technician_id FROM technician_aliases WHERE alias_key = $1
+`
+
+func (q *Queries) GetTechnicianIDByAlias(ctx context.Context, aliasKey string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getTechnicianIDByAlias, aliasKey)
+	var technicianID int64
+	err := row.Scan(&technicianID)
+	return technicianID, err
+}
+
+const getTechnicianIDByEmployeeID = `-- name: GetTechnicianIDByEmployeeID :one
+SELECT id FROM technicians WHERE employee_id = $1
+`
+
+func (q *Queries) GetTechnicianIDByEmployeeID(ctx context.Context, employeeID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getTechnicianIDByEmployeeID, employeeID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getTechnicianIDsForJobs = `-- name: GetTechnicianIDsForJobs :many
+SELECT technician_id FROM job_technicians WHERE job_id = ANY($1::text[])
+UNION
+SELECT technician_id FROM estimates
+WHERE job_id = ANY($1::text[]) AND technician_id IS NOT NULL
+`
+
+// Technicians credited with any of the jobs: linked to them, or named on one
+// of their estimates. Estimates without a technician credit the job's
+// sold_by technician, who is linked.
+func (q *Queries) GetTechnicianIDsForJobs(ctx context.Context, jobIds []string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getTechnicianIDsForJobs, pq.Array(jobIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var technicianID int64
+		if err := rows.Scan(&technicianID); err != nil {
+			return nil, err
+		}
+		items = append(items, technicianID)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTechnicianPerformance = `-- name: GetTechnicianPerformance :many
 SELECT 
     t.id,
@@ -61,7 +232,7 @@ SELECT
     tm.avg_margin_pct
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_sold > 0 OR tm.jobs_serviced > 0
+WHERE t.active AND (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)
 ORDER BY tm.total_sales DESC
 `
 
@@ -121,7 +292,7 @@ SELECT
     tm.avg_estimates_per_job
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_serviced > 0
+WHERE t.active AND tm.jobs_serviced > 0
 ORDER BY tm.avg_hours_per_job ASC
 LIMIT $1
 `
@@ -172,7 +343,7 @@ SELECT
     tm.avg_sale
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.opportunities >= 5  -- minimum sample size
+WHERE t.active AND tm.opportunities >= 5  -- minimum sample size
 ORDER BY tm.conversion_rate DESC
 LIMIT $1
 `
@@ -224,7 +395,7 @@ SELECT
     tm.avg_margin_pct
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_sold > 0
+WHERE t.active AND tm.jobs_sold > 0
 ORDER BY tm.avg_sale DESC
 LIMIT $1
 `
@@ -268,6 +439,230 @@ func (q *Queries) GetTopTechniciansBySales(ctx context.Context, limit int32) ([]
 	return items, nil
 }
 
+const listTechnicians = `-- name: ListTechnicians :many
+SELECT
+    t.id,
+    t.name,
+    t.active,
+    t.team,
+    t.termination_date,
+    t.first_seen_date,
+    t.last_seen_date,
+    COALESCE((
+        SELECT string_agg(a.alias, ', ' ORDER BY a.alias)
+        FROM technician_aliases a
+        WHERE a.technician_id = t.id AND a.alias <> t.name
+    ), '')::text AS aliases,
+    (SELECT COUNT(DISTINCT jt.job_id) FROM job_technicians jt WHERE jt.technician_id = t.id) AS job_count
+FROM technicians t
+ORDER BY t.name
+`
+
+type ListTechniciansRow struct {
+	ID              int64          `json:"id"`
+	Name            string         `json:"name"`
+	Active          bool           `json:"active"`
+	Team            sql.NullString `json:"team"`
+	TerminationDate sql.NullTime   `json:"termination_date"`
+	FirstSeenDate   sql.NullTime   `json:"first_seen_date"`
+	LastSeenDate    sql.NullTime   `json:"last_seen_date"`
+	Aliases         string         `json:"aliases"`
+	JobCount        int64          `json:"job_count"`
+}
+
+// Every technician with the other spellings mapped onto them and how many
+// jobs they're linked to.
+func (q *Queries) ListTechnicians(ctx context.Context) ([]ListTechniciansRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTechnicians)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTechniciansRow{}
+	for rows.Next() {
+		var i ListTechniciansRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Active,
+			&i.Team,
+			&i.TerminationDate,
+			&i.FirstSeenDate,
+			&i.LastSeenDate,
+			&i.Aliases,
+			&i.JobCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeTechnicianSeenDates = `-- name: MergeTechnicianSeenDates :exec
+UPDATE technicians t SET
+    first_seen_date = LEAST(t.first_seen_date, f.first_seen_date),
+    last_seen_date = GREATEST(t.last_seen_date, f.last_seen_date),
+    updated_at = NOW()
+FROM technicians f
+WHERE t.id = $1 AND f.id = $2
+`
+
+type MergeTechnicianSeenDatesParams struct {
+	IntoID int64 `json:"into_id"`
+	FromID int64 `json:"from_id"`
+}
+
+func (q *Queries) MergeTechnicianSeenDates(ctx context.Context, arg MergeTechnicianSeenDatesParams) error {
+	_, err := q.db.ExecContext(ctx, mergeTechnicianSeenDates, arg.IntoID, arg.FromID)
+	return err
+}
+
+const reassignTechnicianAliases = `-- name: ReassignTechnicianAliases :exec
+UPDATE technician_aliases SET technician_id = $1
+WHERE technician_id = $2
+`
+
+type ReassignTechnicianAliasesParams struct {
+	IntoID int64 `json:"into_id"`
+	FromID int64 `json:"from_id"`
+}
+
+func (q *Queries) ReassignTechnicianAliases(ctx context.Context, arg ReassignTechnicianAliasesParams) error {
+	_, err := q.db.ExecContext(ctx, reassignTechnicianAliases, arg.IntoID, arg.FromID)
+	return err
+}
+
+const reassignTechnicianEstimates = `-- name: ReassignTechnicianEstimates :exec
+UPDATE estimates SET technician_id = $1, updated_at = NOW()
+WHERE technician_id = $2
+`
+
+type ReassignTechnicianEstimatesParams struct {
+	IntoID int64 `json:"into_id"`
+	FromID int64 `json:"from_id"`
+}
+
+func (q *Queries) ReassignTechnicianEstimates(ctx context.Context, arg ReassignTechnicianEstimatesParams) error {
+	_, err := q.db.ExecContext(ctx, reassignTechnicianEstimates, arg.IntoID, arg.FromID)
+	return err
+}
+
+const reassignTechnicianTimesheetEntries = `-- name: ReassignTechnicianTimesheetEntries :exec
+UPDATE timesheet_entries SET technician_id = $1
+WHERE technician_id = $2
+`
+
+type ReassignTechnicianTimesheetEntriesParams struct {
+	IntoID int64 `json:"into_id"`
+	FromID int64 `json:"from_id"`
+}
+
+func (q *Queries) ReassignTechnicianTimesheetEntries(ctx context.Context, arg ReassignTechnicianTimesheetEntriesParams) error {
+	_, err := q.db.ExecContext(ctx, reassignTechnicianTimesheetEntries, arg.IntoID, arg.FromID)
+	return err
+}
+
+const renameTechnician = `-- name: RenameTechnician :exec
+UPDATE technicians SET name = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type RenameTechnicianParams struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) RenameTechnician(ctx context.Context, arg RenameTechnicianParams) error {
+	_, err := q.db.ExecContext(ctx, renameTechnician, arg.ID, arg.Name)
+	return err
+}
+
+const setTechnicianActive = `-- name: SetTechnicianActive :exec
+UPDATE technicians SET active = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetTechnicianActiveParams struct {
+	ID     int64 `json:"id"`
+	Active bool  `json:"active"`
+}
+
+func (q *Queries) SetTechnicianActive(ctx context.Context, arg SetTechnicianActiveParams) error {
+	_, err := q.db.ExecContext(ctx, setTechnicianActive, arg.ID, arg.Active)
+	return err
+}
+
+const updateTechnicianRoster = `-- name: UpdateTechnicianRoster :execrows
+UPDATE technicians SET
+    employee_id = $1,
+    team = $2,
+    manager = $3,
+    hire_date = $4,
+    termination_date = $5,
+    hourly_cost = $6,
+    roster_filename = $7,
+    updated_at = NOW()
+WHERE id = $8
+  AND (employee_id, team, manager, hire_date, termination_date, hourly_cost)
+      IS DISTINCT FROM ($1, $2, $3, $4, $5, $6)
+`
+
+type UpdateTechnicianRosterParams struct {
+	EmployeeID      sql.NullString  `json:"employee_id"`
+	Team            sql.NullString  `json:"team"`
+	Manager         sql.NullString  `json:"manager"`
+	HireDate        sql.NullTime    `json:"hire_date"`
+	TerminationDate sql.NullTime    `json:"termination_date"`
+	HourlyCost      decimal.Decimal `json:"hourly_cost"`
+	RosterFilename  sql.NullString  `json:"roster_filename"`
+	ID              int64           `json:"id"`
+}
+
+// The roster is authoritative: blank cells clear the field, e.g. a rehire's
+// termination date. Returns 0 when nothing changed.
+func (q *Queries) UpdateTechnicianRoster(ctx context.Context, arg UpdateTechnicianRosterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateTechnicianRoster,
+		arg.EmployeeID,
+		arg.Team,
+		arg.Manager,
+		arg.HireDate,
+		arg.TerminationDate,
+		arg.HourlyCost,
+		arg.RosterFilename,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateTechnicianSeenDates = `-- name: UpdateTechnicianSeenDates :exec
+UPDATE technicians SET
+    first_seen_date = LEAST(first_seen_date, $1),
+    last_seen_date = GREATEST(last_seen_date, $2),
+    updated_at = NOW()
+WHERE id = $3
+`
+
+type UpdateTechnicianSeenDatesParams struct {
+	FirstSeenDate sql.NullTime `json:"first_seen_date"`
+	LastSeenDate  sql.NullTime `json:"last_seen_date"`
+	ID            int64        `json:"id"`
+}
+
+func (q *Queries) UpdateTechnicianSeenDates(ctx context.Context, arg UpdateTechnicianSeenDatesParams) error {
+	_, err := q.db.ExecContext(ctx, updateTechnicianSeenDates, arg.FirstSeenDate, arg.LastSeenDate, arg.ID)
+	return err
+}
+
 const upsertTechnician = `-- name: UpsertTechnician :one
 INSERT INTO technicians (name, first_seen_date, last_seen_date)
 VALUES ($1, $2, $3)
@@ -275,7 +670,7 @@ ON CONFLICT (name) DO UPDATE SET
     first_seen_date = LEAST(technicians.first_seen_date, EXCLUDED.first_seen_date),
     last_seen_date = GREATEST(technicians.last_seen_date, EXCLUDED.last_seen_date),
     updated_at = NOW()
-RETURNING id, name, first_seen_date, last_seen_date, created_at, updated_at
+RETURNING id, (xmax = 0)::boolean AS inserted
 `
 
 type UpsertTechnicianParams struct {
@@ -284,16 +679,14 @@ type UpsertTechnicianParams struct {
 	LastSeenDate  sql.NullTime `json:"last_seen_date"`
 }
 
-func (q *Queries) UpsertTechnician(ctx context.Context, arg UpsertTechnicianParams) (Technician, error) {
+type UpsertTechnicianRow struct {
+	ID       int64 `json:"id"`
+	Inserted bool  `json:"inserted"`
+}
+
+func (q *Queries) UpsertTechnician(ctx context.Context, arg UpsertTechnicianParams) (UpsertTechnicianRow, error) {
 	row := q.db.QueryRowContext(ctx, upsertTechnician, arg.Name, arg.FirstSeenDate, arg.LastSeenDate)
-	var i Technician
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.FirstSeenDate,
-		&i.LastSeenDate,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
+	var i UpsertTechnicianRow
+	err := row.Scan(&i.ID, &i.Inserted)
 	return i, err
 }
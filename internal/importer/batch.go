@@ -0,0 +1,337 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// DeleteBatchResult contains the results of deleting an import batch
+type DeleteBatchResult struct {
+	BatchID int64
+
+	// Rows the batch created are deleted, rows it overwrote are restored to
+	// the version before it. Rows overwritten by a batch imported before
+	// snapshots existed can't be restored and keep their current values.
+	JobsDeleted       int
+	JobsRestored      int
+	JobsKept          int
+	InvoicesDeleted   int
+	InvoicesRestored  int
+	InvoicesKept      int
+	EstimatesDeleted  int
+	EstimatesRestored int
+	EstimatesKept     int
+
+	CustomersDeleted      int
+	LocationsDeleted      int
+	JobMetricsCalculated  int
+	TechMetricsCalculated int
+	Duration              time.Duration
+}
+
+// PreviewDeleteBatch counts what DeleteBatch would do without changing anything
+func (i *Importer) PreviewDeleteBatch(ctx context.Context, batchID int64) (*db.ImportBatch, *db.GetBatchDeletePreviewRow, error) {
+	batch, err := i.queries.GetImportBatch(ctx, batchID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("import batch %d not found", batchID)
+		}
+		return nil, nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+
+	preview, err := i.queries.GetBatchDeletePreview(ctx, batchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to preview batch delete: %w", err)
+	}
+
+	return &batch, &preview, nil
+}
+
+// DeleteBatch undoes an import batch in a single transaction. Jobs, invoices
+// and estimates the batch created are deleted along with their metrics and
+// technician links, rows it overwrote are restored from their snapshots,
+// job versions it recorded are dropped from job history, and customer job
+// dates and technician metrics are recalculated.
+func (i *Importer) DeleteBatch(ctx context.Context, batchID int64) (*DeleteBatchResult, error) {
+	startTime := time.Now()
+	if i.ChunkSize <= 0 {
+		i.ChunkSize = DefaultChunkSize
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	if _, err := txQueries.GetImportBatch(ctx, batchID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import batch %d not found", batchID)
+		}
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+
+	// Invoices from other batches would be orphaned by deleting their job
+	blocking, err := txQueries.CountInvoicesBlockingBatchDelete(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for linked invoices: %w", err)
+	}
+	if blocking > 0 {
+		return nil, fmt.Errorf("%d invoice(s) from later batches belong to jobs created by batch %d; delete those batches first", blocking, batchID)
+	}
+	blocking, err = txQueries.CountEstimatesBlockingBatchDelete(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for linked estimates: %w", err)
+	}
+	if blocking > 0 {
+		return nil, fmt.Errorf("%d estimate(s) from later batches belong to jobs created by batch %d; delete those batches first", blocking, batchID)
+	}
+
+	// Collect everything whose metrics or dates depend on the batch's rows
+	// before they change
+	affectedJobs := make(map[string]bool)
+	jobIDs, err := txQueries.GetJobIDsForBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch jobs: %w", err)
+	}
+	for _, jobID := range jobIDs {
+		affectedJobs[jobID] = true
+	}
+	invoiceJobIDs, err := txQueries.GetInvoiceJobIDsForBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch invoices: %w", err)
+	}
+	for _, jobID := range invoiceJobIDs {
+		affectedJobs[jobID] = true
+	}
+	customerIDs, err := txQueries.GetCustomerIDsForBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch customers: %w", err)
+	}
+	locationIDs, err := txQueries.GetLocationIDsForJobs(ctx, mapKeys(affectedJobs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch locations: %w", err)
+	}
+	techIDs, err := affectedTechnicianIDs(ctx, txQueries, batchID, mapKeys(affectedJobs), nil)
+	if err != nil {
+		return nil, err
+	}
+	creditedBefore := make(map[int64]bool, len(techIDs))
+	for _, techID := range techIDs {
+		creditedBefore[techID] = true
+	}
+
+	result := &DeleteBatchResult{BatchID: batchID}
+
+	// Estimates and invoices first, so restored rows never point at a deleted job
+	restored, err := txQueries.RestoreEstimatesFromSnapshots(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore estimates: %w", err)
+	}
+	result.EstimatesRestored = int(restored)
+
+	deleted, err := txQueries.DeleteEstimatesCreatedByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete estimates: %w", err)
+	}
+	result.EstimatesDeleted = int(deleted)
+
+	kept, err := txQueries.ReleaseEstimatesFromBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release estimates: %w", err)
+	}
+	result.EstimatesKept = int(kept)
+
+	restored, err = txQueries.RestoreInvoicesFromSnapshots(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore invoices: %w", err)
+	}
+	result.InvoicesRestored = int(restored)
+
+	deleted, err = txQueries.DeleteInvoicesCreatedByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete invoices: %w", err)
+	}
+	result.InvoicesDeleted = int(deleted)
+
+	kept, err = txQueries.ReleaseInvoicesFromBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release invoices: %w", err)
+	}
+	result.InvoicesKept = int(kept)
+
+	restoredJobIDs, err := txQueries.RestoreJobsFromSnapshots(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore jobs: %w", err)
+	}
+	result.JobsRestored = len(restoredJobIDs)
+
+	deleted, err = txQueries.DeleteJobsCreatedByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete jobs: %w", err)
+	}
+	result.JobsDeleted = int(deleted)
+
+	kept, err = txQueries.ReleaseJobsFromBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release jobs: %w", err)
+	}
+	result.JobsKept = int(kept)
+
+	// Restored jobs may list different technicians than the batch's version
+	if err := i.relinkTechnicians(ctx, tx, restoredJobIDs); err != nil {
+		return nil, err
+	}
+
+	// Rows first imported by the batch but changed by a later one now
+	// belong to the later batch
+	if err := txQueries.ReassignEstimatesImportBatch(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to reassign estimates: %w", err)
+	}
+	if err := txQueries.ReassignInvoicesImportBatch(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to reassign invoices: %w", err)
+	}
+	if err := txQueries.ReassignJobsImportBatch(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to reassign jobs: %w", err)
+	}
+	if err := i.rebaseSnapshots(ctx, txQueries, batchID); err != nil {
+		return nil, err
+	}
+
+	// Jobs the batch changed go back to the version they had before it in
+	// their history too
+	if err := txQueries.DeleteJobVersionsForBatch(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to delete job versions: %w", err)
+	}
+
+	// Restored jobs may be back at locations the batch moved them away from
+	restoredLocationIDs, err := txQueries.GetLocationIDsForJobs(ctx, restoredJobIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored locations: %w", err)
+	}
+	locationIDs = append(locationIDs, restoredLocationIDs...)
+
+	// Locations go first: they reference the customers about to be deleted
+	if err := txQueries.RefreshLocations(ctx, locationIDs); err != nil {
+		return nil, fmt.Errorf("failed to refresh locations: %w", err)
+	}
+	locationsDeleted, err := txQueries.DeleteLocationsWithoutJobs(ctx, locationIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete locations without jobs: %w", err)
+	}
+	result.LocationsDeleted = int(locationsDeleted)
+
+	customersDeleted, err := txQueries.DeleteCustomersWithoutJobs(ctx, customerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete customers without jobs: %w", err)
+	}
+	result.CustomersDeleted = int(customersDeleted)
+
+	// Deleted jobs take their metrics with them; everything else is recalculated
+	result.JobMetricsCalculated, err = i.recalculateJobMetrics(ctx, tx, mapKeys(affectedJobs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate job metrics: %w", err)
+	}
+
+	// Customers the batch touched, and those of jobs it restored, lose or
+	// regain jobs and revenue
+	err = txQueries.RefreshCustomerRollups(ctx, db.RefreshCustomerRollupsParams{
+		CustomerIds: customerIDs,
+		JobIds:      mapKeys(affectedJobs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh customer rollups: %w", err)
+	}
+
+	// Technicians credited with the batch's jobs and estimates before the
+	// delete, and those credited with the restored versions
+	techIDs, err = affectedTechnicianIDs(ctx, txQueries, batchID, mapKeys(affectedJobs), creditedBefore)
+	if err != nil {
+		return nil, err
+	}
+	result.TechMetricsCalculated, err = i.calculateAndSaveTechnicianMetrics(ctx, tx, techIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate technician metrics: %w", err)
+	}
+
+	// Snapshots and rejected rows cascade with the batch
+	if err := txQueries.DeleteImportBatch(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to delete import batch: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// rebaseSnapshots updates later batches' snapshots that captured a version
+// of a row written by the deleted batch, so deleting those batches later
+// still restores a version that exists
+func (i *Importer) rebaseSnapshots(ctx context.Context, q *db.Queries, batchID int64) error {
+	if err := q.RebaseLaterSnapshots(ctx, batchID); err != nil {
+		return fmt.Errorf("failed to rebase snapshots: %w", err)
+	}
+	if err := q.DeleteLaterSnapshotsOfBatchRows(ctx, batchID); err != nil {
+		return fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	if err := q.ReassignSnapshotImportBatch(ctx, batchID); err != nil {
+		return fmt.Errorf("failed to reassign snapshots: %w", err)
+	}
+	return nil
+}
+
+// relinkTechnicians rebuilds job_technicians for jobs from their stored
+// technician columns
+func (i *Importer) relinkTechnicians(ctx context.Context, tx *sql.Tx, jobIDs []string) error {
+	if len(jobIDs) == 0 {
+		return nil
+	}
+
+	txQueries := db.New(tx)
+	if _, err := txQueries.DeleteJobTechniciansForJobs(ctx, jobIDs); err != nil {
+		return fmt.Errorf("failed to reset job technicians: %w", err)
+	}
+
+	rows, err := txQueries.GetJobsForTechnicianProcessingByIDs(ctx, jobIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get jobs for technicians: %w", err)
+	}
+
+	jobs := make([]parser.JobRow, 0, len(rows))
+	for _, row := range rows {
+		job := parser.JobRow{
+			JobID:               row.ID,
+			AssignedTechnicians: nullStringPtr(row.AssignedTechnician),
+			SoldBy:              nullStringPtr(row.SoldByTechnician),
+			PrimaryTechnician:   nullStringPtr(row.PrimaryTechnician),
+		}
+		if row.JobCompletionDate.Valid {
+			job.JobCompletionDate = &row.JobCompletionDate.Time
+		}
+		jobs = append(jobs, job)
+	}
+
+	result := technicianImportResult{cache: make(map[string]int64)}
+	if err := i.importTechnicians(ctx, tx, jobs, &result); err != nil {
+		return fmt.Errorf("failed to relink technicians: %w", err)
+	}
+	return nil
+}
+
+// nullStringPtr converts a sql.NullString back into a parsed optional field
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
@@ -0,0 +1,439 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// Bulk loading: each chunk is copied into a temporary staging table with
+// COPY and merged into its target with one INSERT ... SELECT ... ON CONFLICT,
+// instead of one round trip per row. The merges mirror the Upsert* queries:
+// unchanged rows are left alone, and changed rows are returned so the
+// importer can count them and recalculate metrics.
+
+// jobColumns are the jobs columns an export sets, in copyJobValues order,
+// after the id key
+var jobColumns = []string{
+	"customer_id", "job_type", "business_unit", "status",
+	"job_creation_date", "job_schedule_date", "job_completion_date",
+	"assigned_technician", "sold_by_technician", "booked_by",
+	"campaign_name", "campaign_category", "call_campaign",
+	"jobs_subtotal", "job_total", "estimate_sales_subtotal",
+	"invoice_id", "total_hours_worked", "priority", "survey_score",
+	"estimate_count", "is_opportunity", "is_converted", "primary_technician",
+	"location_id", "business_unit_id", "dispatched_by", "summary", "member_status", "tags",
+	"is_warranty", "is_recall", "is_zero_dollar",
+}
+
+// invoiceColumns are the invoices columns an export sets, in
+// copyInvoiceValues order, after the id key
+var invoiceColumns = []string{
+	"job_id", "invoice_date", "invoice_status", "invoice_type", "invoice_summary", "total",
+	"balance", "payments", "material_costs", "equipment_costs", "purchase_order_costs", "return_costs",
+	"costs_total", "material_retail", "material_markup", "equipment_retail", "equipment_markup", "labor",
+	"labor_pay", "labor_burden", "total_labor_costs", "income", "discount_total", "is_adjustment",
+	"customer_id", "location_id", "project_number", "business_unit_id", "payment_types", "payment_term",
+	"pricebook_price", "is_dispatch_service_fee_only", "is_prevailing_wage", "job_type",
+}
+
+// customerColumns are the customers columns copied by copyCustomers, in
+// copyCustomerValues order
+var customerColumns = []string{
+	"id", "customer_name", "customer_type",
+	"customer_city", "customer_state", "customer_zip",
+	"location_city", "location_state", "location_zip",
+}
+
+// mergeCustomers mirrors UpsertCustomer
+const mergeCustomers = `
+	INSERT INTO customers (
+		id, customer_name, customer_type,
+		customer_city, customer_state, customer_zip,
+		location_city, location_state, location_zip
+	)
+	SELECT
+		id, customer_name, customer_type,
+		customer_city, customer_state, customer_zip,
+		location_city, location_state, location_zip
+	FROM staging_customers
+	ON CONFLICT (id) DO UPDATE SET
+		customer_name = EXCLUDED.customer_name,
+		customer_type = EXCLUDED.customer_type,
+		customer_city = EXCLUDED.customer_city,
+		customer_state = EXCLUDED.customer_state,
+		customer_zip = EXCLUDED.customer_zip,
+		location_city = EXCLUDED.location_city,
+		location_state = EXCLUDED.location_state,
+		location_zip = EXCLUDED.location_zip,
+		updated_at = NOW()
+	WHERE (
+		customers.customer_name, customers.customer_type,
+		customers.customer_city, customers.customer_state, customers.customer_zip,
+		customers.location_city, customers.location_state, customers.location_zip
+	) IS DISTINCT FROM (
+		EXCLUDED.customer_name, EXCLUDED.customer_type,
+		EXCLUDED.customer_city, EXCLUDED.customer_state, EXCLUDED.customer_zip,
+		EXCLUDED.location_city, EXCLUDED.location_state, EXCLUDED.location_zip
+	)
+	RETURNING id, (xmax = 0)::boolean AS inserted
+`
+
+// mergeJobTechnicians mirrors CreateJobTechnician
+const mergeJobTechnicians = `
+	INSERT INTO job_technicians (job_id, technician_id, role)
+	SELECT DISTINCT job_id, technician_id, role FROM staging_job_technicians
+	ON CONFLICT (job_id, technician_id, role) DO NOTHING
+`
+
+// load runs bulk, the COPY path, inside a savepoint. If it fails on bad
+// data the savepoint is rolled back and rowByRow loads the same rows one at
+// a time, which reports a bad row with its row number; the first such
+// failure is kept in run.bulkFallback so the import can report it. Any other
+// failure is returned. With RowByRow set only rowByRow runs. bulk must not
+// touch the import's results until its last statement has succeeded.
+func (i *Importer) load(ctx context.Context, tx *sql.Tx, run *importRun, bulk, rowByRow func() error) error {
+	if i.RowByRow {
+		return rowByRow()
+	}
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_load"); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := bulk(); err != nil {
+		if !isDataError(err) {
+			return fmt.Errorf("bulk load failed: %w", err)
+		}
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_load"); rbErr != nil {
+			return fmt.Errorf("failed to roll back bulk load (%v): %w", err, rbErr)
+		}
+		if run.bulkFallback == nil {
+			run.bulkFallback = err
+		}
+		return rowByRow()
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_load"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// isDataError reports whether err is Postgres rejecting a value or a
+// constraint (SQLSTATE classes 22 and 23), the errors a single bad row
+// causes. Loading row by row finds that row; on anything else it would only
+// fail again, more slowly.
+func isDataError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23":
+		return true
+	}
+	return false
+}
+
+// stageRows copies rows into a temporary staging table. The table takes its
+// column types from target, lives until the transaction ends and is emptied
+// before each load. Rows are numbered in order in row_num so merges can keep
+// the last row for each key, as the row-by-row upserts do.
+func stageRows(ctx context.Context, tx *sql.Tx, staging, target string, columns []string, rows [][]interface{}) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TEMP TABLE IF NOT EXISTS %s ON COMMIT DROP AS SELECT 0 AS row_num, %s FROM %s WITH NO DATA",
+		staging, strings.Join(columns, ", "), target))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", staging, err)
+	}
+	if _, err := tx.ExecContext(ctx, "TRUNCATE "+staging); err != nil {
+		return fmt.Errorf("failed to empty %s: %w", staging, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(staging, append([]string{"row_num"}, columns...)...))
+	if err != nil {
+		return fmt.Errorf("failed to start copy into %s: %w", staging, err)
+	}
+	for n, row := range rows {
+		if _, err := stmt.ExecContext(ctx, append([]interface{}{n}, row...)...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy into %s: %w", staging, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to copy into %s: %w", staging, err)
+	}
+	return stmt.Close()
+}
+
+// mergeSQL builds the set-based twin of UpsertJob and UpsertInvoice: the last
+// staged row for each id is inserted into target, or overwrites the existing
+// row when any of columns changed. It returns the id, the returning column
+// and whether the row was inserted for every row it wrote. $1 is the import
+// batch ID.
+func mergeSQL(target, staging string, columns []string, returning string) string {
+	var set, current, excluded []string
+	for _, col := range columns {
+		set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		current = append(current, target+"."+col)
+		excluded = append(excluded, "EXCLUDED."+col)
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (id, import_batch_id, last_import_batch_id, %[3]s)
+		SELECT DISTINCT ON (s.id) s.id, $1::bigint, $1::bigint, s.%[4]s
+		FROM %[2]s s
+		ORDER BY s.id, s.row_num DESC
+		ON CONFLICT (id) DO UPDATE SET
+			%[5]s,
+			last_import_batch_id = EXCLUDED.last_import_batch_id,
+			updated_at = NOW()
+		WHERE (%[6]s) IS DISTINCT FROM (%[7]s)
+		RETURNING id, %[8]s, (xmax = 0)::boolean AS inserted
+	`,
+		target, staging,
+		strings.Join(columns, ", "),
+		strings.Join(columns, ", s."),
+		strings.Join(set, ",\n\t\t\t"),
+		strings.Join(current, ", "),
+		strings.Join(excluded, ", "),
+		returning,
+	)
+}
+
+// mergedRow is a row a merge inserted or changed
+type mergedRow struct {
+	id       string
+	jobID    string
+	inserted bool
+}
+
+// mergeStaged runs a mergeSQL statement and collects the rows it wrote
+func mergeStaged(ctx context.Context, tx *sql.Tx, query string, batchID int64) (map[string]mergedRow, error) {
+	rows, err := tx.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	merged := make(map[string]mergedRow)
+	for rows.Next() {
+		var r mergedRow
+		if err := rows.Scan(&r.id, &r.jobID, &r.inserted); err != nil {
+			return nil, err
+		}
+		merged[r.id] = r
+	}
+	return merged, rows.Err()
+}
+
+// copyCustomers is the bulk version of importCustomers
+func (i *Importer) copyCustomers(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, result *customerImportResult) error {
+	customers := customerUpsertParams(jobs)
+	values := make([][]interface{}, 0, len(customers))
+	for _, c := range customers {
+		values = append(values, []interface{}{
+			c.ID, c.CustomerName, c.CustomerType,
+			c.CustomerCity, c.CustomerState, c.CustomerZip,
+			c.LocationCity, c.LocationState, c.LocationZip,
+		})
+	}
+	if err := stageRows(ctx, tx, "staging_customers", "customers", customerColumns, values); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, mergeCustomers)
+	if err != nil {
+		return fmt.Errorf("failed to merge customers: %w", err)
+	}
+	defer rows.Close()
+
+	merged := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		var inserted bool
+		if err := rows.Scan(&id, &inserted); err != nil {
+			return fmt.Errorf("failed to merge customers: %w", err)
+		}
+		merged[id] = inserted
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to merge customers: %w", err)
+	}
+
+	for _, c := range customers {
+		inserted, ok := merged[c.ID]
+		var err error
+		if !ok {
+			err = sql.ErrNoRows // unchanged, as UpsertCustomer reports it
+		}
+		if err := result.record(c.ID, inserted, err); err != nil {
+			return fmt.Errorf("failed to upsert customer %d: %w", c.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// copyJobs is the bulk version of importJobs
+func (i *Importer) copyJobs(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, batchID int64, result *jobImportResult) error {
+	jobIDs := make([]string, 0, len(jobs))
+	values := make([][]interface{}, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.JobID)
+		values = append(values, copyJobValues(jobUpsertParams(job, batchID)))
+	}
+
+	if err := stageRows(ctx, tx, "staging_jobs", "jobs", append([]string{"id"}, jobColumns...), values); err != nil {
+		return err
+	}
+	merged, err := mergeStaged(ctx, tx, mergeSQL("jobs", "staging_jobs", jobColumns, "id"), batchID)
+	if err != nil {
+		return fmt.Errorf("failed to merge jobs: %w", err)
+	}
+
+	// A job repeated within the chunk is written once, so its later rows
+	// count as unchanged, as they would upserted one at a time
+	for _, jobID := range jobIDs {
+		r, ok := merged[jobID]
+		delete(merged, jobID)
+		result.validJobIDs[jobID] = true
+		switch {
+		case !ok:
+			result.counts.unchanged++
+		case r.inserted:
+			result.counts.inserted++
+			result.newJobIDs = append(result.newJobIDs, jobID)
+		default:
+			result.counts.updated++
+			result.updatedJobIDs = append(result.updatedJobIDs, jobID)
+		}
+	}
+
+	return nil
+}
+
+// copyJobValues orders UpsertJob parameters as id followed by jobColumns
+func copyJobValues(p db.UpsertJobParams) []interface{} {
+	return []interface{}{
+		p.ID,
+		p.CustomerID, p.JobType, p.BusinessUnit, p.Status,
+		p.JobCreationDate, p.JobScheduleDate, p.JobCompletionDate,
+		p.AssignedTechnician, p.SoldByTechnician, p.BookedBy,
+		p.CampaignName, p.CampaignCategory, p.CallCampaign,
+		p.JobsSubtotal, p.JobTotal, p.EstimateSalesSubtotal,
+		p.InvoiceID, p.TotalHoursWorked, p.Priority, p.SurveyScore,
+		p.EstimateCount, p.IsOpportunity, p.IsConverted, p.PrimaryTechnician,
+		p.LocationID, p.BusinessUnitID, p.DispatchedBy, p.Summary, p.MemberStatus, p.Tags,
+		p.IsWarranty, p.IsRecall, p.IsZeroDollar,
+	}
+}
+
+// copyJobTechnicians is the bulk version of createJobTechnicians
+func copyJobTechnicians(ctx context.Context, tx *sql.Tx, links []db.CreateJobTechnicianParams) error {
+	values := make([][]interface{}, 0, len(links))
+	for _, link := range links {
+		values = append(values, []interface{}{link.JobID, link.TechnicianID, link.Role})
+	}
+	columns := []string{"job_id", "technician_id", "role"}
+	if err := stageRows(ctx, tx, "staging_job_technicians", "job_technicians", columns, values); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, mergeJobTechnicians); err != nil {
+		return fmt.Errorf("failed to create job_technicians: %w", err)
+	}
+
+	return nil
+}
+
+// copyInvoices is the bulk version of importInvoices
+func (i *Importer) copyInvoices(ctx context.Context, tx *sql.Tx, invoices []parser.InvoiceRow, batchID int64, validJobIDs map[string]bool, result *invoiceImportResult) error {
+	txQueries := db.New(tx)
+
+	// Find jobs imported by earlier batches
+	var otherJobIDs []string
+	seen := make(map[string]bool)
+	for _, invoice := range invoices {
+		if !validJobIDs[invoice.JobID] && !seen[invoice.JobID] {
+			seen[invoice.JobID] = true
+			otherJobIDs = append(otherJobIDs, invoice.JobID)
+		}
+	}
+	existingJobIDs := make(map[string]bool)
+	if len(otherJobIDs) > 0 {
+		ids, err := txQueries.GetExistingJobIDs(ctx, otherJobIDs)
+		if err != nil {
+			return fmt.Errorf("failed to look up existing jobs: %w", err)
+		}
+		for _, id := range ids {
+			existingJobIDs[id] = true
+		}
+	}
+
+	// Results are only updated once the merge has succeeded
+	var skipped, linked []parser.InvoiceRow
+	var loaded []string
+	values := make([][]interface{}, 0, len(invoices))
+	for _, invoice := range invoices {
+		if !validJobIDs[invoice.JobID] {
+			if !existingJobIDs[invoice.JobID] {
+				skipped = append(skipped, invoice)
+				continue
+			}
+			linked = append(linked, invoice)
+		}
+		loaded = append(loaded, invoice.InvoiceID)
+		values = append(values, copyInvoiceValues(invoiceUpsertParams(invoice, batchID)))
+	}
+
+	if err := stageRows(ctx, tx, "staging_invoices", "invoices", append([]string{"id"}, invoiceColumns...), values); err != nil {
+		return err
+	}
+	merged, err := mergeStaged(ctx, tx, mergeSQL("invoices", "staging_invoices", invoiceColumns, "job_id"), batchID)
+	if err != nil {
+		return fmt.Errorf("failed to merge invoices: %w", err)
+	}
+
+	for _, invoice := range skipped {
+		result.skipped++
+		result.missingJobIDs[invoice.JobID] = true
+	}
+	result.linked += len(linked)
+	for _, invoiceID := range loaded {
+		r, ok := merged[invoiceID]
+		delete(merged, invoiceID)
+		switch {
+		case !ok:
+			result.counts.unchanged++
+			continue
+		case r.inserted:
+			result.counts.inserted++
+		default:
+			result.counts.updated++
+		}
+		result.changedJobIDs[r.jobID] = true
+	}
+
+	return nil
+}
+
+// copyInvoiceValues orders UpsertInvoice parameters as id followed by
+// invoiceColumns
+func copyInvoiceValues(p db.UpsertInvoiceParams) []interface{} {
+	return []interface{}{
+		p.ID,
+		p.JobID, p.InvoiceDate, p.InvoiceStatus, p.InvoiceType, p.InvoiceSummary, p.Total,
+		p.Balance, p.Payments, p.MaterialCosts, p.EquipmentCosts, p.PurchaseOrderCosts, p.ReturnCosts,
+		p.CostsTotal, p.MaterialRetail, p.MaterialMarkup, p.EquipmentRetail, p.EquipmentMarkup, p.Labor,
+		p.LaborPay, p.LaborBurden, p.TotalLaborCosts, p.Income, p.DiscountTotal, p.IsAdjustment,
+		p.CustomerID, p.LocationID, p.ProjectNumber, p.BusinessUnitID, p.PaymentTypes, p.PaymentTerm,
+		p.PricebookPrice, p.IsDispatchServiceFeeOnly, p.IsPrevailingWage, p.JobType,
+	}
+}
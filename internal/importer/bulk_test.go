@@ -0,0 +1,322 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// These tests need a migrated Postgres database in DATABASE_URL and are
+// skipped without one. Every load runs in a transaction that is rolled back,
+// so the database is left as it was.
+
+// testCustomerBase keeps the fixtures' customer IDs clear of real ones
+const testCustomerBase = 9_000_000_000
+
+func openTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		tb.Skip("DATABASE_URL not set")
+	}
+	database, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { database.Close() })
+	if err := database.Ping(); err != nil {
+		tb.Fatalf("failed to connect to database: %v", err)
+	}
+	return database
+}
+
+// writeCSV renders a header and records as a CSV export
+func writeCSV(tb testing.TB, header []string, records [][]string) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		tb.Fatal(err)
+	}
+	if err := w.WriteAll(records); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fixture is a jobs and an invoices export, already parsed
+type fixture struct {
+	jobs     []parser.JobRow
+	invoices []parser.InvoiceRow
+}
+
+// newFixture builds n jobs with one invoice each, plus extra rows that
+// repeat IDs: bulktest-1 twice within the first chunk, and bulktest-2 again
+// in a later chunk, so both the in-chunk and the cross-chunk rule for
+// duplicates are loaded. The last row for an ID is the one that must win.
+func newFixture(tb testing.TB, n int) fixture {
+	tb.Helper()
+	jobHeader := []string{
+		"Job ID", "Customer ID", "Job Type", "Status", "Jobs Subtotal", "Jobs Total",
+		"Customer Name", "Customer City", "Completion Date",
+		"Assigned Technicians", "Sold By", "Primary Technician",
+	}
+	invoiceHeader := []string{"Invoice #", "Job #", "Invoice Date", "Total", "Material Costs"}
+
+	job := func(id, customer int, status, subtotal, name, completed, assigned string) []string {
+		return []string{
+			fmt.Sprintf("bulktest-%d", id), fmt.Sprint(testCustomerBase + customer),
+			"Service", status, subtotal, subtotal,
+			name, "Springfield", completed,
+			assigned, fmt.Sprintf("Bulk Test Tech %d", id%5), fmt.Sprintf("Bulk Test Tech %d", id%3),
+		}
+	}
+	invoice := func(id, jobID int, total string) []string {
+		return []string{
+			fmt.Sprintf("bulktest-inv-%d", id), fmt.Sprintf("bulktest-%d", jobID),
+			"01/15/2025", total, "12.50",
+		}
+	}
+
+	var jobRecords, invoiceRecords [][]string
+	for id := 1; id <= n; id++ {
+		jobRecords = append(jobRecords,
+			job(id, id%(n/2+1), "Completed", "100.00", fmt.Sprintf("Customer %d", id%(n/2+1)), "01/15/2025", "Bulk Test Tech A"))
+		invoiceRecords = append(invoiceRecords, invoice(id, id, "100.00"))
+
+		switch id {
+		case 3:
+			// Same chunk: a changed row for job 1, on a new customer and
+			// with another technician
+			jobRecords = append(jobRecords,
+				job(1, n+1, "Canceled", "250.00", "Moved Customer", "02/01/2025", "Bulk Test Tech A, Bulk Test Tech B"))
+			invoiceRecords = append(invoiceRecords, invoice(1, 1, "250.00"))
+		case n:
+			// Later chunk: a changed row for job 2
+			jobRecords = append(jobRecords,
+				job(2, 2, "Hold", "175.00", "Renamed Customer", "03/01/2025", "Bulk Test Tech C"))
+			invoiceRecords = append(invoiceRecords, invoice(2, 2, "175.00"))
+		}
+	}
+
+	jobs, err := parser.ParseJobs(parser.NewCSVParser(), bytes.NewReader(writeCSV(tb, jobHeader, jobRecords)))
+	if err != nil {
+		tb.Fatalf("failed to parse jobs: %v", err)
+	}
+	invoices, err := parser.ParseInvoices(parser.NewCSVParser(), bytes.NewReader(writeCSV(tb, invoiceHeader, invoiceRecords)))
+	if err != nil {
+		tb.Fatalf("failed to parse invoices: %v", err)
+	}
+	return fixture{jobs: jobs, invoices: invoices}
+}
+
+// load imports f into a new batch inside tx, with or without bulk loading
+func (f fixture) load(ctx context.Context, tx *sql.Tx, database *sql.DB, chunkSize int, rowByRow bool) (*importRun, error) {
+	batch, err := db.New(tx).CreateImportBatch(ctx, db.CreateImportBatchParams{
+		JobReportFilename:     "bulktest-jobs.csv",
+		InvoiceReportFilename: "bulktest-invoices.csv",
+		JobReportHash:         fmt.Sprintf("bulktest-jobs-%d", time.Now().UnixNano()),
+		InvoiceReportHash:     fmt.Sprintf("bulktest-invoices-%d", time.Now().UnixNano()),
+		Status:                "pending",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import batch: %w", err)
+	}
+
+	imp := NewImporter(database)
+	imp.ChunkSize = chunkSize
+	imp.RowByRow = rowByRow
+
+	run := newImportRun(batch.ID)
+	_, err = imp.importJobStream(ctx, tx, func(fn func(parser.JobRow) error) error {
+		for _, job := range f.jobs {
+			if err := fn(job); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, run)
+	if err != nil {
+		return nil, err
+	}
+	_, err = imp.importInvoiceStream(ctx, tx, func(fn func(parser.InvoiceRow) error) error {
+		for _, invoice := range f.invoices {
+			if err := fn(invoice); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, run)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// loadedTables is what a load left in the tables it writes, one JSON row
+// per record. Timestamps, batch IDs and technician IDs differ between two
+// loads of the same rows and are left out.
+type loadedTables struct {
+	jobs, invoices, customers, jobTechnicians []string
+}
+
+var loadedTableQueries = []string{
+	`SELECT (to_jsonb(t) - 'created_at' - 'updated_at' - 'import_batch_id' - 'last_import_batch_id')::text
+	 FROM jobs t WHERE id LIKE 'bulktest-%' ORDER BY id`,
+	`SELECT (to_jsonb(t) - 'created_at' - 'updated_at' - 'import_batch_id' - 'last_import_batch_id')::text
+	 FROM invoices t WHERE id LIKE 'bulktest-inv-%' ORDER BY id`,
+	`SELECT (to_jsonb(t) - 'created_at' - 'updated_at')::text
+	 FROM customers t WHERE id >= 9000000000 ORDER BY id`,
+	`SELECT jsonb_build_object('job_id', jt.job_id, 'technician', t.name, 'role', jt.role)::text
+	 FROM job_technicians jt JOIN technicians t ON t.id = jt.technician_id
+	 WHERE jt.job_id LIKE 'bulktest-%' ORDER BY jt.job_id, t.name, jt.role`,
+}
+
+func readLoadedTables(ctx context.Context, tx *sql.Tx) (loadedTables, error) {
+	var tables loadedTables
+	dests := []*[]string{&tables.jobs, &tables.invoices, &tables.customers, &tables.jobTechnicians}
+	for n, query := range loadedTableQueries {
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return tables, err
+		}
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				rows.Close()
+				return tables, err
+			}
+			*dests[n] = append(*dests[n], row)
+		}
+		if err := rows.Close(); err != nil {
+			return tables, err
+		}
+		if err := rows.Err(); err != nil {
+			return tables, err
+		}
+	}
+	return tables, nil
+}
+
+// loadAndRead loads f in a transaction, reads back what it wrote and rolls
+// the transaction back
+func loadAndRead(t *testing.T, database *sql.DB, f fixture, rowByRow bool) loadedTables {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	run, err := f.load(ctx, tx, database, 4, rowByRow)
+	if err != nil {
+		t.Fatalf("failed to load (row by row: %v): %v", rowByRow, err)
+	}
+	if run.bulkFallback != nil {
+		t.Fatalf("bulk load fell back to row by row: %v", run.bulkFallback)
+	}
+
+	tables, err := readLoadedTables(ctx, tx)
+	if err != nil {
+		t.Fatalf("failed to read loaded tables: %v", err)
+	}
+	return tables
+}
+
+func TestBulkLoadMatchesRowByRow(t *testing.T) {
+	database := openTestDB(t)
+	f := newFixture(t, 10)
+
+	bulk := loadAndRead(t, database, f, false)
+	rowByRow := loadAndRead(t, database, f, true)
+
+	for _, table := range []struct {
+		name           string
+		bulk, rowByRow []string
+	}{
+		{"jobs", bulk.jobs, rowByRow.jobs},
+		{"invoices", bulk.invoices, rowByRow.invoices},
+		{"customers", bulk.customers, rowByRow.customers},
+		{"job_technicians", bulk.jobTechnicians, rowByRow.jobTechnicians},
+	} {
+		if len(table.bulk) == 0 {
+			t.Errorf("%s: nothing was loaded", table.name)
+		}
+		if !reflect.DeepEqual(table.bulk, table.rowByRow) {
+			t.Errorf("%s differ:\nbulk:       %v\nrow by row: %v", table.name, table.bulk, table.rowByRow)
+		}
+	}
+}
+
+func TestBulkLoadKeepsLastDuplicateInChunk(t *testing.T) {
+	database := openTestDB(t)
+	f := newFixture(t, 10)
+	ctx := context.Background()
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Jobs 1-3 and the repeat of job 1 make up the first chunk
+	if _, err := f.load(ctx, tx, database, 4, false); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	var status string
+	var customerID int64
+	err = tx.QueryRowContext(ctx, "SELECT status, customer_id FROM jobs WHERE id = 'bulktest-1'").Scan(&status, &customerID)
+	if err != nil {
+		t.Fatalf("failed to read job: %v", err)
+	}
+	if status != "Canceled" || customerID != testCustomerBase+11 {
+		t.Errorf("bulktest-1 = (%s, %d), want the chunk's last row (Canceled, %d)", status, customerID, testCustomerBase+11)
+	}
+
+	var total string
+	err = tx.QueryRowContext(ctx, "SELECT total::text FROM invoices WHERE id = 'bulktest-inv-1'").Scan(&total)
+	if err != nil {
+		t.Fatalf("failed to read invoice: %v", err)
+	}
+	if total != "250.00" {
+		t.Errorf("bulktest-inv-1 total = %s, want the chunk's last row (250.00)", total)
+	}
+}
+
+func benchmarkLoad(b *testing.B, rowByRow bool) {
+	database := openTestDB(b)
+	f := newFixture(b, 2000)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tx, err := database.BeginTx(ctx, nil)
+		if err != nil {
+			b.Fatalf("failed to start transaction: %v", err)
+		}
+		if _, err := f.load(ctx, tx, database, DefaultChunkSize, rowByRow); err != nil {
+			tx.Rollback()
+			b.Fatalf("failed to load: %v", err)
+		}
+		tx.Rollback()
+	}
+}
+
+func BenchmarkLoadBulk(b *testing.B) {
+	benchmarkLoad(b, false)
+}
+
+func BenchmarkLoadRowByRow(b *testing.B) {
+	benchmarkLoad(b, true)
+}
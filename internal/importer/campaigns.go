@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// CampaignImportResult contains the results of a campaign list import
+type CampaignImportResult struct {
+	RowsRead           int
+	CampaignsInserted  int
+	CampaignsUpdated   int
+	CampaignsUnchanged int
+	UnknownCampaigns   []db.GetUnknownCampaignIDsRow // Campaign IDs on jobs that still have no name
+	BadDates           map[string]int                // Start and end dates that didn't parse, by column
+	Duration           time.Duration
+	DryRun             bool // Nothing was committed
+}
+
+// ImportCampaigns imports the ServiceTitan Campaigns export or a
+// hand-maintained campaign list. Campaigns are keyed by their ServiceTitan
+// ID, so re-importing a file updates names and details in place.
+func (i *Importer) ImportCampaigns(ctx context.Context, path string) (*CampaignImportResult, error) {
+	startTime := time.Now()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open campaign file: %w", err)
+	}
+	defer file.Close()
+
+	// Campaign lists are small, so read the whole file before writing anything
+	badDates := make(dateFailures)
+	rows, err := parser.ParseCampaigns(i.newParser(path, nil, parser.ReportCampaigns, badDates), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse campaign file: %w", err)
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	result := &CampaignImportResult{RowsRead: len(rows), BadDates: badDates}
+	var counts upsertCounts
+	for _, row := range rows {
+		_, err := counts.record(txQueries.UpsertCampaign(ctx, db.UpsertCampaignParams{
+			ID:             strconv.FormatInt(row.CampaignID, 10),
+			Name:           row.Name,
+			Category:       sqlNullString(row.Category),
+			Channel:        sqlNullString(row.Channel),
+			ActiveFrom:     sqlNullTime(row.ActiveFrom),
+			ActiveTo:       sqlNullTime(row.ActiveTo),
+			SourceFilename: sql.NullString{String: filepath.Base(path), Valid: true},
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save campaign %d: %w", row.CampaignID, err)
+		}
+	}
+	result.CampaignsInserted = counts.inserted
+	result.CampaignsUpdated = counts.updated
+	result.CampaignsUnchanged = counts.unchanged
+
+	result.UnknownCampaigns, err = txQueries.GetUnknownCampaignIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for unknown campaigns: %w", err)
+	}
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.DryRun = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// ImportError is returned when ImportFiles fails. The failure is recorded as
+// a failed import batch so it shows up in the import history.
+type ImportError struct {
+	BatchID int64  // ID of the failed batch record, 0 if it couldn't be saved
+	Step    string // Import step that failed, e.g. "jobs" or "validation"
+	Row     int    // CSV row being processed, 0 when not tied to a row
+	Err     error
+}
+
+func (e *ImportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// rowError attaches the CSV row number to a database error for a single row
+type rowError struct {
+	row int
+	err error
+}
+
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rowError) Unwrap() error {
+	return e.err
+}
+
+// failedRow returns the row number carried by err, or 0 if there is none
+func failedRow(err error) int {
+	var validationErr *parser.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Row
+	}
+	var rowErr *rowError
+	if errors.As(err, &rowErr) {
+		return rowErr.row
+	}
+	return 0
+}
+
+// recordFailure saves a failed import batch using the importer's own
+// connection rather than the import transaction, so the record is kept
+// after that transaction rolls back. params holds the files' names and
+// hashes. Dry runs are not recorded.
+func (i *Importer) recordFailure(ctx context.Context, params db.CreateFailedImportBatchParams, step string, err error) *ImportError {
+	importErr := &ImportError{Step: step, Row: failedRow(err), Err: err}
+	if i.DryRun {
+		return importErr
+	}
+
+	params.ErrorMessage = sql.NullString{String: err.Error(), Valid: true}
+	params.FailedStep = sql.NullString{String: step, Valid: true}
+	if importErr.Row > 0 {
+		params.FailedRow = sql.NullInt32{Int32: int32(importErr.Row), Valid: true}
+	}
+
+	batchID, recordErr := i.queries.CreateFailedImportBatch(ctx, params)
+	if recordErr != nil {
+		// Log warning but don't mask the import error
+		fmt.Printf("Warning: failed to record failed import: %v\n", recordErr)
+		return importErr
+	}
+
+	importErr.BatchID = batchID
+	return importErr
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 
 	"github.com/datsun80zx/sta.git/internal/db"
@@ -19,45 +21,148 @@ import (
 type Importer struct {
 	db      *sql.DB
 	queries *db.Queries
+
+	// ChunkSize is how many parsed rows are buffered before they are written,
+	// which bounds memory use for large exports
+	ChunkSize int
+
+	// Mapping adds header aliases for custom ServiceTitan reports
+	Mapping *parser.ColumnMapping
+
+	// Sheet selects the worksheet (by name or 1-based position) when the
+	// exports are XLSX workbooks; empty means the first sheet
+	Sheet string
+
+	// DateFormat is how the exports write dates and the timezone they're in;
+	// the zero value tries the built-in month-first formats in UTC
+	DateFormat parser.DateFormat
+
+	// Lenient quarantines rows that fail to parse in rejected_rows and
+	// imports the rest, instead of failing the whole file on the first bad row
+	Lenient bool
+
+	// DryRun runs the whole import inside a transaction that is always
+	// rolled back, and fills ImportResult.DryRun with what would change
+	DryRun bool
+
+	// RowByRow upserts jobs, invoices, customers and job technicians one row
+	// at a time instead of bulk loading each chunk with COPY. Slower; kept
+	// for comparison and for pinning down a bad row.
+	RowByRow bool
 }
 
+// DefaultChunkSize is the number of rows imported per chunk
+const DefaultChunkSize = 1000
+
 // NewImporter creates a new importer instance
 func NewImporter(database *sql.DB) *Importer {
 	return &Importer{
-		db:      database,
-		queries: db.New(database),
+		db:        database,
+		queries:   db.New(database),
+		ChunkSize: DefaultChunkSize,
 	}
 }
 
 // ImportResult contains the results of an import operation
 type ImportResult struct {
 	BatchID               int64
-	JobsImported          int
-	InvoicesImported      int
+	JobsInserted          int
+	JobsUpdated           int
+	JobsUnchanged         int
+	InvoicesInserted      int
+	InvoicesUpdated       int
+	InvoicesUnchanged     int
+	InvoicesLinked        int // Invoices attached to jobs from earlier batches
 	InvoicesSkipped       int
-	CustomersUpserted     int
+	EstimatesInserted     int
+	EstimatesUpdated      int
+	EstimatesUnchanged    int
+	EstimatesSkipped      int
+	RowsRejected          int            // Rows quarantined in rejected_rows (lenient mode)
+	BadDates              map[string]int // Non-empty date cells that didn't parse and were left empty, by column
+	CustomersInserted     int
+	CustomersUpdated      int
+	CustomersUnchanged    int
+	LocationsInserted     int
+	LocationsUpdated      int
+	LocationsUnchanged    int
 	TechniciansImported   int
 	JobMetricsCalculated  int
+	JobVersionsRecorded   int // New jobs plus jobs whose status, technicians, subtotal or metrics changed
 	TechMetricsCalculated int
+	UnknownCampaigns      []db.GetUnknownCampaignIDsRow // Campaign IDs on jobs with no row in campaigns
 	ValidationResult      *ValidationResult
 	Duration              time.Duration
 	AlreadyImported       bool
+	DryRun                *DryRunReport // Only set for dry runs; nothing was committed
+}
+
+// DryRunReport lists what a dry-run import would have changed
+type DryRunReport struct {
+	NewJobIDs          []string
+	UpdatedJobIDs      []string
+	NewCustomerIDs     []int64
+	UpdatedCustomerIDs []int64
+	NewTechnicians     []string
+	MissingJobIDs      []string // Jobs referenced by invoices or estimates but found nowhere
 }
 
-// ImportFiles imports both jobs and invoices CSV files
-func (i *Importer) ImportFiles(ctx context.Context, jobsPath, invoicesPath string) (*ImportResult, error) {
+// upsertCounts tallies how an upsert pass treated each row
+type upsertCounts struct {
+	inserted  int
+	updated   int
+	unchanged int
+}
+
+// record classifies the outcome of a single Upsert* call.
+// Upserts return sql.ErrNoRows when the existing row already matches.
+func (c *upsertCounts) record(inserted bool, err error) (bool, error) {
+	switch {
+	case err == sql.ErrNoRows:
+		c.unchanged++
+		return false, nil
+	case err != nil:
+		return false, err
+	case inserted:
+		c.inserted++
+	default:
+		c.updated++
+	}
+	return true, nil
+}
+
+// ImportFiles imports the jobs and invoices files, plus the estimates file
+// when estimatesPath isn't empty.
+// Rows that already exist (from an overlapping export) are updated in place,
+// and metrics are only recalculated for rows that changed.
+// When DryRun is set every step still runs, but the transaction is rolled back.
+func (i *Importer) ImportFiles(ctx context.Context, jobsPath, invoicesPath, estimatesPath string) (*ImportResult, error) {
 	startTime := time.Now()
 
+	if i.ChunkSize <= 0 {
+		i.ChunkSize = DefaultChunkSize
+	}
+
 	// Step 1: Calculate file hashes
 	jobsHash, invoicesHash, err := CalculateFileHashes(jobsPath, invoicesPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate file hashes: %w", err)
 	}
+	var estimatesFilename, estimatesHash sql.NullString
+	if estimatesPath != "" {
+		hash, err := CalculateFileHash(estimatesPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate file hashes: estimates file: %w", err)
+		}
+		estimatesFilename = sql.NullString{String: filepath.Base(estimatesPath), Valid: true}
+		estimatesHash = sql.NullString{String: hash, Valid: true}
+	}
 
 	// Step 2: Check if already imported
 	existingBatch, err := i.queries.GetImportBatchByHashes(ctx, db.GetImportBatchByHashesParams{
-		JobReportHash:     jobsHash,
-		InvoiceReportHash: invoicesHash,
+		JobReportHash:      jobsHash,
+		InvoiceReportHash:  invoicesHash,
+		EstimateReportHash: estimatesHash,
 	})
 	if err == nil {
 		// Already imported
@@ -71,113 +176,231 @@ func (i *Importer) ImportFiles(ctx context.Context, jobsPath, invoicesPath strin
 		return nil, fmt.Errorf("failed to check for existing import: %w", err)
 	}
 
-	// Step 3: Parse files
-	jobs, invoices, err := i.parseFiles(jobsPath, invoicesPath)
+	// Failures from here on are recorded outside the import transaction,
+	// which rolls back, so the attempt still shows up in the import history
+	failed := db.CreateFailedImportBatchParams{
+		JobReportFilename:      filepath.Base(jobsPath),
+		InvoiceReportFilename:  filepath.Base(invoicesPath),
+		EstimateReportFilename: estimatesFilename,
+		JobReportHash:          jobsHash,
+		InvoiceReportHash:      invoicesHash,
+		EstimateReportHash:     estimatesHash,
+	}
+	fail := func(step string, err error) (*ImportResult, error) {
+		return nil, i.recordFailure(ctx, failed, step, err)
+	}
+
+	// Step 3: Open files (rows are parsed as they are imported)
+	jobsFile, err := os.Open(jobsPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse files: %w", err)
+		return fail("open files", fmt.Errorf("failed to open jobs file: %w", err))
+	}
+	defer jobsFile.Close()
+
+	invoicesFile, err := os.Open(invoicesPath)
+	if err != nil {
+		return fail("open files", fmt.Errorf("failed to open invoices file: %w", err))
+	}
+	defer invoicesFile.Close()
+
+	var estimatesFile *os.File
+	if estimatesPath != "" {
+		estimatesFile, err = os.Open(estimatesPath)
+		if err != nil {
+			return fail("open files", fmt.Errorf("failed to open estimates file: %w", err))
+		}
+		defer estimatesFile.Close()
 	}
 
 	// Step 4: Start transaction
 	tx, err := i.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
+		return fail("start transaction", fmt.Errorf("failed to start transaction: %w", err))
 	}
 	defer tx.Rollback() // Rollback if not committed
 
 	txQueries := db.New(tx)
 
-	// Step 5: Create import batch
+	// Step 5: Create import batch (row counts are filled in once the files are read)
 	batch, err := txQueries.CreateImportBatch(ctx, db.CreateImportBatchParams{
-		JobReportFilename:     filepath.Base(jobsPath),
-		InvoiceReportFilename: filepath.Base(invoicesPath),
-		JobReportHash:         jobsHash,
-		InvoiceReportHash:     invoicesHash,
-		RowCountJobs:          int32(len(jobs)),
-		RowCountInvoices:      int32(len(invoices)),
-		Status:                "pending",
+		JobReportFilename:      failed.JobReportFilename,
+		InvoiceReportFilename:  failed.InvoiceReportFilename,
+		EstimateReportFilename: estimatesFilename,
+		JobReportHash:          jobsHash,
+		InvoiceReportHash:      invoicesHash,
+		EstimateReportHash:     estimatesHash,
+		Status:                 "pending",
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create import batch: %w", err)
+		return fail("create batch", fmt.Errorf("failed to create import batch: %w", err))
 	}
 
-	// Step 6: Import customers (upsert from job data)
-	customersUpserted, err := i.importCustomers(ctx, tx, jobs)
+	run := newImportRun(batch.ID)
+	run.lenient = i.Lenient
+
+	// Steps 6-7.5: Stream jobs, upserting customers, jobs and technicians chunk by chunk
+	jobRows, err := i.streamJobs(ctx, tx, i.newParser(jobsPath, run, parser.ReportJobs, run.badDates), jobsFile, run)
 	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("failed to import customers: %w", err)
+		return fail("jobs", fmt.Errorf("failed to import jobs file: %w", err))
 	}
 
-	// Step 7: Import jobs and get the set of valid job IDs
-	validJobIDs, err := i.importJobs(ctx, tx, jobs, batch.ID)
+	// Step 8: Stream invoices (skip those without matching jobs)
+	// Invoices for jobs from earlier batches are linked to those jobs
+	invoiceRows, err := i.streamInvoices(ctx, tx, i.newParser(invoicesPath, run, parser.ReportInvoices, run.badDates), invoicesFile, run)
 	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("failed to import jobs: %w", err)
+		return fail("invoices", fmt.Errorf("failed to import invoices file: %w", err))
 	}
 
-	// Step 7.5: Import technicians
-	techniciansImported, err := i.ImportTechnicians(ctx, tx, jobs, batch.ID)
-	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("failed to import technicians: %w", err)
+	// Step 8.5: Stream estimates, if given (skip those without matching jobs)
+	estimateRows := 0
+	if estimatesFile != nil {
+		estimateRows, err = i.streamEstimates(ctx, tx, i.newParser(estimatesPath, run, parser.ReportEstimates, run.badDates), estimatesFile, run)
+		if err != nil {
+			return fail("estimates", fmt.Errorf("failed to import estimates file: %w", err))
+		}
+	}
+
+	// Keep snapshots only for rows this batch actually changed, so deleting
+	// the batch can roll them back
+	if err := txQueries.PruneBatchSnapshots(ctx, batch.ID); err != nil {
+		return fail("snapshots", fmt.Errorf("failed to prune batch snapshots: %w", err))
 	}
 
-	// Step 8: Import invoices (skip those without matching jobs)
-	invoicesImported, invoicesSkipped, skippedJobIDs, err := i.importInvoices(ctx, tx, invoices, batch.ID, validJobIDs)
+	// Quarantine rows that failed to parse (lenient mode only)
+	if err := i.saveRejectedRows(ctx, tx, run); err != nil {
+		return fail("rejected rows", err)
+	}
+
+	err = txQueries.UpdateImportBatchRowCounts(ctx, db.UpdateImportBatchRowCountsParams{
+		ID:                batch.ID,
+		RowCountJobs:      int32(jobRows),
+		RowCountInvoices:  int32(invoiceRows),
+		RowCountEstimates: int32(estimateRows),
+	})
 	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("failed to import invoices: %w", err)
+		return fail("row counts", fmt.Errorf("failed to update batch row counts: %w", err))
 	}
 
 	// Step 9: Validate data
 	validationResult, err := ValidateImport(ctx, tx, batch.ID)
 	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return fail("validation", fmt.Errorf("validation failed: %w", err))
 	}
 
 	// Add skipped invoices warning if any were skipped
-	if invoicesSkipped > 0 {
+	if run.invoices.skipped > 0 {
 		validationResult.Warnings = append(validationResult.Warnings,
-			fmt.Sprintf("Skipped %d invoices referencing %d jobs not in jobs report",
-				invoicesSkipped, len(skippedJobIDs)))
+			fmt.Sprintf("Skipped %d invoices referencing %d jobs not in jobs report or database",
+				run.invoices.skipped, len(run.invoices.missingJobIDs)))
+	}
+	if run.estimates.skipped > 0 {
+		validationResult.Warnings = append(validationResult.Warnings,
+			fmt.Sprintf("Skipped %d estimates referencing %d jobs not in jobs report or database",
+				run.estimates.skipped, len(run.estimates.missingJobIDs)))
+	}
+	if run.bulkFallback != nil {
+		validationResult.Warnings = append(validationResult.Warnings,
+			fmt.Sprintf("Bulk load failed and was redone row by row: %v", run.bulkFallback))
+	}
+	if len(run.rejected) > 0 {
+		validationResult.Warnings = append(validationResult.Warnings,
+			fmt.Sprintf("Rejected %d rows that failed to parse (see: sta import errors %d)",
+				len(run.rejected), batch.ID))
 	}
 
-	// Step 10: Calculate job metrics (Go-side)
-	jobMetricsCalculated, err := i.calculateAndSaveJobMetrics(ctx, tx, jobs, invoices, validJobIDs)
+	// Step 10: Recalculate job metrics (Go-side) for jobs whose job or invoice rows changed
+	// This includes jobs from earlier batches whose late invoices just arrived
+	jobMetricsCalculated, err := i.recalculateJobMetrics(ctx, tx, run.changedJobIDs())
 	if err != nil {
-		txQueries.UpdateImportBatchStatus(ctx, db.UpdateImportBatchStatusParams{
-			ID:           batch.ID,
-			Status:       "failed",
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-		})
-		return nil, fmt.Errorf("failed to calculate job metrics: %w", err)
+		return fail("job metrics", fmt.Errorf("failed to calculate job metrics: %w", err))
 	}
 
-	// Step 10.5: Calculate technician metrics (Go-side)
-	techMetricsCalculated, err := i.calculateAndSaveTechnicianMetrics(ctx, tx, jobs, batch.ID)
+	// Step 10.1: Keep the version each changed job had before this batch in
+	// its history, and record the new one
+	jobVersionsRecorded, err := recordJobVersions(ctx, txQueries, batch.ID, run.changedJobIDs())
 	if err != nil {
-		// Log warning but don't fail - technician metrics are supplementary
-		fmt.Printf("Warning: failed to calculate technician metrics: %v\n", err)
+		return fail("job history", err)
+	}
+
+	// Step 10.2: Roll the changed jobs up into their customers' job dates,
+	// job counts and lifetime revenue. A job that moved to another customer
+	// is only in its old customer's rollups until that customer is refreshed
+	// too, so the customers in this batch's snapshots are included.
+	previousCustomerIDs, err := txQueries.GetCustomerIDsForBatch(ctx, batch.ID)
+	if err != nil {
+		return fail("customer rollups", fmt.Errorf("failed to get customers for batch: %w", err))
+	}
+	err = txQueries.RefreshCustomerRollups(ctx, db.RefreshCustomerRollupsParams{
+		CustomerIds: previousCustomerIDs,
+		JobIds:      run.changedJobIDs(),
+	})
+	if err != nil {
+		return fail("customer rollups", fmt.Errorf("failed to refresh customer rollups: %w", err))
+	}
+
+	// Step 10.5: Recalculate technician metrics (Go-side) for the technicians
+	// of changed jobs and estimates. Changed jobs, late invoices and new
+	// estimates can all move them.
+	techIDs, err := affectedTechnicianIDs(ctx, txQueries, batch.ID, run.changedJobIDs(), run.technicians.unlinked)
+	if err != nil {
+		return fail("technician metrics", err)
+	}
+	techMetricsCalculated, err := i.calculateAndSaveTechnicianMetrics(ctx, tx, techIDs)
+	if err != nil {
+		return fail("technician metrics", fmt.Errorf("failed to calculate technician metrics: %w", err))
+	}
+
+	unknownCampaigns, err := txQueries.GetUnknownCampaignIDs(ctx)
+	if err != nil {
+		return fail("campaigns", fmt.Errorf("failed to check for unknown campaigns: %w", err))
+	}
+
+	result := &ImportResult{
+		BatchID:               batch.ID,
+		JobsInserted:          run.jobs.counts.inserted,
+		JobsUpdated:           run.jobs.counts.updated,
+		JobsUnchanged:         run.jobs.counts.unchanged,
+		InvoicesInserted:      run.invoices.counts.inserted,
+		InvoicesUpdated:       run.invoices.counts.updated,
+		InvoicesUnchanged:     run.invoices.counts.unchanged,
+		InvoicesLinked:        run.invoices.linked,
+		InvoicesSkipped:       run.invoices.skipped,
+		EstimatesInserted:     run.estimates.counts.inserted,
+		EstimatesUpdated:      run.estimates.counts.updated,
+		EstimatesUnchanged:    run.estimates.counts.unchanged,
+		EstimatesSkipped:      run.estimates.skipped,
+		RowsRejected:          len(run.rejected),
+		BadDates:              run.badDates,
+		CustomersInserted:     run.customers.counts.inserted,
+		CustomersUpdated:      run.customers.counts.updated,
+		CustomersUnchanged:    run.customers.counts.unchanged,
+		LocationsInserted:     run.locations.counts.inserted,
+		LocationsUpdated:      run.locations.counts.updated,
+		LocationsUnchanged:    run.locations.counts.unchanged,
+		TechniciansImported:   len(run.technicians.cache),
+		JobMetricsCalculated:  jobMetricsCalculated,
+		JobVersionsRecorded:   jobVersionsRecorded,
+		TechMetricsCalculated: techMetricsCalculated,
+		UnknownCampaigns:      unknownCampaigns,
+		ValidationResult:      validationResult,
+		AlreadyImported:       false,
+	}
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.BatchID = 0
+		result.DryRun = &DryRunReport{
+			NewJobIDs:          run.jobs.newJobIDs,
+			UpdatedJobIDs:      run.jobs.updatedJobIDs,
+			NewCustomerIDs:     run.customers.newIDs,
+			UpdatedCustomerIDs: run.customers.updatedIDs,
+			NewTechnicians:     run.technicians.created,
+			MissingJobIDs:      run.missingJobIDs(),
+		}
+		sortInt64s(result.DryRun.NewCustomerIDs)
+		sortInt64s(result.DryRun.UpdatedCustomerIDs)
+		result.Duration = time.Since(startTime)
+		return result, nil
 	}
 
 	// Step 11: Mark batch as success
@@ -187,53 +410,78 @@ func (i *Importer) ImportFiles(ctx context.Context, jobsPath, invoicesPath strin
 		ErrorMessage: sql.NullString{Valid: false},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update batch status: %w", err)
+		return fail("batch status", fmt.Errorf("failed to update batch status: %w", err))
 	}
 
 	// Step 12: Commit transaction
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return fail("commit", fmt.Errorf("failed to commit transaction: %w", err))
 	}
 
-	return &ImportResult{
-		BatchID:               batch.ID,
-		JobsImported:          len(jobs),
-		InvoicesImported:      invoicesImported,
-		InvoicesSkipped:       invoicesSkipped,
-		CustomersUpserted:     customersUpserted,
-		TechniciansImported:   techniciansImported,
-		JobMetricsCalculated:  jobMetricsCalculated,
-		TechMetricsCalculated: techMetricsCalculated,
-		ValidationResult:      validationResult,
-		Duration:              time.Since(startTime),
-		AlreadyImported:       false,
-	}, nil
+	result.Duration = time.Since(startTime)
+	return result, nil
 }
 
-// calculateAndSaveJobMetrics calculates job metrics in Go and saves to DB
-func (i *Importer) calculateAndSaveJobMetrics(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, invoices []parser.InvoiceRow, validJobIDs map[string]bool) (int, error) {
-	// Convert parser types to metrics types
-	jobData := make([]metrics.JobData, 0, len(jobs))
-	for _, j := range jobs {
-		if !validJobIDs[j.JobID] {
-			continue
+// recalculateJobMetrics recalculates metrics in Go for the given jobs using
+// everything stored for them, including invoices from earlier batches.
+// Jobs are processed ChunkSize at a time to keep memory bounded.
+func (i *Importer) recalculateJobMetrics(ctx context.Context, tx *sql.Tx, jobIDs []string) (int, error) {
+	total := 0
+	for start := 0; start < len(jobIDs); start += i.ChunkSize {
+		end := min(start+i.ChunkSize, len(jobIDs))
+		count, err := i.recalculateJobMetricsChunk(ctx, tx, jobIDs[start:end])
+		if err != nil {
+			return total, err
 		}
+		total += count
+	}
+	return total, nil
+}
+
+// recalculateJobMetricsChunk recalculates and replaces metrics for one chunk of jobs
+func (i *Importer) recalculateJobMetricsChunk(ctx context.Context, tx *sql.Tx, jobIDs []string) (int, error) {
+	txQueries := db.New(tx)
+
+	jobRows, err := txQueries.GetJobsForMetricsByIDs(ctx, jobIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load jobs for metrics: %w", err)
+	}
+
+	invoiceRows, err := txQueries.GetInvoicesForMetricsByJobIDs(ctx, jobIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load invoices for metrics: %w", err)
+	}
+
+	laborRows, err := txQueries.GetJobLaborCostsByJobIDs(ctx, jobIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load labor costs for metrics: %w", err)
+	}
+	laborByJob := make(map[string]decimal.Decimal, len(laborRows))
+	for _, l := range laborRows {
+		laborByJob[l.JobID] = l.LaborCosts
+	}
+
+	// Convert db rows to metrics types
+	jobData := make([]metrics.JobData, 0, len(jobRows))
+	for _, j := range jobRows {
+		laborCosts, hasLabor := laborByJob[j.ID]
 		jobData = append(jobData, metrics.JobData{
-			ID:           j.JobID,
-			Status:       j.Status,
-			JobsSubtotal: decimalOrZero(j.JobsSubtotal),
+			ID:               j.ID,
+			Status:           j.Status,
+			JobsSubtotal:     j.JobsSubtotal,
+			TotalHoursWorked: j.TotalHoursWorked,
+			LaborCosts:       laborCosts,
+			HasLabor:         hasLabor,
 		})
 	}
 
-	invoiceData := make([]metrics.InvoiceData, 0, len(invoices))
-	for _, inv := range invoices {
-		if !validJobIDs[inv.JobID] {
-			continue
-		}
+	invoiceData := make([]metrics.InvoiceData, 0, len(invoiceRows))
+	for _, inv := range invoiceRows {
 		invoiceData = append(invoiceData, metrics.InvoiceData{
-			ID:           inv.InvoiceID,
+			ID:           inv.ID,
 			JobID:        inv.JobID,
-			CostsTotal:   decimalOrZero(inv.CostsTotal),
+			Total:        inv.Total,
+			CostsTotal:   inv.CostsTotal,
 			IsAdjustment: inv.IsAdjustment,
 		})
 	}
@@ -241,8 +489,14 @@ func (i *Importer) calculateAndSaveJobMetrics(ctx context.Context, tx *sql.Tx, j
 	// Calculate metrics in Go
 	jobMetrics := metrics.CalculateJobMetrics(jobData, invoiceData)
 
+	// Clear old metrics first: a job that was re-exported as Canceled or
+	// without revenue no longer qualifies and must not keep a stale row
+	if err := txQueries.DeleteJobMetricsForJobs(ctx, jobIDs); err != nil {
+		return 0, fmt.Errorf("failed to clear job metrics: %w", err)
+	}
+
 	// Save to database
-	err := metrics.SaveJobMetrics(ctx, tx, jobMetrics)
+	err = metrics.SaveJobMetrics(ctx, tx, jobMetrics)
 	if err != nil {
 		return 0, err
 	}
@@ -250,73 +504,176 @@ func (i *Importer) calculateAndSaveJobMetrics(ctx context.Context, tx *sql.Tx, j
 	return len(jobMetrics), nil
 }
 
-// calculateAndSaveTechnicianMetrics calculates technician metrics in Go and saves to DB
-func (i *Importer) calculateAndSaveTechnicianMetrics(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, batchID int64) (int, error) {
-	// Get all technician IDs
-	rows, err := tx.QueryContext(ctx, "SELECT id FROM technicians")
+// recordJobVersions adds a job_versions row from the batch for each of the
+// jobs that is new or no longer matches its current version, ending that
+// version. It runs after recalculateJobMetrics, since versions carry the
+// job's metrics. Returns how many versions were opened.
+func recordJobVersions(ctx context.Context, q *db.Queries, batchID int64, jobIDs []string) (int, error) {
+	err := q.CloseChangedJobVersions(ctx, db.CloseChangedJobVersionsParams{
+		JobIds:        jobIDs,
+		ImportBatchID: batchID,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get technician IDs: %w", err)
+		return 0, fmt.Errorf("failed to close job versions: %w", err)
 	}
-	defer rows.Close()
 
-	var techIDs []int64
-	for rows.Next() {
-		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return 0, err
-		}
-		techIDs = append(techIDs, id)
+	opened, err := q.CreateJobVersions(ctx, db.CreateJobVersionsParams{
+		ImportBatchID: batchID,
+		JobIds:        jobIDs,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create job versions: %w", err)
 	}
+	return int(opened), nil
+}
 
-	if len(techIDs) == 0 {
+// calculateAndSaveTechnicianMetrics recalculates the given technicians'
+// metrics in Go over every job credited to them and saves them to the DB.
+// Callers pass the technicians their changes can have moved; see
+// affectedTechnicianIDs. The technicians' jobs are collected in a temporary
+// table and read back ChunkSize at a time, so memory stays bounded however
+// long their history is.
+func (i *Importer) calculateAndSaveTechnicianMetrics(ctx context.Context, tx *sql.Tx, technicianIDs []int64) (int, error) {
+	if len(technicianIDs) == 0 {
 		return 0, nil
 	}
 
-	// Get job_technicians for this batch
-	jtRows, err := tx.QueryContext(ctx, `
-		SELECT jt.job_id, jt.technician_id, jt.role
-		FROM job_technicians jt
-		JOIN jobs j ON jt.job_id = j.id
-		WHERE j.import_batch_id = $1
-	`, batchID)
+	_, err := tx.ExecContext(ctx,
+		"CREATE TEMP TABLE IF NOT EXISTS technician_metric_jobs (job_id TEXT PRIMARY KEY) ON COMMIT DROP")
 	if err != nil {
-		return 0, fmt.Errorf("failed to get job_technicians: %w", err)
+		return 0, fmt.Errorf("failed to create technician_metric_jobs: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "TRUNCATE technician_metric_jobs"); err != nil {
+		return 0, fmt.Errorf("failed to empty technician_metric_jobs: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO technician_metric_jobs (job_id)
+		SELECT job_id FROM job_technicians WHERE technician_id = ANY($1::bigint[])
+		UNION
+		SELECT job_id FROM estimates WHERE technician_id = ANY($1::bigint[])
+	`, pq.Array(technicianIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to collect technician jobs: %w", err)
+	}
+
+	pageSize := i.ChunkSize
+	if pageSize <= 0 {
+		pageSize = DefaultChunkSize
 	}
-	defer jtRows.Close()
 
-	var jobTechs []metrics.JobTechnicianData
-	for jtRows.Next() {
-		var jt metrics.JobTechnicianData
-		if err := jtRows.Scan(&jt.JobID, &jt.TechnicianID, &jt.Role); err != nil {
+	builder := metrics.NewTechnicianMetricsBuilder(technicianIDs)
+	lastJobID := ""
+	for {
+		jobIDs, err := technicianMetricJobsPage(ctx, tx, lastJobID, pageSize)
+		if err != nil {
 			return 0, err
 		}
-		jobTechs = append(jobTechs, jt)
+		if len(jobIDs) == 0 {
+			break
+		}
+		if err := addTechnicianMetricsPage(ctx, tx, builder, jobIDs); err != nil {
+			return 0, err
+		}
+		lastJobID = jobIDs[len(jobIDs)-1]
 	}
 
-	// Convert jobs to metrics format
-	jobsForMetrics := make([]metrics.JobForTechMetrics, 0, len(jobs))
-	for _, j := range jobs {
-		estimateCount := 0
-		if j.EstimateCount != nil {
-			estimateCount = int(*j.EstimateCount)
+	techMetrics := builder.Metrics()
+	if err := metrics.SaveTechnicianMetrics(ctx, tx, techMetrics); err != nil {
+		return 0, err
+	}
+
+	return len(techMetrics), nil
+}
+
+// technicianMetricJobsPage returns up to limit job IDs from
+// technician_metric_jobs after lastJobID, in order
+func technicianMetricJobsPage(ctx context.Context, tx *sql.Tx, lastJobID string, limit int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT job_id FROM technician_metric_jobs WHERE job_id > $1 ORDER BY job_id LIMIT $2",
+		lastJobID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page technician jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobIDs []string
+	for rows.Next() {
+		var jobID string
+		if err := rows.Scan(&jobID); err != nil {
+			return nil, err
 		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs, rows.Err()
+}
+
+// addTechnicianMetricsPage loads the links, jobs, job metrics and estimates
+// of one page of jobs and adds them to builder
+func addTechnicianMetricsPage(ctx context.Context, tx *sql.Tx, builder *metrics.TechnicianMetricsBuilder, jobIDs []string) error {
+	txQueries := db.New(tx)
+
+	jtRows, err := txQueries.GetJobTechniciansForJobs(ctx, jobIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get job_technicians: %w", err)
+	}
+
+	jobTechs := make([]metrics.JobTechnicianData, 0, len(jtRows))
+	for _, jt := range jtRows {
+		jobTechs = append(jobTechs, metrics.JobTechnicianData{
+			JobID:        jt.JobID,
+			TechnicianID: jt.TechnicianID,
+			Role:         jt.Role,
+		})
+	}
+
+	// Convert jobs to metrics format
+	jobRows, err := txQueries.GetJobsForTechnicianMetricsByIDs(ctx, jobIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get jobs for technician metrics: %w", err)
+	}
+	jobsForMetrics := make([]metrics.JobForTechMetrics, 0, len(jobRows))
+	for _, j := range jobRows {
 		jobsForMetrics = append(jobsForMetrics, metrics.JobForTechMetrics{
-			ID:                    j.JobID,
+			ID:                    j.ID,
 			Status:                j.Status,
-			JobsSubtotal:          decimalOrZero(j.JobsSubtotal),
-			EstimateSalesSubtotal: decimalOrZero(j.EstimateSalesSubtotal),
-			TotalHoursWorked:      decimalOrZero(j.TotalHoursWorked),
-			EstimateCount:         estimateCount,
+			JobsSubtotal:          j.JobsSubtotal,
+			EstimateSalesSubtotal: j.EstimateSalesSubtotal,
+			TotalHoursWorked:      j.TotalHoursWorked,
+			EstimateCount:         int(j.EstimateCount),
 		})
 	}
 
+	// Estimates on these jobs, credited to the estimate's technician
+	estimateRows, err := txQueries.GetEstimatesForTechnicianMetricsByJobIDs(ctx, jobIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get estimates for technician metrics: %w", err)
+	}
+	estimatesForMetrics := make([]metrics.EstimateForTechMetrics, 0, len(estimateRows))
+	for _, e := range estimateRows {
+		estimate := metrics.EstimateForTechMetrics{
+			ID:           e.ID,
+			JobID:        e.JobID,
+			TechnicianID: e.TechnicianID,
+			Status:       e.Status,
+			Subtotal:     e.Subtotal,
+		}
+		if e.CreatedOn.Valid {
+			estimate.CreatedOn = &e.CreatedOn.Time
+		}
+		if e.SoldOn.Valid {
+			estimate.SoldOn = &e.SoldOn.Time
+		}
+		estimatesForMetrics = append(estimatesForMetrics, estimate)
+	}
+
 	// Get existing job metrics
 	jmRows, err := tx.QueryContext(ctx, `
 		SELECT job_id, revenue, total_costs, gross_profit, gross_margin_pct, invoice_count, has_adjustment
 		FROM job_metrics
-	`)
+		WHERE job_id = ANY($1::text[])
+	`, pq.Array(jobIDs))
 	if err != nil {
-		return 0, fmt.Errorf("failed to get job_metrics: %w", err)
+		return fmt.Errorf("failed to get job_metrics: %w", err)
 	}
 	defer jmRows.Close()
 
@@ -325,7 +682,7 @@ func (i *Importer) calculateAndSaveTechnicianMetrics(ctx context.Context, tx *sq
 		var jm metrics.JobMetric
 		var marginPct sql.NullFloat64
 		if err := jmRows.Scan(&jm.JobID, &jm.Revenue, &jm.TotalCosts, &jm.GrossProfit, &marginPct, &jm.InvoiceCount, &jm.HasAdjustment); err != nil {
-			return 0, err
+			return err
 		}
 		if marginPct.Valid {
 			jm.GrossMarginPct = decimal.NullDecimal{
@@ -335,55 +692,105 @@ func (i *Importer) calculateAndSaveTechnicianMetrics(ctx context.Context, tx *sq
 		}
 		jobMetrics = append(jobMetrics, jm)
 	}
-
-	// Calculate metrics in Go
-	techMetrics := metrics.CalculateTechnicianMetrics(techIDs, jobTechs, jobsForMetrics, jobMetrics)
-
-	// Save to database
-	err = metrics.SaveTechnicianMetrics(ctx, tx, techMetrics)
-	if err != nil {
-		return 0, err
+	if err := jmRows.Err(); err != nil {
+		return fmt.Errorf("failed to get job_metrics: %w", err)
 	}
 
-	return len(techMetrics), nil
+	builder.Add(jobTechs, jobsForMetrics, jobMetrics, estimatesForMetrics)
+	return nil
 }
 
-// parseFiles parses both CSV files
-func (i *Importer) parseFiles(jobsPath, invoicesPath string) ([]parser.JobRow, []parser.InvoiceRow, error) {
-	csvParser := parser.NewCSVParser()
+// affectedTechnicianIDs returns the technicians whose metrics changes to
+// jobIDs and to a batch's estimates can have moved: those credited with the
+// jobs now, those credited with the batch's estimates before and after it
+// changed them, and extra, technicians the caller already knows about, such
+// as those that lost their links to the jobs.
+func affectedTechnicianIDs(ctx context.Context, q *db.Queries, batchID int64, jobIDs []string, extra map[int64]bool) ([]int64, error) {
+	affected := make(map[int64]bool, len(extra))
+	for techID := range extra {
+		affected[techID] = true
+	}
 
-	// Parse jobs file
-	jobsFile, err := os.Open(jobsPath)
+	jobTechIDs, err := q.GetTechnicianIDsForJobs(ctx, jobIDs)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open jobs file: %w", err)
+		return nil, fmt.Errorf("failed to get technicians of changed jobs: %w", err)
 	}
-	defer jobsFile.Close()
-
-	jobs, err := csvParser.ParseJobs(jobsFile)
+	estimateTechIDs, err := q.GetTechnicianIDsForBatchEstimates(ctx, batchID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse jobs: %w", err)
+		return nil, fmt.Errorf("failed to get technicians of changed estimates: %w", err)
+	}
+	for _, techID := range append(jobTechIDs, estimateTechIDs...) {
+		affected[techID] = true
 	}
 
-	// Parse invoices file
-	invoicesFile, err := os.Open(invoicesPath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open invoices file: %w", err)
+	return mapKeys(affected), nil
+}
+
+// customerImportResult accumulates importCustomers passes over every chunk
+type customerImportResult struct {
+	counts     upsertCounts
+	newIDs     []int64
+	updatedIDs []int64
+	seen       map[int64]bool // customers counted by an earlier chunk
+	changed    map[int64]bool // customers inserted or updated by an earlier chunk
+}
+
+// record counts a customer once per import, even when its jobs span chunks
+func (r *customerImportResult) record(customerID int64, inserted bool, err error) error {
+	if !r.seen[customerID] {
+		r.seen[customerID] = true
+		changed, err := r.counts.record(inserted, err)
+		if err != nil {
+			return err
+		}
+		if changed && inserted {
+			r.newIDs = append(r.newIDs, customerID)
+		} else if changed {
+			r.updatedIDs = append(r.updatedIDs, customerID)
+		}
+		r.changed[customerID] = changed
+		return nil
 	}
-	defer invoicesFile.Close()
 
-	invoices, err := csvParser.ParseInvoices(invoicesFile)
+	// Seen before: only a first change moves it from unchanged to updated
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse invoices: %w", err)
+		return err
 	}
-
-	return jobs, invoices, nil
+	if !r.changed[customerID] {
+		r.changed[customerID] = true
+		r.counts.unchanged--
+		r.counts.updated++
+		r.updatedIDs = append(r.updatedIDs, customerID)
+	}
+	return nil
 }
 
-// importCustomers upserts customer records from job data
-func (i *Importer) importCustomers(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow) (int, error) {
+// importCustomers upserts customer records from a chunk of job data. Only
+// the export's customer fields are written here; first/last job dates, job
+// counts and lifetime revenue are computed from the jobs table by
+// RefreshCustomerRollups once every chunk is loaded.
+func (i *Importer) importCustomers(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, result *customerImportResult) error {
 	txQueries := db.New(tx)
 
-	// Build unique set of customers
+	for _, params := range customerUpsertParams(jobs) {
+		inserted, err := txQueries.UpsertCustomer(ctx, params)
+		if err := result.record(params.ID, inserted, err); err != nil {
+			return fmt.Errorf("failed to upsert customer %d: %w", params.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// customerUpsertParams returns one UpsertCustomer parameter set per customer
+// in a chunk of jobs, taking the customer's details from their most recent
+// job. Job dates are rolled up in SQL by RefreshCustomerRollups.
+func customerUpsertParams(jobs []parser.JobRow) []db.UpsertCustomerParams {
+	// Build unique set of customers, in order of first appearance
+	var order []int64
 	customerMap := make(map[int64]*parser.JobRow)
 	for idx := range jobs {
 		job := &jobs[idx]
@@ -396,26 +803,14 @@ func (i *Importer) importCustomers(ctx context.Context, tx *sql.Tx, jobs []parse
 			}
 		} else {
 			customerMap[job.CustomerID] = job
+			order = append(order, job.CustomerID)
 		}
 	}
 
-	// Upsert each customer
-	count := 0
-	for customerID, job := range customerMap {
-		// Determine first and last job dates for this customer
-		var firstJobDate, lastJobDate *time.Time
-		for _, j := range jobs {
-			if j.CustomerID == customerID && j.JobCompletionDate != nil {
-				if firstJobDate == nil || j.JobCompletionDate.Before(*firstJobDate) {
-					firstJobDate = j.JobCompletionDate
-				}
-				if lastJobDate == nil || j.JobCompletionDate.After(*lastJobDate) {
-					lastJobDate = j.JobCompletionDate
-				}
-			}
-		}
-
-		params := db.UpsertCustomerParams{
+	customers := make([]db.UpsertCustomerParams, 0, len(order))
+	for _, customerID := range order {
+		job := customerMap[customerID]
+		customers = append(customers, db.UpsertCustomerParams{
 			ID:            customerID,
 			CustomerName:  stringOrEmpty(job.CustomerName),
 			CustomerType:  sqlNullString(job.CustomerType),
@@ -425,118 +820,393 @@ func (i *Importer) importCustomers(ctx context.Context, tx *sql.Tx, jobs []parse
 			LocationCity:  sqlNullString(job.LocationCity),
 			LocationState: sqlNullString(job.LocationState),
 			LocationZip:   sqlNullString(job.LocationZip),
-			FirstJobDate:  sqlNullTime(firstJobDate),
-			LastJobDate:   sqlNullTime(lastJobDate),
+		})
+	}
+
+	return customers
+}
+
+// importLocations upserts the service locations from a chunk of job data.
+// Each location takes its address and customer from its most recent job in
+// the chunk; job dates are merged in SQL, as for customers.
+func (i *Importer) importLocations(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, result *customerImportResult) error {
+	txQueries := db.New(tx)
+
+	type location struct {
+		job                       *parser.JobRow // most recent job at the location
+		firstJobDate, lastJobDate *time.Time
+	}
+	locations := make(map[int64]*location)
+	var locationIDs []int64
+	for idx := range jobs {
+		job := &jobs[idx]
+		if job.LocationID == nil {
+			continue
+		}
+
+		loc, ok := locations[*job.LocationID]
+		if !ok {
+			loc = &location{job: job}
+			locations[*job.LocationID] = loc
+			locationIDs = append(locationIDs, *job.LocationID)
+		} else if job.JobCompletionDate != nil &&
+			(loc.job.JobCompletionDate == nil || job.JobCompletionDate.After(*loc.job.JobCompletionDate)) {
+			loc.job = job
 		}
 
-		_, err := txQueries.UpsertCustomer(ctx, params)
+		if date := job.JobCompletionDate; date != nil {
+			if loc.firstJobDate == nil || date.Before(*loc.firstJobDate) {
+				loc.firstJobDate = date
+			}
+			if loc.lastJobDate == nil || date.After(*loc.lastJobDate) {
+				loc.lastJobDate = date
+			}
+		}
+	}
+
+	for _, locationID := range locationIDs {
+		loc := locations[locationID]
+		inserted, err := txQueries.UpsertLocation(ctx, db.UpsertLocationParams{
+			ID:           locationID,
+			CustomerID:   loc.job.CustomerID,
+			City:         sqlNullString(loc.job.LocationCity),
+			State:        sqlNullString(loc.job.LocationState),
+			Zip:          sqlNullString(loc.job.LocationZip),
+			FirstJobDate: sqlNullTime(loc.firstJobDate),
+			LastJobDate:  sqlNullTime(loc.lastJobDate),
+		})
+		if err := result.record(locationID, inserted, err); err != nil {
+			return fmt.Errorf("failed to upsert location %d: %w", locationID, err)
+		}
+	}
+
+	return nil
+}
+
+// importBusinessUnits upserts the business units referenced by a chunk of
+// jobs, named after the last job in the chunk that has a name for them
+func (i *Importer) importBusinessUnits(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow) error {
+	txQueries := db.New(tx)
+
+	names := make(map[int64]*string)
+	var unitIDs []int64
+	for _, job := range jobs {
+		if job.BusinessUnitID == nil {
+			continue
+		}
+		name, ok := names[*job.BusinessUnitID]
+		if !ok {
+			unitIDs = append(unitIDs, *job.BusinessUnitID)
+		}
+		if job.BusinessUnit != nil || name == nil {
+			names[*job.BusinessUnitID] = job.BusinessUnit
+		}
+	}
+
+	for _, unitID := range unitIDs {
+		err := txQueries.UpsertBusinessUnit(ctx, db.UpsertBusinessUnitParams{
+			ID:   unitID,
+			Name: sqlNullString(names[unitID]),
+		})
 		if err != nil {
-			return count, fmt.Errorf("failed to upsert customer %d: %w", customerID, err)
+			return fmt.Errorf("failed to upsert business unit %d: %w", unitID, err)
 		}
-		count++
 	}
 
-	return count, nil
+	return nil
+}
+
+// jobImportResult accumulates importJobs passes over every chunk
+type jobImportResult struct {
+	counts        upsertCounts
+	validJobIDs   map[string]bool // every job ID in the file
+	newJobIDs     []string
+	updatedJobIDs []string
+}
+
+// snapshotJobs saves the current version of the jobs a chunk may overwrite,
+// so deleting the batch can restore them
+func snapshotJobs(ctx context.Context, q *db.Queries, jobs []parser.JobRow, batchID int64) error {
+	jobIDs := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.JobID)
+	}
+	err := q.SnapshotJobs(ctx, db.SnapshotJobsParams{
+		ImportBatchID: batchID,
+		JobIds:        jobIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot jobs: %w", err)
+	}
+	return nil
 }
 
-// importJobs inserts job records and returns the set of valid job IDs
-func (i *Importer) importJobs(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, batchID int64) (map[string]bool, error) {
+// importJobs upserts a chunk of job records
+func (i *Importer) importJobs(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, batchID int64, result *jobImportResult) error {
 	txQueries := db.New(tx)
-	validJobIDs := make(map[string]bool)
-
-	for idx, job := range jobs {
-		params := db.CreateJobParams{
-			ID:                    job.JobID,
-			CustomerID:            job.CustomerID,
-			ImportBatchID:         batchID,
-			JobType:               job.JobType,
-			BusinessUnit:          sqlNullString(job.BusinessUnit),
-			Status:                job.Status,
-			JobCreationDate:       sqlNullTime(job.JobCreationDate),
-			JobScheduleDate:       sqlNullTime(job.JobScheduleDate),
-			JobCompletionDate:     sqlNullTime(job.JobCompletionDate),
-			AssignedTechnician:    sqlNullString(job.AssignedTechnicians),
-			SoldByTechnician:      sqlNullString(job.SoldBy),
-			BookedBy:              sqlNullString(job.BookedBy),
-			CampaignName:          sqlNullString(stringFromInt64Ptr(job.JobCampaignID)),
-			CampaignCategory:      sqlNullString(job.CampaignCategory),
-			CallCampaign:          sqlNullString(stringFromInt64Ptr(job.CallCampaignID)),
-			JobsSubtotal:          decimalOrZero(job.JobsSubtotal),
-			JobTotal:              decimalOrZero(job.JobTotal),
-			EstimateSalesSubtotal: decimalOrZero(job.EstimateSalesSubtotal),
-			InvoiceID:             sqlNullString(job.InvoiceID),
-			TotalHoursWorked:      decimalOrZero(job.TotalHoursWorked),
-			Priority:              sqlNullString(job.Priority),
-			SurveyScore:           sqlNullInt32FromDecimal(job.SurveyResult),
-			EstimateCount:         sqlNullInt32FromInt64Ptr(job.EstimateCount),
-			IsOpportunity:         job.Opportunity,
-			IsConverted:           job.Converted,
-			PrimaryTechnician:     sqlNullString(job.PrimaryTechnician),
-		}
-
-		_, err := txQueries.CreateJob(ctx, params)
+
+	for _, job := range jobs {
+		params := jobUpsertParams(job, batchID)
+
+		inserted, err := txQueries.UpsertJob(ctx, params)
+		changed, err := result.counts.record(inserted, err)
 		if err != nil {
-			return nil, fmt.Errorf("failed to insert job %v (row %d): %w", job.JobID, idx+2, err)
+			return &rowError{row: job.Row, err: fmt.Errorf("failed to upsert job %v (row %d): %w", job.JobID, job.Row, err)}
+		}
+		result.validJobIDs[job.JobID] = true
+		if changed && inserted {
+			result.newJobIDs = append(result.newJobIDs, job.JobID)
+		} else if changed {
+			result.updatedJobIDs = append(result.updatedJobIDs, job.JobID)
 		}
-		validJobIDs[job.JobID] = true
 	}
 
-	return validJobIDs, nil
+	return nil
+}
+
+// jobUpsertParams converts a parsed job into UpsertJob parameters
+func jobUpsertParams(job parser.JobRow, batchID int64) db.UpsertJobParams {
+	return db.UpsertJobParams{
+		ID:                    job.JobID,
+		CustomerID:            job.CustomerID,
+		ImportBatchID:         batchID,
+		JobType:               job.JobType,
+		BusinessUnit:          sqlNullString(job.BusinessUnit),
+		Status:                job.Status,
+		JobCreationDate:       sqlNullTime(job.JobCreationDate),
+		JobScheduleDate:       sqlNullTime(job.JobScheduleDate),
+		JobCompletionDate:     sqlNullTime(job.JobCompletionDate),
+		AssignedTechnician:    sqlNullString(job.AssignedTechnicians),
+		SoldByTechnician:      sqlNullString(job.SoldBy),
+		BookedBy:              sqlNullString(job.BookedBy),
+		CampaignName:          sqlNullString(stringFromInt64Ptr(job.JobCampaignID)),
+		CampaignCategory:      sqlNullString(job.CampaignCategory),
+		CallCampaign:          sqlNullString(stringFromInt64Ptr(job.CallCampaignID)),
+		JobsSubtotal:          decimalOrZero(job.JobsSubtotal),
+		JobTotal:              decimalOrZero(job.JobTotal),
+		EstimateSalesSubtotal: decimalOrZero(job.EstimateSalesSubtotal),
+		InvoiceID:             sqlNullString(job.InvoiceID),
+		TotalHoursWorked:      decimalOrZero(job.TotalHoursWorked),
+		Priority:              sqlNullString(job.Priority),
+		SurveyScore:           sqlNullInt32FromDecimal(job.SurveyResult),
+		EstimateCount:         sqlNullInt32FromInt64Ptr(job.EstimateCount),
+		IsOpportunity:         job.Opportunity,
+		IsConverted:           job.Converted,
+		PrimaryTechnician:     sqlNullString(job.PrimaryTechnician),
+		LocationID:            sqlNullInt64(job.LocationID),
+		BusinessUnitID:        sqlNullInt64(job.BusinessUnitID),
+		DispatchedBy:          sqlNullString(job.DispatchedBy),
+		Summary:               sqlNullString(job.Summary),
+		MemberStatus:          sqlNullString(job.MemberStatus),
+		Tags:                  sqlNullString(job.Tags),
+		IsWarranty:            job.Warranty,
+		IsRecall:              job.Recall,
+		IsZeroDollar:          job.ZeroDollarJob,
+	}
 }
 
-// importInvoices inserts invoice records, skipping those without matching jobs
-// Returns: (imported count, skipped count, set of missing job IDs, error)
-func (i *Importer) importInvoices(ctx context.Context, tx *sql.Tx, invoices []parser.InvoiceRow, batchID int64, validJobIDs map[string]bool) (int, int, map[string]bool, error) {
+// invoiceImportResult accumulates importInvoices passes over every chunk
+type invoiceImportResult struct {
+	counts        upsertCounts
+	skipped       int             // invoices whose job is nowhere to be found
+	linked        int             // invoices attached to jobs from earlier batches
+	missingJobIDs map[string]bool // job IDs referenced by skipped invoices
+	changedJobIDs map[string]bool // job IDs with inserted/updated invoices
+}
+
+// snapshotInvoices saves the current version of the invoices a chunk may
+// overwrite, so deleting the batch can restore them
+func snapshotInvoices(ctx context.Context, q *db.Queries, invoices []parser.InvoiceRow, batchID int64) error {
+	invoiceIDs := make([]string, 0, len(invoices))
+	for _, invoice := range invoices {
+		invoiceIDs = append(invoiceIDs, invoice.InvoiceID)
+	}
+	err := q.SnapshotInvoices(ctx, db.SnapshotInvoicesParams{
+		ImportBatchID: batchID,
+		InvoiceIds:    invoiceIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot invoices: %w", err)
+	}
+	return nil
+}
+
+// importInvoices upserts a chunk of invoice records, skipping those without
+// matching jobs. Invoices often arrive one export after their job, so job IDs
+// that aren't in the current jobs file are looked up in the jobs table before
+// giving up.
+func (i *Importer) importInvoices(ctx context.Context, tx *sql.Tx, invoices []parser.InvoiceRow, batchID int64, validJobIDs map[string]bool, result *invoiceImportResult) error {
 	txQueries := db.New(tx)
-	imported := 0
-	skipped := 0
-	missingJobIDs := make(map[string]bool)
 
-	for idx, invoice := range invoices {
-		// Check if the job exists
+	// Find jobs imported by earlier batches
+	var otherJobIDs []string
+	seen := make(map[string]bool)
+	for _, invoice := range invoices {
+		if !validJobIDs[invoice.JobID] && !seen[invoice.JobID] {
+			seen[invoice.JobID] = true
+			otherJobIDs = append(otherJobIDs, invoice.JobID)
+		}
+	}
+	existingJobIDs := make(map[string]bool)
+	if len(otherJobIDs) > 0 {
+		ids, err := txQueries.GetExistingJobIDs(ctx, otherJobIDs)
+		if err != nil {
+			return fmt.Errorf("failed to look up existing jobs: %w", err)
+		}
+		for _, id := range ids {
+			existingJobIDs[id] = true
+		}
+	}
+
+	for _, invoice := range invoices {
+		// Check if the job exists, either in this file or from an earlier batch
 		if !validJobIDs[invoice.JobID] {
-			skipped++
-			missingJobIDs[invoice.JobID] = true
+			if !existingJobIDs[invoice.JobID] {
+				result.skipped++
+				result.missingJobIDs[invoice.JobID] = true
+				continue
+			}
+			result.linked++
+		}
+
+		params := invoiceUpsertParams(invoice, batchID)
+
+		changed, err := result.counts.record(txQueries.UpsertInvoice(ctx, params))
+		if err != nil {
+			return &rowError{row: invoice.Row, err: fmt.Errorf("failed to upsert invoice %v (row %d): %w", invoice.InvoiceID, invoice.Row, err)}
+		}
+		if changed {
+			result.changedJobIDs[invoice.JobID] = true
+		}
+	}
+
+	return nil
+}
+
+// invoiceUpsertParams converts a parsed invoice into UpsertInvoice parameters
+func invoiceUpsertParams(invoice parser.InvoiceRow, batchID int64) db.UpsertInvoiceParams {
+	return db.UpsertInvoiceParams{
+		ID:                       invoice.InvoiceID,
+		JobID:                    invoice.JobID,
+		ImportBatchID:            batchID,
+		InvoiceDate:              invoice.InvoiceDate,
+		InvoiceStatus:            sqlNullString(invoice.InvoiceStatus),
+		InvoiceType:              sqlNullString(invoice.InvoiceType),
+		InvoiceSummary:           sqlNullString(invoice.InvoiceSummary),
+		Total:                    decimalOrZero(invoice.Total),
+		Balance:                  decimalOrZero(invoice.Balance),
+		Payments:                 decimalOrZero(invoice.Payments),
+		MaterialCosts:            decimalOrZero(invoice.MaterialCosts),
+		EquipmentCosts:           decimalOrZero(invoice.EquipmentCosts),
+		PurchaseOrderCosts:       decimalOrZero(invoice.PurchaseOrderCosts),
+		ReturnCosts:              decimalOrZero(invoice.ReturnCosts),
+		CostsTotal:               decimalOrZero(invoice.CostsTotal),
+		MaterialRetail:           decimalOrZero(invoice.MaterialRetail),
+		MaterialMarkup:           decimalOrZero(invoice.MaterialMarkup),
+		EquipmentRetail:          decimalOrZero(invoice.EquipmentRetail),
+		EquipmentMarkup:          decimalOrZero(invoice.EquipmentMarkup),
+		Labor:                    decimalOrZero(invoice.Labor),
+		LaborPay:                 decimalOrZero(invoice.LaborPay),
+		LaborBurden:              decimalOrZero(invoice.LaborBurden),
+		TotalLaborCosts:          decimalOrZero(invoice.TotalLaborCosts),
+		Income:                   decimalOrZero(invoice.Income),
+		DiscountTotal:            decimalOrZero(invoice.DiscountTotal),
+		IsAdjustment:             invoice.IsAdjustment,
+		CustomerID:               sqlNullInt64(invoice.CustomerID),
+		LocationID:               sqlNullInt64(invoice.LocationID),
+		ProjectNumber:            sqlNullInt64(invoice.ProjectNumber),
+		BusinessUnitID:           sqlNullInt64(invoice.InvoiceBusinessUnitID),
+		PaymentTypes:             sqlNullString(invoice.PaymentTypes),
+		PaymentTerm:              sqlNullString(invoice.PaymentTerm),
+		PricebookPrice:           decimalOrZero(invoice.PricebookPrice),
+		IsDispatchServiceFeeOnly: invoice.DispatchServiceFeeOnly,
+		IsPrevailingWage:         invoice.PrevailingWage,
+		JobType:                  sqlNullString(invoice.JobType),
+	}
+}
+
+// estimateImportResult accumulates importEstimates passes over every chunk
+type estimateImportResult struct {
+	counts        upsertCounts
+	skipped       int             // estimates whose job is nowhere to be found
+	missingJobIDs map[string]bool // job IDs referenced by skipped estimates
+}
+
+// importEstimates upserts a chunk of estimate records, skipping those without
+// matching jobs in the jobs file or the jobs table. Technicians named on an
+// estimate are created if they don't exist yet.
+func (i *Importer) importEstimates(ctx context.Context, tx *sql.Tx, estimates []parser.EstimateRow, batchID int64, validJobIDs map[string]bool, technicians *technicianImportResult, result *estimateImportResult) error {
+	txQueries := db.New(tx)
+
+	// Save the current version of estimates this chunk may overwrite
+	estimateIDs := make([]string, 0, len(estimates))
+	for _, estimate := range estimates {
+		estimateIDs = append(estimateIDs, estimate.EstimateID)
+	}
+	err := txQueries.SnapshotEstimates(ctx, db.SnapshotEstimatesParams{
+		ImportBatchID: batchID,
+		EstimateIds:   estimateIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot estimates: %w", err)
+	}
+
+	// Find jobs imported by earlier batches
+	var otherJobIDs []string
+	seen := make(map[string]bool)
+	for _, estimate := range estimates {
+		if !validJobIDs[estimate.JobID] && !seen[estimate.JobID] {
+			seen[estimate.JobID] = true
+			otherJobIDs = append(otherJobIDs, estimate.JobID)
+		}
+	}
+	existingJobIDs := make(map[string]bool)
+	if len(otherJobIDs) > 0 {
+		ids, err := txQueries.GetExistingJobIDs(ctx, otherJobIDs)
+		if err != nil {
+			return fmt.Errorf("failed to look up existing jobs: %w", err)
+		}
+		for _, id := range ids {
+			existingJobIDs[id] = true
+		}
+	}
+
+	for _, estimate := range estimates {
+		if !validJobIDs[estimate.JobID] && !existingJobIDs[estimate.JobID] {
+			result.skipped++
+			result.missingJobIDs[estimate.JobID] = true
 			continue
 		}
 
-		params := db.CreateInvoiceParams{
-			ID:                 invoice.InvoiceID,
-			JobID:              invoice.JobID,
-			ImportBatchID:      batchID,
-			InvoiceDate:        invoice.InvoiceDate,
-			InvoiceStatus:      sqlNullString(invoice.InvoiceStatus),
-			InvoiceType:        sqlNullString(invoice.InvoiceType),
-			InvoiceSummary:     sqlNullString(invoice.InvoiceSummary),
-			Total:              decimalOrZero(invoice.Total),
-			Balance:            decimalOrZero(invoice.Balance),
-			Payments:           decimalOrZero(invoice.Payments),
-			MaterialCosts:      decimalOrZero(invoice.MaterialCosts),
-			EquipmentCosts:     decimalOrZero(invoice.EquipmentCosts),
-			PurchaseOrderCosts: decimalOrZero(invoice.PurchaseOrderCosts),
-			ReturnCosts:        decimalOrZero(invoice.ReturnCosts),
-			CostsTotal:         decimalOrZero(invoice.CostsTotal),
-			MaterialRetail:     decimalOrZero(invoice.MaterialRetail),
-			MaterialMarkup:     decimalOrZero(invoice.MaterialMarkup),
-			EquipmentRetail:    decimalOrZero(invoice.EquipmentRetail),
-			EquipmentMarkup:    decimalOrZero(invoice.EquipmentMarkup),
-			Labor:              decimalOrZero(invoice.Labor),
-			LaborPay:           decimalOrZero(invoice.LaborPay),
-			LaborBurden:        decimalOrZero(invoice.LaborBurden),
-			TotalLaborCosts:    decimalOrZero(invoice.TotalLaborCosts),
-			Income:             decimalOrZero(invoice.Income),
-			DiscountTotal:      decimalOrZero(invoice.DiscountTotal),
-			IsAdjustment:       invoice.IsAdjustment,
-		}
-
-		_, err := txQueries.CreateInvoice(ctx, params)
+		var technicianID sql.NullInt64
+		if estimate.Technician != nil && *estimate.Technician != "" {
+			techID, err := i.upsertTechnician(ctx, txQueries, *estimate.Technician, estimate.CreatedOn, technicians)
+			if err != nil {
+				return &rowError{row: estimate.Row, err: fmt.Errorf("failed to upsert estimate technician (row %d): %w", estimate.Row, err)}
+			}
+			technicianID = sql.NullInt64{Int64: techID, Valid: true}
+		}
+
+		params := db.UpsertEstimateParams{
+			ID:            estimate.EstimateID,
+			JobID:         estimate.JobID,
+			ImportBatchID: batchID,
+			Name:          sqlNullString(estimate.Name),
+			Status:        estimate.Status,
+			Technician:    sqlNullString(estimate.Technician),
+			TechnicianID:  technicianID,
+			Subtotal:      decimalOrZero(estimate.Subtotal),
+			CreatedOn:     sqlNullTime(estimate.CreatedOn),
+			SoldOn:        sqlNullTime(estimate.SoldOn),
+		}
+
+		_, err := result.counts.record(txQueries.UpsertEstimate(ctx, params))
 		if err != nil {
-			return imported, skipped, missingJobIDs, fmt.Errorf("failed to insert invoice %v (row %d): %w", invoice.InvoiceID, idx+2, err)
+			return &rowError{row: estimate.Row, err: fmt.Errorf("failed to upsert estimate %v (row %d): %w", estimate.EstimateID, estimate.Row, err)}
 		}
-		imported++
 	}
 
-	return imported, skipped, missingJobIDs, nil
+	return nil
 }
 
 // Helper functions for converting types
@@ -555,6 +1225,13 @@ func sqlNullString(s *string) sql.NullString {
 	return sql.NullString{String: *s, Valid: true}
 }
 
+func sqlNullInt64(i *int64) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{Valid: false}
+	}
+	return sql.NullInt64{Int64: *i, Valid: true}
+}
+
 func sqlNullInt32FromDecimal(d *decimal.Decimal) sql.NullInt32 {
 	if d == nil {
 		return sql.NullInt32{Valid: false}
@@ -584,6 +1261,18 @@ func stringFromInt64Ptr(i *int64) *string {
 	return &s
 }
 
+func sortInt64s(s []int64) {
+	sort.Slice(s, func(a, b int) bool { return s[a] < s[b] })
+}
+
+func mapKeys[K comparable](m map[K]bool) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
 func sqlNullInt32FromInt64Ptr(i *int64) sql.NullInt32 {
 	if i == nil {
 		return sql.NullInt32{Valid: false}
@@ -0,0 +1,238 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// RebuildResult contains the results of rebuilding from staged raw rows
+type RebuildResult struct {
+	BatchesRebuilt        int
+	BatchesUnstaged       int // Imported before raw rows were staged, so left as they are
+	JobRows               int // Staged rows that parsed
+	InvoiceRows           int
+	RowsRejected          int            // Staged rows that no longer parse
+	BadDates              map[string]int // Staged date cells that still don't parse, by column
+	JobMetricsCalculated  int
+	TechMetricsCalculated int
+	Duration              time.Duration
+	DryRun                bool // Nothing was committed
+}
+
+// Rebuild re-parses every batch's staged raw jobs and invoices rows with the
+// current parser rules and column mapping, re-imports them oldest batch
+// first, and recalculates metrics for every job, all without the original
+// files. Replaying every batch in order leaves each row at the version from
+// the latest batch that has it, as the original imports did. Batch
+// snapshots and earlier job versions are left as they were taken, so
+// batches can still be deleted and job history still shows each import.
+// Rows that no longer parse are counted but not quarantined again.
+func (i *Importer) Rebuild(ctx context.Context) (*RebuildResult, error) {
+	startTime := time.Now()
+
+	if i.ChunkSize <= 0 {
+		i.ChunkSize = DefaultChunkSize
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	batchIDs, err := txQueries.ListStagedBatchIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged batches: %w", err)
+	}
+	unstaged, err := txQueries.CountUnstagedBatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unstaged batches: %w", err)
+	}
+
+	result := &RebuildResult{BatchesUnstaged: int(unstaged), BadDates: make(map[string]int)}
+
+	for _, batchID := range batchIDs {
+		run := newImportRun(batchID)
+		run.lenient = true
+		run.rebuild = true
+
+		jobsParser := i.newCSVParser(run, parser.ReportJobs, run.badDates)
+		jobRows, err := i.importJobStream(ctx, tx, func(fn func(parser.JobRow) error) error {
+			return jobsParser.StreamRawJobs(i.rawRows(ctx, txQueries, batchID, parser.ReportJobs), fn)
+		}, run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild jobs for batch %d: %w", batchID, err)
+		}
+
+		invoicesParser := i.newCSVParser(run, parser.ReportInvoices, run.badDates)
+		invoiceRows, err := i.importInvoiceStream(ctx, tx, func(fn func(parser.InvoiceRow) error) error {
+			return invoicesParser.StreamRawInvoices(i.rawRows(ctx, txQueries, batchID, parser.ReportInvoices), fn)
+		}, run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild invoices for batch %d: %w", batchID, err)
+		}
+
+		result.BatchesRebuilt++
+		result.JobRows += jobRows
+		result.InvoiceRows += invoiceRows
+		result.RowsRejected += len(run.rejected)
+		for column, count := range run.badDates {
+			result.BadDates[column] += count
+		}
+	}
+
+	// Data quality issues are rechecked once every batch is back to its latest version
+	for _, batchID := range batchIDs {
+		if _, err := ValidateImport(ctx, tx, batchID); err != nil {
+			return nil, fmt.Errorf("failed to validate batch %d: %w", batchID, err)
+		}
+	}
+
+	// Metric logic may have changed too, so every job is recalculated
+	allJobIDs, err := txQueries.GetAllJobIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	result.JobMetricsCalculated, err = i.recalculateJobMetrics(ctx, tx, allJobIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate job metrics: %w", err)
+	}
+	// A rebuild corrects jobs rather than seeing new versions of them, so
+	// current versions are updated in place and earlier ones left alone
+	if err := txQueries.RefreshCurrentJobVersions(ctx, allJobIDs); err != nil {
+		return nil, fmt.Errorf("failed to refresh job versions: %w", err)
+	}
+	// Rebuilt jobs may have moved between customers, and customers left
+	// without jobs still need zeroing, so every customer is refreshed
+	allCustomerIDs, err := txQueries.GetAllCustomerIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers: %w", err)
+	}
+	err = txQueries.RefreshCustomerRollups(ctx, db.RefreshCustomerRollupsParams{
+		CustomerIds: allCustomerIDs,
+		JobIds:      []string{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh customer rollups: %w", err)
+	}
+	// Every job was replayed, so every technician is recalculated
+	allTechIDs, err := txQueries.GetAllTechnicianIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get technicians: %w", err)
+	}
+	result.TechMetricsCalculated, err = i.calculateAndSaveTechnicianMetrics(ctx, tx, allTechIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate technician metrics: %w", err)
+	}
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.DryRun = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// rawRows returns an iterator over a batch's staged jobs or invoices rows in
+// file order, numbered by their staged line numbers. Rows are fetched
+// ChunkSize at a time, and each page is read in full before the rows are
+// parsed and imported on the same transaction.
+func (i *Importer) rawRows(ctx context.Context, q *db.Queries, batchID int64, reportType parser.ReportType) func() (parser.RawRow, error) {
+	var page []db.ListRawJobRowsRow
+	var lastLine int32
+	done := false
+
+	return func() (parser.RawRow, error) {
+		if len(page) == 0 && !done {
+			params := db.ListRawJobRowsParams{
+				ImportBatchID: batchID,
+				AfterLine:     lastLine,
+				RowLimit:      int32(i.ChunkSize),
+			}
+			if reportType == parser.ReportJobs {
+				rows, err := q.ListRawJobRows(ctx, params)
+				if err != nil {
+					return parser.RawRow{}, fmt.Errorf("failed to read staged job rows: %w", err)
+				}
+				page = append(page, rows...)
+			} else {
+				rows, err := q.ListRawInvoiceRows(ctx, db.ListRawInvoiceRowsParams(params))
+				if err != nil {
+					return parser.RawRow{}, fmt.Errorf("failed to read staged invoice rows: %w", err)
+				}
+				for _, row := range rows {
+					page = append(page, db.ListRawJobRowsRow(row))
+				}
+			}
+			done = len(page) < i.ChunkSize
+			if len(page) > 0 {
+				lastLine = page[len(page)-1].LineNumber
+			}
+		}
+		if len(page) == 0 {
+			return parser.RawRow{}, io.EOF
+		}
+
+		row := parser.RawRow{Row: int(page[0].LineNumber)}
+		if err := json.Unmarshal(page[0].Record, &row.Record); err != nil {
+			return parser.RawRow{}, fmt.Errorf("failed to decode staged row %d: %w", row.Row, err)
+		}
+		page = page[1:]
+		return row, nil
+	}
+}
+
+// saveRawRows stages the jobs or invoices rows read since the last chunk
+func (i *Importer) saveRawRows(ctx context.Context, tx *sql.Tx, run *importRun, reportType parser.ReportType) error {
+	if len(run.raw) == 0 {
+		return nil
+	}
+
+	lineNumbers := make([]int32, 0, len(run.raw))
+	records := make([]string, 0, len(run.raw))
+	for _, row := range run.raw {
+		record, err := json.Marshal(row.Record)
+		if err != nil {
+			return fmt.Errorf("failed to encode raw row %d: %w", row.Row, err)
+		}
+		lineNumbers = append(lineNumbers, int32(run.rawLineOffset+row.Row))
+		records = append(records, string(record))
+	}
+	run.raw = run.raw[:0]
+
+	txQueries := db.New(tx)
+	var err error
+	if reportType == parser.ReportJobs {
+		err = txQueries.CreateRawJobRows(ctx, db.CreateRawJobRowsParams{
+			ImportBatchID: run.batchID,
+			LineNumbers:   lineNumbers,
+			Records:       records,
+		})
+	} else {
+		err = txQueries.CreateRawInvoiceRows(ctx, db.CreateRawInvoiceRowsParams{
+			ImportBatchID: run.batchID,
+			LineNumbers:   lineNumbers,
+			Records:       records,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stage raw %s rows: %w", reportType, err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// RetryResult contains the results of re-ingesting fixed rejected rows
+type RetryResult struct {
+	BatchID              int64
+	ReportType           parser.ReportType
+	RowsRead             int
+	RowsResolved         int // Earlier rejected rows replaced by a row from the fixed file
+	RowsRejected         int // Rows in the fixed file that still fail to parse
+	JobsInserted         int
+	JobsUpdated          int
+	InvoicesInserted     int
+	InvoicesUpdated      int
+	InvoicesSkipped      int
+	EstimatesInserted    int
+	EstimatesUpdated     int
+	EstimatesSkipped     int
+	JobMetricsCalculated int
+	BadDates             map[string]int // Date cells left empty because they didn't parse
+	Duration             time.Duration
+}
+
+// RetryRejected re-ingests a corrected file for a batch's rejected rows.
+// The file may be a jobs, invoices or estimates export containing just the
+// fixed rows. Rows are imported into the original batch, and earlier rejected
+// rows with a matching Job ID/Invoice #/Estimate ID are marked resolved. Rows that still fail to parse
+// are quarantined again.
+func (i *Importer) RetryRejected(ctx context.Context, batchID int64, fixedPath string) (*RetryResult, error) {
+	startTime := time.Now()
+
+	if i.ChunkSize <= 0 {
+		i.ChunkSize = DefaultChunkSize
+	}
+
+	if _, err := i.queries.GetImportBatch(ctx, batchID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import batch %d not found", batchID)
+		}
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+
+	file, err := os.Open(fixedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixed file: %w", err)
+	}
+	defer file.Close()
+
+	fixedParser := i.newParser(fixedPath, nil, "", nil)
+	reportType, err := fixedParser.DetectReportType(file)
+	if err != nil {
+		return nil, err
+	}
+	switch reportType {
+	case parser.ReportTimesheets, parser.ReportSpend, parser.ReportCampaigns, parser.ReportRoster:
+		return nil, fmt.Errorf("%s files aren't part of import batches; use sta import %s instead", reportType, reportType)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind fixed file: %w", err)
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	run := newImportRun(batchID)
+	run.lenient = true
+	run.rowKeys = make(map[string]bool)
+
+	// Stage the fixed rows after the batch's own, so a rebuild replays them
+	// over the rows they fix
+	var lastLine int32
+	switch reportType {
+	case parser.ReportJobs:
+		lastLine, err = db.New(tx).GetLastRawJobLine(ctx, batchID)
+	case parser.ReportInvoices:
+		lastLine, err = db.New(tx).GetLastRawInvoiceLine(ctx, batchID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find staged rows: %w", err)
+	}
+	run.rawLineOffset = int(lastLine) - 1 // the fixed file's first row is row 2
+	fixedParser = i.newParser(fixedPath, run, reportType, run.badDates)
+
+	var rowsRead int
+	switch reportType {
+	case parser.ReportJobs:
+		rowsRead, err = i.streamJobs(ctx, tx, fixedParser, file, run)
+	case parser.ReportEstimates:
+		rowsRead, err = i.streamEstimates(ctx, tx, fixedParser, file, run)
+	default:
+		rowsRead, err = i.streamInvoices(ctx, tx, fixedParser, file, run)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import fixed rows: %w", err)
+	}
+	if err := db.New(tx).PruneBatchSnapshots(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to prune batch snapshots: %w", err)
+	}
+
+	// Resolve before saving, so rows that are still broken are re-quarantined
+	resolved, err := db.New(tx).ResolveRejectedRows(ctx, db.ResolveRejectedRowsParams{
+		ImportBatchID: batchID,
+		ReportType:    string(reportType),
+		RecordKeys:    mapKeys(run.rowKeys),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve rejected rows: %w", err)
+	}
+
+	if err := i.saveRejectedRows(ctx, tx, run); err != nil {
+		return nil, err
+	}
+
+	jobMetricsCalculated, err := i.recalculateJobMetrics(ctx, tx, run.changedJobIDs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate job metrics: %w", err)
+	}
+
+	// Fixed rows belong to the original batch, so their versions do too
+	if _, err := recordJobVersions(ctx, db.New(tx), batchID, run.changedJobIDs()); err != nil {
+		return nil, err
+	}
+
+	// Fixed jobs may have moved away from the customer they had before
+	previousCustomerIDs, err := db.New(tx).GetCustomerIDsForBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers for batch: %w", err)
+	}
+	err = db.New(tx).RefreshCustomerRollups(ctx, db.RefreshCustomerRollupsParams{
+		CustomerIds: previousCustomerIDs,
+		JobIds:      run.changedJobIDs(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh customer rollups: %w", err)
+	}
+
+	techIDs, err := affectedTechnicianIDs(ctx, db.New(tx), batchID, run.changedJobIDs(), run.technicians.unlinked)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := i.calculateAndSaveTechnicianMetrics(ctx, tx, techIDs); err != nil {
+		return nil, fmt.Errorf("failed to calculate technician metrics: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &RetryResult{
+		BatchID:              batchID,
+		ReportType:           reportType,
+		RowsRead:             rowsRead,
+		RowsResolved:         int(resolved),
+		RowsRejected:         len(run.rejected),
+		JobsInserted:         run.jobs.counts.inserted,
+		JobsUpdated:          run.jobs.counts.updated,
+		InvoicesInserted:     run.invoices.counts.inserted,
+		InvoicesUpdated:      run.invoices.counts.updated,
+		InvoicesSkipped:      run.invoices.skipped,
+		EstimatesInserted:    run.estimates.counts.inserted,
+		EstimatesUpdated:     run.estimates.counts.updated,
+		EstimatesSkipped:     run.estimates.skipped,
+		JobMetricsCalculated: jobMetricsCalculated,
+		BadDates:             run.badDates,
+		Duration:             time.Since(startTime),
+	}, nil
+}
+
+// ListRejectedRows returns a batch's rejected rows that have not been fixed yet
+func (i *Importer) ListRejectedRows(ctx context.Context, batchID int64) ([]db.RejectedRow, error) {
+	if _, err := i.queries.GetImportBatch(ctx, batchID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import batch %d not found", batchID)
+		}
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+
+	rows, err := i.queries.ListRejectedRows(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rejected rows: %w", err)
+	}
+	return rows, nil
+}
+
+// saveRejectedRows writes the run's rejected rows to the rejected_rows table
+func (i *Importer) saveRejectedRows(ctx context.Context, tx *sql.Tx, run *importRun) error {
+	txQueries := db.New(tx)
+
+	for _, row := range run.rejected {
+		record, err := json.Marshal(row.Record)
+		if err != nil {
+			return fmt.Errorf("failed to encode rejected row %d: %w", row.Row, err)
+		}
+
+		err = txQueries.CreateRejectedRow(ctx, db.CreateRejectedRowParams{
+			ImportBatchID: run.batchID,
+			ReportType:    string(row.reportType),
+			RowNumber:     int32(row.Row),
+			ColumnName:    row.Column,
+			RawValue:      row.Value,
+			ErrorMessage:  row.Error,
+			RecordKey:     sql.NullString{String: row.Key, Valid: row.Key != ""},
+			RawRecord:     record,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save rejected row %d: %w", row.Row, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// RosterImportResult contains the results of a technician roster import
+type RosterImportResult struct {
+	RowsRead             int
+	TechniciansUpdated   int
+	TechniciansUnchanged int
+	NewTechnicians       []string       // On the roster but never seen on a job
+	Terminated           int            // Rows with a termination date on or before today
+	NameConflicts        []string       // Roster names already used by a different technician
+	BadDates             map[string]int // Hire and termination dates that didn't parse, by column
+	Duration             time.Duration
+	DryRun               bool // Nothing was committed
+}
+
+// ImportRoster imports a technician roster. Rows are matched to technicians
+// by employee ID, then by any name the technician has been seen under;
+// technicians that aren't matched are created. The roster is authoritative,
+// so re-importing it replaces every technician's team, manager, employment
+// dates and hourly cost.
+func (i *Importer) ImportRoster(ctx context.Context, path string) (*RosterImportResult, error) {
+	startTime := time.Now()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open roster file: %w", err)
+	}
+	defer file.Close()
+
+	// Rosters are small, so read the whole file before writing anything
+	badDates := make(dateFailures)
+	rows, err := parser.ParseRoster(i.newParser(path, nil, parser.ReportRoster, badDates), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse roster file: %w", err)
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	result := &RosterImportResult{RowsRead: len(rows), BadDates: badDates}
+	technicians := technicianImportResult{cache: make(map[string]int64)}
+	today := time.Now()
+	filename := sql.NullString{String: filepath.Base(path), Valid: true}
+
+	for _, row := range rows {
+		techID, err := i.rosterTechnician(ctx, txQueries, row, &technicians, result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match technician %q (row %d): %w", row.Name, row.Row, err)
+		}
+
+		updated, err := txQueries.UpdateTechnicianRoster(ctx, db.UpdateTechnicianRosterParams{
+			EmployeeID:      sqlNullString(row.EmployeeID),
+			Team:            sqlNullString(row.Team),
+			Manager:         sqlNullString(row.Manager),
+			HireDate:        sqlNullTime(row.HireDate),
+			TerminationDate: sqlNullTime(row.TerminationDate),
+			HourlyCost:      decimalOrZero(row.HourlyCost),
+			RosterFilename:  filename,
+			ID:              techID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update technician %q (row %d): %w", row.Name, row.Row, err)
+		}
+		if updated > 0 {
+			result.TechniciansUpdated++
+		} else {
+			result.TechniciansUnchanged++
+		}
+
+		if row.TerminationDate != nil && !row.TerminationDate.After(today) {
+			result.Terminated++
+		}
+	}
+	result.NewTechnicians = technicians.created
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.DryRun = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// rosterTechnician finds the technician a roster row describes, creating
+// them if needed, and makes sure the roster name maps onto them in later
+// imports
+func (i *Importer) rosterTechnician(ctx context.Context, q *db.Queries, row parser.RosterRow, technicians *technicianImportResult, result *RosterImportResult) (int64, error) {
+	if row.EmployeeID == nil {
+		return i.upsertTechnician(ctx, q, row.Name, nil, technicians)
+	}
+
+	techID, err := q.GetTechnicianIDByEmployeeID(ctx, sqlNullString(row.EmployeeID))
+	if err == sql.ErrNoRows {
+		return i.upsertTechnician(ctx, q, row.Name, nil, technicians)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// A known employee under a new name, e.g. after a name change
+	name := strings.Join(strings.Fields(row.Name), " ")
+	key := technicianAliasKey(name)
+	owner, err := q.GetTechnicianIDByAlias(ctx, key)
+	if err == sql.ErrNoRows {
+		return techID, q.CreateTechnicianAlias(ctx, db.CreateTechnicianAliasParams{
+			AliasKey:     key,
+			TechnicianID: techID,
+			Alias:        name,
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+	if owner != techID {
+		result.NameConflicts = append(result.NameConflicts,
+			fmt.Sprintf("%s (employee %s) is technician %d, but the name belongs to technician %d", name, *row.EmployeeID, techID, owner))
+	}
+	return techID, nil
+}
@@ -0,0 +1,112 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// SpendImportResult contains the results of a marketing spend import
+type SpendImportResult struct {
+	RowsRead           int
+	MonthsInserted     int
+	MonthsUpdated      int
+	MonthsUnchanged    int
+	TotalAmount        decimal.Decimal
+	Campaigns          int
+	UnmatchedCampaigns []string // Campaigns no imported job was booked under
+	Duration           time.Duration
+	DryRun             bool // Nothing was committed
+}
+
+// spendKey identifies one campaign's spend for one month
+type spendKey struct {
+	campaign string
+	month    time.Time
+}
+
+// ImportSpend imports a marketing spend file. Rows for the same campaign and
+// month are summed, and the total replaces whatever was stored for that
+// month before, so re-importing a corrected file is safe.
+func (i *Importer) ImportSpend(ctx context.Context, path string) (*SpendImportResult, error) {
+	startTime := time.Now()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spend file: %w", err)
+	}
+	defer file.Close()
+
+	// Spend files are small, so read the whole file before writing anything
+	rows, err := parser.ParseSpend(i.newParser(path, nil, parser.ReportSpend, nil), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spend file: %w", err)
+	}
+
+	totals := make(map[spendKey]decimal.Decimal)
+	var keys []spendKey
+	for _, row := range rows {
+		key := spendKey{campaign: row.Campaign, month: row.Month}
+		if _, ok := totals[key]; !ok {
+			keys = append(keys, key)
+		}
+		totals[key] = totals[key].Add(row.Amount)
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	result := &SpendImportResult{RowsRead: len(rows)}
+	var counts upsertCounts
+	campaigns := make(map[string]bool)
+	for _, key := range keys {
+		amount := totals[key]
+		_, err := counts.record(txQueries.UpsertCampaignSpend(ctx, db.UpsertCampaignSpendParams{
+			Campaign:       key.campaign,
+			Month:          key.month,
+			Amount:         amount.StringFixed(2),
+			SourceFilename: sql.NullString{String: filepath.Base(path), Valid: true},
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save spend for campaign %s (%s): %w", key.campaign, key.month.Format("2006-01"), err)
+		}
+		result.TotalAmount = result.TotalAmount.Add(amount)
+		campaigns[key.campaign] = true
+	}
+	result.MonthsInserted = counts.inserted
+	result.MonthsUpdated = counts.updated
+	result.MonthsUnchanged = counts.unchanged
+	result.Campaigns = len(campaigns)
+
+	result.UnmatchedCampaigns, err = txQueries.GetUnmatchedSpendCampaigns(ctx, mapKeys(campaigns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check spend campaigns: %w", err)
+	}
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.DryRun = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
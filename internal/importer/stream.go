@@ -0,0 +1,346 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// importRun holds the state of a single import while its files are streamed
+// in chunks. Only IDs and counts are kept across chunks, never parsed rows.
+type importRun struct {
+	batchID     int64
+	customers   customerImportResult
+	locations   customerImportResult // counted by Location ID, the same way as customers
+	jobs        jobImportResult
+	technicians technicianImportResult
+	invoices    invoiceImportResult
+	estimates   estimateImportResult
+
+	lenient  bool            // collect bad rows in rejected instead of failing
+	rejected []rejectedRow   // rows that failed to parse, saved once streaming is done
+	rowKeys  map[string]bool // Job IDs/Invoice #s/Estimate IDs read, only tracked for retries
+
+	raw           []parser.RawRow // jobs/invoices rows read since the last chunk, staged with it
+	rawLineOffset int             // added to staged line numbers, so retried rows follow the batch's
+	rebuild       bool            // replaying staged rows: nothing is staged or snapshotted again
+
+	badDates dateFailures
+
+	bulkFallback error // first bulk load that failed on bad data and was redone row by row
+}
+
+// dateFailures counts non-empty optional date cells that didn't match the
+// importer's DateFormat and were left empty, by column
+type dateFailures map[string]int
+
+func (d dateFailures) record(column, value string) {
+	d[column]++
+}
+
+// rejectedRow is a parser rejection tagged with the file it came from
+type rejectedRow struct {
+	parser.RejectedRow
+	reportType parser.ReportType
+}
+
+func newImportRun(batchID int64) *importRun {
+	return &importRun{
+		batchID:  batchID,
+		badDates: make(dateFailures),
+		customers: customerImportResult{
+			seen:    make(map[int64]bool),
+			changed: make(map[int64]bool),
+		},
+		locations: customerImportResult{
+			seen:    make(map[int64]bool),
+			changed: make(map[int64]bool),
+		},
+		jobs: jobImportResult{
+			validJobIDs: make(map[string]bool),
+		},
+		technicians: technicianImportResult{
+			cache:    make(map[string]int64),
+			unlinked: make(map[int64]bool),
+		},
+		invoices: invoiceImportResult{
+			missingJobIDs: make(map[string]bool),
+			changedJobIDs: make(map[string]bool),
+		},
+		estimates: estimateImportResult{
+			missingJobIDs: make(map[string]bool),
+		},
+	}
+}
+
+// changedJobIDs returns every job whose job row or invoices were inserted or
+// updated, including jobs from earlier batches whose late invoices just arrived
+func (run *importRun) changedJobIDs() []string {
+	changed := make(map[string]bool)
+	for _, jobID := range run.jobs.newJobIDs {
+		changed[jobID] = true
+	}
+	for _, jobID := range run.jobs.updatedJobIDs {
+		changed[jobID] = true
+	}
+	for jobID := range run.invoices.changedJobIDs {
+		changed[jobID] = true
+	}
+	return mapKeys(changed)
+}
+
+// missingJobIDs returns the sorted job IDs that invoices or estimates
+// referenced but that exist nowhere
+func (run *importRun) missingJobIDs() []string {
+	missing := make(map[string]bool)
+	for jobID := range run.invoices.missingJobIDs {
+		missing[jobID] = true
+	}
+	for jobID := range run.estimates.missingJobIDs {
+		missing[jobID] = true
+	}
+	jobIDs := mapKeys(missing)
+	sort.Strings(jobIDs)
+	return jobIDs
+}
+
+// newParser returns a parser for the file at path using the importer's column
+// mapping and date format. XLSX workbooks are read natively, anything else as
+// CSV. In lenient mode rejected rows are collected into the run instead of
+// aborting. Dates that don't parse are counted in badDates when it isn't nil.
+func (i *Importer) newParser(path string, run *importRun, reportType parser.ReportType, badDates dateFailures) parser.Parser {
+	csvParser := i.newCSVParser(run, reportType, badDates)
+	if parser.IsXLSX(path) {
+		return &parser.XLSXParser{CSVParser: *csvParser, Sheet: i.Sheet}
+	}
+	return csvParser
+}
+
+// newCSVParser returns a CSV parser using the importer's column mapping and
+// date format. Jobs and invoices rows are collected into the run for staging
+// as raw rows.
+func (i *Importer) newCSVParser(run *importRun, reportType parser.ReportType, badDates dateFailures) *parser.CSVParser {
+	csvParser := parser.NewCSVParser()
+	csvParser.Mapping = i.Mapping
+	csvParser.DateFormat = i.DateFormat
+	if badDates != nil {
+		csvParser.OnBadDate = badDates.record
+	}
+	if run == nil {
+		return csvParser
+	}
+	if run.lenient {
+		csvParser.OnRejectedRow = func(row parser.RejectedRow) error {
+			run.rejected = append(run.rejected, rejectedRow{RejectedRow: row, reportType: reportType})
+			run.trackKey(row.Key)
+			return nil
+		}
+	}
+	if !run.rebuild && (reportType == parser.ReportJobs || reportType == parser.ReportInvoices) {
+		csvParser.OnRawRow = func(row parser.RawRow) error {
+			run.raw = append(run.raw, row)
+			return nil
+		}
+	}
+	return csvParser
+}
+
+// trackKey remembers a row key when the run is a retry
+func (run *importRun) trackKey(key string) {
+	if run.rowKeys != nil && key != "" {
+		run.rowKeys[key] = true
+	}
+}
+
+// streamJobs parses the jobs file with p and imports it ChunkSize rows at a time.
+// Returns the number of rows read.
+func (i *Importer) streamJobs(ctx context.Context, tx *sql.Tx, p parser.Parser, r io.Reader, run *importRun) (int, error) {
+	return i.importJobStream(ctx, tx, func(fn func(parser.JobRow) error) error {
+		return parser.StreamJobs(p, r, fn)
+	}, run)
+}
+
+// importJobStream imports the jobs stream delivers ChunkSize rows at a time,
+// staging the raw rows read along with each chunk. Returns the number of
+// rows read.
+func (i *Importer) importJobStream(ctx context.Context, tx *sql.Tx, stream func(func(parser.JobRow) error) error, run *importRun) (int, error) {
+	rows := 0
+	chunk := make([]parser.JobRow, 0, i.ChunkSize)
+	flush := func() error {
+		if err := i.saveRawRows(ctx, tx, run, parser.ReportJobs); err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := i.importJobChunk(ctx, tx, chunk, run)
+		chunk = chunk[:0]
+		return err
+	}
+
+	err := stream(func(job parser.JobRow) error {
+		run.trackKey(job.JobID)
+		chunk = append(chunk, job)
+		rows++
+		if len(chunk) >= i.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, flush()
+}
+
+// importJobChunk upserts the customers, locations, business units, jobs and
+// technicians for one chunk of jobs
+func (i *Importer) importJobChunk(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, run *importRun) error {
+	txQueries := db.New(tx)
+
+	err := i.load(ctx, tx, run,
+		func() error { return i.copyCustomers(ctx, tx, jobs, &run.customers) },
+		func() error { return i.importCustomers(ctx, tx, jobs, &run.customers) })
+	if err != nil {
+		return fmt.Errorf("failed to import customers: %w", err)
+	}
+
+	if err := i.importLocations(ctx, tx, jobs, &run.locations); err != nil {
+		return fmt.Errorf("failed to import locations: %w", err)
+	}
+
+	if err := i.importBusinessUnits(ctx, tx, jobs); err != nil {
+		return fmt.Errorf("failed to import business units: %w", err)
+	}
+
+	// A rebuild replays batches whose snapshots were taken when they were
+	// first imported
+	if !run.rebuild {
+		if err := snapshotJobs(ctx, txQueries, jobs, run.batchID); err != nil {
+			return err
+		}
+	}
+
+	updatedBefore := len(run.jobs.updatedJobIDs)
+	err = i.load(ctx, tx, run,
+		func() error { return i.copyJobs(ctx, tx, jobs, run.batchID, &run.jobs) },
+		func() error { return i.importJobs(ctx, tx, jobs, run.batchID, &run.jobs) })
+	if err != nil {
+		return fmt.Errorf("failed to import jobs: %w", err)
+	}
+
+	// Jobs that changed may have new technicians, so drop their old links first
+	if updated := run.jobs.updatedJobIDs[updatedBefore:]; len(updated) > 0 {
+		unlinked, err := txQueries.DeleteJobTechniciansForJobs(ctx, updated)
+		if err != nil {
+			return fmt.Errorf("failed to reset job technicians: %w", err)
+		}
+		for _, techID := range unlinked {
+			run.technicians.unlinked[techID] = true
+		}
+	}
+
+	// Technicians are resolved outside the bulk load so a fallback to
+	// row-by-row doesn't leave rolled-back technician IDs in the cache
+	links, err := i.jobTechnicianLinks(ctx, txQueries, jobs, &run.technicians)
+	if err != nil {
+		return fmt.Errorf("failed to import technicians: %w", err)
+	}
+	err = i.load(ctx, tx, run,
+		func() error { return copyJobTechnicians(ctx, tx, links) },
+		func() error { return createJobTechnicians(ctx, txQueries, links) })
+	if err != nil {
+		return fmt.Errorf("failed to import technicians: %w", err)
+	}
+
+	return nil
+}
+
+// streamInvoices parses the invoices file with p and imports it ChunkSize rows at a
+// time. Must run after streamJobs so every job ID in the file is known.
+// Returns the number of rows read.
+func (i *Importer) streamInvoices(ctx context.Context, tx *sql.Tx, p parser.Parser, r io.Reader, run *importRun) (int, error) {
+	return i.importInvoiceStream(ctx, tx, func(fn func(parser.InvoiceRow) error) error {
+		return parser.StreamInvoices(p, r, fn)
+	}, run)
+}
+
+// importInvoiceStream imports the invoices stream delivers ChunkSize rows at
+// a time, staging the raw rows read along with each chunk. Returns the
+// number of rows read.
+func (i *Importer) importInvoiceStream(ctx context.Context, tx *sql.Tx, stream func(func(parser.InvoiceRow) error) error, run *importRun) (int, error) {
+	rows := 0
+	chunk := make([]parser.InvoiceRow, 0, i.ChunkSize)
+	flush := func() error {
+		if err := i.saveRawRows(ctx, tx, run, parser.ReportInvoices); err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if !run.rebuild {
+			if err := snapshotInvoices(ctx, db.New(tx), chunk, run.batchID); err != nil {
+				return err
+			}
+		}
+		err := i.load(ctx, tx, run,
+			func() error { return i.copyInvoices(ctx, tx, chunk, run.batchID, run.jobs.validJobIDs, &run.invoices) },
+			func() error {
+				return i.importInvoices(ctx, tx, chunk, run.batchID, run.jobs.validJobIDs, &run.invoices)
+			})
+		chunk = chunk[:0]
+		return err
+	}
+
+	err := stream(func(invoice parser.InvoiceRow) error {
+		run.trackKey(invoice.InvoiceID)
+		chunk = append(chunk, invoice)
+		rows++
+		if len(chunk) >= i.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, flush()
+}
+
+// streamEstimates parses the estimates file with p and imports it ChunkSize
+// rows at a time. Must run after streamJobs so every job ID in the file is
+// known. Returns the number of rows read.
+func (i *Importer) streamEstimates(ctx context.Context, tx *sql.Tx, p parser.Parser, r io.Reader, run *importRun) (int, error) {
+	rows := 0
+	chunk := make([]parser.EstimateRow, 0, i.ChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := i.importEstimates(ctx, tx, chunk, run.batchID, run.jobs.validJobIDs, &run.technicians, &run.estimates)
+		chunk = chunk[:0]
+		return err
+	}
+
+	err := parser.StreamEstimates(p, r, func(estimate parser.EstimateRow) error {
+		run.trackKey(estimate.EstimateID)
+		chunk = append(chunk, estimate)
+		rows++
+		if len(chunk) >= i.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, flush()
+}
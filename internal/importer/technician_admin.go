@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+)
+
+// MergeTechniciansResult contains the results of merging one technician into another
+type MergeTechniciansResult struct {
+	From                  db.Technician // Deleted by the merge
+	Into                  db.Technician
+	JobLinksMoved         int // Job/role links the target didn't already have
+	TechMetricsCalculated int
+	Duration              time.Duration
+}
+
+// ListTechnicians returns every technician with their aliases and job counts
+func (i *Importer) ListTechnicians(ctx context.Context) ([]db.ListTechniciansRow, error) {
+	techs, err := i.queries.ListTechnicians(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list technicians: %w", err)
+	}
+	return techs, nil
+}
+
+// FindTechnician looks a technician up by ID or by any name they've been
+// seen under
+func (i *Importer) FindTechnician(ctx context.Context, ref string) (*db.Technician, error) {
+	return findTechnician(ctx, i.queries, ref)
+}
+
+func findTechnician(ctx context.Context, q *db.Queries, ref string) (*db.Technician, error) {
+	ref = strings.TrimSpace(ref)
+
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		id, err = q.GetTechnicianIDByAlias(ctx, technicianAliasKey(ref))
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("technician %q not found", ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up technician %q: %w", ref, err)
+		}
+	}
+
+	tech, err := q.GetTechnician(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("technician %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get technician %d: %w", id, err)
+	}
+	return &tech, nil
+}
+
+// MergeTechnicians folds a duplicate technician into the canonical one in a
+// single transaction. Job links, estimates, timesheet entries and aliases
+// move to the target, the duplicate is deleted, and technician metrics are
+// recalculated. Later imports map the duplicate's names onto the target.
+func (i *Importer) MergeTechnicians(ctx context.Context, fromRef, intoRef string) (*MergeTechniciansResult, error) {
+	startTime := time.Now()
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	from, err := findTechnician(ctx, txQueries, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	into, err := findTechnician(ctx, txQueries, intoRef)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == into.ID {
+		return nil, fmt.Errorf("%q and %q are the same technician", fromRef, intoRef)
+	}
+
+	moved, err := txQueries.CopyJobTechnicians(ctx, db.CopyJobTechniciansParams{IntoID: into.ID, FromID: from.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move job technicians: %w", err)
+	}
+	err = txQueries.ReassignTechnicianEstimates(ctx, db.ReassignTechnicianEstimatesParams{IntoID: into.ID, FromID: from.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move estimates: %w", err)
+	}
+	err = txQueries.ReassignTechnicianTimesheetEntries(ctx, db.ReassignTechnicianTimesheetEntriesParams{IntoID: into.ID, FromID: from.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move timesheet entries: %w", err)
+	}
+	err = txQueries.ReassignTechnicianAliases(ctx, db.ReassignTechnicianAliasesParams{IntoID: into.ID, FromID: from.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move aliases: %w", err)
+	}
+	// Technicians created before aliases existed may not have one for their own name
+	err = txQueries.CreateTechnicianAlias(ctx, db.CreateTechnicianAliasParams{
+		AliasKey:     technicianAliasKey(from.Name),
+		TechnicianID: into.ID,
+		Alias:        from.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add alias %q: %w", from.Name, err)
+	}
+	err = txQueries.MergeTechnicianSeenDates(ctx, db.MergeTechnicianSeenDatesParams{IntoID: into.ID, FromID: from.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge seen dates: %w", err)
+	}
+
+	// Cascades to the duplicate's remaining job links and metrics
+	if err := txQueries.DeleteTechnician(ctx, from.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete technician %d: %w", from.ID, err)
+	}
+
+	// The duplicate's metrics went with it; only the target's can change
+	techMetricsCalculated, err := i.calculateAndSaveTechnicianMetrics(ctx, tx, []int64{into.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate technician metrics: %w", err)
+	}
+
+	merged, err := txQueries.GetTechnician(ctx, into.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get technician %d: %w", into.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &MergeTechniciansResult{
+		From:                  *from,
+		Into:                  merged,
+		JobLinksMoved:         int(moved),
+		TechMetricsCalculated: techMetricsCalculated,
+		Duration:              time.Since(startTime),
+	}, nil
+}
+
+// RenameTechnician changes a technician's display name. The old name stays
+// an alias so exports using it still map onto the technician.
+func (i *Importer) RenameTechnician(ctx context.Context, ref, newName string) (*db.Technician, error) {
+	newName = strings.Join(strings.Fields(newName), " ")
+	if newName == "" {
+		return nil, fmt.Errorf("technician name cannot be empty")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	tech, err := findTechnician(ctx, txQueries, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	key := technicianAliasKey(newName)
+	owner, err := txQueries.GetTechnicianIDByAlias(ctx, key)
+	if err == nil && owner != tech.ID {
+		return nil, fmt.Errorf("%q is already technician %d; use sta technicians merge to combine them", newName, owner)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up technician %q: %w", newName, err)
+	}
+
+	for _, alias := range []string{tech.Name, newName} {
+		err = txQueries.CreateTechnicianAlias(ctx, db.CreateTechnicianAliasParams{
+			AliasKey:     technicianAliasKey(alias),
+			TechnicianID: tech.ID,
+			Alias:        alias,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add alias %q: %w", alias, err)
+		}
+	}
+
+	err = txQueries.RenameTechnician(ctx, db.RenameTechnicianParams{ID: tech.ID, Name: newName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename technician %d: %w", tech.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	tech.Name = newName
+	return tech, nil
+}
+
+// SetTechnicianActive activates or deactivates a technician. Inactive
+// technicians keep their history and job links but are left out of
+// technician reports.
+func (i *Importer) SetTechnicianActive(ctx context.Context, ref string, active bool) (*db.Technician, error) {
+	tech, err := findTechnician(ctx, i.queries, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.queries.SetTechnicianActive(ctx, db.SetTechnicianActiveParams{ID: tech.ID, Active: active})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update technician %d: %w", tech.ID, err)
+	}
+
+	tech.Active = active
+	return tech, nil
+}
@@ -11,12 +11,40 @@ import (
 	"github.com/datsun80zx/sta.git/internal/parser"
 )
 
-// ImportTechnicians extracts technicians from jobs and creates relationships
-func (i *Importer) ImportTechnicians(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, batchID int64) (int, error) {
+// technicianImportResult accumulates importTechnicians passes over every chunk
+type technicianImportResult struct {
+	cache    map[string]int64 // alias key -> id of every technician seen
+	created  []string         // technicians that did not exist before this import
+	unlinked map[int64]bool   // technicians whose links to updated jobs were dropped
+}
+
+// importTechnicians extracts technicians from a chunk of jobs and creates relationships
+func (i *Importer) importTechnicians(ctx context.Context, tx *sql.Tx, jobs []parser.JobRow, result *technicianImportResult) error {
 	txQueries := db.New(tx)
 
-	// Track unique technicians we've seen
-	techCache := make(map[string]int64) // name -> id
+	links, err := i.jobTechnicianLinks(ctx, txQueries, jobs, result)
+	if err != nil {
+		return err
+	}
+
+	return createJobTechnicians(ctx, txQueries, links)
+}
+
+// createJobTechnicians links technicians to their jobs one row at a time
+func createJobTechnicians(ctx context.Context, q *db.Queries, links []db.CreateJobTechnicianParams) error {
+	for _, link := range links {
+		if err := q.CreateJobTechnician(ctx, link); err != nil {
+			return fmt.Errorf("failed to create job_technician (%s): %w", link.Role, err)
+		}
+	}
+
+	return nil
+}
+
+// jobTechnicianLinks upserts the technicians named on a chunk of jobs and
+// returns the job_technicians rows linking them to their jobs
+func (i *Importer) jobTechnicianLinks(ctx context.Context, q *db.Queries, jobs []parser.JobRow, result *technicianImportResult) ([]db.CreateJobTechnicianParams, error) {
+	var links []db.CreateJobTechnicianParams
 
 	for _, job := range jobs {
 		var completionDate *time.Time
@@ -26,68 +54,62 @@ func (i *Importer) ImportTechnicians(ctx context.Context, tx *sql.Tx, jobs []par
 
 		// Process Sold By technician
 		if job.SoldBy != nil && *job.SoldBy != "" {
-			techID, err := i.upsertTechnician(ctx, txQueries, *job.SoldBy, completionDate, techCache)
+			techID, err := i.upsertTechnician(ctx, q, *job.SoldBy, completionDate, result)
 			if err != nil {
-				return 0, fmt.Errorf("failed to upsert sold_by technician: %w", err)
+				return nil, fmt.Errorf("failed to upsert sold_by technician: %w", err)
 			}
-			err = txQueries.CreateJobTechnician(ctx, db.CreateJobTechnicianParams{
+			links = append(links, db.CreateJobTechnicianParams{
 				JobID:        job.JobID,
 				TechnicianID: techID,
 				Role:         "sold_by",
 			})
-			if err != nil {
-				return 0, fmt.Errorf("failed to create job_technician (sold_by): %w", err)
-			}
 		}
 
 		// Process Primary Technician
 		if job.PrimaryTechnician != nil && *job.PrimaryTechnician != "" {
-			techID, err := i.upsertTechnician(ctx, txQueries, *job.PrimaryTechnician, completionDate, techCache)
+			techID, err := i.upsertTechnician(ctx, q, *job.PrimaryTechnician, completionDate, result)
 			if err != nil {
-				return 0, fmt.Errorf("failed to upsert primary technician: %w", err)
+				return nil, fmt.Errorf("failed to upsert primary technician: %w", err)
 			}
-			err = txQueries.CreateJobTechnician(ctx, db.CreateJobTechnicianParams{
+			links = append(links, db.CreateJobTechnicianParams{
 				JobID:        job.JobID,
 				TechnicianID: techID,
 				Role:         "primary",
 			})
-			if err != nil {
-				return 0, fmt.Errorf("failed to create job_technician (primary): %w", err)
-			}
 		}
 
 		// Process Assigned Technicians (can be comma-separated list)
 		if job.AssignedTechnicians != nil && *job.AssignedTechnicians != "" {
 			techNames := splitTechnicianNames(*job.AssignedTechnicians)
 			for _, techName := range techNames {
-				techID, err := i.upsertTechnician(ctx, txQueries, techName, completionDate, techCache)
+				techID, err := i.upsertTechnician(ctx, q, techName, completionDate, result)
 				if err != nil {
-					return 0, fmt.Errorf("failed to upsert assigned technician: %w", err)
+					return nil, fmt.Errorf("failed to upsert assigned technician: %w", err)
 				}
-				err = txQueries.CreateJobTechnician(ctx, db.CreateJobTechnicianParams{
+				links = append(links, db.CreateJobTechnicianParams{
 					JobID:        job.JobID,
 					TechnicianID: techID,
 					Role:         "assigned",
 				})
-				if err != nil {
-					return 0, fmt.Errorf("failed to create job_technician (assigned): %w", err)
-				}
 			}
 		}
 	}
 
-	return len(techCache), nil
+	return links, nil
 }
 
-// upsertTechnician creates or updates a technician and returns their ID
-func (i *Importer) upsertTechnician(ctx context.Context, q *db.Queries, name string, jobDate *time.Time, cache map[string]int64) (int64, error) {
-	name = strings.TrimSpace(name)
+// upsertTechnician maps a name from an export onto its technician through
+// technician_aliases, creating the technician the first time a name is seen,
+// and returns their ID
+func (i *Importer) upsertTechnician(ctx context.Context, q *db.Queries, name string, jobDate *time.Time, result *technicianImportResult) (int64, error) {
+	name = strings.Join(strings.Fields(name), " ")
 	if name == "" {
 		return 0, fmt.Errorf("technician name cannot be empty")
 	}
+	key := technicianAliasKey(name)
 
 	// Check cache first
-	if id, ok := cache[name]; ok {
+	if id, ok := result.cache[key]; ok {
 		return id, nil
 	}
 
@@ -98,6 +120,24 @@ func (i *Importer) upsertTechnician(ctx context.Context, q *db.Queries, name str
 		lastSeen = sql.NullTime{Time: *jobDate, Valid: true}
 	}
 
+	// A known spelling, possibly of a technician that was merged or renamed
+	id, err := q.GetTechnicianIDByAlias(ctx, key)
+	if err == nil {
+		err = q.UpdateTechnicianSeenDates(ctx, db.UpdateTechnicianSeenDatesParams{
+			FirstSeenDate: firstSeen,
+			LastSeenDate:  lastSeen,
+			ID:            id,
+		})
+		if err != nil {
+			return 0, err
+		}
+		result.cache[key] = id
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
 	tech, err := q.UpsertTechnician(ctx, db.UpsertTechnicianParams{
 		Name:          name,
 		FirstSeenDate: firstSeen,
@@ -106,11 +146,28 @@ func (i *Importer) upsertTechnician(ctx context.Context, q *db.Queries, name str
 	if err != nil {
 		return 0, err
 	}
+	err = q.CreateTechnicianAlias(ctx, db.CreateTechnicianAliasParams{
+		AliasKey:     key,
+		TechnicianID: tech.ID,
+		Alias:        name,
+	})
+	if err != nil {
+		return 0, err
+	}
 
-	cache[name] = tech.ID
+	result.cache[key] = tech.ID
+	if tech.Inserted {
+		result.created = append(result.created, name)
+	}
 	return tech.ID, nil
 }
 
+// technicianAliasKey is the technician_aliases key for a name: lowercased
+// with runs of whitespace collapsed, so "John  Smith" and "john smith" match
+func technicianAliasKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
 // splitTechnicianNames handles the comma-separated list of technician names
 func splitTechnicianNames(names string) []string {
 	var result []string
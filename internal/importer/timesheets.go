@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/datsun80zx/sta.git/internal/db"
+	"github.com/datsun80zx/sta.git/internal/parser"
+)
+
+// TimesheetImportResult contains the results of a timesheet import
+type TimesheetImportResult struct {
+	ImportID              int64
+	EntriesImported       int
+	EntriesReplaced       int // Entries from earlier timesheet imports for the same job and day
+	TotalHours            decimal.Decimal
+	TotalLaborCost        decimal.Decimal
+	JobsMatched           int      // Jobs in the file that have already been imported
+	MissingJobIDs         []string // Jobs not imported yet; their labor counts once they are
+	NewTechnicians        []string
+	BadDates              map[string]int // Work dates that didn't parse and were left empty
+	JobMetricsCalculated  int
+	TechMetricsCalculated int
+	Duration              time.Duration
+	AlreadyImported       bool
+	DryRun                bool // Nothing was committed
+}
+
+// timesheetImportResult accumulates importTimesheetChunk passes over every chunk
+type timesheetImportResult struct {
+	importID    int64
+	entries     int
+	replaced    int
+	hours       decimal.Decimal
+	laborCost   decimal.Decimal
+	jobIDs      map[string]bool
+	technicians technicianImportResult
+}
+
+// ImportTimesheets imports a Timesheet/Payroll export and recalculates job
+// and technician metrics with the labor it adds. Entries replace whatever earlier timesheet
+// imports recorded for the same job and day, so overlapping exports don't
+// double-count hours. Entries for jobs that haven't been imported yet are
+// stored and picked up when those jobs are.
+func (i *Importer) ImportTimesheets(ctx context.Context, path string) (*TimesheetImportResult, error) {
+	startTime := time.Now()
+
+	if i.ChunkSize <= 0 {
+		i.ChunkSize = DefaultChunkSize
+	}
+
+	hash, err := CalculateFileHash(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+
+	existing, err := i.queries.GetTimesheetImportByHash(ctx, hash)
+	if err == nil {
+		return &TimesheetImportResult{
+			ImportID:        existing.ID,
+			AlreadyImported: true,
+			Duration:        time.Since(startTime),
+		}, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check for existing import: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open timesheet file: %w", err)
+	}
+	defer file.Close()
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	txQueries := db.New(tx)
+
+	tsImport, err := txQueries.CreateTimesheetImport(ctx, db.CreateTimesheetImportParams{
+		Filename: filepath.Base(path),
+		FileHash: hash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timesheet import: %w", err)
+	}
+
+	run := &timesheetImportResult{
+		importID:    tsImport.ID,
+		jobIDs:      make(map[string]bool),
+		technicians: technicianImportResult{cache: make(map[string]int64)},
+	}
+
+	badDates := make(dateFailures)
+	rows, err := i.streamTimesheets(ctx, tx, i.newParser(path, nil, parser.ReportTimesheets, badDates), file, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import timesheet file: %w", err)
+	}
+
+	err = txQueries.UpdateTimesheetImportRowCount(ctx, db.UpdateTimesheetImportRowCountParams{
+		ID:       tsImport.ID,
+		RowCount: int32(rows),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update timesheet row count: %w", err)
+	}
+
+	// Only jobs already in the database have metrics to recalculate
+	jobIDs := mapKeys(run.jobIDs)
+	existingJobIDs, err := txQueries.GetExistingJobIDs(ctx, jobIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check timesheet jobs: %w", err)
+	}
+	found := make(map[string]bool, len(existingJobIDs))
+	for _, jobID := range existingJobIDs {
+		found[jobID] = true
+	}
+	var missing []string
+	for _, jobID := range jobIDs {
+		if !found[jobID] {
+			missing = append(missing, jobID)
+		}
+	}
+	sort.Strings(missing)
+
+	jobMetricsCalculated, err := i.recalculateJobMetrics(ctx, tx, existingJobIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate job metrics: %w", err)
+	}
+
+	// Labor moves the jobs' gross profit, which their sold_by technicians
+	// are credited with, so those technicians are recalculated too
+	techIDs, err := txQueries.GetTechnicianIDsForJobs(ctx, existingJobIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get technicians of timesheet jobs: %w", err)
+	}
+	techMetricsCalculated, err := i.calculateAndSaveTechnicianMetrics(ctx, tx, techIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate technician metrics: %w", err)
+	}
+
+	result := &TimesheetImportResult{
+		ImportID:              tsImport.ID,
+		EntriesImported:       run.entries,
+		EntriesReplaced:       run.replaced,
+		TotalHours:            run.hours,
+		TotalLaborCost:        run.laborCost,
+		JobsMatched:           len(existingJobIDs),
+		MissingJobIDs:         missing,
+		NewTechnicians:        run.technicians.created,
+		BadDates:              badDates,
+		JobMetricsCalculated:  jobMetricsCalculated,
+		TechMetricsCalculated: techMetricsCalculated,
+	}
+
+	// Dry run: report what would have happened and let the deferred Rollback undo it
+	if i.DryRun {
+		result.ImportID = 0
+		result.DryRun = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// streamTimesheets parses the timesheet file with p and imports it ChunkSize
+// rows at a time. Returns the number of rows read.
+func (i *Importer) streamTimesheets(ctx context.Context, tx *sql.Tx, p parser.Parser, r io.Reader, run *timesheetImportResult) (int, error) {
+	rows := 0
+	chunk := make([]parser.TimesheetRow, 0, i.ChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := i.importTimesheetChunk(ctx, tx, chunk, run)
+		chunk = chunk[:0]
+		return err
+	}
+
+	err := parser.StreamTimesheets(p, r, func(entry parser.TimesheetRow) error {
+		chunk = append(chunk, entry)
+		rows++
+		if len(chunk) >= i.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, flush()
+}
+
+// importTimesheetChunk replaces earlier entries for the chunk's jobs and days,
+// then inserts the chunk's entries
+func (i *Importer) importTimesheetChunk(ctx context.Context, tx *sql.Tx, entries []parser.TimesheetRow, run *timesheetImportResult) error {
+	txQueries := db.New(tx)
+
+	jobIDs := make([]string, 0, len(entries))
+	workDates := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		workDate := ""
+		if entry.WorkDate != nil {
+			workDate = entry.WorkDate.Format("2006-01-02")
+		}
+		jobIDs = append(jobIDs, entry.JobID)
+		workDates = append(workDates, workDate)
+	}
+
+	replaced, err := txQueries.DeleteReplacedTimesheetEntries(ctx, db.DeleteReplacedTimesheetEntriesParams{
+		JobIds:            jobIDs,
+		WorkDates:         workDates,
+		TimesheetImportID: run.importID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace earlier timesheet entries: %w", err)
+	}
+	run.replaced += int(replaced)
+
+	for _, entry := range entries {
+		techID, err := i.upsertTechnician(ctx, txQueries, entry.Technician, entry.WorkDate, &run.technicians)
+		if err != nil {
+			return &rowError{row: entry.Row, err: fmt.Errorf("failed to upsert technician %q (row %d): %w", entry.Technician, entry.Row, err)}
+		}
+
+		laborCost := entry.LaborCost()
+		err = txQueries.InsertTimesheetEntry(ctx, db.InsertTimesheetEntryParams{
+			TimesheetImportID: run.importID,
+			JobID:             entry.JobID,
+			Technician:        entry.Technician,
+			TechnicianID:      sql.NullInt64{Int64: techID, Valid: true},
+			WorkDate:          sqlNullTime(entry.WorkDate),
+			Hours:             entry.Hours.String(),
+			PayRate:           decimalOrZero(entry.PayRate),
+			BurdenRate:        decimalOrZero(entry.BurdenRate),
+			LaborCost:         laborCost.String(),
+		})
+		if err != nil {
+			return &rowError{row: entry.Row, err: fmt.Errorf("failed to insert timesheet entry for job %v (row %d): %w", entry.JobID, entry.Row, err)}
+		}
+
+		run.entries++
+		run.hours = run.hours.Add(entry.Hours)
+		run.laborCost = run.laborCost.Add(laborCost)
+		run.jobIDs[entry.JobID] = true
+	}
+
+	return nil
+}
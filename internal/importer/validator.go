@@ -8,34 +8,108 @@ import (
 	"github.com/datsun80zx/sta.git/internal/db"
 )
 
+// Data quality checks recorded in data_quality_issues
+const (
+	CheckJobWithoutInvoices        = "job_without_invoices"
+	CheckInvoiceCustomerMismatch   = "invoice_customer_mismatch"
+	CheckInvoiceLocationMismatch   = "invoice_location_mismatch"
+	CheckInvoiceJobTypeMismatch    = "invoice_job_type_mismatch"
+	CheckInvoiceCostsTotalMismatch = "invoice_costs_total_mismatch"
+	CheckJobSubtotalMismatch       = "job_subtotal_mismatch"
+)
+
 // ValidationResult contains validation warnings and errors
 type ValidationResult struct {
 	JobsWithoutInvoices []string
+	CustomerMismatches  []string // Invoice #s whose customer isn't the job's
+	LocationMismatches  []string // Invoice #s whose location isn't the job's
+	JobTypeMismatches   []string // Invoice #s whose job type isn't the job's
+	CostsTotalMismatch  []string // Invoice #s whose costs total isn't the sum of its costs
+	SubtotalMismatches  []string // Job IDs whose subtotal is well off their invoice totals
 	Warnings            []string
 }
 
-// ValidateImport checks data quality after import
+// IssueCount returns the number of data quality issues found
+func (v *ValidationResult) IssueCount() int {
+	return len(v.JobsWithoutInvoices) + len(v.CustomerMismatches) + len(v.LocationMismatches) +
+		len(v.JobTypeMismatches) + len(v.CostsTotalMismatch) + len(v.SubtotalMismatches)
+}
+
+// ValidateImport checks data quality after import. Invoices from the batch
+// are cross-checked against their jobs, and jobs against their invoices.
+// Every issue found is recorded against the batch, replacing whatever an
+// earlier validation of the batch recorded.
 func ValidateImport(ctx context.Context, tx *sql.Tx, batchID int64) (*ValidationResult, error) {
-	queries := db.New(tx) // FIX: Pass tx to db.New()
+	queries := db.New(tx)
 
 	result := &ValidationResult{
 		JobsWithoutInvoices: make([]string, 0),
 		Warnings:            make([]string, 0),
 	}
 
+	if err := queries.DeleteDataQualityIssues(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("failed to clear data quality issues: %w", err)
+	}
+
 	// Check for jobs without invoices
-	jobsWithoutInvoices, err := queries.GetJobsWithoutInvoices(ctx, batchID) // FIX: Remove tx parameter - it's already in the queries object
+	jobsWithoutInvoices, err := queries.RecordJobsWithoutInvoices(ctx, batchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check jobs without invoices: %w", err)
 	}
+	result.JobsWithoutInvoices = append(result.JobsWithoutInvoices, jobsWithoutInvoices...)
 
-	if len(jobsWithoutInvoices) > 0 {
-		for _, job := range jobsWithoutInvoices {
-			result.JobsWithoutInvoices = append(result.JobsWithoutInvoices, job.ID)
+	// Check invoices against their jobs
+	mismatches, err := queries.RecordInvoiceMismatches(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check invoices against jobs: %w", err)
+	}
+	for _, m := range mismatches {
+		switch m.CheckName {
+		case CheckInvoiceCustomerMismatch:
+			result.CustomerMismatches = append(result.CustomerMismatches, m.InvoiceID.String)
+		case CheckInvoiceLocationMismatch:
+			result.LocationMismatches = append(result.LocationMismatches, m.InvoiceID.String)
+		case CheckInvoiceJobTypeMismatch:
+			result.JobTypeMismatches = append(result.JobTypeMismatches, m.InvoiceID.String)
+		case CheckInvoiceCostsTotalMismatch:
+			result.CostsTotalMismatch = append(result.CostsTotalMismatch, m.InvoiceID.String)
 		}
-		result.Warnings = append(result.Warnings,
-			fmt.Sprintf("Found %d jobs without invoices", len(jobsWithoutInvoices)))
 	}
 
+	// Check job subtotals against their invoices
+	result.SubtotalMismatches, err = queries.RecordJobSubtotalMismatches(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check job subtotals: %w", err)
+	}
+
+	warn := func(ids []string, format string) {
+		if len(ids) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(format, len(ids)))
+		}
+	}
+	warn(result.JobsWithoutInvoices, "Found %d jobs without invoices")
+	warn(result.CustomerMismatches, "Found %d invoices whose customer doesn't match their job")
+	warn(result.LocationMismatches, "Found %d invoices whose location doesn't match their job")
+	warn(result.JobTypeMismatches, "Found %d invoices whose job type doesn't match their job")
+	warn(result.CostsTotalMismatch, "Found %d invoices whose costs total isn't material + equipment + PO - returns")
+	warn(result.SubtotalMismatches, "Found %d jobs whose subtotal differs substantially from their invoice totals")
+
 	return result, nil
 }
+
+// ListDataQualityIssues returns the data quality issues found when a batch
+// was validated
+func (i *Importer) ListDataQualityIssues(ctx context.Context, batchID int64) ([]db.DataQualityIssue, error) {
+	if _, err := i.queries.GetImportBatch(ctx, batchID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import batch %d not found", batchID)
+		}
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+
+	issues, err := i.queries.ListDataQualityIssues(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data quality issues: %w", err)
+	}
+	return issues, nil
+}
@@ -4,9 +4,22 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
+// Data quality flags recorded on job metrics
+const (
+	FlagNoCosts              = "no_costs"               // Invoices carry no costs, so margin reads 100%
+	FlagAdjustmentUsed       = "adjustment_used"        // Costs come from an adjustment invoice
+	FlagInvoiceTotalMismatch = "invoice_total_mismatch" // Invoice totals are well off the job subtotal
+	FlagHoursMissing         = "hours_missing"          // No hours on the job and no timesheet entries
+)
+
+// UnreliableFlags are the flags that make a job's margin untrustworthy.
+// Reports can leave jobs with any of them out.
+var UnreliableFlags = []string{FlagNoCosts, FlagInvoiceTotalMismatch, FlagHoursMissing}
+
 // JobMetric represents calculated metrics for a single job
 type JobMetric struct {
 	JobID          string
@@ -16,21 +29,34 @@ type JobMetric struct {
 	GrossMarginPct decimal.NullDecimal
 	InvoiceCount   int
 	HasAdjustment  bool
+
+	// Labor from imported timesheets, on top of the invoice costs above
+	LaborCosts            decimal.Decimal
+	HasLabor              bool
+	GrossProfitAfterLabor decimal.Decimal
+	MarginAfterLaborPct   decimal.NullDecimal
+
+	QualityFlags []string
+	Reliable     bool // No unreliable flags
 }
 
 // InvoiceData holds the invoice fields needed for calculations
 type InvoiceData struct {
 	ID           string
 	JobID        string
+	Total        decimal.Decimal
 	CostsTotal   decimal.Decimal
 	IsAdjustment bool
 }
 
 // JobData holds the job fields needed for calculations
 type JobData struct {
-	ID           string
-	Status       string
-	JobsSubtotal decimal.Decimal
+	ID               string
+	Status           string
+	JobsSubtotal     decimal.Decimal
+	TotalHoursWorked decimal.Decimal
+	LaborCosts       decimal.Decimal // Sum of the job's timesheet entries
+	HasLabor         bool            // Whether any timesheet entries exist for the job
 }
 
 // CalculateJobMetrics computes profitability metrics for all jobs in a batch
@@ -84,13 +110,17 @@ func calculateSingleJobMetric(job JobData, invoices []InvoiceData) JobMetric {
 	// Calculate total costs
 	// If there's an adjustment invoice, use its costs (it replaces the original)
 	// Otherwise, sum all non-adjustment invoice costs
+	// Invoice totals are compared with revenue on the same basis
+	invoiceTotal := decimal.Zero
 	if adjustmentInvoice != nil {
 		metric.TotalCosts = adjustmentInvoice.CostsTotal
+		invoiceTotal = adjustmentInvoice.Total
 	} else {
 		totalCosts := decimal.Zero
 		for _, inv := range invoices {
 			if !inv.IsAdjustment {
 				totalCosts = totalCosts.Add(inv.CostsTotal)
+				invoiceTotal = invoiceTotal.Add(inv.Total)
 			}
 		}
 		metric.TotalCosts = totalCosts
@@ -108,14 +138,75 @@ func calculateSingleJobMetric(job JobData, invoices []InvoiceData) JobMetric {
 		}
 	}
 
+	// Subtract timesheet labor; jobs without timesheets keep the invoice-only figures
+	metric.LaborCosts = job.LaborCosts
+	metric.HasLabor = job.HasLabor
+	metric.GrossProfitAfterLabor = metric.GrossProfit.Sub(job.LaborCosts)
+	if metric.Revenue.GreaterThan(decimal.Zero) {
+		marginPct := metric.GrossProfitAfterLabor.Div(metric.Revenue).Mul(decimal.NewFromInt(100))
+		metric.MarginAfterLaborPct = decimal.NullDecimal{
+			Decimal: marginPct,
+			Valid:   true,
+		}
+	}
+
+	metric.QualityFlags = qualityFlags(job, metric, invoiceTotal)
+	metric.Reliable = true
+	for _, flag := range metric.QualityFlags {
+		if isUnreliable(flag) {
+			metric.Reliable = false
+		}
+	}
+
 	return metric
 }
 
+// invoiceMismatchFloor is the smallest difference between revenue and invoice
+// totals worth flagging; invoice totals include tax, revenue doesn't
+var invoiceMismatchFloor = decimal.NewFromInt(25)
+
+// qualityFlags returns the data quality flags for a calculated job metric
+func qualityFlags(job JobData, metric JobMetric, invoiceTotal decimal.Decimal) []string {
+	flags := []string{}
+
+	if metric.TotalCosts.IsZero() {
+		flags = append(flags, FlagNoCosts)
+	}
+	if metric.HasAdjustment {
+		flags = append(flags, FlagAdjustmentUsed)
+	}
+
+	// Off by more than $25 or 10% of revenue, whichever is larger
+	tolerance := decimal.Max(invoiceMismatchFloor, metric.Revenue.Abs().Mul(decimal.NewFromFloat(0.10)))
+	if metric.Revenue.Sub(invoiceTotal).Abs().GreaterThan(tolerance) {
+		flags = append(flags, FlagInvoiceTotalMismatch)
+	}
+
+	if job.TotalHoursWorked.IsZero() && !job.HasLabor {
+		flags = append(flags, FlagHoursMissing)
+	}
+
+	return flags
+}
+
+func isUnreliable(flag string) bool {
+	for _, f := range UnreliableFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
 // SaveJobMetrics persists calculated job metrics to the database
 func SaveJobMetrics(ctx context.Context, tx *sql.Tx, metrics []JobMetric) error {
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO job_metrics (job_id, revenue, total_costs, gross_profit, gross_margin_pct, invoice_count, has_adjustment)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO job_metrics (
+			job_id, revenue, total_costs, gross_profit, gross_margin_pct, invoice_count, has_adjustment,
+			labor_costs, has_labor, gross_profit_after_labor, margin_after_labor_pct,
+			quality_flags, reliable
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT (job_id) DO UPDATE SET
 			revenue = EXCLUDED.revenue,
 			total_costs = EXCLUDED.total_costs,
@@ -123,6 +214,12 @@ func SaveJobMetrics(ctx context.Context, tx *sql.Tx, metrics []JobMetric) error
 			gross_margin_pct = EXCLUDED.gross_margin_pct,
 			invoice_count = EXCLUDED.invoice_count,
 			has_adjustment = EXCLUDED.has_adjustment,
+			labor_costs = EXCLUDED.labor_costs,
+			has_labor = EXCLUDED.has_labor,
+			gross_profit_after_labor = EXCLUDED.gross_profit_after_labor,
+			margin_after_labor_pct = EXCLUDED.margin_after_labor_pct,
+			quality_flags = EXCLUDED.quality_flags,
+			reliable = EXCLUDED.reliable,
 			calculated_at = NOW()
 	`)
 	if err != nil {
@@ -137,6 +234,10 @@ func SaveJobMetrics(ctx context.Context, tx *sql.Tx, metrics []JobMetric) error
 		} else {
 			marginPct = nil
 		}
+		var laborMarginPct interface{}
+		if m.MarginAfterLaborPct.Valid {
+			laborMarginPct = m.MarginAfterLaborPct.Decimal
+		}
 
 		_, err := stmt.ExecContext(ctx,
 			m.JobID,
@@ -146,6 +247,12 @@ func SaveJobMetrics(ctx context.Context, tx *sql.Tx, metrics []JobMetric) error
 			marginPct,
 			m.InvoiceCount,
 			m.HasAdjustment,
+			m.LaborCosts,
+			m.HasLabor,
+			m.GrossProfitAfterLabor,
+			laborMarginPct,
+			pq.Array(m.QualityFlags),
+			m.Reliable,
 		)
 		if err != nil {
 			return err
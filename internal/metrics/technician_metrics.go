@@ -3,6 +3,7 @@ package metrics
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -26,8 +27,21 @@ type TechnicianMetric struct {
 	TotalHoursWorked   decimal.Decimal
 	AvgHoursPerJob     decimal.NullDecimal
 	TotalEstimates     int
+	JobsWithEstimates  int // Primary jobs with at least one estimate
 	AvgEstimatesPerJob decimal.NullDecimal
 
+	// Estimate metrics (from the Estimates report, technician on the estimate)
+	EstimatesGiven     int
+	EstimatesSold      int
+	EstimatesDismissed int
+	TotalEstimateValue decimal.Decimal
+	CloseRate          decimal.NullDecimal // EstimatesSold / (EstimatesSold + EstimatesDismissed) * 100
+	AvgEstimateValue   decimal.NullDecimal // TotalEstimateValue / EstimatesGiven
+	AvgDaysToSold      decimal.NullDecimal // Created to sold, for sold estimates with both dates
+
+	daysToSold    int // Sum over soldWithDates estimates
+	soldWithDates int
+
 	// Profitability (sold_by role, from job_metrics)
 	TotalGrossProfit decimal.NullDecimal
 	AvgGrossProfit   decimal.NullDecimal
@@ -51,13 +65,50 @@ type JobForTechMetrics struct {
 	EstimateCount         int
 }
 
-// CalculateTechnicianMetrics computes performance metrics for all technicians
-func CalculateTechnicianMetrics(
-	technicianIDs []int64,
+// EstimateForTechMetrics holds estimate fields needed for technician calculations
+type EstimateForTechMetrics struct {
+	ID           string
+	JobID        string
+	TechnicianID int64
+	Status       string // "Open", "Sold" or "Dismissed"
+	Subtotal     decimal.Decimal
+	CreatedOn    *time.Time
+	SoldOn       *time.Time
+}
+
+// TechnicianMetricsBuilder accumulates technician metrics over jobs added a
+// page at a time, so a technician's whole history never has to be in memory.
+// Each page must carry every link and estimate of the jobs in it, and no job
+// may be in more than one page.
+type TechnicianMetricsBuilder struct {
+	metricsMap        map[int64]*TechnicianMetric
+	jobsWithEstimates map[int64]int
+}
+
+// NewTechnicianMetricsBuilder starts empty metrics for each technician;
+// links and estimates of any other technician are ignored
+func NewTechnicianMetricsBuilder(technicianIDs []int64) *TechnicianMetricsBuilder {
+	b := &TechnicianMetricsBuilder{
+		metricsMap:        make(map[int64]*TechnicianMetric),
+		jobsWithEstimates: make(map[int64]int),
+	}
+	for _, techID := range technicianIDs {
+		b.metricsMap[techID] = &TechnicianMetric{
+			TechnicianID: techID,
+			TotalSales:   decimal.Zero,
+		}
+	}
+	return b
+}
+
+// Add counts one page of jobs towards the technicians' metrics
+func (b *TechnicianMetricsBuilder) Add(
 	jobTechnicians []JobTechnicianData,
 	jobs []JobForTechMetrics,
 	jobMetrics []JobMetric,
-) []TechnicianMetric {
+	estimates []EstimateForTechMetrics,
+) {
+	metricsMap := b.metricsMap
 
 	// Build lookup maps
 	jobsByID := make(map[string]JobForTechMetrics)
@@ -93,13 +144,15 @@ func CalculateTechnicianMetrics(
 		}
 	}
 
-	// Initialize metrics for each technician
-	metricsMap := make(map[int64]*TechnicianMetric)
-	for _, techID := range technicianIDs {
-		metricsMap[techID] = &TechnicianMetric{
-			TechnicianID: techID,
-			TotalSales:   decimal.Zero,
+	// Jobs with estimates, per technician: primary jobs that reported an
+	// estimate count, plus jobs of estimates they gave. A job is only ever
+	// in one page, so the counts of each page add up.
+	jobsWithEstimates := make(map[int64]map[string]bool)
+	markJobWithEstimates := func(techID int64, jobID string) {
+		if jobsWithEstimates[techID] == nil {
+			jobsWithEstimates[techID] = make(map[string]bool)
 		}
+		jobsWithEstimates[techID][jobID] = true
 	}
 
 	// Process each job-technician relationship
@@ -143,6 +196,9 @@ func CalculateTechnicianMetrics(
 				m.TotalHoursWorked = m.TotalHoursWorked.Add(job.TotalHoursWorked)
 			}
 			m.TotalEstimates += job.EstimateCount
+			if job.EstimateCount > 0 {
+				markJobWithEstimates(jt.TechnicianID, jt.JobID)
+			}
 
 			// Sales calculation:
 			// If they have estimate sales subtotal > 0, use that (they sold an estimate)
@@ -156,11 +212,43 @@ func CalculateTechnicianMetrics(
 		}
 	}
 
-	// Calculate averages and build result slice
+	// Process each estimate; open estimates count towards the average value
+	// but not the close rate, since they haven't been decided yet
+	for _, e := range estimates {
+		m := metricsMap[e.TechnicianID]
+		if m == nil {
+			continue
+		}
+
+		m.EstimatesGiven++
+		m.TotalEstimateValue = m.TotalEstimateValue.Add(e.Subtotal)
+		markJobWithEstimates(e.TechnicianID, e.JobID)
+
+		switch e.Status {
+		case "Sold":
+			m.EstimatesSold++
+			if e.CreatedOn != nil && e.SoldOn != nil && !e.SoldOn.Before(*e.CreatedOn) {
+				m.daysToSold += int(e.SoldOn.Sub(*e.CreatedOn).Hours() / 24)
+				m.soldWithDates++
+			}
+		case "Dismissed":
+			m.EstimatesDismissed++
+		}
+	}
+
+	for techID, jobIDs := range jobsWithEstimates {
+		b.jobsWithEstimates[techID] += len(jobIDs)
+	}
+}
+
+// Metrics returns every technician's metrics over the jobs added so far
+func (b *TechnicianMetricsBuilder) Metrics() []TechnicianMetric {
 	var results []TechnicianMetric
-	for _, m := range metricsMap {
-		calculateTechnicianAverages(m)
-		results = append(results, *m)
+	for _, m := range b.metricsMap {
+		result := *m
+		result.JobsWithEstimates = b.jobsWithEstimates[m.TechnicianID]
+		calculateTechnicianAverages(&result)
+		results = append(results, result)
 	}
 
 	return results
@@ -202,6 +290,32 @@ func calculateTechnicianAverages(m *TechnicianMetric) {
 		}
 	}
 
+	// Close rate = EstimatesSold / decided estimates * 100
+	if decided := m.EstimatesSold + m.EstimatesDismissed; decided > 0 {
+		m.CloseRate = decimal.NullDecimal{
+			Decimal: decimal.NewFromInt(int64(m.EstimatesSold)).
+				Div(decimal.NewFromInt(int64(decided))).
+				Mul(decimal.NewFromInt(100)),
+			Valid: true,
+		}
+	}
+
+	// Average estimate value
+	if m.EstimatesGiven > 0 {
+		m.AvgEstimateValue = decimal.NullDecimal{
+			Decimal: m.TotalEstimateValue.Div(decimal.NewFromInt(int64(m.EstimatesGiven))),
+			Valid:   true,
+		}
+	}
+
+	// Average days from estimate to sale
+	if m.soldWithDates > 0 {
+		m.AvgDaysToSold = decimal.NullDecimal{
+			Decimal: decimal.NewFromInt(int64(m.daysToSold)).Div(decimal.NewFromInt(int64(m.soldWithDates))),
+			Valid:   true,
+		}
+	}
+
 	// Average gross profit and margin
 	if m.SoldJobs > 0 && m.TotalGrossProfit.Valid {
 		m.AvgGrossProfit = decimal.NullDecimal{
@@ -227,8 +341,9 @@ func SaveTechnicianMetrics(ctx context.Context, tx *sql.Tx, metrics []Technician
 			opportunities, conversions, conversion_rate,
 			jobs_serviced, total_hours_worked, avg_hours_per_job,
 			total_estimates, jobs_with_estimates, avg_estimates_per_job,
-			total_gross_profit, avg_gross_profit, avg_margin_pct
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			total_gross_profit, avg_gross_profit, avg_margin_pct,
+			estimates_sold, estimates_dismissed, close_rate, avg_estimate_value, avg_days_to_sold
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		ON CONFLICT (technician_id) DO UPDATE SET
 			jobs_sold = EXCLUDED.jobs_sold,
 			total_sales = EXCLUDED.total_sales,
@@ -245,6 +360,11 @@ func SaveTechnicianMetrics(ctx context.Context, tx *sql.Tx, metrics []Technician
 			total_gross_profit = EXCLUDED.total_gross_profit,
 			avg_gross_profit = EXCLUDED.avg_gross_profit,
 			avg_margin_pct = EXCLUDED.avg_margin_pct,
+			estimates_sold = EXCLUDED.estimates_sold,
+			estimates_dismissed = EXCLUDED.estimates_dismissed,
+			close_rate = EXCLUDED.close_rate,
+			avg_estimate_value = EXCLUDED.avg_estimate_value,
+			avg_days_to_sold = EXCLUDED.avg_days_to_sold,
 			calculated_at = NOW()
 	`)
 	if err != nil {
@@ -265,11 +385,16 @@ func SaveTechnicianMetrics(ctx context.Context, tx *sql.Tx, metrics []Technician
 			m.TotalHoursWorked,                // total_hours_worked
 			nullableDecimal(m.AvgHoursPerJob), // avg_hours_per_job
 			m.TotalEstimates,                  // total_estimates
-			m.TotalEstimates,                  // jobs_with_estimates (simplified)
+			m.JobsWithEstimates,               // jobs_with_estimates
 			nullableDecimal(m.AvgEstimatesPerJob),
 			nullableDecimal(m.TotalGrossProfit),
 			nullableDecimal(m.AvgGrossProfit),
 			nullableDecimal(m.AvgMarginPct),
+			m.EstimatesSold,
+			m.EstimatesDismissed,
+			nullableDecimal(m.CloseRate),
+			nullableDecimal(m.AvgEstimateValue),
+			nullableDecimal(m.AvgDaysToSold),
 		)
 		if err != nil {
 			return err
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -10,6 +11,28 @@ import (
 type CSVParser struct {
 	TrimWhitespace bool
 	SkipEmptyRows  bool
+
+	// Mapping adds header aliases for renamed columns; nil uses the defaults
+	Mapping *ColumnMapping
+
+	// OnRejectedRow enables lenient mode: rows that fail to parse are passed
+	// here and parsing continues. Returning an error aborts the file.
+	// When nil, the first bad row fails the whole file.
+	OnRejectedRow func(RejectedRow) error
+
+	// OnRawRow, when set, receives every data row before it is parsed,
+	// including rows that are then rejected. Returning an error aborts the
+	// file.
+	OnRawRow func(RawRow) error
+
+	// DateFormat is how date columns are written; the zero value tries the
+	// built-in month-first formats in UTC
+	DateFormat DateFormat
+
+	// OnBadDate, when set, receives every non-empty optional date cell that
+	// doesn't match DateFormat, by column header. The cell is left empty;
+	// a bad required date still fails or rejects its row.
+	OnBadDate func(column, value string)
 }
 
 func NewCSVParser() *CSVParser {
@@ -19,85 +42,152 @@ func NewCSVParser() *CSVParser {
 	}
 }
 
-// ParseJobs reads a Jobs CSV and returns parsed rows
-func (p *CSVParser) ParseJobs(r io.Reader) ([]JobRow, error) {
+// openRows sets up a csv.Reader and consumes the header row
+func (p *CSVParser) openRows(r io.Reader) (rowReader, []string, error) {
 	reader := csv.NewReader(r)
 	reader.TrimLeadingSpace = true
 	reader.LazyQuotes = true
+	reader.ReuseRecord = true
 
-	records, err := reader.ReadAll()
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("CSV file is empty")
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+		return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
 	}
 
-	if len(records) == 0 {
-		return nil, fmt.Errorf("CSV file is empty")
-	}
+	// The reader reuses its record slice, so keep our own copy of the headers
+	return &csvRows{reader: reader, row: 1}, append([]string(nil), headers...), nil
+}
 
-	headers := records[0]
-	colMap := buildColumnMap(headers)
+// csvRows adapts csv.Reader to rowReader, counting records from the header
+type csvRows struct {
+	reader *csv.Reader
+	row    int
+}
 
-	jobs := make([]JobRow, 0, len(records)-1)
-	for i, record := range records[1:] {
-		rowNum := i + 2
+func (c *csvRows) Read() ([]string, int, error) {
+	c.row++
+	record, err := c.reader.Read()
+	return record, c.row, err
+}
 
-		job, err := p.parseJobRow(record, colMap, rowNum)
-		if err != nil {
-			return nil, err
-		}
+func (c *csvRows) Close() error {
+	return nil
+}
+
+// csvParser lets the report functions reach the parsing options through
+// the Parser interface
+func (p *CSVParser) csvParser() *CSVParser {
+	return p
+}
+
+// reject hands a row that failed to parse to OnRejectedRow, or returns the
+// parse error unchanged when lenient mode is off
+func (p *CSVParser) reject(parseErr error, rowNum int, key string, headers, record []string) error {
+	if p.OnRejectedRow == nil {
+		return parseErr
+	}
 
-		jobs = append(jobs, job)
+	rejected := RejectedRow{
+		Row:    rowNum,
+		Key:    key,
+		Error:  parseErr.Error(),
+		Record: recordMap(headers, record),
+	}
+	var validationErr *ValidationError
+	if errors.As(parseErr, &validationErr) {
+		rejected.Column = validationErr.Column
+		rejected.Value = validationErr.Value
+		rejected.Error = validationErr.Err.Error()
 	}
 
-	return jobs, nil
+	return p.OnRejectedRow(rejected)
 }
 
-// ParseInvoices reads an Invoices CSV and returns parsed rows
-func (p *CSVParser) ParseInvoices(r io.Reader) ([]InvoiceRow, error) {
-	reader := csv.NewReader(r)
-	reader.TrimLeadingSpace = true
-	reader.LazyQuotes = true
+// recordMap keys a raw record by header
+func recordMap(headers, record []string) map[string]string {
+	m := make(map[string]string, len(headers))
+	for idx, header := range headers {
+		if idx < len(record) {
+			m[header] = record[idx]
+		}
+	}
+	return m
+}
 
-	records, err := reader.ReadAll()
+// DetectReportType reads the header row and reports which export the file is
+func (p *CSVParser) DetectReportType(r io.Reader) (ReportType, error) {
+	report, err := p.Inspect(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+		return "", err
 	}
-
-	if len(records) == 0 {
-		return nil, fmt.Errorf("CSV file is empty")
+	if err := p.missingColumnsError(report); err != nil {
+		return "", err
 	}
+	return report.ReportType, nil
+}
 
-	headers := records[0]
-	colMap := buildColumnMap(headers)
+// Inspect reads the header row, guesses the report type from whichever
+// report's required columns match best, and reports how every header maps
+func (p *CSVParser) Inspect(r io.Reader) (*ColumnReport, error) {
+	_, headers, err := p.openRows(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.inspectHeaders(headers)
+}
 
-	invoices := make([]InvoiceRow, 0, len(records)-1)
-	for i, record := range records[1:] {
-		rowNum := i + 2
+// inspectHeaders builds the ColumnReport for a header row
+func (p *CSVParser) inspectHeaders(headers []string) (*ColumnReport, error) {
+	candidates := []struct {
+		reportType ReportType
+		specs      []columnSpec
+	}{
+		{ReportJobs, jobColumns},
+		{ReportInvoices, invoiceColumns},
+		{ReportEstimates, estimateColumns},
+		{ReportTimesheets, timesheetColumns},
+		{ReportSpend, spendColumns},
+		{ReportCampaigns, campaignColumns},
+		{ReportRoster, rosterColumns},
+	}
 
-		invoice, err := p.parseInvoiceRow(record, colMap, rowNum)
-		if err != nil {
-			return nil, err
+	// Pick the report with fewer missing required columns; reports share
+	// columns like Job ID and Status, so ties go to the closer match
+	var best *ColumnReport
+	bestMissing, bestFound := 0, 0
+	for _, c := range candidates {
+		_, report := p.resolveColumns(headers, c.reportType)
+		missing := len(report.MissingRequired())
+		found := len(requiredColumns(c.specs)) - missing
+		if found == 0 {
+			continue
+		}
+		if best == nil || missing < bestMissing || (missing == bestMissing && found > bestFound) {
+			best, bestMissing, bestFound = report, missing, found
 		}
-
-		invoices = append(invoices, invoice)
 	}
-
-	return invoices, nil
+	if best == nil {
+		return nil, fmt.Errorf("unrecognized report: no Job ID, Invoice #, Estimate ID, Job #, Campaign, Campaign ID or Name column (add aliases with a column mapping)")
+	}
+	return best, nil
 }
 
-// buildColumnMap creates a case-insensitive map of column name → index
-func buildColumnMap(headers []string) map[string]int {
-	m := make(map[string]int)
-	for i, header := range headers {
-		normalized := strings.ToLower(strings.TrimSpace(header))
-		m[normalized] = i
+func requiredColumns(specs []columnSpec) []columnSpec {
+	var required []columnSpec
+	for _, spec := range specs {
+		if spec.Required {
+			required = append(required, spec)
+		}
 	}
-	return m
+	return required
 }
 
 // parseJobRow converts a CSV row into a JobRow struct
 func (p *CSVParser) parseJobRow(record []string, colMap map[string]int, rowNum int) (JobRow, error) {
-	var job JobRow
+	job := JobRow{Row: rowNum}
 	var err error
 
 	// Required fields
@@ -163,9 +253,9 @@ func (p *CSVParser) parseJobRow(record []string, colMap map[string]int, rowNum i
 	job.BusinessUnit = parseNullableString(getField(record, colMap, "business unit"))
 
 	// Dates
-	job.JobCreationDate = parseNullableDate(getField(record, colMap, "created date"))
-	job.JobScheduleDate = parseNullableDate(getField(record, colMap, "scheduled date"))
-	job.JobCompletionDate = parseNullableDate(getField(record, colMap, "completion date"))
+	job.JobCreationDate = p.parseNullableDate(getField(record, colMap, "created date"), "Created Date")
+	job.JobScheduleDate = p.parseNullableDate(getField(record, colMap, "scheduled date"), "Scheduled Date")
+	job.JobCompletionDate = p.parseNullableDate(getField(record, colMap, "completion date"), "Completion Date")
 
 	// People
 	job.AssignedTechnicians = parseNullableString(getField(record, colMap, "assigned technicians"))
@@ -203,7 +293,7 @@ func (p *CSVParser) parseJobRow(record []string, colMap map[string]int, rowNum i
 
 // parseInvoiceRow converts a CSV row into an InvoiceRow struct
 func (p *CSVParser) parseInvoiceRow(record []string, colMap map[string]int, rowNum int) (InvoiceRow, error) {
-	var invoice InvoiceRow
+	invoice := InvoiceRow{Row: rowNum}
 	var err error
 
 	// Required fields
@@ -221,11 +311,11 @@ func (p *CSVParser) parseInvoiceRow(record []string, colMap map[string]int, rowN
 	if invoiceDateStr == "" {
 		return invoice, &ValidationError{Row: rowNum, Column: "Invoice Date", Err: fmt.Errorf("required field is empty")}
 	}
-	invoiceDate := parseNullableDate(invoiceDateStr)
-	if invoiceDate == nil {
-		return invoice, &ValidationError{Row: rowNum, Column: "Invoice Date", Value: invoiceDateStr, Err: fmt.Errorf("invalid date format")}
+	invoiceDate, ok := p.DateFormat.Parse(invoiceDateStr)
+	if !ok {
+		return invoice, &ValidationError{Row: rowNum, Column: "Invoice Date", Value: invoiceDateStr, Err: fmt.Errorf("invalid date format, expected %s", p.DateFormat)}
 	}
-	invoice.InvoiceDate = *invoiceDate
+	invoice.InvoiceDate = invoiceDate
 
 	// Total is required
 	totalStr := getField(record, colMap, "total")
@@ -289,6 +379,169 @@ func (p *CSVParser) parseInvoiceRow(record []string, colMap map[string]int, rowN
 	return invoice, nil
 }
 
+// parseEstimateRow converts a CSV row into an EstimateRow struct
+func (p *CSVParser) parseEstimateRow(record []string, colMap map[string]int, rowNum int) (EstimateRow, error) {
+	estimate := EstimateRow{Row: rowNum}
+	var err error
+
+	// Required fields
+	estimate.EstimateID, err = parseRequiredString(getField(record, colMap, "estimate id"), rowNum, "Estimate ID")
+	if err != nil {
+		return estimate, err
+	}
+
+	estimate.JobID, err = parseRequiredString(getField(record, colMap, "job id"), rowNum, "Job ID")
+	if err != nil {
+		return estimate, err
+	}
+
+	estimate.Status, err = parseEstimateStatus(getField(record, colMap, "status"), rowNum)
+	if err != nil {
+		return estimate, err
+	}
+
+	subtotalStr := getField(record, colMap, "subtotal")
+	if subtotalStr != "" {
+		estimate.Subtotal, err = parseDecimal(subtotalStr, rowNum, "Subtotal")
+		if err != nil {
+			return estimate, err
+		}
+	}
+
+	// Optional fields
+	estimate.Name = parseNullableString(getField(record, colMap, "estimate name"))
+	estimate.Technician = parseNullableString(getField(record, colMap, "technician"))
+
+	// Dates
+	estimate.CreatedOn = p.parseNullableDate(getField(record, colMap, "created on"), "Created On")
+	estimate.SoldOn = p.parseNullableDate(getField(record, colMap, "sold on"), "Sold On")
+
+	return estimate, nil
+}
+
+// parseTimesheetRow converts a CSV row into a TimesheetRow struct
+func (p *CSVParser) parseTimesheetRow(record []string, colMap map[string]int, rowNum int) (TimesheetRow, error) {
+	entry := TimesheetRow{Row: rowNum}
+	var err error
+
+	// Required fields
+	entry.JobID, err = parseRequiredString(getField(record, colMap, "job #"), rowNum, "Job #")
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Technician, err = parseRequiredString(getField(record, colMap, "technician"), rowNum, "Technician")
+	if err != nil {
+		return entry, err
+	}
+
+	hours, err := parseDecimal(getField(record, colMap, "hours"), rowNum, "Hours")
+	if err != nil {
+		return entry, err
+	}
+	entry.Hours = *hours
+
+	// Hourly costs
+	payRateStr := getField(record, colMap, "pay rate")
+	if payRateStr != "" {
+		entry.PayRate, err = parseDecimal(payRateStr, rowNum, "Pay Rate")
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	burdenRateStr := getField(record, colMap, "burden rate")
+	if burdenRateStr != "" {
+		entry.BurdenRate, err = parseDecimal(burdenRateStr, rowNum, "Burden Rate")
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	// Dates
+	entry.WorkDate = p.parseNullableDate(getField(record, colMap, "date"), "Date")
+
+	return entry, nil
+}
+
+// parseSpendRow converts a CSV row into a SpendRow struct
+func (p *CSVParser) parseSpendRow(record []string, colMap map[string]int, rowNum int) (SpendRow, error) {
+	row := SpendRow{Row: rowNum}
+	var err error
+
+	row.Campaign, err = parseRequiredString(getField(record, colMap, "campaign"), rowNum, "Campaign")
+	if err != nil {
+		return row, err
+	}
+
+	row.Month, err = parseMonth(getField(record, colMap, "month"), rowNum, p.DateFormat)
+	if err != nil {
+		return row, err
+	}
+
+	amount, err := parseDecimal(getField(record, colMap, "amount"), rowNum, "Amount")
+	if err != nil {
+		return row, err
+	}
+	row.Amount = *amount
+
+	return row, nil
+}
+
+// parseCampaignRow converts a CSV row into a CampaignRow struct
+func (p *CSVParser) parseCampaignRow(record []string, colMap map[string]int, rowNum int) (CampaignRow, error) {
+	row := CampaignRow{Row: rowNum}
+	var err error
+
+	row.CampaignID, err = parseInt64(getField(record, colMap, "campaign id"), rowNum, "Campaign ID")
+	if err != nil {
+		return row, err
+	}
+
+	row.Name, err = parseRequiredString(getField(record, colMap, "name"), rowNum, "Name")
+	if err != nil {
+		return row, err
+	}
+
+	row.Category = parseNullableString(getField(record, colMap, "category"))
+	row.Channel = parseNullableString(getField(record, colMap, "channel"))
+
+	// Dates
+	row.ActiveFrom = p.parseNullableDate(getField(record, colMap, "start date"), "Start Date")
+	row.ActiveTo = p.parseNullableDate(getField(record, colMap, "end date"), "End Date")
+
+	return row, nil
+}
+
+// parseRosterRow converts a CSV row into a RosterRow struct
+func (p *CSVParser) parseRosterRow(record []string, colMap map[string]int, rowNum int) (RosterRow, error) {
+	row := RosterRow{Row: rowNum}
+	var err error
+
+	row.Name, err = parseRequiredString(getField(record, colMap, "name"), rowNum, "Name")
+	if err != nil {
+		return row, err
+	}
+
+	row.EmployeeID = parseNullableString(getField(record, colMap, "employee id"))
+	row.Team = parseNullableString(getField(record, colMap, "team"))
+	row.Manager = parseNullableString(getField(record, colMap, "manager"))
+
+	// Dates
+	row.HireDate = p.parseNullableDate(getField(record, colMap, "hire date"), "Hire Date")
+	row.TerminationDate = p.parseNullableDate(getField(record, colMap, "termination date"), "Termination Date")
+
+	hourlyCostStr := getField(record, colMap, "hourly cost")
+	if hourlyCostStr != "" {
+		row.HourlyCost, err = parseDecimal(hourlyCostStr, rowNum, "Hourly Cost")
+		if err != nil {
+			return row, err
+		}
+	}
+
+	return row, nil
+}
+
 // getField safely retrieves a field from a CSV row by column name
 func getField(record []string, colMap map[string]int, columnName string) string {
 	idx, ok := colMap[strings.ToLower(columnName)]
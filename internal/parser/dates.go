@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateFormat says how an export writes its dates. The zero value tries the
+// built-in ServiceTitan formats, month first, in UTC.
+type DateFormat struct {
+	// Layout is a Go reference-time layout such as "02/01/2006" for day-first
+	// dates or "2006-01-02 15:04" for datetimes. Empty tries defaultDateLayouts.
+	Layout string
+
+	// Location is the timezone of values written without an offset; values
+	// with one are converted into it. nil means UTC.
+	Location *time.Location
+}
+
+// defaultDateLayouts are tried in order when no layout is given
+var defaultDateLayouts = []string{
+	"1/2/2006",            // M/D/YYYY (most common from your data)
+	"01/02/2006",          // MM/DD/YYYY
+	"2006-01-02",          // ISO 8601
+	"2006-01-02 15:04:05", // ISO 8601 with time (XLSX date cells)
+	"1-2-2006",            // M-D-YYYY
+	"01-02-2006",          // MM-DD-YYYY
+	"1/2/2006 3:04:05 PM", // Datetime columns
+	"1/2/2006 3:04 PM",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// xlsxDateLayouts are how the XLSX reader writes date cells, so they are
+// accepted whatever layout the text cells use
+var xlsxDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05"}
+
+// NewDateFormat builds a DateFormat from a layout and an IANA timezone name
+// such as "America/Chicago", either of which may be empty. A layout that
+// can't round-trip a date (e.g. "DD/MM/YYYY" rather than "02/01/2006") is
+// rejected, since it would otherwise match nothing.
+func NewDateFormat(layout, timezone string) (DateFormat, error) {
+	var f DateFormat
+
+	if layout != "" {
+		ref := time.Date(2009, time.November, 17, 0, 0, 0, 0, time.UTC)
+		t, err := time.Parse(layout, ref.Format(layout))
+		if err != nil || t.Year() != ref.Year() || t.Month() != ref.Month() || t.Day() != ref.Day() {
+			return f, fmt.Errorf("invalid date format %q: use a Go layout such as 02/01/2006 (day first) or 2006-01-02 15:04", layout)
+		}
+		f.Layout = layout
+	}
+
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return f, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		f.Location = loc
+	}
+
+	return f, nil
+}
+
+// Parse reads a date or datetime, reporting whether it matched
+func (f DateFormat) Parse(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+
+	loc := f.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	layouts := defaultDateLayouts
+	if f.Layout != "" {
+		layouts = append([]string{f.Layout}, xlsxDateLayouts...)
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.In(loc), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// String describes the format for error messages
+func (f DateFormat) String() string {
+	s := "M/D/YYYY"
+	if f.Layout != "" {
+		s = f.Layout
+	}
+	if f.Location != nil {
+		s += " in " + f.Location.String()
+	}
+	return s
+}
+
+// parseNullableDate parses an optional date column. A value that doesn't
+// match DateFormat is passed to OnBadDate and left empty.
+func (p *CSVParser) parseNullableDate(s, column string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	t, ok := p.DateFormat.Parse(s)
+	if !ok {
+		if p.OnBadDate != nil {
+			p.OnBadDate(column, s)
+		}
+		return nil
+	}
+
+	return &t
+}
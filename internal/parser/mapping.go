@@ -0,0 +1,350 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ColumnMapping lists extra header names (aliases) for JobRow/InvoiceRow/EstimateRow/
+// TimesheetRow/SpendRow/CampaignRow/RosterRow fields, for custom ServiceTitan reports whose columns have been renamed.
+// Keys are Go field names, e.g.:
+//
+//	{
+//	  "jobs":     {"JobsSubtotal": ["Subtotal", "Job Subtotal"]},
+//	  "invoices": {"InvoiceID": ["Invoice Number"]},
+//	  "estimates": {"Technician": ["Sold By"]},
+//	  "timesheets": {"BurdenRate": ["Burden"]},
+//	  "spend":     {"Amount": ["Cost"]},
+//	  "campaigns": {"CampaignID": ["Id"]},
+//	  "roster":    {"EmployeeID": ["Payroll ID"]},
+//	  "date_format": "02/01/2006",
+//	  "timezone":    "Europe/London"
+//	}
+//
+// The default ServiceTitan header is always tried first. date_format and
+// timezone describe how the reports write dates (see NewDateFormat).
+type ColumnMapping struct {
+	Jobs       map[string][]string `json:"jobs"`
+	Invoices   map[string][]string `json:"invoices"`
+	Estimates  map[string][]string `json:"estimates"`
+	Timesheets map[string][]string `json:"timesheets"`
+	Spend      map[string][]string `json:"spend"`
+	Campaigns  map[string][]string `json:"campaigns"`
+	Roster     map[string][]string `json:"roster"`
+	DateFormat string              `json:"date_format"`
+	Timezone   string              `json:"timezone"`
+}
+
+// columnSpec describes a row field and its default header
+type columnSpec struct {
+	Field    string
+	Header   string
+	Required bool
+}
+
+var jobColumns = []columnSpec{
+	{"JobID", "Job ID", true},
+	{"CustomerID", "Customer ID", true},
+	{"JobType", "Job Type", true},
+	{"Status", "Status", true},
+	{"JobsSubtotal", "Jobs Subtotal", false},
+	{"JobTotal", "Jobs Total", false},
+	{"EstimateSalesSubtotal", "Jobs Estimate Sales Subtotal", false},
+	{"CustomerName", "Customer Name", false},
+	{"CustomerType", "Customer Type", false},
+	{"CustomerCity", "Customer City", false},
+	{"CustomerState", "Customer State", false},
+	{"CustomerZip", "Customer Zip", false},
+	{"LocationID", "Location ID", false},
+	{"LocationCity", "Location City", false},
+	{"LocationState", "Location State", false},
+	{"LocationZip", "Location Zip", false},
+	{"BusinessUnitID", "Business Unit ID", false},
+	{"BusinessUnit", "Business Unit", false},
+	{"JobCreationDate", "Created Date", false},
+	{"JobScheduleDate", "Scheduled Date", false},
+	{"JobCompletionDate", "Completion Date", false},
+	{"AssignedTechnicians", "Assigned Technicians", false},
+	{"SoldBy", "Sold By", false},
+	{"BookedBy", "Booked By", false},
+	{"DispatchedBy", "Dispatched By", false},
+	{"PrimaryTechnician", "Primary Technician", false},
+	{"JobCampaignID", "Job Campaign ID", false},
+	{"CallCampaignID", "Call Campaign ID", false},
+	{"CampaignCategory", "Campaign Category", false},
+	{"InvoiceID", "Invoice ID", false},
+	{"Summary", "Summary", false},
+	{"Priority", "Priority", false},
+	{"TotalHoursWorked", "Total Hours Worked", false},
+	{"SurveyResult", "Survey Result", false},
+	{"MemberStatus", "Member Status", false},
+	{"Tags", "Tags", false},
+	{"EstimateCount", "Estimates", false},
+	{"Opportunity", "Opportunity", false},
+	{"Warranty", "Warranty", false},
+	{"Recall", "Recall", false},
+	{"Converted", "Converted", false},
+	{"ZeroDollarJob", "Zero Dollar Job", false},
+}
+
+var invoiceColumns = []columnSpec{
+	{"InvoiceID", "Invoice #", true},
+	{"JobID", "Job #", true},
+	{"InvoiceDate", "Invoice Date", true},
+	{"Total", "Total", false},
+	{"ProjectNumber", "Project Number", false},
+	{"InvoiceStatus", "Invoice Status", false},
+	{"InvoiceBusinessUnitID", "Invoice Business Unit ID", false},
+	{"InvoiceType", "Invoice Type", false},
+	{"InvoiceSummary", "Invoice Summary", false},
+	{"Balance", "Balance", false},
+	{"Payments", "Payments", false},
+	{"PaymentTypes", "Payment Types", false},
+	{"PaymentTerm", "Payment Term", false},
+	{"MaterialCosts", "Material Costs", false},
+	{"EquipmentCosts", "Equipment Costs", false},
+	{"PurchaseOrderCosts", "Purchase Order Costs", false},
+	{"ReturnCosts", "Return Costs", false},
+	{"CostsTotal", "Costs Total", false},
+	{"MaterialRetail", "Material Retail", false},
+	{"MaterialMarkup", "Material Markup", false},
+	{"EquipmentRetail", "Equipment Retail", false},
+	{"EquipmentMarkup", "Equipment Markup", false},
+	{"Labor", "Labor", false},
+	{"Income", "Income", false},
+	{"DiscountTotal", "Discount Total", false},
+	{"PricebookPrice", "Pricebook Price", false},
+	{"LaborPay", "Labor Pay", false},
+	{"LaborBurden", "Labor Burden", false},
+	{"TotalLaborCosts", "Total Labor Costs", false},
+	{"CustomerID", "Customer ID", false},
+	{"LocationID", "Location ID", false},
+	{"IsAdjustment", "Is Adjustment", false},
+	{"DispatchServiceFeeOnly", "Dispatch/Service Fee Only", false},
+	{"PrevailingWage", "Prevailing Wage", false},
+	{"JobType", "Job Type", false},
+}
+
+var estimateColumns = []columnSpec{
+	{"EstimateID", "Estimate ID", true},
+	{"JobID", "Job ID", true},
+	{"Status", "Status", true},
+	{"Name", "Estimate Name", false},
+	{"Technician", "Technician", false},
+	{"Subtotal", "Subtotal", false},
+	{"CreatedOn", "Created On", false},
+	{"SoldOn", "Sold On", false},
+}
+
+var timesheetColumns = []columnSpec{
+	{"JobID", "Job #", true},
+	{"Technician", "Technician", true},
+	{"Hours", "Hours", true},
+	{"WorkDate", "Date", false},
+	{"PayRate", "Pay Rate", false},
+	{"BurdenRate", "Burden Rate", false},
+}
+
+var spendColumns = []columnSpec{
+	{"Campaign", "Campaign", true},
+	{"Month", "Month", true},
+	{"Amount", "Amount", true},
+}
+
+var campaignColumns = []columnSpec{
+	{"CampaignID", "Campaign ID", true},
+	{"Name", "Name", true},
+	{"Category", "Category", false},
+	{"Channel", "Channel", false},
+	{"ActiveFrom", "Start Date", false},
+	{"ActiveTo", "End Date", false},
+}
+
+var rosterColumns = []columnSpec{
+	{"Name", "Name", true},
+	{"EmployeeID", "Employee ID", false},
+	{"Team", "Team", false},
+	{"Manager", "Manager", false},
+	{"HireDate", "Hire Date", false},
+	{"TerminationDate", "Termination Date", false},
+	{"HourlyCost", "Hourly Cost", false},
+}
+
+// LoadColumnMapping reads a JSON column mapping file.
+// Unknown field names are rejected so typos don't go unnoticed.
+func LoadColumnMapping(path string) (*ColumnMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column mapping: %w", err)
+	}
+
+	var mapping ColumnMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse column mapping %s: %w", path, err)
+	}
+
+	if err := checkMappingFields(mapping.Jobs, jobColumns, ReportJobs); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Invoices, invoiceColumns, ReportInvoices); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Estimates, estimateColumns, ReportEstimates); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Timesheets, timesheetColumns, ReportTimesheets); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Spend, spendColumns, ReportSpend); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Campaigns, campaignColumns, ReportCampaigns); err != nil {
+		return nil, err
+	}
+	if err := checkMappingFields(mapping.Roster, rosterColumns, ReportRoster); err != nil {
+		return nil, err
+	}
+	if _, err := NewDateFormat(mapping.DateFormat, mapping.Timezone); err != nil {
+		return nil, fmt.Errorf("column mapping %s: %w", path, err)
+	}
+
+	return &mapping, nil
+}
+
+func checkMappingFields(aliases map[string][]string, specs []columnSpec, reportType ReportType) error {
+	var unknown []string
+	for field := range aliases {
+		if findSpec(specs, field) == nil {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("column mapping has unknown %s field(s): %s", reportType, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func findSpec(specs []columnSpec, field string) *columnSpec {
+	for idx := range specs {
+		if specs[idx].Field == field {
+			return &specs[idx]
+		}
+	}
+	return nil
+}
+
+// columnsFor returns the field specs and aliases for a report type
+func (p *CSVParser) columnsFor(reportType ReportType) ([]columnSpec, map[string][]string) {
+	mapping := p.Mapping
+	if mapping == nil {
+		mapping = &ColumnMapping{}
+	}
+	switch reportType {
+	case ReportInvoices:
+		return invoiceColumns, mapping.Invoices
+	case ReportEstimates:
+		return estimateColumns, mapping.Estimates
+	case ReportTimesheets:
+		return timesheetColumns, mapping.Timesheets
+	case ReportSpend:
+		return spendColumns, mapping.Spend
+	case ReportCampaigns:
+		return campaignColumns, mapping.Campaigns
+	case ReportRoster:
+		return rosterColumns, mapping.Roster
+	default:
+		return jobColumns, mapping.Jobs
+	}
+}
+
+// ColumnMatch describes how one header or field was mapped
+type ColumnMatch struct {
+	Header   string // Header as it appears in the file; empty for missing fields
+	Field    string // Row field (e.g. JobRow.JobsSubtotal); empty for headers that aren't used
+	Required bool
+}
+
+// ColumnReport describes how a file's header row maps onto row fields
+type ColumnReport struct {
+	ReportType ReportType
+	Columns    []ColumnMatch // One per header, in file order
+	Missing    []ColumnMatch // Fields no header matched
+}
+
+// MissingRequired returns the required fields that no header matched
+func (r *ColumnReport) MissingRequired() []ColumnMatch {
+	var missing []ColumnMatch
+	for _, m := range r.Missing {
+		if m.Required {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
+
+// resolveColumns maps each field's default header (lowercase) to its index
+// in headers, trying the default header first and then any aliases
+func (p *CSVParser) resolveColumns(headers []string, reportType ReportType) (map[string]int, *ColumnReport) {
+	specs, aliases := p.columnsFor(reportType)
+
+	headerIdx := make(map[string]int, len(headers))
+	for idx, header := range headers {
+		normalized := normalizeHeader(header)
+		if _, ok := headerIdx[normalized]; !ok {
+			headerIdx[normalized] = idx
+		}
+	}
+
+	report := &ColumnReport{
+		ReportType: reportType,
+		Columns:    make([]ColumnMatch, len(headers)),
+	}
+	for idx, header := range headers {
+		report.Columns[idx] = ColumnMatch{Header: header}
+	}
+
+	colMap := make(map[string]int)
+	for _, spec := range specs {
+		names := append([]string{spec.Header}, aliases[spec.Field]...)
+		found := false
+		for _, name := range names {
+			if idx, ok := headerIdx[normalizeHeader(name)]; ok {
+				colMap[strings.ToLower(spec.Header)] = idx
+				report.Columns[idx].Field = spec.Field
+				report.Columns[idx].Required = spec.Required
+				found = true
+				break
+			}
+		}
+		if !found {
+			report.Missing = append(report.Missing, ColumnMatch{Field: spec.Field, Required: spec.Required})
+		}
+	}
+
+	return colMap, report
+}
+
+// missingColumnsError lists required fields with the headers that were tried
+func (p *CSVParser) missingColumnsError(report *ColumnReport) error {
+	missing := report.MissingRequired()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	specs, aliases := p.columnsFor(report.ReportType)
+	var parts []string
+	for _, m := range missing {
+		names := append([]string{findSpec(specs, m.Field).Header}, aliases[m.Field]...)
+		parts = append(parts, fmt.Sprintf(`%s (tried "%s")`, m.Field, strings.Join(names, `", "`)))
+	}
+	return fmt.Errorf("%s report is missing required column(s): %s", report.ReportType, strings.Join(parts, "; "))
+}
+
+// normalizeHeader lowercases and trims a header, dropping any UTF-8 BOM
+func normalizeHeader(header string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+}
@@ -5,17 +5,59 @@ import (
 	"io"
 )
 
-// Parser defines the interface for parsing ServiceTitan export files
+// Parser defines the interface for reading ServiceTitan export files.
+// CSVParser and XLSXParser only differ in how they read raw records; rows
+// are parsed the same way for both by StreamJobs, ParseJobs and the other
+// report functions.
 type Parser interface {
-	ParseJobs(r io.Reader) ([]JobRow, error)
-	ParseInvoices(r io.Reader) ([]InvoiceRow, error)
+	DetectReportType(r io.Reader) (ReportType, error)
+	Inspect(r io.Reader) (*ColumnReport, error)
+
+	// openRows consumes the header row and returns a reader for the data rows
+	openRows(r io.Reader) (rowReader, []string, error)
+	// csvParser returns the options rows are parsed with
+	csvParser() *CSVParser
 }
 
 // ParseResult contains the parsed data and any warnings
 type ParseResult struct {
-	Jobs     []JobRow
-	Invoices []InvoiceRow
-	Warnings []string
+	Jobs       []JobRow
+	Invoices   []InvoiceRow
+	Estimates  []EstimateRow
+	Timesheets []TimesheetRow
+	Spend      []SpendRow
+	Campaigns  []CampaignRow
+	Roster     []RosterRow
+	Warnings   []string
+}
+
+// ReportType identifies which ServiceTitan export a file contains
+type ReportType string
+
+const (
+	ReportJobs       ReportType = "jobs"
+	ReportInvoices   ReportType = "invoices"
+	ReportEstimates  ReportType = "estimates"
+	ReportTimesheets ReportType = "timesheets"
+	ReportSpend      ReportType = "spend"
+	ReportCampaigns  ReportType = "campaigns"
+	ReportRoster     ReportType = "roster"
+)
+
+// RejectedRow is a row that failed to parse in lenient mode
+type RejectedRow struct {
+	Row    int
+	Column string
+	Value  string
+	Error  string
+	Key    string            // Job ID, Invoice # or Estimate ID from the raw row, if present
+	Record map[string]string // Raw row keyed by header, for re-ingesting later
+}
+
+// RawRow is a data row exactly as it was read from an export, before parsing
+type RawRow struct {
+	Row    int
+	Record map[string]string // Keyed by header
 }
 
 // ValidationError represents a parsing error with context
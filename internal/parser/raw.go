@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"io"
+	"sort"
+)
+
+// StreamRawJobs parses jobs rows captured earlier with OnRawRow, so they can
+// be re-parsed under the current rules without the original file. next
+// returns the rows in file order and io.EOF after the last; parsed rows
+// keep the row numbers they were captured with.
+func (p *CSVParser) StreamRawJobs(next func() (RawRow, error), fn func(JobRow) error) error {
+	reader, headers, err := newRecordReader(next)
+	if err != nil || reader == nil {
+		return err
+	}
+	return streamRows(p, reader, headers, ReportJobs, (*CSVParser).parseJobRow, fn)
+}
+
+// StreamRawInvoices parses invoices rows captured earlier with OnRawRow; see
+// StreamRawJobs
+func (p *CSVParser) StreamRawInvoices(next func() (RawRow, error), fn func(InvoiceRow) error) error {
+	reader, headers, err := newRecordReader(next)
+	if err != nil || reader == nil {
+		return err
+	}
+	return streamRows(p, reader, headers, ReportInvoices, (*CSVParser).parseInvoiceRow, fn)
+}
+
+// recordReader turns records keyed by header back into rows, so stored raw
+// rows go through the same parsing as a file
+type recordReader struct {
+	headers []string
+	next    func() (RawRow, error)
+	pending *RawRow // first row, read to find the headers
+	record  []string
+}
+
+// newRecordReader reads the first record to work out the headers. It
+// returns a nil reader when there are no records.
+func newRecordReader(next func() (RawRow, error)) (*recordReader, []string, error) {
+	first, err := next()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make([]string, 0, len(first.Record))
+	for header := range first.Record {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+
+	return &recordReader{headers: headers, next: next, pending: &first}, headers, nil
+}
+
+func (r *recordReader) Close() error {
+	return nil
+}
+
+func (r *recordReader) Read() ([]string, int, error) {
+	var row RawRow
+	if r.pending != nil {
+		row = *r.pending
+		r.pending = nil
+	} else {
+		var err error
+		if row, err = r.next(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	r.record = r.record[:0]
+	for _, header := range r.headers {
+		r.record = append(r.record, row.Record[header])
+	}
+	return r.record, row.Row, nil
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+)
+
+// rowReader yields raw records one at a time, returning io.EOF at the end.
+// CSV files, XLSX sheets and staged raw rows each have one. Read also
+// returns the record's 1-based row number, which is what error messages
+// and rejected rows report.
+type rowReader interface {
+	Read() (record []string, row int, err error)
+	Close() error
+}
+
+// rowBuilder converts one record of a report into its row type
+type rowBuilder[T any] func(p *CSVParser, record []string, colMap map[string]int, rowNum int) (T, error)
+
+// recordKeyColumns is the column that identifies a row of each report. It
+// is kept with rejected rows so a corrected file can be matched to them.
+var recordKeyColumns = map[ReportType]string{
+	ReportJobs:       "job id",
+	ReportInvoices:   "invoice #",
+	ReportEstimates:  "estimate id",
+	ReportTimesheets: "job #",
+	ReportSpend:      "campaign",
+	ReportCampaigns:  "campaign id",
+	ReportRoster:     "name",
+}
+
+// StreamJobs reads a Jobs export one record at a time and calls fn for each
+// parsed row, so large exports never have to be held in memory at once.
+// Parsing stops at the first error, including any error returned by fn,
+// unless OnRejectedRow is set.
+func StreamJobs(p Parser, r io.Reader, fn func(JobRow) error) error {
+	return stream(p, r, ReportJobs, (*CSVParser).parseJobRow, fn)
+}
+
+// StreamInvoices reads an Invoices export one record at a time; see StreamJobs
+func StreamInvoices(p Parser, r io.Reader, fn func(InvoiceRow) error) error {
+	return stream(p, r, ReportInvoices, (*CSVParser).parseInvoiceRow, fn)
+}
+
+// StreamEstimates reads an Estimates export one record at a time; see StreamJobs
+func StreamEstimates(p Parser, r io.Reader, fn func(EstimateRow) error) error {
+	return stream(p, r, ReportEstimates, (*CSVParser).parseEstimateRow, fn)
+}
+
+// StreamTimesheets reads a Timesheet export one record at a time; see StreamJobs
+func StreamTimesheets(p Parser, r io.Reader, fn func(TimesheetRow) error) error {
+	return stream(p, r, ReportTimesheets, (*CSVParser).parseTimesheetRow, fn)
+}
+
+// ParseJobs reads a Jobs export and returns every parsed row
+func ParseJobs(p Parser, r io.Reader) ([]JobRow, error) {
+	return parseAll(p, r, ReportJobs, (*CSVParser).parseJobRow)
+}
+
+// ParseInvoices reads an Invoices export and returns every parsed row
+func ParseInvoices(p Parser, r io.Reader) ([]InvoiceRow, error) {
+	return parseAll(p, r, ReportInvoices, (*CSVParser).parseInvoiceRow)
+}
+
+// ParseEstimates reads an Estimates export and returns every parsed row
+func ParseEstimates(p Parser, r io.Reader) ([]EstimateRow, error) {
+	return parseAll(p, r, ReportEstimates, (*CSVParser).parseEstimateRow)
+}
+
+// ParseTimesheets reads a Timesheet export and returns every parsed row
+func ParseTimesheets(p Parser, r io.Reader) ([]TimesheetRow, error) {
+	return parseAll(p, r, ReportTimesheets, (*CSVParser).parseTimesheetRow)
+}
+
+// ParseSpend reads a marketing spend file and returns every parsed row
+func ParseSpend(p Parser, r io.Reader) ([]SpendRow, error) {
+	return parseAll(p, r, ReportSpend, (*CSVParser).parseSpendRow)
+}
+
+// ParseCampaigns reads a campaign list and returns every parsed row
+func ParseCampaigns(p Parser, r io.Reader) ([]CampaignRow, error) {
+	return parseAll(p, r, ReportCampaigns, (*CSVParser).parseCampaignRow)
+}
+
+// ParseRoster reads a technician roster and returns every parsed row
+func ParseRoster(p Parser, r io.Reader) ([]RosterRow, error) {
+	return parseAll(p, r, ReportRoster, (*CSVParser).parseRosterRow)
+}
+
+// parseAll collects every row of a report into a slice
+func parseAll[T any](p Parser, r io.Reader, reportType ReportType, build rowBuilder[T]) ([]T, error) {
+	var rows []T
+	err := stream(p, r, reportType, build, func(row T) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// stream opens r with p and parses its data rows as reportType
+func stream[T any](p Parser, r io.Reader, reportType ReportType, build rowBuilder[T], fn func(T) error) error {
+	reader, headers, err := p.openRows(r)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return streamRows(p.csvParser(), reader, headers, reportType, build, fn)
+}
+
+// streamRows maps headers onto reportType's columns, parses every record
+// with build and calls fn with the result. Records build fails on go to
+// OnRejectedRow in lenient mode; otherwise the first one stops the file.
+func streamRows[T any](p *CSVParser, reader rowReader, headers []string, reportType ReportType, build rowBuilder[T], fn func(T) error) error {
+	colMap, report := p.resolveColumns(headers, reportType)
+	if err := p.missingColumnsError(report); err != nil {
+		return err
+	}
+
+	for {
+		record, rowNum, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row %d: %w", rowNum, err)
+		}
+		if p.OnRawRow != nil {
+			if err := p.OnRawRow(RawRow{Row: rowNum, Record: recordMap(headers, record)}); err != nil {
+				return err
+			}
+		}
+
+		row, err := build(p, record, colMap, rowNum)
+		if err != nil {
+			key := getField(record, colMap, recordKeyColumns[reportType])
+			if err := p.reject(err, rowNum, key, headers, record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 // JobRow represents a parsed row from the Jobs report
 type JobRow struct {
+	Row int // Row number in the export, for error messages and rejected rows
+
 	// Core identifiers
 	JobID      string
 	CustomerID int64
@@ -73,6 +75,8 @@ type JobRow struct {
 
 // InvoiceRow represents a parsed row from the Invoices report
 type InvoiceRow struct {
+	Row int // Row number in the export
+
 	// Core identifiers
 	InvoiceID             string
 	JobID                 string
@@ -126,3 +130,89 @@ type InvoiceRow struct {
 	// Job type (for validation)
 	JobType *string
 }
+
+// EstimateRow represents a parsed row from the Estimates report
+type EstimateRow struct {
+	Row int // Row number in the export
+
+	// Core identifiers
+	EstimateID string
+	JobID      string
+
+	// Estimate details
+	Name       *string
+	Status     string // Open, Sold or Dismissed
+	Technician *string
+	Subtotal   *decimal.Decimal
+
+	// Dates
+	CreatedOn *time.Time
+	SoldOn    *time.Time
+}
+
+// TimesheetRow represents a parsed row from the Timesheet/Payroll report
+type TimesheetRow struct {
+	Row int // Row number in the export
+
+	// Core identifiers
+	JobID      string
+	Technician string
+
+	// Time worked
+	WorkDate *time.Time
+	Hours    decimal.Decimal
+
+	// Hourly costs; burden is the employer cost on top of pay (taxes,
+	// insurance, benefits), also per hour
+	PayRate    *decimal.Decimal
+	BurdenRate *decimal.Decimal
+}
+
+// LaborCost returns what the row's hours cost: hours × (pay rate + burden rate)
+func (t TimesheetRow) LaborCost() decimal.Decimal {
+	rate := decimal.Zero
+	if t.PayRate != nil {
+		rate = rate.Add(*t.PayRate)
+	}
+	if t.BurdenRate != nil {
+		rate = rate.Add(*t.BurdenRate)
+	}
+	return t.Hours.Mul(rate).Round(2)
+}
+
+// SpendRow represents a parsed row from a marketing spend file
+type SpendRow struct {
+	Row      int       // Row number in the file
+	Campaign string    // ServiceTitan campaign ID or name
+	Month    time.Time // First day of the month the money was spent
+	Amount   decimal.Decimal
+}
+
+// CampaignRow represents a parsed row from the ServiceTitan Campaigns export
+// or a hand-maintained campaign list
+type CampaignRow struct {
+	Row        int   // Row number in the file
+	CampaignID int64 // Matches Job Campaign ID in the Jobs export
+	Name       string
+	Category   *string
+	Channel    *string
+
+	// Dates the campaign ran; either end may be open
+	ActiveFrom *time.Time
+	ActiveTo   *time.Time
+}
+
+// RosterRow represents a parsed row from a technician roster
+type RosterRow struct {
+	Row        int    // Row number in the file
+	Name       string // Matched to technicians by name when the employee ID is new
+	EmployeeID *string
+	Team       *string
+	Manager    *string
+
+	// Employment dates; no termination date means still employed
+	HireDate        *time.Time
+	TerminationDate *time.Time
+
+	HourlyCost *decimal.Decimal // Fully loaded cost of an hour of their time
+}
@@ -148,34 +148,51 @@ func parseNullableString(s string) *string {
 	return &s
 }
 
-// parseNullableDate handles multiple date formats
-func parseNullableDate(s string) *time.Time {
-	if s == "" {
-		return nil
+// parseBool handles TRUE/FALSE from ServiceTitan
+func parseBool(s string) bool {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return s == "TRUE" || s == "YES" || s == "1"
+}
+
+// parseEstimateStatus normalizes an estimate status to Open, Sold or Dismissed
+func parseEstimateStatus(value string, rowNum int) (string, error) {
+	switch strings.ToLower(value) {
+	case "open":
+		return "Open", nil
+	case "sold":
+		return "Sold", nil
+	case "dismissed":
+		return "Dismissed", nil
+	case "":
+		return "", &ValidationError{Row: rowNum, Column: "Status", Err: fmt.Errorf("required field is empty")}
+	default:
+		return "", &ValidationError{Row: rowNum, Column: "Status", Value: value, Err: fmt.Errorf("expected Open, Sold or Dismissed")}
 	}
+}
 
-	s = strings.TrimSpace(s)
+// parseMonth parses a spend month such as 2024-11, 11/2024, Nov 2024 or
+// any full date in dates, returning the first day of that month
+func parseMonth(value string, rowNum int, dates DateFormat) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, &ValidationError{Row: rowNum, Column: "Month", Err: fmt.Errorf("required field is empty")}
+	}
 
-	// ServiceTitan exports dates as M/D/YYYY format
 	formats := []string{
-		"1/2/2006",   // M/D/YYYY (most common from your data)
-		"01/02/2006", // MM/DD/YYYY
-		"2006-01-02", // ISO 8601
-		"1-2-2006",   // M-D-YYYY
-		"01-02-2006", // MM-DD-YYYY
+		"2006-01",  // YYYY-MM
+		"1/2006",   // M/YYYY
+		"01/2006",  // MM/YYYY
+		"Jan 2006", // Mon YYYY
+		"January 2006",
 	}
-
 	for _, format := range formats {
-		if t, err := time.Parse(format, s); err == nil {
-			return &t
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
 		}
 	}
+	if t, ok := dates.Parse(value); ok {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
 
-	return nil
-}
-
-// parseBool handles TRUE/FALSE from ServiceTitan
-func parseBool(s string) bool {
-	s = strings.ToUpper(strings.TrimSpace(s))
-	return s == "TRUE" || s == "YES" || s == "1"
+	return time.Time{}, &ValidationError{Row: rowNum, Column: "Month", Value: value, Err: fmt.Errorf("expected a month like 2024-11 or 11/2024")}
 }
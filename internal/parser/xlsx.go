@@ -0,0 +1,549 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXParser reads ServiceTitan exports saved as Excel workbooks.
+// It embeds CSVParser for row parsing, column mapping and lenient mode, and
+// only replaces how raw records are read, by overriding openRows.
+type XLSXParser struct {
+	CSVParser
+
+	// Sheet selects the worksheet by name, or by 1-based position when no
+	// sheet has that name. Empty reads the first sheet.
+	Sheet string
+}
+
+func NewXLSXParser() *XLSXParser {
+	return &XLSXParser{CSVParser: *NewCSVParser()}
+}
+
+// IsXLSX reports whether a file should be read with XLSXParser
+func IsXLSX(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".xlsx")
+}
+
+// DetectReportType reads the header row and reports which export the workbook is
+func (p *XLSXParser) DetectReportType(r io.Reader) (ReportType, error) {
+	report, err := p.Inspect(r)
+	if err != nil {
+		return "", err
+	}
+	if err := p.missingColumnsError(report); err != nil {
+		return "", err
+	}
+	return report.ReportType, nil
+}
+
+// Inspect reads the header row and reports how every header maps
+func (p *XLSXParser) Inspect(r io.Reader) (*ColumnReport, error) {
+	sheet, headers, err := p.openRows(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sheet.Close()
+	return p.inspectHeaders(headers)
+}
+
+// openRows opens the selected worksheet and consumes its header row
+func (p *XLSXParser) openRows(r io.Reader) (rowReader, []string, error) {
+	readerAt, size, err := toReaderAt(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+
+	zr, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+
+	wb, err := readWorkbook(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sheetPath, err := wb.sheetPath(p.Sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sheet, err := wb.openSheet(zr, sheetPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers, _, err := sheet.Read()
+	if err == io.EOF {
+		sheet.Close()
+		return nil, nil, fmt.Errorf("XLSX sheet is empty")
+	}
+	if err != nil {
+		sheet.Close()
+		return nil, nil, err
+	}
+
+	return sheet, headers, nil
+}
+
+// toReaderAt adapts r for archive/zip, which needs random access.
+// Files are used directly; anything else is read into memory.
+func toReaderAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if rs, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return rs, size, nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// workbook holds the parts of an XLSX package needed to read cell values
+type workbook struct {
+	sheets        []workbookSheet
+	sharedStrings []string
+	dateStyles    map[int]bool // cellXfs index -> number format is a date
+	date1904      bool
+}
+
+type workbookSheet struct {
+	Name string
+	Path string
+}
+
+func readWorkbook(zr *zip.Reader) (*workbook, error) {
+	var wbXML struct {
+		WorkbookPr struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(zr, "xl/workbook.xml", &wbXML); err != nil {
+		return nil, err
+	}
+
+	var relsXML struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(zr, "xl/_rels/workbook.xml.rels", &relsXML); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for _, rel := range relsXML.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	wb := &workbook{
+		date1904: wbXML.WorkbookPr.Date1904 == "1" || wbXML.WorkbookPr.Date1904 == "true",
+	}
+	for _, s := range wbXML.Sheets {
+		wb.sheets = append(wb.sheets, workbookSheet{Name: s.Name, Path: targets[s.RID]})
+	}
+
+	var err error
+	if wb.sharedStrings, err = readSharedStrings(zr); err != nil {
+		return nil, err
+	}
+	if wb.dateStyles, err = readDateStyles(zr); err != nil {
+		return nil, err
+	}
+
+	return wb, nil
+}
+
+// sheetPath resolves a sheet name or 1-based position to its file in the package
+func (wb *workbook) sheetPath(sheet string) (string, error) {
+	if len(wb.sheets) == 0 {
+		return "", fmt.Errorf("XLSX workbook has no sheets")
+	}
+	if sheet == "" {
+		return wb.sheets[0].Path, nil
+	}
+
+	names := make([]string, len(wb.sheets))
+	for idx, s := range wb.sheets {
+		if strings.EqualFold(s.Name, sheet) {
+			return s.Path, nil
+		}
+		names[idx] = s.Name
+	}
+
+	if n, err := strconv.Atoi(sheet); err == nil {
+		if n < 1 || n > len(wb.sheets) {
+			return "", fmt.Errorf("XLSX sheet %d out of range (workbook has %d sheets)", n, len(wb.sheets))
+		}
+		return wb.sheets[n-1].Path, nil
+	}
+
+	return "", fmt.Errorf("XLSX sheet %q not found (sheets: %s)", sheet, strings.Join(names, ", "))
+}
+
+func (wb *workbook) openSheet(zr *zip.Reader, sheetPath string) (*sheetReader, error) {
+	f, err := zr.Open(sheetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX sheet %s: %w", sheetPath, err)
+	}
+	return &sheetReader{wb: wb, file: f, decoder: xml.NewDecoder(f)}, nil
+}
+
+// readSharedStrings loads the workbook's shared string table, if it has one
+func readSharedStrings(zr *zip.Reader) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := decodeZipXML(zr, "xl/sharedStrings.xml", &sst); err != nil {
+		if err == errZipPartMissing {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	strs := make([]string, len(sst.Items))
+	for idx, item := range sst.Items {
+		if len(item.Runs) == 0 {
+			strs[idx] = item.Text
+			continue
+		}
+		var b strings.Builder
+		for _, run := range item.Runs {
+			b.WriteString(run.Text)
+		}
+		strs[idx] = b.String()
+	}
+	return strs, nil
+}
+
+// readDateStyles finds which cell styles format numbers as dates, so date
+// serials can be told apart from plain numbers
+func readDateStyles(zr *zip.Reader) (map[int]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodeZipXML(zr, "xl/styles.xml", &styles); err != nil {
+		if err == errZipPartMissing {
+			return map[int]bool{}, nil
+		}
+		return nil, err
+	}
+
+	customDate := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDate[f.ID] = isDateFormatCode(f.Code)
+	}
+
+	dateStyles := make(map[int]bool)
+	for idx, xf := range styles.CellXfs {
+		if isBuiltinDateFormat(xf.NumFmtID) || customDate[xf.NumFmtID] {
+			dateStyles[idx] = true
+		}
+	}
+	return dateStyles, nil
+}
+
+// isBuiltinDateFormat reports whether a built-in number format is a date/time
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// isDateFormatCode reports whether a custom number format shows a date/time.
+// Quoted literals, escapes and [color]/[$-locale] sections are ignored.
+func isDateFormatCode(code string) bool {
+	inQuote, inBracket := false, false
+	for idx := 0; idx < len(code); idx++ {
+		c := code[idx]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			idx++ // skip the escaped/padding character
+		default:
+			switch c {
+			case 'd', 'D', 'm', 'M', 'y', 'Y', 'h', 'H', 's', 'S':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var errZipPartMissing = errors.New("XLSX part missing")
+
+func decodeZipXML(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return errZipPartMissing
+	}
+	defer f.Close()
+
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse XLSX %s: %w", name, err)
+	}
+	return nil
+}
+
+// sheetReader streams a worksheet's rows as string records, decoding the
+// sheet XML token by token so only one row is in memory at a time
+type sheetReader struct {
+	wb      *workbook
+	file    io.ReadCloser
+	decoder *xml.Decoder
+	row     int // number of the last row read
+}
+
+type xlsxCell struct {
+	Ref       string `xml:"r,attr"`
+	Type      string `xml:"t,attr"`
+	Style     int    `xml:"s,attr"`
+	Value     string `xml:"v"`
+	InlineStr struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// Read returns the next row's cell values and its row number as Excel shows
+// it, or io.EOF after the last row. Rows with no values, such as the styled
+// but empty rows at the end of many exports, are skipped.
+func (s *sheetReader) Read() ([]string, int, error) {
+	for {
+		tok, err := s.decoder.Token()
+		if err == io.EOF {
+			return nil, s.row + 1, io.EOF
+		}
+		if err != nil {
+			return nil, s.row + 1, fmt.Errorf("failed to read XLSX sheet: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row struct {
+			Ref   string     `xml:"r,attr"`
+			Cells []xlsxCell `xml:"c"`
+		}
+		if err := s.decoder.DecodeElement(&row, &start); err != nil {
+			return nil, s.row + 1, fmt.Errorf("failed to read XLSX row: %w", err)
+		}
+
+		// Sheets leave out rows with nothing in them, so count from the
+		// row's own number when it has one
+		s.row++
+		if n, err := strconv.Atoi(row.Ref); err == nil {
+			s.row = n
+		}
+
+		record, err := s.record(row.Cells)
+		if err != nil {
+			return nil, s.row, err
+		}
+		if isEmptyRecord(record) {
+			continue
+		}
+		return record, s.row, nil
+	}
+}
+
+// isEmptyRecord reports whether every value in a record is blank
+func isEmptyRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sheetReader) Close() error {
+	return s.file.Close()
+}
+
+// record places cells at their column positions; empty cells are omitted
+// from the sheet XML, so positions come from each cell's reference
+func (s *sheetReader) record(cells []xlsxCell) ([]string, error) {
+	var record []string
+	next := 0
+	for _, cell := range cells {
+		col := next
+		if cell.Ref != "" {
+			var err error
+			if col, err = columnIndex(cell.Ref); err != nil {
+				return nil, err
+			}
+		}
+		if col >= xlsxMaxColumns {
+			return nil, fmt.Errorf("row has more than %d cells", xlsxMaxColumns)
+		}
+		for len(record) <= col {
+			record = append(record, "")
+		}
+		value, err := s.cellValue(cell)
+		if err != nil {
+			return nil, fmt.Errorf("cell %s: %w", cell.Ref, err)
+		}
+		record[col] = value
+		next = col + 1
+	}
+	return record, nil
+}
+
+// cellValue converts a cell to the text the CSV export would have contained
+func (s *sheetReader) cellValue(cell xlsxCell) (string, error) {
+	switch cell.Type {
+	case "s":
+		idx, err := strconv.Atoi(cell.Value)
+		if err != nil || idx < 0 || idx >= len(s.wb.sharedStrings) {
+			return "", fmt.Errorf("invalid shared string index %q", cell.Value)
+		}
+		return s.wb.sharedStrings[idx], nil
+	case "inlineStr":
+		if len(cell.InlineStr.Runs) == 0 {
+			return cell.InlineStr.Text, nil
+		}
+		var b strings.Builder
+		for _, run := range cell.InlineStr.Runs {
+			b.WriteString(run.Text)
+		}
+		return b.String(), nil
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "d":
+		return formatISODate(cell.Value), nil
+	case "str", "e":
+		// Formula strings and errors (#N/A) pass through
+		return cell.Value, nil
+	}
+
+	// Numeric cell: either a plain number or a date serial
+	if cell.Value == "" {
+		return "", nil
+	}
+	f, err := strconv.ParseFloat(cell.Value, 64)
+	if err != nil {
+		return cell.Value, nil
+	}
+	if s.wb.dateStyles[cell.Style] {
+		return formatExcelDate(f, s.wb.date1904), nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// formatExcelDate converts an Excel date serial to a date (or date and
+// time) in a layout DateFormat always accepts
+func formatExcelDate(serial float64, date1904 bool) string {
+	// The 1900 system counts from 1899-12-30 to absorb Excel's fictitious
+	// 1900-02-29, which is correct for every date after February 1900
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// formatISODate rewrites an ISO 8601 date cell in the same layouts as
+// formatExcelDate; values it doesn't recognize pass through unchanged
+func formatISODate(value string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+				return t.Format("2006-01-02")
+			}
+			return t.Format("2006-01-02 15:04:05")
+		}
+	}
+	return value
+}
+
+// xlsxMaxColumns is the number of columns Excel allows, A through XFD
+const xlsxMaxColumns = 16384
+
+// columnIndex converts a cell reference like "AB12" to a 0-based column.
+// References past XFD are rejected, so a malformed sheet can't make record
+// allocate an arbitrarily long row.
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c >= 'A' && c <= 'Z' {
+			col = col*26 + int(c-'A'+1)
+		} else if c >= 'a' && c <= 'z' {
+			col = col*26 + int(c-'a'+1)
+		} else {
+			break
+		}
+		n++
+		if col > xlsxMaxColumns {
+			return 0, fmt.Errorf("cell reference %q is past the last column (XFD)", ref)
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWorkbook is the parts of an XLSX package, by path. newTestWorkbook
+// fills in a one-sheet workbook; tests replace or add parts before build.
+type testWorkbook map[string]string
+
+const testJobsHeader = `<row r="1">` +
+	`<c r="A1" t="inlineStr"><is><t>Job ID</t></is></c>` +
+	`<c r="B1" t="inlineStr"><is><t>Customer ID</t></is></c>` +
+	`<c r="C1" t="inlineStr"><is><t>Job Type</t></is></c>` +
+	`<c r="D1" t="inlineStr"><is><t>Status</t></is></c>` +
+	`<c r="E1" t="inlineStr"><is><t>Jobs Subtotal</t></is></c>` +
+	`<c r="F1" t="inlineStr"><is><t>Completion Date</t></is></c>` +
+	`<c r="G1" t="inlineStr"><is><t>Summary</t></is></c>` +
+	`</row>`
+
+func newTestWorkbook(rows ...string) testWorkbook {
+	return testWorkbook{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Jobs" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			testJobsHeader + strings.Join(rows, "") +
+			`</sheetData></worksheet>`,
+	}
+}
+
+// build zips the parts into an XLSX file
+func (wb testWorkbook) build(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range wb {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// parseTestWorkbook parses the workbook as a Jobs export, returning the
+// parsed rows and every raw row the parser saw
+func parseTestWorkbook(t *testing.T, wb testWorkbook) ([]JobRow, []RawRow) {
+	t.Helper()
+	var raw []RawRow
+	p := NewXLSXParser()
+	p.OnRawRow = func(row RawRow) error {
+		raw = append(raw, row)
+		return nil
+	}
+	jobs, err := ParseJobs(p, bytes.NewReader(wb.build(t)))
+	if err != nil {
+		t.Fatalf("ParseJobs: %v", err)
+	}
+	return jobs, raw
+}
+
+// inlineCell is a cell holding an inline string
+func inlineCell(ref, value string) string {
+	return fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, value)
+}
+
+// jobCells are the required cells of a Jobs row
+func jobCells(row int, jobID string) string {
+	return inlineCell(fmt.Sprintf("A%d", row), jobID) +
+		fmt.Sprintf(`<c r="B%d"><v>%d</v></c>`, row, 1000+row) +
+		inlineCell(fmt.Sprintf("C%d", row), "Service") +
+		inlineCell(fmt.Sprintf("D%d", row), "Completed")
+}
+
+func rawRowNumbers(raw []RawRow) []int {
+	rows := make([]int, len(raw))
+	for idx, row := range raw {
+		rows[idx] = row.Row
+	}
+	return rows
+}
+
+func TestXLSXSparseCells(t *testing.T) {
+	wb := newTestWorkbook(
+		// E and F are left out; G is placed by its reference
+		`<row r="2">`+jobCells(2, "J1")+inlineCell("G2", "Tune-up")+`</row>`,
+		// Rows 3 and 4 are left out of the sheet XML entirely
+		`<row r="5">`+jobCells(5, "J2")+`<c r="E5"><v>250.5</v></c></row>`,
+		// Cells without references follow the one before them
+		`<row r="6">`+inlineCell("A6", "J3")+`<c><v>1006</v></c>`+
+			`<c t="inlineStr"><is><t>Install</t></is></c><c t="inlineStr"><is><t>Hold</t></is></c></row>`,
+	)
+
+	jobs, raw := parseTestWorkbook(t, wb)
+
+	if got, want := rawRowNumbers(raw), []int{2, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("got %d jobs, want 3", len(jobs))
+	}
+	for idx, want := range []int{2, 5, 6} {
+		if jobs[idx].Row != want {
+			t.Errorf("job %s row = %d, want %d", jobs[idx].JobID, jobs[idx].Row, want)
+		}
+	}
+
+	if jobs[0].Summary == nil || *jobs[0].Summary != "Tune-up" {
+		t.Errorf("J1 summary = %v, want Tune-up", jobs[0].Summary)
+	}
+	if jobs[0].JobsSubtotal != nil {
+		t.Errorf("J1 subtotal = %v, want none", jobs[0].JobsSubtotal)
+	}
+	if got := raw[0].Record["Jobs Subtotal"]; got != "" {
+		t.Errorf("J1 raw subtotal = %q, want empty", got)
+	}
+	if jobs[1].JobsSubtotal == nil || jobs[1].JobsSubtotal.String() != "250.5" {
+		t.Errorf("J2 subtotal = %v, want 250.5", jobs[1].JobsSubtotal)
+	}
+	if jobs[2].CustomerID != 1006 || jobs[2].JobType != "Install" || jobs[2].Status != "Hold" {
+		t.Errorf("J3 = (%d, %s, %s), want (1006, Install, Hold)", jobs[2].CustomerID, jobs[2].JobType, jobs[2].Status)
+	}
+}
+
+func TestXLSXSharedStrings(t *testing.T) {
+	wb := newTestWorkbook(
+		`<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2"><v>1002</v></c>`+
+			`<c r="C2" t="s"><v>1</v></c><c r="D2" t="s"><v>2</v></c><c r="G2" t="s"><v>3</v></c></row>`,
+		`<row r="3"><c r="A3" t="s"><v>4</v></c><c r="B3"><v>1003</v></c>`+
+			`<c r="C3" t="s"><v>1</v></c><c r="D3" t="s"><v>2</v></c></row>`,
+	)
+	wb["xl/sharedStrings.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="5" uniqueCount="5">
+<si><t>J1</t></si>
+<si><t>Service</t></si>
+<si><t>Completed</t></si>
+<si><r><t>Replaced </t></r><r><rPr><b/></rPr><t>capacitor</t></r></si>
+<si><t>J2</t></si>
+</sst>`
+
+	jobs, raw := parseTestWorkbook(t, wb)
+
+	if got, want := rawRowNumbers(raw), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	if jobs[0].JobID != "J1" || jobs[0].JobType != "Service" || jobs[0].Status != "Completed" {
+		t.Errorf("row 2 = (%s, %s, %s), want (J1, Service, Completed)", jobs[0].JobID, jobs[0].JobType, jobs[0].Status)
+	}
+	if jobs[0].Summary == nil || *jobs[0].Summary != "Replaced capacitor" {
+		t.Errorf("rich text summary = %v, want the runs joined", jobs[0].Summary)
+	}
+	if jobs[1].JobID != "J2" {
+		t.Errorf("row 3 job = %s, want J2", jobs[1].JobID)
+	}
+}
+
+// testStyles has cell styles 0: General, 1: built-in date format 14 and
+// 2: a custom date format
+const testStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="[$-409]yyyy\-mm\-dd;@"/></numFmts>
+<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs>
+</styleSheet>`
+
+func TestXLSXDateStyledNumbers(t *testing.T) {
+	wb := newTestWorkbook(
+		// Built-in date style; a plain number in General style next to it
+		`<row r="2">`+jobCells(2, "J1")+`<c r="E2" s="0"><v>45672</v></c><c r="F2" s="1"><v>45672</v></c></row>`,
+		// Custom date style, with a time of day
+		`<row r="3">`+jobCells(3, "J2")+`<c r="F3" s="2"><v>45627.75</v></c></row>`,
+	)
+	wb["xl/styles.xml"] = testStyles
+
+	jobs, raw := parseTestWorkbook(t, wb)
+
+	if got, want := rawRowNumbers(raw), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if got := raw[0].Record["Jobs Subtotal"]; got != "45672" {
+		t.Errorf("General-styled number = %q, want 45672", got)
+	}
+	if got := raw[0].Record["Completion Date"]; got != "2025-01-15" {
+		t.Errorf("date-styled serial = %q, want 2025-01-15", got)
+	}
+	if got := raw[1].Record["Completion Date"]; got != "2024-12-01 18:00:00" {
+		t.Errorf("custom date-styled serial = %q, want 2024-12-01 18:00:00", got)
+	}
+
+	want := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if len(jobs) != 2 || jobs[0].JobCompletionDate == nil || !jobs[0].JobCompletionDate.Equal(want) {
+		t.Fatalf("J1 completion date = %v, want %v", jobs[0].JobCompletionDate, want)
+	}
+}
+
+func TestXLSX1904Epoch(t *testing.T) {
+	wb := newTestWorkbook(
+		`<row r="2">` + jobCells(2, "J1") + `<c r="F2" s="1"><v>44210</v></c></row>`,
+	)
+	wb["xl/styles.xml"] = testStyles
+	wb["xl/workbook.xml"] = strings.Replace(wb["xl/workbook.xml"], "<sheets>", `<workbookPr date1904="1"/><sheets>`, 1)
+
+	jobs, raw := parseTestWorkbook(t, wb)
+
+	if got, want := rawRowNumbers(raw), []int{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if got := raw[0].Record["Completion Date"]; got != "2025-01-15" {
+		t.Errorf("1904 serial = %q, want 2025-01-15", got)
+	}
+	want := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if len(jobs) != 1 || jobs[0].JobCompletionDate == nil || !jobs[0].JobCompletionDate.Equal(want) {
+		t.Fatalf("J1 completion date = %v, want %v", jobs[0].JobCompletionDate, want)
+	}
+}
+
+func TestXLSXSkipsEmptyRows(t *testing.T) {
+	wb := newTestWorkbook(
+		`<row r="2">`+jobCells(2, "J1")+`</row>`,
+		// Styled but empty, and whitespace only
+		`<row r="3"><c r="A3" s="1"/><c r="B3" s="1"/></row>`,
+		`<row r="4">`+inlineCell("A4", " ")+`</row>`,
+		`<row r="5">`+jobCells(5, "J2")+`</row>`,
+		// Trailing formatted rows, as many exports end with
+		`<row r="6"><c r="A6" s="1"/></row><row r="7"/>`,
+	)
+
+	jobs, raw := parseTestWorkbook(t, wb)
+
+	if got, want := rawRowNumbers(raw), []int{2, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if len(jobs) != 2 || jobs[0].JobID != "J1" || jobs[1].JobID != "J2" || jobs[1].Row != 5 {
+		t.Errorf("jobs = %+v, want J1 and J2 from rows 2 and 5", jobs)
+	}
+}
+
+func TestXLSXRejectedRowNumbers(t *testing.T) {
+	wb := newTestWorkbook(
+		`<row r="2">`+jobCells(2, "J1")+`</row>`,
+		`<row r="3"/>`,
+		// No Job Type
+		`<row r="4">`+inlineCell("A4", "J2")+`<c r="B4"><v>1004</v></c>`+inlineCell("D4", "Completed")+`</row>`,
+	)
+
+	var raw []RawRow
+	var rejected []RejectedRow
+	p := NewXLSXParser()
+	p.OnRawRow = func(row RawRow) error {
+		raw = append(raw, row)
+		return nil
+	}
+	p.OnRejectedRow = func(row RejectedRow) error {
+		rejected = append(rejected, row)
+		return nil
+	}
+	jobs, err := ParseJobs(p, bytes.NewReader(wb.build(t)))
+	if err != nil {
+		t.Fatalf("ParseJobs: %v", err)
+	}
+
+	if got, want := rawRowNumbers(raw), []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("raw rows = %v, want %v", got, want)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("got %d jobs, want 1", len(jobs))
+	}
+	if len(rejected) != 1 || rejected[0].Row != 4 || rejected[0].Key != "J2" {
+		t.Errorf("rejected = %+v, want J2 at row 4", rejected)
+	}
+}
+
+func TestXLSXRejectsColumnPastXFD(t *testing.T) {
+	for _, ref := range []string{"XFE2", "ZZZZZZZ2", strings.Repeat("Z", 40) + "2"} {
+		wb := newTestWorkbook(`<row r="2">` + jobCells(2, "J1") + inlineCell(ref, "x") + `</row>`)
+
+		_, err := ParseJobs(NewXLSXParser(), bytes.NewReader(wb.build(t)))
+		if err == nil || !strings.Contains(err.Error(), "past the last column") {
+			t.Errorf("%s: err = %v, want a reference past XFD error", ref, err)
+		}
+	}
+
+	// XFD itself is the last valid column
+	if col, err := columnIndex("XFD1"); err != nil || col != xlsxMaxColumns-1 {
+		t.Errorf("columnIndex(XFD1) = %d, %v; want %d", col, err, xlsxMaxColumns-1)
+	}
+}
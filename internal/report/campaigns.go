@@ -0,0 +1,151 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CampaignReturn holds what a campaign's marketing spend bought. Fields are
+// nil when there is no spend (or nothing to divide it by).
+type CampaignReturn struct {
+	CostPerJob      *float64
+	CostPerCustomer *float64 // Spend per customer whose first job came from the campaign
+	ROIPct          *float64 // (profit - spend) / spend, as a percentage
+	ROAS            *float64 // Revenue per dollar of spend
+}
+
+// CalculateCampaignReturn works out cost per job, cost per acquired customer,
+// ROI and ROAS from a campaign's totals
+func CalculateCampaignReturn(spend, revenue, profit float64, jobs, newCustomers int) CampaignReturn {
+	var r CampaignReturn
+	if spend <= 0 {
+		return r
+	}
+
+	if jobs > 0 {
+		costPerJob := spend / float64(jobs)
+		r.CostPerJob = &costPerJob
+	}
+	if newCustomers > 0 {
+		costPerCustomer := spend / float64(newCustomers)
+		r.CostPerCustomer = &costPerCustomer
+	}
+	roiPct := (profit - spend) / spend * 100
+	r.ROIPct = &roiPct
+	roas := revenue / spend
+	r.ROAS = &roas
+
+	return r
+}
+
+// buildSpendMonthClause limits campaign_spend (aliased s) to the months the
+// date range touches. It reuses the placeholders buildDateClause assigns for
+// the same dates and offset, so both clauses share one argument list.
+func buildSpendMonthClause(fromDate, toDate *time.Time, argOffset int) string {
+	var clause string
+
+	if fromDate != nil {
+		argOffset++
+		clause += fmt.Sprintf(" AND s.month >= date_trunc('month', $%d::date)", argOffset)
+	}
+
+	if toDate != nil {
+		argOffset++
+		clause += fmt.Sprintf(" AND s.month <= $%d", argOffset)
+	}
+
+	return clause
+}
+
+// LoadCampaigns returns profit and marketing return per campaign for jobs
+// completed in the date range, with spend from every month the range touches
+func LoadCampaigns(ctx context.Context, db *sql.DB, cols ProfitColumns, fromDate, toDate *time.Time) ([]CampaignStats, error) {
+	dateClause, dateArgs := buildDateClause(fromDate, toDate, 0)
+	spendClause := buildSpendMonthClause(fromDate, toDate, 0)
+
+	// Jobs store the campaign ID; names and categories come from campaigns.
+	// Spend may name a campaign by ID or name and is joined per campaign, so
+	// campaigns with spend but no completed jobs in the range still show up
+	query := `
+		WITH job_stats AS (
+			SELECT 
+				j.campaign_name,
+				MAX(j.campaign_category) as campaign_category,
+				COUNT(*) as job_count,
+				SUM(m.revenue) as total_revenue,
+				AVG(m.revenue) as avg_revenue,
+				AVG(` + cols.Profit + `) as avg_gross_profit,
+				AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL) as avg_margin_pct,
+				SUM(` + cols.Profit + `) as total_profit,
+				COUNT(DISTINCT j.customer_id) FILTER (WHERE j.job_completion_date = c.first_job_date) as new_customers
+			FROM jobs j
+			JOIN job_metrics m ON j.id = m.job_id
+			JOIN customers c ON j.customer_id = c.id
+			WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
+			GROUP BY j.campaign_name
+		),
+		spend AS (
+			SELECT COALESCE(cp.id, s.campaign) as campaign_id, SUM(s.amount) as spend
+			FROM campaign_spend s
+			LEFT JOIN LATERAL (
+				SELECT id FROM campaigns
+				WHERE id = s.campaign OR lower(name) = lower(s.campaign)
+				ORDER BY id = s.campaign DESC
+				LIMIT 1
+			) cp ON TRUE
+			WHERE TRUE` + spendClause + `
+			GROUP BY COALESCE(cp.id, s.campaign)
+		)
+		SELECT 
+			COALESCE(cp.name, js.campaign_name, sp.campaign_id, 'Unknown') as campaign_name,
+			COALESCE(cp.category, js.campaign_category, 'Uncategorized') as campaign_category,
+			COALESCE(js.job_count, 0) as job_count,
+			COALESCE(js.total_revenue, 0)::numeric(12,2) as total_revenue,
+			COALESCE(js.avg_revenue, 0)::numeric(12,2) as avg_revenue,
+			COALESCE(js.avg_gross_profit, 0)::numeric(12,2) as avg_gross_profit,
+			js.avg_margin_pct::numeric(8,2) as avg_margin_pct,
+			COALESCE(js.total_profit, 0)::numeric(12,2) as total_profit,
+			COALESCE(js.new_customers, 0) as new_customers,
+			COALESCE(sp.spend, 0)::numeric(12,2) as spend
+		FROM job_stats js
+		FULL OUTER JOIN spend sp ON sp.campaign_id = js.campaign_name
+		LEFT JOIN campaigns cp ON cp.id = COALESCE(js.campaign_name, sp.campaign_id)
+		ORDER BY total_profit DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, dateArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []CampaignStats
+	for rows.Next() {
+		var r CampaignStats
+		var marginPct sql.NullFloat64
+		err := rows.Scan(
+			&r.CampaignName,
+			&r.CampaignCategory,
+			&r.JobCount,
+			&r.TotalRevenue,
+			&r.AvgRevenue,
+			&r.AvgProfit,
+			&marginPct,
+			&r.TotalProfit,
+			&r.NewCustomers,
+			&r.Spend,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if marginPct.Valid {
+			r.AvgMarginPct = &marginPct.Float64
+		}
+		r.CampaignReturn = CalculateCampaignReturn(r.Spend, r.TotalRevenue, r.TotalProfit, r.JobCount, r.NewCustomers)
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
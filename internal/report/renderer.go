@@ -20,14 +20,17 @@ type Renderer struct {
 // NewRenderer creates a new template renderer
 func NewRenderer() (*Renderer, error) {
 	funcMap := template.FuncMap{
-		"formatMoney":   formatMoney,
-		"formatPercent": formatPercent,
-		"formatDate":    formatDate,
-		"truncate":      truncate,
-		"abs":           math.Abs,
-		"isNegative":    func(f float64) bool { return f < 0 },
-		"add":           func(a, b int) int { return a + b },
-		"mul":           func(a, b float64) float64 { return a * b },
+		"formatMoney":         formatMoney,
+		"formatPercent":       formatPercent,
+		"formatOptionalMoney": formatOptionalMoney,
+		"formatRatio":         formatRatio,
+		"deref":               func(f *float64) float64 { return *f },
+		"formatDate":          formatDate,
+		"truncate":            truncate,
+		"abs":                 math.Abs,
+		"isNegative":          func(f float64) bool { return f < 0 },
+		"add":                 func(a, b int) int { return a + b },
+		"mul":                 func(a, b float64) float64 { return a * b },
 		"div": func(a, b float64) float64 {
 			if b == 0 {
 				return 0
@@ -128,6 +131,22 @@ func formatMoney(amount float64) string {
 	return formatted
 }
 
+// formatOptionalMoney formats a float pointer as currency, or N/A when nil
+func formatOptionalMoney(amount *float64) string {
+	if amount == nil {
+		return "N/A"
+	}
+	return formatMoney(*amount)
+}
+
+// formatRatio formats a float pointer as a multiple, e.g. 3.2x
+func formatRatio(ratio *float64) string {
+	if ratio == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1fx", *ratio)
+}
+
 // formatPercent formats a float as percentage
 func formatPercent(pct *float64) string {
 	if pct == nil {
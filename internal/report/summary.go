@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/datsun80zx/sta.git/internal/metrics"
 )
 
 // SummaryReport contains all data for the summary report
@@ -12,6 +16,7 @@ type SummaryReport struct {
 	GeneratedAt time.Time
 	FromDate    *time.Time
 	ToDate      *time.Time
+	AfterLabor  bool // Costs and profit include timesheet labor
 
 	// Executive Summary
 	TotalJobs    int
@@ -22,11 +27,23 @@ type SummaryReport struct {
 	JobsWithLoss int
 	TotalLoss    float64
 
+	// Timesheet labor (included in TotalCosts when AfterLabor is set)
+	LaborCosts       float64
+	JobsWithoutLabor int // Jobs with no timesheet entries
+
+	// Jobs whose metrics are flagged unreliable, left out of every figure
+	// when ExcludeUnreliable is set
+	ExcludeUnreliable bool
+	UnreliableJobs    int
+	UnreliableReasons []QualityReason
+
 	// Breakdowns
-	JobTypes     []JobTypeStats
-	Campaigns    []CampaignStats
-	TopCustomers []CustomerStats
-	RedFlagJobs  []RedFlagJob
+	JobTypes      []JobTypeStats
+	BusinessUnits []BusinessUnitStats
+	Campaigns     []CampaignStats
+	CampaignSpend float64 // Total spend across Campaigns; zero hides the spend columns
+	TopCustomers  []CustomerStats
+	RedFlagJobs   []RedFlagJob
 }
 
 // JobTypeStats represents profitability stats for a job type
@@ -40,15 +57,30 @@ type JobTypeStats struct {
 	TotalProfit  float64
 }
 
-// CampaignStats represents profitability stats for a campaign
+// BusinessUnitStats represents the P&L of a business unit
+type BusinessUnitStats struct {
+	BusinessUnit string
+	JobCount     int
+	Revenue      float64
+	Costs        float64
+	GrossProfit  float64
+	MarginPct    *float64 // Gross profit over revenue, not an average of job margins
+	AvgTicket    float64
+}
+
+// CampaignStats represents profitability and spend stats for a campaign
 type CampaignStats struct {
 	CampaignName     string
 	CampaignCategory string
 	JobCount         int
+	TotalRevenue     float64
 	AvgRevenue       float64
 	AvgProfit        float64
 	AvgMarginPct     *float64
 	TotalProfit      float64
+	NewCustomers     int     // Customers whose first job came from the campaign
+	Spend            float64 // Marketing spend in the months the date range touches
+	CampaignReturn
 }
 
 // CustomerStats represents profitability stats for a customer
@@ -62,6 +94,22 @@ type CustomerStats struct {
 	TotalProfit  float64
 }
 
+// QualityReason counts the jobs carrying one unreliable data quality flag.
+// A job can carry several, so the counts may add up to more than the jobs.
+type QualityReason struct {
+	Flag     string
+	Label    string
+	JobCount int
+}
+
+// QualityFlagLabels describes each job metric quality flag for people
+var QualityFlagLabels = map[string]string{
+	metrics.FlagNoCosts:              "No costs recorded",
+	metrics.FlagAdjustmentUsed:       "Adjustment invoice used",
+	metrics.FlagInvoiceTotalMismatch: "Invoice totals don't match revenue",
+	metrics.FlagHoursMissing:         "Hours missing",
+}
+
 // RedFlagJob represents a job with negative margin
 type RedFlagJob struct {
 	JobID          string
@@ -73,38 +121,96 @@ type RedFlagJob struct {
 	CompletionDate *time.Time
 }
 
-// GenerateSummary builds the complete summary report
-func GenerateSummary(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) (*SummaryReport, error) {
+// ProfitColumns holds the job_metrics expressions (aliased m) that reports
+// use for costs, profit and margin
+type ProfitColumns struct {
+	Costs      string
+	Profit     string
+	Margin     string
+	AfterLabor bool
+	Filter     string // Extra condition on m, e.g. from UnreliableFilter
+}
+
+// UnreliableFilter returns the condition that leaves out jobs whose metrics
+// are flagged unreliable, or "" to keep them. Jobs without metrics are kept,
+// so it also works with job_metrics left-joined.
+func UnreliableFilter(exclude bool) string {
+	if exclude {
+		return " AND m.reliable IS NOT FALSE"
+	}
+	return ""
+}
+
+// ProfitColumnsFor returns invoice-only profit columns, or the after-labor
+// ones that also subtract timesheet labor
+func ProfitColumnsFor(afterLabor bool) ProfitColumns {
+	if afterLabor {
+		return ProfitColumns{
+			Costs:      "(m.total_costs + m.labor_costs)",
+			Profit:     "m.gross_profit_after_labor",
+			Margin:     "m.margin_after_labor_pct",
+			AfterLabor: true,
+		}
+	}
+	return ProfitColumns{
+		Costs:  "m.total_costs",
+		Profit: "m.gross_profit",
+		Margin: "m.gross_margin_pct",
+	}
+}
+
+// GenerateSummary builds the complete summary report. With afterLabor set,
+// costs and profit include labor from imported timesheets. With
+// excludeUnreliable set, jobs whose metrics are flagged unreliable are left
+// out.
+func GenerateSummary(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, afterLabor, excludeUnreliable bool) (*SummaryReport, error) {
 	report := &SummaryReport{
-		GeneratedAt: time.Now(),
-		FromDate:    fromDate,
-		ToDate:      toDate,
+		GeneratedAt:       time.Now(),
+		FromDate:          fromDate,
+		ToDate:            toDate,
+		AfterLabor:        afterLabor,
+		ExcludeUnreliable: excludeUnreliable,
 	}
+	cols := ProfitColumnsFor(afterLabor)
+	cols.Filter = UnreliableFilter(excludeUnreliable)
 
 	var err error
 
+	// Get unreliable job counts, whether or not they're excluded
+	if err = loadUnreliableJobs(ctx, db, report, fromDate, toDate); err != nil {
+		return nil, fmt.Errorf("loading unreliable jobs: %w", err)
+	}
+
 	// Get executive summary stats
-	if err = loadExecutiveSummary(ctx, db, report, fromDate, toDate); err != nil {
+	if err = loadExecutiveSummary(ctx, db, report, cols, fromDate, toDate); err != nil {
 		return nil, fmt.Errorf("loading executive summary: %w", err)
 	}
 
 	// Get job type breakdown
-	if report.JobTypes, err = loadJobTypes(ctx, db, fromDate, toDate); err != nil {
+	if report.JobTypes, err = loadJobTypes(ctx, db, cols, fromDate, toDate); err != nil {
 		return nil, fmt.Errorf("loading job types: %w", err)
 	}
 
+	// Get business unit P&L
+	if report.BusinessUnits, err = loadBusinessUnits(ctx, db, cols, fromDate, toDate); err != nil {
+		return nil, fmt.Errorf("loading business units: %w", err)
+	}
+
 	// Get campaign breakdown
-	if report.Campaigns, err = loadCampaigns(ctx, db, fromDate, toDate); err != nil {
+	if report.Campaigns, err = LoadCampaigns(ctx, db, cols, fromDate, toDate); err != nil {
 		return nil, fmt.Errorf("loading campaigns: %w", err)
 	}
+	for _, c := range report.Campaigns {
+		report.CampaignSpend += c.Spend
+	}
 
 	// Get top customers
-	if report.TopCustomers, err = loadTopCustomers(ctx, db, fromDate, toDate, 10); err != nil {
+	if report.TopCustomers, err = loadTopCustomers(ctx, db, cols, fromDate, toDate, 10); err != nil {
 		return nil, fmt.Errorf("loading top customers: %w", err)
 	}
 
 	// Get red flag jobs
-	if report.RedFlagJobs, err = loadRedFlagJobs(ctx, db, fromDate, toDate); err != nil {
+	if report.RedFlagJobs, err = loadRedFlagJobs(ctx, db, cols, fromDate, toDate); err != nil {
 		return nil, fmt.Errorf("loading red flag jobs: %w", err)
 	}
 
@@ -130,21 +236,73 @@ func buildDateClause(fromDate, toDate *time.Time, argOffset int) (string, []inte
 	return clause, args
 }
 
-func loadExecutiveSummary(ctx context.Context, db *sql.DB, report *SummaryReport, fromDate, toDate *time.Time) error {
+func loadUnreliableJobs(ctx context.Context, db *sql.DB, report *SummaryReport, fromDate, toDate *time.Time) error {
+	dateClause, dateArgs := buildDateClause(fromDate, toDate, 1) // offset by 1 for the flags
+
+	query := `
+		SELECT f.flag, COUNT(*) as job_count
+		FROM jobs j
+		JOIN job_metrics m ON j.id = m.job_id
+		CROSS JOIN LATERAL unnest(m.quality_flags) f(flag)
+		WHERE j.status = 'Completed'
+		  AND NOT m.reliable
+		  AND f.flag = ANY($1)` + dateClause + `
+		GROUP BY f.flag
+		ORDER BY job_count DESC
+	`
+
+	queryArgs := []interface{}{pq.Array(metrics.UnreliableFlags)}
+	queryArgs = append(queryArgs, dateArgs...)
+
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r QualityReason
+		if err := rows.Scan(&r.Flag, &r.JobCount); err != nil {
+			return err
+		}
+		r.Label = QualityFlagLabels[r.Flag]
+		if r.Label == "" {
+			r.Label = r.Flag
+		}
+		report.UnreliableReasons = append(report.UnreliableReasons, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dateClause, dateArgs = buildDateClause(fromDate, toDate, 0)
+	query = `
+		SELECT COUNT(*)
+		FROM jobs j
+		JOIN job_metrics m ON j.id = m.job_id
+		WHERE j.status = 'Completed'
+		  AND NOT m.reliable` + dateClause
+
+	return db.QueryRowContext(ctx, query, dateArgs...).Scan(&report.UnreliableJobs)
+}
+
+func loadExecutiveSummary(ctx context.Context, db *sql.DB, report *SummaryReport, cols ProfitColumns, fromDate, toDate *time.Time) error {
 	dateClause, dateArgs := buildDateClause(fromDate, toDate, 0)
 
 	query := `
 		SELECT 
 			COUNT(*) as total_jobs,
 			COALESCE(SUM(m.revenue), 0) as total_revenue,
-			COALESCE(SUM(m.total_costs), 0) as total_costs,
-			COALESCE(SUM(m.gross_profit), 0) as total_profit,
-			COALESCE(AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL), 0) as avg_margin_pct,
-			COUNT(*) FILTER (WHERE m.gross_profit < 0) as jobs_with_loss,
-			COALESCE(SUM(m.gross_profit) FILTER (WHERE m.gross_profit < 0), 0) as total_loss
+			COALESCE(SUM(` + cols.Costs + `), 0) as total_costs,
+			COALESCE(SUM(` + cols.Profit + `), 0) as total_profit,
+			COALESCE(AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL), 0) as avg_margin_pct,
+			COUNT(*) FILTER (WHERE ` + cols.Profit + ` < 0) as jobs_with_loss,
+			COALESCE(SUM(` + cols.Profit + `) FILTER (WHERE ` + cols.Profit + ` < 0), 0) as total_loss,
+			COALESCE(SUM(m.labor_costs), 0) as labor_costs,
+			COUNT(*) FILTER (WHERE NOT m.has_labor) as jobs_without_labor
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause
 
 	row := db.QueryRowContext(ctx, query, dateArgs...)
 	return row.Scan(
@@ -155,10 +313,12 @@ func loadExecutiveSummary(ctx context.Context, db *sql.DB, report *SummaryReport
 		&report.AvgMarginPct,
 		&report.JobsWithLoss,
 		&report.TotalLoss,
+		&report.LaborCosts,
+		&report.JobsWithoutLabor,
 	)
 }
 
-func loadJobTypes(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) ([]JobTypeStats, error) {
+func loadJobTypes(ctx context.Context, db *sql.DB, cols ProfitColumns, fromDate, toDate *time.Time) ([]JobTypeStats, error) {
 	dateClause, dateArgs := buildDateClause(fromDate, toDate, 0)
 
 	query := `
@@ -166,13 +326,13 @@ func loadJobTypes(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time)
 			j.job_type,
 			COUNT(*) as job_count,
 			AVG(m.revenue)::numeric(12,2) as avg_revenue,
-			AVG(m.total_costs)::numeric(12,2) as avg_costs,
-			AVG(m.gross_profit)::numeric(12,2) as avg_gross_profit,
-			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit
+			AVG(` + cols.Costs + `)::numeric(12,2) as avg_costs,
+			AVG(` + cols.Profit + `)::numeric(12,2) as avg_gross_profit,
+			AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL)::numeric(8,2) as avg_margin_pct,
+			SUM(` + cols.Profit + `)::numeric(12,2) as total_profit
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
 		GROUP BY j.job_type
 		ORDER BY total_profit DESC
 	`
@@ -208,23 +368,26 @@ func loadJobTypes(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time)
 	return results, rows.Err()
 }
 
-func loadCampaigns(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) ([]CampaignStats, error) {
+func loadBusinessUnits(ctx context.Context, db *sql.DB, cols ProfitColumns, fromDate, toDate *time.Time) ([]BusinessUnitStats, error) {
 	dateClause, dateArgs := buildDateClause(fromDate, toDate, 0)
 
+	// Jobs imported before business unit IDs were stored only have the name,
+	// so those are grouped by name instead
 	query := `
 		SELECT 
-			COALESCE(j.campaign_name, 'Unknown') as campaign_name,
-			COALESCE(j.campaign_category, 'Uncategorized') as campaign_category,
+			COALESCE(bu.name, MAX(j.business_unit), 'Unassigned') as business_unit,
 			COUNT(*) as job_count,
-			AVG(m.revenue)::numeric(12,2) as avg_revenue,
-			AVG(m.gross_profit)::numeric(12,2) as avg_gross_profit,
-			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit
+			SUM(m.revenue)::numeric(12,2) as revenue,
+			SUM(` + cols.Costs + `)::numeric(12,2) as costs,
+			SUM(` + cols.Profit + `)::numeric(12,2) as gross_profit,
+			(SUM(` + cols.Profit + `) / NULLIF(SUM(m.revenue), 0) * 100)::numeric(8,2) as margin_pct,
+			AVG(m.revenue)::numeric(12,2) as avg_ticket
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
-		GROUP BY j.campaign_name, j.campaign_category
-		ORDER BY total_profit DESC
+		LEFT JOIN business_units bu ON bu.id = j.business_unit_id
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
+		GROUP BY j.business_unit_id, bu.name, CASE WHEN j.business_unit_id IS NULL THEN j.business_unit END
+		ORDER BY gross_profit DESC
 	`
 
 	rows, err := db.QueryContext(ctx, query, dateArgs...)
@@ -233,24 +396,24 @@ func loadCampaigns(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time)
 	}
 	defer rows.Close()
 
-	var results []CampaignStats
+	var results []BusinessUnitStats
 	for rows.Next() {
-		var r CampaignStats
+		var r BusinessUnitStats
 		var marginPct sql.NullFloat64
 		err := rows.Scan(
-			&r.CampaignName,
-			&r.CampaignCategory,
+			&r.BusinessUnit,
 			&r.JobCount,
-			&r.AvgRevenue,
-			&r.AvgProfit,
+			&r.Revenue,
+			&r.Costs,
+			&r.GrossProfit,
 			&marginPct,
-			&r.TotalProfit,
+			&r.AvgTicket,
 		)
 		if err != nil {
 			return nil, err
 		}
 		if marginPct.Valid {
-			r.AvgMarginPct = &marginPct.Float64
+			r.MarginPct = &marginPct.Float64
 		}
 		results = append(results, r)
 	}
@@ -258,7 +421,7 @@ func loadCampaigns(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time)
 	return results, rows.Err()
 }
 
-func loadTopCustomers(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, limit int) ([]CustomerStats, error) {
+func loadTopCustomers(ctx context.Context, db *sql.DB, cols ProfitColumns, fromDate, toDate *time.Time, limit int) ([]CustomerStats, error) {
 	dateClause, dateArgs := buildDateClause(fromDate, toDate, 1) // offset by 1 for LIMIT
 
 	query := `
@@ -267,13 +430,13 @@ func loadTopCustomers(ctx context.Context, db *sql.DB, fromDate, toDate *time.Ti
 			c.customer_name,
 			COALESCE(c.customer_type, 'Unknown') as customer_type,
 			COUNT(j.id) as job_count,
-			AVG(m.gross_profit)::numeric(12,2) as avg_profit_per_job,
-			AVG(m.gross_margin_pct) FILTER (WHERE m.gross_margin_pct IS NOT NULL)::numeric(8,2) as avg_margin_pct,
-			SUM(m.gross_profit)::numeric(12,2) as total_profit
+			AVG(` + cols.Profit + `)::numeric(12,2) as avg_profit_per_job,
+			AVG(` + cols.Margin + `) FILTER (WHERE ` + cols.Margin + ` IS NOT NULL)::numeric(8,2) as avg_margin_pct,
+			SUM(` + cols.Profit + `)::numeric(12,2) as total_profit
 		FROM customers c
 		JOIN jobs j ON c.id = j.customer_id
 		JOIN job_metrics m ON j.id = m.job_id
-		WHERE j.status = 'Completed'` + dateClause + `
+		WHERE j.status = 'Completed'` + cols.Filter + dateClause + `
 		GROUP BY c.id, c.customer_name, c.customer_type
 		ORDER BY total_profit DESC
 		LIMIT $1
@@ -313,7 +476,7 @@ func loadTopCustomers(ctx context.Context, db *sql.DB, fromDate, toDate *time.Ti
 	return results, rows.Err()
 }
 
-func loadRedFlagJobs(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) ([]RedFlagJob, error) {
+func loadRedFlagJobs(ctx context.Context, db *sql.DB, cols ProfitColumns, fromDate, toDate *time.Time) ([]RedFlagJob, error) {
 	dateClause, dateArgs := buildDateClause(fromDate, toDate, 0)
 
 	query := `
@@ -322,15 +485,15 @@ func loadRedFlagJobs(ctx context.Context, db *sql.DB, fromDate, toDate *time.Tim
 			c.customer_name,
 			j.job_type,
 			m.revenue,
-			m.total_costs,
-			m.gross_profit,
+			` + cols.Costs + `,
+			` + cols.Profit + `,
 			j.job_completion_date
 		FROM jobs j
 		JOIN job_metrics m ON j.id = m.job_id
 		JOIN customers c ON j.customer_id = c.id
 		WHERE j.status = 'Completed'
-		  AND m.gross_profit < 0` + dateClause + `
-		ORDER BY m.gross_profit ASC
+		  AND ` + cols.Profit + ` < 0` + cols.Filter + dateClause + `
+		ORDER BY ` + cols.Profit + ` ASC
 		LIMIT 20
 	`
 
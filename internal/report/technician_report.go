@@ -4,15 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 )
 
+// avgDaysPerMonth converts tenure in days to months
+const avgDaysPerMonth = 30.44
+
 // TechnicianReport contains all data for the technician performance report
 type TechnicianReport struct {
 	GeneratedAt time.Time
 	FromDate    *time.Time
 	ToDate      *time.Time
 
+	// Technicians terminated on or before today are left out
+	ExcludeTerminated bool
+
 	// Summary stats
 	TotalTechnicians   int
 	TotalJobsCompleted int
@@ -22,6 +29,11 @@ type TechnicianReport struct {
 	// Individual technician performance
 	Technicians []TechnicianPerformance
 
+	// Roll-ups from the technician roster; empty until one is imported
+	HasRoster bool
+	Teams     []TechnicianGroupStats
+	Managers  []TechnicianGroupStats
+
 	// Monthly trends (for charts/tables)
 	MonthlyTrends []MonthlyTechTrend
 }
@@ -42,10 +54,36 @@ type TechnicianPerformance struct {
 	AvgGrossProfit     float64
 	AvgMarginPct       float64
 
+	// From the technician roster
+	Team            string
+	Manager         string
+	HireDate        *time.Time
+	TerminationDate *time.Time
+
+	// Tenure-normalized results: months employed within the report period,
+	// and sales and profit per month of it. Nil without a hire date.
+	MonthsEmployed *float64
+	SalesPerMonth  *float64
+	ProfitPerMonth *float64
+
 	// Monthly breakdown for this technician
 	MonthlyData []TechMonthData
 }
 
+// TechnicianGroupStats rolls technician performance up by team or manager
+type TechnicianGroupStats struct {
+	Name             string // Team or manager; "(none)" for technicians without one
+	Technicians      int
+	TotalJobs        int
+	SoldJobs         int
+	ConversionRate   float64
+	TotalSales       float64
+	TotalGrossProfit float64
+
+	// Sales per month employed, over technicians with a hire date
+	SalesPerTechMonth *float64
+}
+
 // TechMonthData represents a technician's performance in a specific month
 type TechMonthData struct {
 	Month          string // "2024-11"
@@ -67,21 +105,41 @@ type MonthlyTechTrend struct {
 }
 
 // GenerateTechnicianReport builds the complete technician performance report
-func GenerateTechnicianReport(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) (*TechnicianReport, error) {
+func GenerateTechnicianReport(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, excludeTerminated bool) (*TechnicianReport, error) {
 	report := &TechnicianReport{
-		GeneratedAt: time.Now(),
-		FromDate:    fromDate,
-		ToDate:      toDate,
+		GeneratedAt:       time.Now(),
+		FromDate:          fromDate,
+		ToDate:            toDate,
+		ExcludeTerminated: excludeTerminated,
 	}
 
 	var err error
 
 	// Load technician performance
-	report.Technicians, err = loadTechnicianPerformance(ctx, db, fromDate, toDate)
+	report.Technicians, err = loadTechnicianPerformance(ctx, db, fromDate, toDate, excludeTerminated)
 	if err != nil {
 		return nil, fmt.Errorf("loading technician performance: %w", err)
 	}
 
+	// Normalize by tenure and roll up by team and manager
+	for i := range report.Technicians {
+		t := &report.Technicians[i]
+		t.MonthsEmployed = MonthsEmployed(t.HireDate, t.TerminationDate, fromDate, toDate, report.GeneratedAt)
+		if t.MonthsEmployed != nil {
+			salesPerMonth := t.TotalSales / *t.MonthsEmployed
+			profitPerMonth := t.TotalGrossProfit / *t.MonthsEmployed
+			t.SalesPerMonth = &salesPerMonth
+			t.ProfitPerMonth = &profitPerMonth
+		}
+		if t.Team != "" || t.Manager != "" || t.HireDate != nil {
+			report.HasRoster = true
+		}
+	}
+	if report.HasRoster {
+		report.Teams = RollupTechnicians(report.Technicians, func(t TechnicianPerformance) string { return t.Team })
+		report.Managers = RollupTechnicians(report.Technicians, func(t TechnicianPerformance) string { return t.Manager })
+	}
+
 	// Calculate summary stats
 	report.TotalTechnicians = len(report.Technicians)
 	totalConvRate := 0.0
@@ -99,7 +157,7 @@ func GenerateTechnicianReport(ctx context.Context, db *sql.DB, fromDate, toDate
 	}
 
 	// Load monthly trends
-	report.MonthlyTrends, err = loadMonthlyTrends(ctx, db, fromDate, toDate)
+	report.MonthlyTrends, err = loadMonthlyTrends(ctx, db, fromDate, toDate, excludeTerminated)
 	if err != nil {
 		return nil, fmt.Errorf("loading monthly trends: %w", err)
 	}
@@ -115,7 +173,7 @@ func GenerateTechnicianReport(ctx context.Context, db *sql.DB, fromDate, toDate
 	return report, nil
 }
 
-func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) ([]TechnicianPerformance, error) {
+func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, excludeTerminated bool) ([]TechnicianPerformance, error) {
 	dateClause, dateArgs := buildTechDateClause(fromDate, toDate, 0)
 
 	query := `
@@ -133,7 +191,8 @@ func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate
 			JOIN job_technicians jt ON t.id = jt.technician_id
 			JOIN jobs j ON jt.job_id = j.id
 			LEFT JOIN job_metrics jm ON j.id = jm.job_id
-			WHERE j.status = 'Completed'` + dateClause + `
+			WHERE j.status = 'Completed'
+			  AND t.active` + buildTerminatedClause(excludeTerminated) + dateClause + `
 		),
 		tech_primary AS (
 			SELECT 
@@ -181,10 +240,15 @@ func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate
 			COALESCE(p.estimate_sales, 0) + COALESCE(sv.same_visit_sales, 0) as total_sales,
 			COALESCE(p.total_hours, 0) as total_hours,
 			COALESCE(p.total_estimates, 0) as total_estimates,
-			COALESCE(s.total_profit, 0) as total_profit
+			COALESCE(s.total_profit, 0) as total_profit,
+			COALESCE(r.team, '') as team,
+			COALESCE(r.manager, '') as manager,
+			r.hire_date,
+			r.termination_date
 		FROM tech_primary p
 		FULL OUTER JOIN tech_sold s ON p.name = s.name
 		LEFT JOIN tech_same_visit sv ON COALESCE(p.name, s.name) = sv.name
+		JOIN technicians r ON r.name = COALESCE(p.name, s.name)
 		WHERE COALESCE(p.total_jobs, 0) > 0 OR COALESCE(s.sold_jobs, 0) > 0
 		ORDER BY total_sales DESC
 	`
@@ -200,6 +264,7 @@ func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate
 		var t TechnicianPerformance
 		var totalSales, totalHours, totalProfit sql.NullFloat64
 		var totalEstimates sql.NullInt64
+		var hireDate, terminationDate sql.NullTime
 
 		err := rows.Scan(
 			&t.Name,
@@ -209,11 +274,21 @@ func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate
 			&totalHours,
 			&totalEstimates,
 			&totalProfit,
+			&t.Team,
+			&t.Manager,
+			&hireDate,
+			&terminationDate,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if hireDate.Valid {
+			t.HireDate = &hireDate.Time
+		}
+		if terminationDate.Valid {
+			t.TerminationDate = &terminationDate.Time
+		}
 		if totalSales.Valid {
 			t.TotalSales = totalSales.Float64
 		}
@@ -247,7 +322,7 @@ func loadTechnicianPerformance(ctx context.Context, db *sql.DB, fromDate, toDate
 	return results, rows.Err()
 }
 
-func loadMonthlyTrends(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time) ([]MonthlyTechTrend, error) {
+func loadMonthlyTrends(ctx context.Context, db *sql.DB, fromDate, toDate *time.Time, excludeTerminated bool) ([]MonthlyTechTrend, error) {
 	dateClause, dateArgs := buildTechDateClause(fromDate, toDate, 0)
 
 	query := `
@@ -263,6 +338,7 @@ func loadMonthlyTrends(ctx context.Context, db *sql.DB, fromDate, toDate *time.T
 			JOIN job_technicians jt ON t.id = jt.technician_id
 			JOIN jobs j ON jt.job_id = j.id
 			WHERE j.status = 'Completed'
+			  AND t.active` + buildTerminatedClause(excludeTerminated) + `
 			  AND j.job_completion_date IS NOT NULL` + dateClause + `
 		),
 		monthly_primary AS (
@@ -462,6 +538,91 @@ func loadTechnicianMonthlyData(ctx context.Context, db *sql.DB, techName string,
 	return results, rows.Err()
 }
 
+// buildTerminatedClause leaves out technicians terminated on or before today
+func buildTerminatedClause(excludeTerminated bool) string {
+	if !excludeTerminated {
+		return ""
+	}
+	return `
+			  AND (t.termination_date IS NULL OR t.termination_date > CURRENT_DATE)`
+}
+
+// MonthsEmployed returns how many months a technician was employed within
+// the report period, which is open-ended when from or to is nil. Returns nil
+// without a hire date or when their employment doesn't overlap the period.
+func MonthsEmployed(hireDate, terminationDate, fromDate, toDate *time.Time, now time.Time) *float64 {
+	if hireDate == nil {
+		return nil
+	}
+
+	start := *hireDate
+	if fromDate != nil && fromDate.After(start) {
+		start = *fromDate
+	}
+	end := now
+	if terminationDate != nil && terminationDate.Before(end) {
+		end = *terminationDate
+	}
+	if toDate != nil && toDate.Before(end) {
+		end = *toDate
+	}
+	if !end.After(start) {
+		return nil
+	}
+
+	months := end.Sub(start).Hours() / 24 / avgDaysPerMonth
+	return &months
+}
+
+// RollupTechnicians groups technicians by the key (team or manager),
+// highest sales first
+func RollupTechnicians(techs []TechnicianPerformance, key func(TechnicianPerformance) string) []TechnicianGroupStats {
+	groups := make(map[string]*TechnicianGroupStats)
+	months := make(map[string]float64)
+	monthSales := make(map[string]float64)
+	var order []string
+
+	for _, t := range techs {
+		name := key(t)
+		if name == "" {
+			name = "(none)"
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &TechnicianGroupStats{Name: name}
+			groups[name] = g
+			order = append(order, name)
+		}
+		g.Technicians++
+		g.TotalJobs += t.TotalJobs
+		g.SoldJobs += t.SoldJobs
+		g.TotalSales += t.TotalSales
+		g.TotalGrossProfit += t.TotalGrossProfit
+		if t.MonthsEmployed != nil {
+			months[name] += *t.MonthsEmployed
+			monthSales[name] += t.TotalSales
+		}
+	}
+
+	results := make([]TechnicianGroupStats, 0, len(order))
+	for _, name := range order {
+		g := groups[name]
+		if g.TotalJobs > 0 {
+			g.ConversionRate = float64(g.SoldJobs) / float64(g.TotalJobs) * 100
+		}
+		if months[name] > 0 {
+			salesPerMonth := monthSales[name] / months[name]
+			g.SalesPerTechMonth = &salesPerMonth
+		}
+		results = append(results, *g)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].TotalSales > results[j].TotalSales
+	})
+
+	return results
+}
+
 func buildTechDateClause(fromDate, toDate *time.Time, argOffset int) (string, []interface{}) {
 	var clause string
 	var args []interface{}
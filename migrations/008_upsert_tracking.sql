@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Track which batch last inserted or changed each job/invoice
+-- import_batch_id keeps pointing at the batch that first created the row,
+-- last_import_batch_id moves forward every time an overlapping export changes it
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS last_import_batch_id BIGINT REFERENCES import_batches(id);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE jobs SET last_import_batch_id = import_batch_id WHERE last_import_batch_id IS NULL;
+ALTER TABLE jobs ALTER COLUMN last_import_batch_id SET NOT NULL;
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS last_import_batch_id BIGINT REFERENCES import_batches(id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE invoices SET last_import_batch_id = import_batch_id WHERE last_import_batch_id IS NULL;
+ALTER TABLE invoices ALTER COLUMN last_import_batch_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_last_import_batch_id ON jobs(last_import_batch_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_last_import_batch_id ON invoices(last_import_batch_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_invoices_last_import_batch_id;
+DROP INDEX IF EXISTS idx_jobs_last_import_batch_id;
+ALTER TABLE invoices DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE invoices DROP COLUMN IF EXISTS last_import_batch_id;
+ALTER TABLE jobs DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE jobs DROP COLUMN IF EXISTS last_import_batch_id;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Quarantine for rows that failed to parse during a lenient import
+-- The raw row is kept so it can be inspected and re-ingested once fixed
+CREATE TABLE rejected_rows (
+    id BIGSERIAL PRIMARY KEY,
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    report_type TEXT NOT NULL CHECK (report_type IN ('jobs', 'invoices')),
+    row_number INTEGER NOT NULL,
+    column_name TEXT NOT NULL,
+    raw_value TEXT NOT NULL,
+    error_message TEXT NOT NULL,
+    record_key TEXT, -- Job ID or Invoice # from the raw row, used to match fixed rows
+    raw_record JSONB NOT NULL,
+    resolved_at TIMESTAMPTZ, -- Set once a fixed version of the row is re-ingested
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rejected_rows_batch ON rejected_rows(import_batch_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS rejected_rows;
+
+-- +goose StatementEnd
\ No newline at end of file
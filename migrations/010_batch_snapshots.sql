@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Previous versions of jobs and invoices that an import batch overwrote
+-- Deleting the batch rolls those rows back instead of removing them
+CREATE TABLE batch_snapshots (
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    record_type TEXT NOT NULL CHECK (record_type IN ('jobs', 'invoices')),
+    record_id TEXT NOT NULL, -- Job ID or Invoice #
+    previous JSONB NOT NULL, -- The row as it was before the batch changed it
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (import_batch_id, record_type, record_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS batch_snapshots;
+
+-- +goose StatementEnd
\ No newline at end of file
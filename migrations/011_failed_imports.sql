@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Failed imports are recorded outside the import transaction, so keep where
+-- they failed alongside the error message
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS failed_step TEXT;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS failed_row INTEGER;
+
+-- A failed attempt must not stop the same files from being imported again
+DROP INDEX IF EXISTS idx_import_batches_hashes;
+CREATE UNIQUE INDEX idx_import_batches_hashes
+    ON import_batches(job_report_hash, invoice_report_hash)
+    WHERE status <> 'failed';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM import_batches WHERE status = 'failed';
+DROP INDEX IF EXISTS idx_import_batches_hashes;
+CREATE UNIQUE INDEX idx_import_batches_hashes
+    ON import_batches(job_report_hash, invoice_report_hash);
+ALTER TABLE import_batches DROP COLUMN IF EXISTS failed_row;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS failed_step;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Job report fields that were parsed but never stored
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS location_id BIGINT; -- ServiceTitan Location ID
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS business_unit_id BIGINT; -- ServiceTitan Business Unit ID
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dispatched_by TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS summary TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS member_status TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS tags TEXT; -- Comma-separated, as exported
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS is_warranty BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS is_recall BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS is_zero_dollar BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_member_status ON jobs(member_status);
+CREATE INDEX IF NOT EXISTS idx_jobs_warranty ON jobs(is_warranty) WHERE is_warranty = true;
+CREATE INDEX IF NOT EXISTS idx_jobs_recall ON jobs(is_recall) WHERE is_recall = true;
+
+-- Invoice report fields that were parsed but never stored
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS customer_id BIGINT; -- As exported, not checked against customers
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS location_id BIGINT;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS project_number BIGINT;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS business_unit_id BIGINT; -- Invoice Business Unit ID
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS payment_types TEXT; -- Comma-separated, as exported
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS payment_term TEXT;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS pricebook_price NUMERIC(12, 2);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS is_dispatch_service_fee_only BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS is_prevailing_wage BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS is_prevailing_wage;
+ALTER TABLE invoices DROP COLUMN IF EXISTS is_dispatch_service_fee_only;
+ALTER TABLE invoices DROP COLUMN IF EXISTS pricebook_price;
+ALTER TABLE invoices DROP COLUMN IF EXISTS payment_term;
+ALTER TABLE invoices DROP COLUMN IF EXISTS payment_types;
+ALTER TABLE invoices DROP COLUMN IF EXISTS business_unit_id;
+ALTER TABLE invoices DROP COLUMN IF EXISTS project_number;
+ALTER TABLE invoices DROP COLUMN IF EXISTS location_id;
+ALTER TABLE invoices DROP COLUMN IF EXISTS customer_id;
+
+DROP INDEX IF EXISTS idx_jobs_recall;
+DROP INDEX IF EXISTS idx_jobs_warranty;
+DROP INDEX IF EXISTS idx_jobs_member_status;
+ALTER TABLE jobs DROP COLUMN IF EXISTS is_zero_dollar;
+ALTER TABLE jobs DROP COLUMN IF EXISTS is_recall;
+ALTER TABLE jobs DROP COLUMN IF EXISTS is_warranty;
+ALTER TABLE jobs DROP COLUMN IF EXISTS tags;
+ALTER TABLE jobs DROP COLUMN IF EXISTS member_status;
+ALTER TABLE jobs DROP COLUMN IF EXISTS summary;
+ALTER TABLE jobs DROP COLUMN IF EXISTS dispatched_by;
+ALTER TABLE jobs DROP COLUMN IF EXISTS business_unit_id;
+ALTER TABLE jobs DROP COLUMN IF EXISTS location_id;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,85 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One row per estimate from the ServiceTitan Estimates report
+-- Gives technician metrics a real close rate instead of counting estimates per job
+CREATE TABLE estimates (
+    id TEXT PRIMARY KEY, -- Estimate ID
+    job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id),
+    last_import_batch_id BIGINT NOT NULL REFERENCES import_batches(id),
+
+    name TEXT,
+    status TEXT NOT NULL CHECK (status IN ('Open', 'Sold', 'Dismissed')),
+    technician TEXT, -- Raw name from the report
+    technician_id BIGINT REFERENCES technicians(id) ON DELETE SET NULL,
+    subtotal NUMERIC(12, 2),
+
+    created_on DATE,
+    sold_on DATE,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_estimates_job_id ON estimates(job_id);
+CREATE INDEX idx_estimates_technician_id ON estimates(technician_id);
+CREATE INDEX idx_estimates_last_import_batch ON estimates(last_import_batch_id);
+
+-- The Estimates report is optional, so its hash can be NULL
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS estimate_report_filename TEXT;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS estimate_report_hash TEXT;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS row_count_estimates INTEGER NOT NULL DEFAULT 0;
+
+DROP INDEX IF EXISTS idx_import_batches_hashes;
+CREATE UNIQUE INDEX idx_import_batches_hashes
+    ON import_batches(job_report_hash, invoice_report_hash, COALESCE(estimate_report_hash, ''))
+    WHERE status <> 'failed';
+
+ALTER TABLE batch_snapshots DROP CONSTRAINT IF EXISTS batch_snapshots_record_type_check;
+ALTER TABLE batch_snapshots ADD CONSTRAINT batch_snapshots_record_type_check
+    CHECK (record_type IN ('jobs', 'invoices', 'estimates'));
+
+ALTER TABLE rejected_rows DROP CONSTRAINT IF EXISTS rejected_rows_report_type_check;
+ALTER TABLE rejected_rows ADD CONSTRAINT rejected_rows_report_type_check
+    CHECK (report_type IN ('jobs', 'invoices', 'estimates'));
+
+-- Estimate metrics from the estimates table (technician is the estimate's technician)
+ALTER TABLE technician_metrics ADD COLUMN IF NOT EXISTS estimates_sold INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE technician_metrics ADD COLUMN IF NOT EXISTS estimates_dismissed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE technician_metrics ADD COLUMN IF NOT EXISTS close_rate NUMERIC(5, 2); -- sold / (sold + dismissed), as percentage
+ALTER TABLE technician_metrics ADD COLUMN IF NOT EXISTS avg_estimate_value NUMERIC(12, 2);
+ALTER TABLE technician_metrics ADD COLUMN IF NOT EXISTS avg_days_to_sold NUMERIC(8, 2);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE technician_metrics DROP COLUMN IF EXISTS avg_days_to_sold;
+ALTER TABLE technician_metrics DROP COLUMN IF EXISTS avg_estimate_value;
+ALTER TABLE technician_metrics DROP COLUMN IF EXISTS close_rate;
+ALTER TABLE technician_metrics DROP COLUMN IF EXISTS estimates_dismissed;
+ALTER TABLE technician_metrics DROP COLUMN IF EXISTS estimates_sold;
+
+DELETE FROM rejected_rows WHERE report_type = 'estimates';
+ALTER TABLE rejected_rows DROP CONSTRAINT IF EXISTS rejected_rows_report_type_check;
+ALTER TABLE rejected_rows ADD CONSTRAINT rejected_rows_report_type_check
+    CHECK (report_type IN ('jobs', 'invoices'));
+
+DELETE FROM batch_snapshots WHERE record_type = 'estimates';
+ALTER TABLE batch_snapshots DROP CONSTRAINT IF EXISTS batch_snapshots_record_type_check;
+ALTER TABLE batch_snapshots ADD CONSTRAINT batch_snapshots_record_type_check
+    CHECK (record_type IN ('jobs', 'invoices'));
+
+DROP INDEX IF EXISTS idx_import_batches_hashes;
+CREATE UNIQUE INDEX idx_import_batches_hashes
+    ON import_batches(job_report_hash, invoice_report_hash)
+    WHERE status <> 'failed';
+ALTER TABLE import_batches DROP COLUMN IF EXISTS row_count_estimates;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS estimate_report_hash;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS estimate_report_filename;
+
+DROP TABLE IF EXISTS estimates;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One row per Timesheet/Payroll export that has been imported
+CREATE TABLE timesheet_imports (
+    id BIGSERIAL PRIMARY KEY,
+    filename TEXT NOT NULL,
+    file_hash TEXT NOT NULL UNIQUE,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Hours a technician worked on a job, with what those hours cost.
+-- job_id has no foreign key: payroll is often exported before the jobs it
+-- covers, and those entries count as soon as the job is imported.
+CREATE TABLE timesheet_entries (
+    id BIGSERIAL PRIMARY KEY,
+    timesheet_import_id BIGINT NOT NULL REFERENCES timesheet_imports(id) ON DELETE CASCADE,
+    job_id TEXT NOT NULL,
+
+    technician TEXT NOT NULL, -- Raw name from the report
+    technician_id BIGINT REFERENCES technicians(id) ON DELETE SET NULL,
+    work_date DATE,
+
+    hours NUMERIC(8, 2) NOT NULL,
+    pay_rate NUMERIC(10, 2),
+    burden_rate NUMERIC(10, 2),
+    labor_cost NUMERIC(12, 2) NOT NULL, -- hours × (pay_rate + burden_rate)
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_timesheet_entries_job_id ON timesheet_entries(job_id);
+CREATE INDEX idx_timesheet_entries_import ON timesheet_entries(timesheet_import_id);
+
+-- Labor from timesheets, kept apart from invoice costs so reports can show
+-- profit either way. has_labor is false for jobs with no timesheet entries,
+-- whose after-labor figures equal the invoice-only ones.
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS labor_costs NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS has_labor BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS gross_profit_after_labor NUMERIC(12, 2);
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS margin_after_labor_pct NUMERIC(8, 2);
+
+UPDATE job_metrics
+SET gross_profit_after_labor = gross_profit,
+    margin_after_labor_pct = gross_margin_pct;
+
+ALTER TABLE job_metrics ALTER COLUMN gross_profit_after_labor SET NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS margin_after_labor_pct;
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS gross_profit_after_labor;
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS has_labor;
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS labor_costs;
+
+DROP TABLE IF EXISTS timesheet_entries;
+DROP TABLE IF EXISTS timesheet_imports;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Marketing spend per campaign per month, for campaign ROI
+-- campaign holds whatever the spend file used: a ServiceTitan campaign ID
+-- (as stored in jobs.campaign_name) or a campaign name
+CREATE TABLE campaign_spend (
+    id BIGSERIAL PRIMARY KEY,
+    campaign TEXT NOT NULL,
+    month DATE NOT NULL CHECK (EXTRACT(DAY FROM month) = 1),
+    amount NUMERIC(12, 2) NOT NULL,
+
+    source_filename TEXT, -- Spend file the amount was last imported from
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (campaign, month)
+);
+
+CREATE INDEX idx_campaign_spend_month ON campaign_spend(month);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS campaign_spend;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Campaign names and details for the numeric IDs ServiceTitan puts on jobs.
+-- id is the ServiceTitan campaign ID as text, the form jobs.campaign_name
+-- and jobs.call_campaign store it in.
+CREATE TABLE campaigns (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    category TEXT,
+    channel TEXT,
+
+    -- Dates the campaign ran; NULL means open-ended
+    active_from DATE,
+    active_to DATE,
+
+    source_filename TEXT, -- Campaign file the row was last imported from
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_campaigns_name ON campaigns(lower(name));
+
+-- Campaign reports join jobs to campaigns on the ID
+CREATE INDEX idx_jobs_campaign_name ON jobs(campaign_name);
+
+COMMENT ON COLUMN jobs.campaign_name IS 'ServiceTitan Job Campaign ID; the name is in campaigns';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+COMMENT ON COLUMN jobs.campaign_name IS NULL;
+
+DROP INDEX IF EXISTS idx_jobs_campaign_name;
+
+DROP TABLE IF EXISTS campaigns;
+
+-- +goose StatementEnd
\ No newline at end of file
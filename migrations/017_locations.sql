@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Service locations (one row per ServiceTitan Location ID). A customer can
+-- have many sites; each keeps its own address instead of the customer row
+-- holding whichever site was serviced last.
+CREATE TABLE locations (
+    id BIGINT PRIMARY KEY, -- ServiceTitan Location ID
+    customer_id BIGINT NOT NULL REFERENCES customers(id), -- Customer on the most recent job
+    city TEXT,
+    state TEXT,
+    zip TEXT,
+    first_job_date DATE,
+    last_job_date DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_locations_customer_id ON locations(customer_id);
+CREATE INDEX idx_locations_zip ON locations(zip);
+
+-- Seed from jobs already imported. Only the customer's last-seen address is
+-- stored, so multi-site addresses are corrected when the jobs are re-imported.
+INSERT INTO locations (id, customer_id, city, state, zip, first_job_date, last_job_date)
+SELECT DISTINCT ON (j.location_id)
+    j.location_id,
+    j.customer_id,
+    c.location_city,
+    c.location_state,
+    c.location_zip,
+    MIN(j.job_completion_date) OVER w,
+    MAX(j.job_completion_date) OVER w
+FROM jobs j
+JOIN customers c ON c.id = j.customer_id
+WHERE j.location_id IS NOT NULL
+WINDOW w AS (PARTITION BY j.location_id)
+ORDER BY j.location_id, j.job_completion_date DESC NULLS LAST;
+
+CREATE INDEX idx_jobs_location_id ON jobs(location_id);
+ALTER TABLE jobs ADD CONSTRAINT jobs_location_id_fkey
+    FOREIGN KEY (location_id) REFERENCES locations(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_location_id_fkey;
+DROP INDEX IF EXISTS idx_jobs_location_id;
+DROP TABLE IF EXISTS locations;
+
+-- +goose StatementEnd
\ No newline at end of file
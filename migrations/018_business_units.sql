@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Business units (one row per ServiceTitan Business Unit ID), so jobs can be
+-- grouped by unit even when the exported name changes
+CREATE TABLE business_units (
+    id BIGINT PRIMARY KEY, -- ServiceTitan Business Unit ID
+    name TEXT, -- Latest name seen on a job; NULL if never exported
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Seed from jobs already imported, naming each unit after its latest job
+INSERT INTO business_units (id, name)
+SELECT DISTINCT ON (business_unit_id) business_unit_id, business_unit
+FROM jobs
+WHERE business_unit_id IS NOT NULL
+ORDER BY business_unit_id, (business_unit IS NULL), job_completion_date DESC NULLS LAST;
+
+CREATE INDEX idx_jobs_business_unit_id ON jobs(business_unit_id);
+ALTER TABLE jobs ADD CONSTRAINT jobs_business_unit_id_fkey
+    FOREIGN KEY (business_unit_id) REFERENCES business_units(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_business_unit_id_fkey;
+DROP INDEX IF EXISTS idx_jobs_business_unit_id;
+DROP TABLE IF EXISTS business_units;
+
+-- +goose StatementEnd
\ No newline at end of file
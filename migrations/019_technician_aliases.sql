@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Inactive technicians keep their history but are left out of technician reports
+ALTER TABLE technicians ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
+
+-- Every spelling of a technician's name seen in exports, so later imports
+-- map onto the canonical technician. alias_key is the name lowercased with
+-- runs of whitespace collapsed to one space.
+CREATE TABLE technician_aliases (
+    alias_key TEXT PRIMARY KEY,
+    technician_id BIGINT NOT NULL REFERENCES technicians(id) ON DELETE CASCADE,
+    alias TEXT NOT NULL, -- Name as first seen
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_technician_aliases_technician_id ON technician_aliases(technician_id);
+
+-- Seed with each technician's own name. Names that only differ by case or
+-- spacing go to the oldest technician; the others can then be merged.
+INSERT INTO technician_aliases (alias_key, technician_id, alias)
+SELECT lower(regexp_replace(btrim(name), '\s+', ' ', 'g')), id, name
+FROM technicians
+ORDER BY id
+ON CONFLICT (alias_key) DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS technician_aliases;
+ALTER TABLE technicians DROP COLUMN IF EXISTS active;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- HR details from a roster import. Technicians only seen on jobs leave these NULL.
+ALTER TABLE technicians
+    ADD COLUMN IF NOT EXISTS employee_id TEXT,
+    ADD COLUMN IF NOT EXISTS team TEXT,
+    ADD COLUMN IF NOT EXISTS manager TEXT,
+    ADD COLUMN IF NOT EXISTS hire_date DATE,
+    ADD COLUMN IF NOT EXISTS termination_date DATE,
+    ADD COLUMN IF NOT EXISTS hourly_cost NUMERIC(10,2) NOT NULL DEFAULT 0, -- Fully loaded cost of an hour; 0 when unknown
+    ADD COLUMN IF NOT EXISTS roster_filename TEXT; -- Roster file the HR details last came from
+
+CREATE UNIQUE INDEX idx_technicians_employee_id ON technicians(employee_id) WHERE employee_id IS NOT NULL;
+CREATE INDEX idx_technicians_team ON technicians(team);
+CREATE INDEX idx_technicians_manager ON technicians(manager);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_technicians_manager;
+DROP INDEX IF EXISTS idx_technicians_team;
+DROP INDEX IF EXISTS idx_technicians_employee_id;
+ALTER TABLE technicians
+    DROP COLUMN IF EXISTS roster_filename,
+    DROP COLUMN IF EXISTS hourly_cost,
+    DROP COLUMN IF EXISTS termination_date,
+    DROP COLUMN IF EXISTS hire_date,
+    DROP COLUMN IF EXISTS manager,
+    DROP COLUMN IF EXISTS team,
+    DROP COLUMN IF EXISTS employee_id;
+
+-- +goose StatementEnd
\ No newline at end of file
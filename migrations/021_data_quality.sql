@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Job Type as exported on the invoice, checked against the job's
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS job_type TEXT;
+
+-- Problems found by validating each import batch: invoices that disagree
+-- with their job, cost totals that don't add up, and jobs whose subtotal
+-- doesn't match their invoices. Replaced whenever the batch is validated.
+CREATE TABLE data_quality_issues (
+    id BIGSERIAL PRIMARY KEY,
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    check_name TEXT NOT NULL, -- e.g. invoice_customer_mismatch, job_subtotal_mismatch
+    job_id TEXT NOT NULL,
+    invoice_id TEXT, -- NULL for job-level checks
+    expected TEXT, -- Value on the job, or the computed value
+    actual TEXT, -- Value on the invoice, or as exported
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_data_quality_issues_batch ON data_quality_issues(import_batch_id, check_name);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS data_quality_issues;
+ALTER TABLE invoices DROP COLUMN IF EXISTS job_type;
+
+-- +goose StatementEnd
\ No newline at end of file
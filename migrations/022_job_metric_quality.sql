@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Data quality flags on each job's metrics, e.g. no_costs or hours_missing.
+-- reliable is false when a flag makes the job's margin untrustworthy; reports
+-- can leave those jobs out. adjustment_used is informational only.
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS quality_flags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE job_metrics ADD COLUMN IF NOT EXISTS reliable BOOLEAN NOT NULL DEFAULT true;
+
+-- Flag existing metrics the same way the importer does
+UPDATE job_metrics m
+SET quality_flags = f.flags,
+    reliable = NOT (f.flags && ARRAY['no_costs', 'invoice_total_mismatch', 'hours_missing'])
+FROM (
+    SELECT
+        m.job_id,
+        array_remove(ARRAY[
+            CASE WHEN m.total_costs = 0 THEN 'no_costs' END,
+            CASE WHEN m.has_adjustment THEN 'adjustment_used' END,
+            CASE WHEN ABS(m.revenue - t.invoice_total) > GREATEST(25, 0.10 * ABS(m.revenue))
+                THEN 'invoice_total_mismatch' END,
+            CASE WHEN COALESCE(j.total_hours_worked, 0) = 0 AND NOT m.has_labor THEN 'hours_missing' END
+        ], NULL) AS flags
+    FROM job_metrics m
+    JOIN jobs j ON j.id = m.job_id
+    JOIN (
+        -- An adjustment invoice replaces the others, as it does for costs
+        SELECT
+            job_id,
+            COALESCE(
+                (array_agg(COALESCE(total, 0)) FILTER (WHERE is_adjustment))[1],
+                SUM(COALESCE(total, 0)) FILTER (WHERE NOT is_adjustment),
+                0
+            ) AS invoice_total
+        FROM invoices
+        GROUP BY job_id
+    ) t ON t.job_id = m.job_id
+) f
+WHERE f.job_id = m.job_id;
+
+CREATE INDEX idx_job_metrics_unreliable ON job_metrics(job_id) WHERE NOT reliable;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_job_metrics_unreliable;
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS reliable;
+ALTER TABLE job_metrics DROP COLUMN IF EXISTS quality_flags;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Customer lifetime rollups, kept in sync with their jobs by the importer and
+-- by batch deletion. lifetime_revenue sums the customer's job_metrics revenue.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS job_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS lifetime_revenue NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+UPDATE customers c SET
+    first_job_date = d.first_job_date,
+    last_job_date = d.last_job_date,
+    job_count = d.job_count,
+    lifetime_revenue = d.lifetime_revenue
+FROM (
+    SELECT
+        j.customer_id,
+        MIN(j.job_completion_date) AS first_job_date,
+        MAX(j.job_completion_date) AS last_job_date,
+        COUNT(*) AS job_count,
+        COALESCE(SUM(m.revenue), 0) AS lifetime_revenue
+    FROM jobs j
+    LEFT JOIN job_metrics m ON m.job_id = j.id
+    GROUP BY j.customer_id
+) d
+WHERE c.id = d.customer_id;
+
+CREATE INDEX idx_customers_lifetime_revenue ON customers(lifetime_revenue DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_customers_lifetime_revenue;
+ALTER TABLE customers DROP COLUMN IF EXISTS lifetime_revenue;
+ALTER TABLE customers DROP COLUMN IF EXISTS job_count;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Raw staging: every data row of a batch's jobs and invoices exports exactly
+-- as read, keyed by header, so sta rebuild can re-parse and re-import a batch
+-- after a parser or metric change without the original files. Rows fixed by
+-- sta import retry follow the batch's own rows in line_number.
+CREATE TABLE raw_job_rows (
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL, -- Row number in the file; the header is row 1
+    record JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (import_batch_id, line_number)
+);
+
+CREATE TABLE raw_invoice_rows (
+    import_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    record JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (import_batch_id, line_number)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS raw_invoice_rows;
+DROP TABLE IF EXISTS raw_job_rows;
+
+-- +goose StatementEnd
\ No newline at end of file
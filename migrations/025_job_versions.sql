@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Job history: one row per version of a job as seen by successive imports.
+-- A version is valid from the batch that first saw it until the batch that
+-- replaced it, so a job that comes back with a new status, subtotal or
+-- technician keeps its earlier versions. Revenue, costs and margin are
+-- copied from job_metrics when the version is recorded.
+CREATE TABLE job_versions (
+    id BIGSERIAL PRIMARY KEY,
+    job_id TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    valid_from_batch_id BIGINT NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    valid_to_batch_id BIGINT REFERENCES import_batches(id), -- NULL for the current version
+
+    status TEXT NOT NULL,
+    job_completion_date DATE,
+    primary_technician TEXT,
+    assigned_technician TEXT,
+    sold_by_technician TEXT,
+    jobs_subtotal NUMERIC(12, 2),
+
+    revenue NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total_costs NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    gross_profit NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    gross_margin_pct NUMERIC(8, 2),
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_job_versions_current ON job_versions(job_id) WHERE valid_to_batch_id IS NULL;
+CREATE INDEX idx_job_versions_job_id ON job_versions(job_id, id);
+CREATE INDEX idx_job_versions_status ON job_versions(status);
+
+-- Existing jobs start their history at the version they have now
+INSERT INTO job_versions (
+    job_id, valid_from_batch_id, status, job_completion_date,
+    primary_technician, assigned_technician, sold_by_technician, jobs_subtotal,
+    revenue, total_costs, gross_profit, gross_margin_pct
+)
+SELECT
+    j.id, j.last_import_batch_id, j.status, j.job_completion_date,
+    j.primary_technician, j.assigned_technician, j.sold_by_technician, j.jobs_subtotal,
+    COALESCE(m.revenue, 0), COALESCE(m.total_costs, 0), COALESCE(m.gross_profit, 0), m.gross_margin_pct
+FROM jobs j
+LEFT JOIN job_metrics m ON m.job_id = j.id;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS job_versions;
+
+-- +goose StatementEnd
\ No newline at end of file
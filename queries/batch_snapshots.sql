@@ -0,0 +1,185 @@
+-- name: SnapshotJobs :exec
+-- Saves the current version of jobs a batch is about to upsert. Jobs the
+-- batch already touched keep their first snapshot.
+INSERT INTO batch_snapshots (import_batch_id, record_type, record_id, previous)
+SELECT @import_batch_id::bigint, 'jobs', j.id, to_jsonb(j)
+FROM jobs j
+WHERE j.id = ANY(@job_ids::text[])
+AND j.last_import_batch_id <> @import_batch_id::bigint
+ON CONFLICT DO NOTHING;
+
+-- name: SnapshotInvoices :exec
+-- Saves the current version of invoices a batch is about to upsert. Invoices
+-- the batch already touched keep their first snapshot.
+INSERT INTO batch_snapshots (import_batch_id, record_type, record_id, previous)
+SELECT @import_batch_id::bigint, 'invoices', i.id, to_jsonb(i)
+FROM invoices i
+WHERE i.id = ANY(@invoice_ids::text[])
+AND i.last_import_batch_id <> @import_batch_id::bigint
+ON CONFLICT DO NOTHING;
+
+-- name: SnapshotEstimates :exec
+-- Saves the current version of estimates a batch is about to upsert.
+-- Estimates the batch already touched keep their first snapshot.
+INSERT INTO batch_snapshots (import_batch_id, record_type, record_id, previous)
+SELECT @import_batch_id::bigint, 'estimates', e.id, to_jsonb(e)
+FROM estimates e
+WHERE e.id = ANY(@estimate_ids::text[])
+AND e.last_import_batch_id <> @import_batch_id::bigint
+ON CONFLICT DO NOTHING;
+
+-- name: PruneBatchSnapshots :exec
+-- Drops snapshots of rows the batch left unchanged.
+DELETE FROM batch_snapshots s
+WHERE s.import_batch_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM jobs j
+    WHERE s.record_type = 'jobs' AND j.id = s.record_id AND j.last_import_batch_id = $1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM invoices i
+    WHERE s.record_type = 'invoices' AND i.id = s.record_id AND i.last_import_batch_id = $1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM estimates e
+    WHERE s.record_type = 'estimates' AND e.id = s.record_id AND e.last_import_batch_id = $1
+);
+
+-- name: RestoreJobsFromSnapshots :many
+-- Rolls jobs last changed by a batch back to the version before it.
+UPDATE jobs j SET
+    customer_id = p.customer_id,
+    import_batch_id = p.import_batch_id,
+    last_import_batch_id = p.last_import_batch_id,
+    job_type = p.job_type,
+    business_unit = p.business_unit,
+    status = p.status,
+    job_creation_date = p.job_creation_date,
+    job_schedule_date = p.job_schedule_date,
+    job_completion_date = p.job_completion_date,
+    assigned_technician = p.assigned_technician,
+    sold_by_technician = p.sold_by_technician,
+    booked_by = p.booked_by,
+    campaign_name = p.campaign_name,
+    campaign_category = p.campaign_category,
+    call_campaign = p.call_campaign,
+    jobs_subtotal = p.jobs_subtotal,
+    job_total = p.job_total,
+    estimate_sales_subtotal = p.estimate_sales_subtotal,
+    invoice_id = p.invoice_id,
+    total_hours_worked = p.total_hours_worked,
+    priority = p.priority,
+    survey_score = p.survey_score,
+    estimate_count = p.estimate_count,
+    is_opportunity = p.is_opportunity,
+    is_converted = p.is_converted,
+    primary_technician = p.primary_technician,
+    location_id = p.location_id,
+    business_unit_id = p.business_unit_id,
+    dispatched_by = p.dispatched_by,
+    summary = p.summary,
+    member_status = p.member_status,
+    tags = p.tags,
+    is_warranty = p.is_warranty,
+    is_recall = p.is_recall,
+    is_zero_dollar = p.is_zero_dollar,
+    updated_at = NOW()
+FROM batch_snapshots s, jsonb_populate_record(NULL::jobs, s.previous) p
+WHERE s.import_batch_id = $1
+AND s.record_type = 'jobs'
+AND j.id = s.record_id
+AND j.last_import_batch_id = $1
+RETURNING j.id;
+
+-- name: RestoreInvoicesFromSnapshots :execrows
+-- Rolls invoices last changed by a batch back to the version before it.
+UPDATE invoices i SET
+    job_id = p.job_id,
+    import_batch_id = p.import_batch_id,
+    last_import_batch_id = p.last_import_batch_id,
+    invoice_date = p.invoice_date,
+    invoice_status = p.invoice_status,
+    invoice_type = p.invoice_type,
+    invoice_summary = p.invoice_summary,
+    total = p.total,
+    balance = p.balance,
+    payments = p.payments,
+    material_costs = p.material_costs,
+    equipment_costs = p.equipment_costs,
+    purchase_order_costs = p.purchase_order_costs,
+    return_costs = p.return_costs,
+    costs_total = p.costs_total,
+    material_retail = p.material_retail,
+    material_markup = p.material_markup,
+    equipment_retail = p.equipment_retail,
+    equipment_markup = p.equipment_markup,
+    labor = p.labor,
+    labor_pay = p.labor_pay,
+    labor_burden = p.labor_burden,
+    total_labor_costs = p.total_labor_costs,
+    income = p.income,
+    discount_total = p.discount_total,
+    is_adjustment = p.is_adjustment,
+    customer_id = p.customer_id,
+    location_id = p.location_id,
+    project_number = p.project_number,
+    business_unit_id = p.business_unit_id,
+    payment_types = p.payment_types,
+    payment_term = p.payment_term,
+    pricebook_price = p.pricebook_price,
+    is_dispatch_service_fee_only = p.is_dispatch_service_fee_only,
+    is_prevailing_wage = p.is_prevailing_wage,
+    job_type = p.job_type,
+    updated_at = NOW()
+FROM batch_snapshots s, jsonb_populate_record(NULL::invoices, s.previous) p
+WHERE s.import_batch_id = $1
+AND s.record_type = 'invoices'
+AND i.id = s.record_id
+AND i.last_import_batch_id = $1;
+
+-- name: RestoreEstimatesFromSnapshots :execrows
+-- Rolls estimates last changed by a batch back to the version before it.
+UPDATE estimates e SET
+    job_id = p.job_id,
+    import_batch_id = p.import_batch_id,
+    last_import_batch_id = p.last_import_batch_id,
+    name = p.name,
+    status = p.status,
+    technician = p.technician,
+    technician_id = p.technician_id,
+    subtotal = p.subtotal,
+    created_on = p.created_on,
+    sold_on = p.sold_on,
+    updated_at = NOW()
+FROM batch_snapshots s, jsonb_populate_record(NULL::estimates, s.previous) p
+WHERE s.import_batch_id = $1
+AND s.record_type = 'estimates'
+AND e.id = s.record_id
+AND e.last_import_batch_id = $1;
+
+-- name: RebaseLaterSnapshots :exec
+-- Later batches that overwrote a deleted batch's version of a row now roll
+-- back to the version from before the deleted batch instead.
+UPDATE batch_snapshots later
+SET previous = earlier.previous
+FROM batch_snapshots earlier
+WHERE earlier.import_batch_id = $1
+AND later.import_batch_id <> $1
+AND later.record_type = earlier.record_type
+AND later.record_id = earlier.record_id
+AND (later.previous->>'last_import_batch_id')::bigint = $1;
+
+-- name: DeleteLaterSnapshotsOfBatchRows :exec
+-- Rows a deleted batch created count as created by the later batch that
+-- overwrote them, so that batch has nothing to roll back to.
+DELETE FROM batch_snapshots
+WHERE import_batch_id <> $1
+AND (previous->>'last_import_batch_id')::bigint = $1;
+
+-- name: ReassignSnapshotImportBatch :exec
+-- Points snapshots of rows first imported by a deleted batch at the batch
+-- that last changed them.
+UPDATE batch_snapshots
+SET previous = jsonb_set(previous, '{import_batch_id}', previous->'last_import_batch_id')
+WHERE import_batch_id <> $1
+AND (previous->>'import_batch_id')::bigint = $1;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- name: UpsertBusinessUnit :exec
+-- Renames the unit when a job exports a new name; a job without a name
+-- keeps the one already stored.
+INSERT INTO business_units (id, name)
+VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET
+    name = COALESCE(EXCLUDED.name, business_units.name),
+    updated_at = NOW()
+WHERE business_units.name IS DISTINCT FROM COALESCE(EXCLUDED.name, business_units.name);
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- name: UpsertCampaignSpend :one
+-- Re-importing a month replaces its amount. Rows whose amount hasn't changed
+-- are left alone and return no row.
+INSERT INTO campaign_spend (campaign, month, amount, source_filename)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (campaign, month) DO UPDATE SET
+    amount = EXCLUDED.amount,
+    source_filename = EXCLUDED.source_filename,
+    updated_at = NOW()
+WHERE campaign_spend.amount IS DISTINCT FROM EXCLUDED.amount
+RETURNING (xmax = 0)::boolean AS inserted;
+
+-- name: GetUnmatchedSpendCampaigns :many
+-- Campaigns from a spend file that no imported job was booked under, by
+-- campaign ID or by the name in campaigns.
+SELECT c.campaign::text AS campaign
+FROM unnest(@campaigns::text[]) AS c(campaign)
+WHERE NOT EXISTS (
+    SELECT 1 FROM jobs j
+    LEFT JOIN campaigns cp ON cp.id = j.campaign_name
+    WHERE j.campaign_name = c.campaign OR lower(cp.name) = lower(c.campaign)
+)
+ORDER BY c.campaign;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: UpsertCampaign :one
+-- Re-importing a campaign replaces its details. Rows that haven't changed
+-- are left alone and return no row.
+INSERT INTO campaigns (id, name, category, channel, active_from, active_to, source_filename)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    category = EXCLUDED.category,
+    channel = EXCLUDED.channel,
+    active_from = EXCLUDED.active_from,
+    active_to = EXCLUDED.active_to,
+    source_filename = EXCLUDED.source_filename,
+    updated_at = NOW()
+WHERE (campaigns.name, campaigns.category, campaigns.channel, campaigns.active_from, campaigns.active_to)
+    IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.category, EXCLUDED.channel, EXCLUDED.active_from, EXCLUDED.active_to)
+RETURNING (xmax = 0)::boolean AS inserted;
+
+-- name: GetUnknownCampaignIDs :many
+-- Campaign IDs on jobs that have no row in campaigns, busiest first.
+SELECT j.campaign_name::text AS campaign_id, COUNT(*) AS job_count
+FROM jobs j
+WHERE j.campaign_name IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM campaigns c WHERE c.id = j.campaign_name)
+GROUP BY j.campaign_name
+ORDER BY job_count DESC, campaign_id;
\ No newline at end of file
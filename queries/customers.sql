@@ -1,10 +1,11 @@
 -- name: UpsertCustomer :one
+-- Job dates, job count and lifetime revenue are rollups of the customer's
+-- jobs, kept up to date by RefreshCustomerRollups instead.
 INSERT INTO customers (
     id, customer_name, customer_type,
     customer_city, customer_state, customer_zip,
-    location_city, location_state, location_zip,
-    first_job_date, last_job_date
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    location_city, location_state, location_zip
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 ON CONFLICT (id) DO UPDATE SET
     customer_name = EXCLUDED.customer_name,
     customer_type = EXCLUDED.customer_type,
@@ -14,10 +15,53 @@ ON CONFLICT (id) DO UPDATE SET
     location_city = EXCLUDED.location_city,
     location_state = EXCLUDED.location_state,
     location_zip = EXCLUDED.location_zip,
-    last_job_date = GREATEST(customers.last_job_date, EXCLUDED.last_job_date),
-    first_job_date = LEAST(customers.first_job_date, EXCLUDED.first_job_date),
     updated_at = NOW()
-RETURNING *;
+WHERE (
+    customers.customer_name, customers.customer_type,
+    customers.customer_city, customers.customer_state, customers.customer_zip,
+    customers.location_city, customers.location_state, customers.location_zip
+) IS DISTINCT FROM (
+    EXCLUDED.customer_name, EXCLUDED.customer_type,
+    EXCLUDED.customer_city, EXCLUDED.customer_state, EXCLUDED.customer_zip,
+    EXCLUDED.location_city, EXCLUDED.location_state, EXCLUDED.location_zip
+)
+RETURNING (xmax = 0)::boolean AS inserted;
 
 -- name: GetCustomer :one
-SELECT * FROM customers WHERE id = $1;
\ No newline at end of file
+SELECT * FROM customers WHERE id = $1;
+
+-- name: GetAllCustomerIDs :many
+SELECT id FROM customers;
+
+-- name: RefreshCustomerRollups :exec
+-- Recomputes job dates, job count and lifetime revenue from the jobs that
+-- remain, for the given customers and the customers of the given jobs.
+-- Customers left without jobs are zeroed.
+UPDATE customers c SET
+    first_job_date = d.first_job_date,
+    last_job_date = d.last_job_date,
+    job_count = d.job_count,
+    lifetime_revenue = d.lifetime_revenue,
+    updated_at = NOW()
+FROM (
+    SELECT
+        t.id AS customer_id,
+        MIN(j.job_completion_date) AS first_job_date,
+        MAX(j.job_completion_date) AS last_job_date,
+        COUNT(j.id)::integer AS job_count,
+        COALESCE(SUM(m.revenue), 0)::numeric(12,2) AS lifetime_revenue
+    FROM customers t
+    LEFT JOIN jobs j ON j.customer_id = t.id
+    LEFT JOIN job_metrics m ON m.job_id = j.id
+    WHERE t.id = ANY(@customer_ids::bigint[])
+    OR t.id IN (SELECT customer_id FROM jobs WHERE id = ANY(@job_ids::text[]))
+    GROUP BY t.id
+) d
+WHERE c.id = d.customer_id
+AND (c.first_job_date, c.last_job_date, c.job_count, c.lifetime_revenue)
+    IS DISTINCT FROM (d.first_job_date, d.last_job_date, d.job_count, d.lifetime_revenue);
+
+-- name: DeleteCustomersWithoutJobs :execrows
+DELETE FROM customers c
+WHERE c.id = ANY(@customer_ids::bigint[])
+AND NOT EXISTS (SELECT 1 FROM jobs j WHERE j.customer_id = c.id);
\ No newline at end of file
@@ -0,0 +1,60 @@
+-- name: DeleteDataQualityIssues :exec
+-- Clears a batch's issues before it is validated again
+DELETE FROM data_quality_issues WHERE import_batch_id = $1;
+
+-- name: RecordJobsWithoutInvoices :many
+-- Jobs from the batch that have no invoice at all
+INSERT INTO data_quality_issues (import_batch_id, check_name, job_id)
+SELECT @import_batch_id::bigint, 'job_without_invoices', j.id
+FROM jobs j
+WHERE j.last_import_batch_id = @import_batch_id
+  AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.job_id = j.id)
+RETURNING job_id;
+
+-- name: RecordInvoiceMismatches :many
+-- Invoices from the batch whose customer, location or job type disagrees with
+-- their job, or whose costs total isn't material + equipment + PO - returns.
+-- Values missing from the invoice export aren't flagged.
+INSERT INTO data_quality_issues (import_batch_id, check_name, job_id, invoice_id, expected, actual)
+SELECT @import_batch_id::bigint, c.check_name, i.job_id, i.id, c.expected, c.actual
+FROM invoices i
+JOIN jobs j ON j.id = i.job_id
+CROSS JOIN LATERAL (VALUES
+    ('invoice_customer_mismatch', j.customer_id::text, i.customer_id::text,
+        i.customer_id IS NOT NULL AND i.customer_id <> j.customer_id),
+    ('invoice_location_mismatch', j.location_id::text, i.location_id::text,
+        i.location_id IS NOT NULL AND j.location_id IS NOT NULL AND i.location_id <> j.location_id),
+    ('invoice_job_type_mismatch', j.job_type, i.job_type,
+        i.job_type IS NOT NULL AND lower(btrim(i.job_type)) <> lower(btrim(j.job_type))),
+    ('invoice_costs_total_mismatch',
+        (COALESCE(i.material_costs, 0) + COALESCE(i.equipment_costs, 0)
+            + COALESCE(i.purchase_order_costs, 0) - COALESCE(i.return_costs, 0))::text,
+        COALESCE(i.costs_total, 0)::text,
+        ABS(COALESCE(i.costs_total, 0) - (COALESCE(i.material_costs, 0) + COALESCE(i.equipment_costs, 0)
+            + COALESCE(i.purchase_order_costs, 0) - COALESCE(i.return_costs, 0))) > 0.01)
+) AS c(check_name, expected, actual, failed)
+WHERE i.last_import_batch_id = @import_batch_id
+  AND c.failed
+RETURNING check_name, job_id, invoice_id;
+
+-- name: RecordJobSubtotalMismatches :many
+-- Jobs touched by the batch whose subtotal is off from the sum of their
+-- invoice totals by more than $25 or 10%, whichever is larger. Invoice totals
+-- include tax, so small differences are expected.
+INSERT INTO data_quality_issues (import_batch_id, check_name, job_id, expected, actual)
+SELECT @import_batch_id::bigint, 'job_subtotal_mismatch', j.id, t.invoice_total::text, COALESCE(j.jobs_subtotal, 0)::text
+FROM jobs j
+JOIN (
+    SELECT job_id, SUM(COALESCE(total, 0)) AS invoice_total
+    FROM invoices
+    GROUP BY job_id
+) t ON t.job_id = j.id
+WHERE (j.last_import_batch_id = @import_batch_id
+       OR EXISTS (SELECT 1 FROM invoices i WHERE i.job_id = j.id AND i.last_import_batch_id = @import_batch_id))
+  AND ABS(COALESCE(j.jobs_subtotal, 0) - t.invoice_total) > GREATEST(25, 0.10 * ABS(COALESCE(j.jobs_subtotal, 0)))
+RETURNING job_id;
+
+-- name: ListDataQualityIssues :many
+SELECT * FROM data_quality_issues
+WHERE import_batch_id = $1
+ORDER BY check_name, job_id, invoice_id;
\ No newline at end of file
@@ -0,0 +1,83 @@
+-- name: UpsertEstimate :one
+-- Inserts a new estimate or updates an existing one from an overlapping export.
+-- Rows whose values haven't changed are left alone and return no row.
+INSERT INTO estimates (
+    id, job_id, import_batch_id, last_import_batch_id,
+    name, status, technician, technician_id, subtotal,
+    created_on, sold_on
+) VALUES (
+    $1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (id) DO UPDATE SET
+    job_id = EXCLUDED.job_id,
+    name = EXCLUDED.name,
+    status = EXCLUDED.status,
+    technician = EXCLUDED.technician,
+    technician_id = EXCLUDED.technician_id,
+    subtotal = EXCLUDED.subtotal,
+    created_on = EXCLUDED.created_on,
+    sold_on = EXCLUDED.sold_on,
+    last_import_batch_id = EXCLUDED.last_import_batch_id,
+    updated_at = NOW()
+WHERE (
+    estimates.job_id, estimates.name, estimates.status, estimates.technician, estimates.technician_id,
+    estimates.subtotal, estimates.created_on, estimates.sold_on
+) IS DISTINCT FROM (
+    EXCLUDED.job_id, EXCLUDED.name, EXCLUDED.status, EXCLUDED.technician, EXCLUDED.technician_id,
+    EXCLUDED.subtotal, EXCLUDED.created_on, EXCLUDED.sold_on
+)
+RETURNING (xmax = 0)::boolean AS inserted;
+
+-- name: GetEstimatesForTechnicianMetricsByJobIDs :many
+-- Estimates without a technician of their own count for the job's sold_by technician.
+SELECT
+    e.id,
+    e.job_id,
+    COALESCE(e.technician_id, jt.technician_id)::bigint AS technician_id,
+    e.status,
+    e.subtotal,
+    e.created_on,
+    e.sold_on
+FROM estimates e
+LEFT JOIN job_technicians jt ON jt.job_id = e.job_id AND jt.role = 'sold_by'
+WHERE e.job_id = ANY(@job_ids::text[])
+AND COALESCE(e.technician_id, jt.technician_id) IS NOT NULL;
+
+-- name: GetTechnicianIDsForBatchEstimates :many
+-- Technicians credited with estimates last changed by a batch, before and
+-- after the change.
+SELECT COALESCE(e.technician_id, jt.technician_id)::bigint AS technician_id
+FROM estimates e
+LEFT JOIN job_technicians jt ON jt.job_id = e.job_id AND jt.role = 'sold_by'
+WHERE e.last_import_batch_id = $1
+AND COALESCE(e.technician_id, jt.technician_id) IS NOT NULL
+UNION
+SELECT COALESCE((s.previous->>'technician_id')::bigint, jt.technician_id)::bigint
+FROM batch_snapshots s
+LEFT JOIN job_technicians jt ON jt.job_id = s.previous->>'job_id' AND jt.role = 'sold_by'
+WHERE s.import_batch_id = $1 AND s.record_type = 'estimates'
+AND COALESCE((s.previous->>'technician_id')::bigint, jt.technician_id) IS NOT NULL;
+
+-- name: CountEstimatesBlockingBatchDelete :one
+-- Estimates from other batches attached to jobs that deleting the batch would remove.
+SELECT COUNT(*) FROM estimates e
+JOIN jobs j ON j.id = e.job_id
+WHERE j.last_import_batch_id = $1
+AND j.import_batch_id = $1
+AND e.last_import_batch_id <> $1;
+
+-- name: DeleteEstimatesCreatedByBatch :execrows
+DELETE FROM estimates
+WHERE import_batch_id = $1 AND last_import_batch_id = $1;
+
+-- name: ReleaseEstimatesFromBatch :execrows
+-- Estimates still marked as changed by a batch after restoring snapshots were
+-- overwritten before snapshots existed; they keep their current values.
+UPDATE estimates SET last_import_batch_id = import_batch_id
+WHERE last_import_batch_id = $1 AND import_batch_id <> $1;
+
+-- name: ReassignEstimatesImportBatch :exec
+-- Estimates first imported by a batch but changed since count as imported
+-- by the batch that last changed them.
+UPDATE estimates SET import_batch_id = last_import_batch_id
+WHERE import_batch_id = $1;
\ No newline at end of file
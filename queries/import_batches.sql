@@ -2,17 +2,21 @@
 INSERT INTO import_batches (
     job_report_filename,
     invoice_report_filename,
+    estimate_report_filename,
     job_report_hash,
     invoice_report_hash,
+    estimate_report_hash,
     row_count_jobs,
     row_count_invoices,
     status
-) VALUES ($1, $2, $3, $4, $5, $6, $7)
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetImportBatchByHashes :one
 SELECT * FROM import_batches
 WHERE job_report_hash = $1 AND invoice_report_hash = $2
+AND estimate_report_hash IS NOT DISTINCT FROM $3
+AND status <> 'failed'
 LIMIT 1;
 
 -- name: UpdateImportBatchStatus :exec
@@ -23,4 +27,72 @@ WHERE id = $1;
 -- name: ListImportBatches :many
 SELECT * FROM import_batches
 ORDER BY imported_at DESC
-LIMIT $1;
\ No newline at end of file
+LIMIT $1;
+
+-- name: UpdateImportBatchRowCounts :exec
+UPDATE import_batches
+SET row_count_jobs = $2, row_count_invoices = $3, row_count_estimates = $4
+WHERE id = $1;
+
+-- name: GetImportBatch :one
+SELECT * FROM import_batches WHERE id = $1;
+
+-- name: GetBatchDeletePreview :one
+-- Counts what deleting a batch would do to the rows it last changed: rows
+-- with a snapshot are restored, rows it created are deleted, and rows
+-- overwritten before snapshots existed are kept as they are.
+SELECT
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND r.has_snapshot)::int AS jobs_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND NOT r.has_snapshot AND r.created)::int AS jobs_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'jobs' AND NOT r.has_snapshot AND NOT r.created)::int AS jobs_kept,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND r.has_snapshot)::int AS invoices_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND NOT r.has_snapshot AND r.created)::int AS invoices_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'invoices' AND NOT r.has_snapshot AND NOT r.created)::int AS invoices_kept,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND r.has_snapshot)::int AS estimates_restored,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND NOT r.has_snapshot AND r.created)::int AS estimates_deleted,
+    COUNT(*) FILTER (WHERE r.record_type = 'estimates' AND NOT r.has_snapshot AND NOT r.created)::int AS estimates_kept
+FROM (
+    SELECT 'jobs' AS record_type, j.import_batch_id = @import_batch_id::bigint AS created,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = @import_batch_id::bigint AND s.record_type = 'jobs' AND s.record_id = j.id
+        ) AS has_snapshot
+    FROM jobs j
+    WHERE j.last_import_batch_id = @import_batch_id::bigint
+    UNION ALL
+    SELECT 'invoices', i.import_batch_id = @import_batch_id::bigint,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = @import_batch_id::bigint AND s.record_type = 'invoices' AND s.record_id = i.id
+        )
+    FROM invoices i
+    WHERE i.last_import_batch_id = @import_batch_id::bigint
+    UNION ALL
+    SELECT 'estimates', e.import_batch_id = @import_batch_id::bigint,
+        EXISTS (
+            SELECT 1 FROM batch_snapshots s
+            WHERE s.import_batch_id = @import_batch_id::bigint AND s.record_type = 'estimates' AND s.record_id = e.id
+        )
+    FROM estimates e
+    WHERE e.last_import_batch_id = @import_batch_id::bigint
+) r;
+
+-- name: DeleteImportBatch :exec
+DELETE FROM import_batches WHERE id = $1;
+
+-- name: CreateFailedImportBatch :one
+-- Records a failed import attempt. Runs outside the import transaction so
+-- the record survives its rollback.
+INSERT INTO import_batches (
+    job_report_filename,
+    invoice_report_filename,
+    estimate_report_filename,
+    job_report_hash,
+    invoice_report_hash,
+    estimate_report_hash,
+    status,
+    error_message,
+    failed_step,
+    failed_row
+) VALUES ($1, $2, $3, $4, $5, $6, 'failed', $7, $8, $9)
+RETURNING id;
\ No newline at end of file
@@ -1,18 +1,98 @@
--- name: CreateInvoice :one
+-- name: UpsertInvoice :one
+-- Inserts a new invoice or updates an existing one from an overlapping export.
+-- Rows whose values haven't changed are left alone and return no row.
 INSERT INTO invoices (
-    id, job_id, import_batch_id,
+    id, job_id, import_batch_id, last_import_batch_id,
     invoice_date, invoice_status, invoice_type, invoice_summary,
     total, balance, payments,
     material_costs, equipment_costs, purchase_order_costs, return_costs, costs_total,
     material_retail, material_markup, equipment_retail, equipment_markup,
     labor, labor_pay, labor_burden, total_labor_costs,
-    income, discount_total, is_adjustment
+    income, discount_total, is_adjustment,
+    customer_id, location_id, project_number, business_unit_id, payment_types, payment_term,
+    pricebook_price, is_dispatch_service_fee_only, is_prevailing_wage, job_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+    $1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26,
+    $27, $28, $29, $30, $31, $32, $33, $34, $35, $36
 )
-RETURNING *;
+ON CONFLICT (id) DO UPDATE SET
+    job_id = EXCLUDED.job_id,
+    invoice_date = EXCLUDED.invoice_date,
+    invoice_status = EXCLUDED.invoice_status,
+    invoice_type = EXCLUDED.invoice_type,
+    invoice_summary = EXCLUDED.invoice_summary,
+    total = EXCLUDED.total,
+    balance = EXCLUDED.balance,
+    payments = EXCLUDED.payments,
+    material_costs = EXCLUDED.material_costs,
+    equipment_costs = EXCLUDED.equipment_costs,
+    purchase_order_costs = EXCLUDED.purchase_order_costs,
+    return_costs = EXCLUDED.return_costs,
+    costs_total = EXCLUDED.costs_total,
+    material_retail = EXCLUDED.material_retail,
+    material_markup = EXCLUDED.material_markup,
+    equipment_retail = EXCLUDED.equipment_retail,
+    equipment_markup = EXCLUDED.equipment_markup,
+    labor = EXCLUDED.labor,
+    labor_pay = EXCLUDED.labor_pay,
+    labor_burden = EXCLUDED.labor_burden,
+    total_labor_costs = EXCLUDED.total_labor_costs,
+    income = EXCLUDED.income,
+    discount_total = EXCLUDED.discount_total,
+    is_adjustment = EXCLUDED.is_adjustment,
+    customer_id = EXCLUDED.customer_id,
+    location_id = EXCLUDED.location_id,
+    project_number = EXCLUDED.project_number,
+    business_unit_id = EXCLUDED.business_unit_id,
+    payment_types = EXCLUDED.payment_types,
+    payment_term = EXCLUDED.payment_term,
+    pricebook_price = EXCLUDED.pricebook_price,
+    is_dispatch_service_fee_only = EXCLUDED.is_dispatch_service_fee_only,
+    is_prevailing_wage = EXCLUDED.is_prevailing_wage,
+    job_type = EXCLUDED.job_type,
+    last_import_batch_id = EXCLUDED.last_import_batch_id,
+    updated_at = NOW()
+WHERE (
+    invoices.job_id, invoices.invoice_date, invoices.invoice_status, invoices.invoice_type, invoices.invoice_summary, invoices.total,
+    invoices.balance, invoices.payments, invoices.material_costs, invoices.equipment_costs, invoices.purchase_order_costs, invoices.return_costs,
+    invoices.costs_total, invoices.material_retail, invoices.material_markup, invoices.equipment_retail, invoices.equipment_markup, invoices.labor,
+    invoices.labor_pay, invoices.labor_burden, invoices.total_labor_costs, invoices.income, invoices.discount_total, invoices.is_adjustment,
+    invoices.customer_id, invoices.location_id, invoices.project_number, invoices.business_unit_id, invoices.payment_types, invoices.payment_term,
+    invoices.pricebook_price, invoices.is_dispatch_service_fee_only, invoices.is_prevailing_wage, invoices.job_type
+) IS DISTINCT FROM (
+    EXCLUDED.job_id, EXCLUDED.invoice_date, EXCLUDED.invoice_status, EXCLUDED.invoice_type, EXCLUDED.invoice_summary, EXCLUDED.total,
+    EXCLUDED.balance, EXCLUDED.payments, EXCLUDED.material_costs, EXCLUDED.equipment_costs, EXCLUDED.purchase_order_costs, EXCLUDED.return_costs,
+    EXCLUDED.costs_total, EXCLUDED.material_retail, EXCLUDED.material_markup, EXCLUDED.equipment_retail, EXCLUDED.equipment_markup, EXCLUDED.labor,
+    EXCLUDED.labor_pay, EXCLUDED.labor_burden, EXCLUDED.total_labor_costs, EXCLUDED.income, EXCLUDED.discount_total, EXCLUDED.is_adjustment,
+    EXCLUDED.customer_id, EXCLUDED.location_id, EXCLUDED.project_number, EXCLUDED.business_unit_id, EXCLUDED.payment_types, EXCLUDED.payment_term,
+    EXCLUDED.pricebook_price, EXCLUDED.is_dispatch_service_fee_only, EXCLUDED.is_prevailing_wage, EXCLUDED.job_type
+)
+RETURNING (xmax = 0)::boolean AS inserted;
 
 -- name: GetInvoicesForJob :many
 SELECT * FROM invoices
 WHERE job_id = $1
-ORDER BY invoice_date DESC;
\ No newline at end of file
+ORDER BY invoice_date DESC;
+
+-- name: GetInvoiceJobIDsForBatch :many
+-- Jobs of invoices last changed by a batch, before and after the change.
+SELECT job_id FROM invoices WHERE last_import_batch_id = $1
+UNION
+SELECT previous->>'job_id' FROM batch_snapshots
+WHERE import_batch_id = $1 AND record_type = 'invoices';
+
+-- name: DeleteInvoicesCreatedByBatch :execrows
+DELETE FROM invoices
+WHERE import_batch_id = $1 AND last_import_batch_id = $1;
+
+-- name: ReleaseInvoicesFromBatch :execrows
+-- Invoices still marked as changed by a batch after restoring snapshots were
+-- overwritten before snapshots existed; they keep their current values.
+UPDATE invoices SET last_import_batch_id = import_batch_id
+WHERE last_import_batch_id = $1 AND import_batch_id <> $1;
+
+-- name: ReassignInvoicesImportBatch :exec
+-- Invoices first imported by a batch but changed since count as imported
+-- by the batch that last changed them.
+UPDATE invoices SET import_batch_id = last_import_batch_id
+WHERE import_batch_id = $1;
\ No newline at end of file
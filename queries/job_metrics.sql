@@ -13,4 +13,8 @@ FROM jobs j
 JOIN job_metrics m ON j.id = m.job_id
 WHERE j.status = 'Completed'
 GROUP BY j.job_type
-ORDER BY avg_gross_profit DESC;
\ No newline at end of file
+ORDER BY avg_gross_profit DESC;
+
+-- name: DeleteJobMetricsForJobs :exec
+DELETE FROM job_metrics
+WHERE job_id = ANY(@job_ids::text[]);
\ No newline at end of file
@@ -0,0 +1,83 @@
+-- name: CloseChangedJobVersions :exec
+-- Ends the current version of jobs whose status, completion date,
+-- technicians, subtotal or metrics no longer match it. A version opened by
+-- the same batch is replaced rather than ended, so retries don't leave
+-- versions that were never valid.
+WITH changed AS (
+    SELECT v.id, v.valid_from_batch_id
+    FROM job_versions v
+    JOIN jobs j ON j.id = v.job_id
+    LEFT JOIN job_metrics m ON m.job_id = j.id
+    WHERE v.job_id = ANY(@job_ids::text[])
+    AND v.valid_to_batch_id IS NULL
+    AND (
+        v.status, v.job_completion_date,
+        v.primary_technician, v.assigned_technician, v.sold_by_technician, v.jobs_subtotal,
+        v.revenue, v.total_costs, v.gross_profit, v.gross_margin_pct
+    ) IS DISTINCT FROM (
+        j.status, j.job_completion_date,
+        j.primary_technician, j.assigned_technician, j.sold_by_technician, j.jobs_subtotal,
+        COALESCE(m.revenue, 0), COALESCE(m.total_costs, 0), COALESCE(m.gross_profit, 0), m.gross_margin_pct
+    )
+),
+replaced AS (
+    DELETE FROM job_versions v
+    USING changed c
+    WHERE v.id = c.id AND c.valid_from_batch_id = @import_batch_id::bigint
+)
+UPDATE job_versions v SET valid_to_batch_id = @import_batch_id::bigint
+FROM changed c
+WHERE v.id = c.id AND c.valid_from_batch_id <> @import_batch_id::bigint;
+
+-- name: CreateJobVersions :execrows
+-- Opens a version for each job that has no current one: new jobs and jobs
+-- whose version CloseChangedJobVersions just ended.
+INSERT INTO job_versions (
+    job_id, valid_from_batch_id, status, job_completion_date,
+    primary_technician, assigned_technician, sold_by_technician, jobs_subtotal,
+    revenue, total_costs, gross_profit, gross_margin_pct
+)
+SELECT
+    j.id, @import_batch_id::bigint, j.status, j.job_completion_date,
+    j.primary_technician, j.assigned_technician, j.sold_by_technician, j.jobs_subtotal,
+    COALESCE(m.revenue, 0), COALESCE(m.total_costs, 0), COALESCE(m.gross_profit, 0), m.gross_margin_pct
+FROM jobs j
+LEFT JOIN job_metrics m ON m.job_id = j.id
+WHERE j.id = ANY(@job_ids::text[])
+AND NOT EXISTS (
+    SELECT 1 FROM job_versions v
+    WHERE v.job_id = j.id AND v.valid_to_batch_id IS NULL
+);
+
+-- name: RefreshCurrentJobVersions :exec
+-- Brings current versions in line with their jobs without starting new
+-- ones, for when a rebuild rather than a new export changed them.
+UPDATE job_versions v SET
+    status = j.status,
+    job_completion_date = j.job_completion_date,
+    primary_technician = j.primary_technician,
+    assigned_technician = j.assigned_technician,
+    sold_by_technician = j.sold_by_technician,
+    jobs_subtotal = j.jobs_subtotal,
+    revenue = COALESCE(m.revenue, 0),
+    total_costs = COALESCE(m.total_costs, 0),
+    gross_profit = COALESCE(m.gross_profit, 0),
+    gross_margin_pct = m.gross_margin_pct
+FROM jobs j
+LEFT JOIN job_metrics m ON m.job_id = j.id
+WHERE v.job_id = j.id
+AND v.valid_to_batch_id IS NULL
+AND j.id = ANY(@job_ids::text[]);
+
+-- name: DeleteJobVersionsForBatch :exec
+-- Drops the versions a batch opened and hands their validity back to the
+-- versions they replaced, so those become current again unless a later
+-- batch replaced the job too.
+WITH removed AS (
+    DELETE FROM job_versions
+    WHERE valid_from_batch_id = $1
+    RETURNING job_id, valid_to_batch_id
+)
+UPDATE job_versions v SET valid_to_batch_id = r.valid_to_batch_id
+FROM removed r
+WHERE v.job_id = r.job_id AND v.valid_to_batch_id = $1;
\ No newline at end of file
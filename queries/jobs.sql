@@ -1,24 +1,79 @@
--- name: CreateJob :one
+-- name: UpsertJob :one
+-- Inserts a new job or updates an existing one from an overlapping export.
+-- Rows whose values haven't changed are left alone and return no row.
 INSERT INTO jobs (
-    id, customer_id, import_batch_id,
+    id, customer_id, import_batch_id, last_import_batch_id,
     job_type, business_unit, status,
     job_creation_date, job_schedule_date, job_completion_date,
     assigned_technician, sold_by_technician, booked_by,
     campaign_name, campaign_category, call_campaign,
     jobs_subtotal, job_total, estimate_sales_subtotal,
     invoice_id, total_hours_worked, priority, survey_score,
-    estimate_count, is_opportunity, is_converted, primary_technician
+    estimate_count, is_opportunity, is_converted, primary_technician,
+    location_id, business_unit_id, dispatched_by, summary, member_status, tags,
+    is_warranty, is_recall, is_zero_dollar
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+    $1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26,
+    $27, $28, $29, $30, $31, $32, $33, $34, $35
 )
-RETURNING *;
-
--- name: GetJobsWithoutInvoices :many
-SELECT j.id, j.job_type, j.customer_id
-FROM jobs j
-LEFT JOIN invoices i ON j.id = i.job_id
-WHERE i.id IS NULL
-AND j.import_batch_id = $1;
+ON CONFLICT (id) DO UPDATE SET
+    customer_id = EXCLUDED.customer_id,
+    job_type = EXCLUDED.job_type,
+    business_unit = EXCLUDED.business_unit,
+    status = EXCLUDED.status,
+    job_creation_date = EXCLUDED.job_creation_date,
+    job_schedule_date = EXCLUDED.job_schedule_date,
+    job_completion_date = EXCLUDED.job_completion_date,
+    assigned_technician = EXCLUDED.assigned_technician,
+    sold_by_technician = EXCLUDED.sold_by_technician,
+    booked_by = EXCLUDED.booked_by,
+    campaign_name = EXCLUDED.campaign_name,
+    campaign_category = EXCLUDED.campaign_category,
+    call_campaign = EXCLUDED.call_campaign,
+    jobs_subtotal = EXCLUDED.jobs_subtotal,
+    job_total = EXCLUDED.job_total,
+    estimate_sales_subtotal = EXCLUDED.estimate_sales_subtotal,
+    invoice_id = EXCLUDED.invoice_id,
+    total_hours_worked = EXCLUDED.total_hours_worked,
+    priority = EXCLUDED.priority,
+    survey_score = EXCLUDED.survey_score,
+    estimate_count = EXCLUDED.estimate_count,
+    is_opportunity = EXCLUDED.is_opportunity,
+    is_converted = EXCLUDED.is_converted,
+    primary_technician = EXCLUDED.primary_technician,
+    location_id = EXCLUDED.location_id,
+    business_unit_id = EXCLUDED.business_unit_id,
+    dispatched_by = EXCLUDED.dispatched_by,
+    summary = EXCLUDED.summary,
+    member_status = EXCLUDED.member_status,
+    tags = EXCLUDED.tags,
+    is_warranty = EXCLUDED.is_warranty,
+    is_recall = EXCLUDED.is_recall,
+    is_zero_dollar = EXCLUDED.is_zero_dollar,
+    last_import_batch_id = EXCLUDED.last_import_batch_id,
+    updated_at = NOW()
+WHERE (
+    jobs.customer_id, jobs.job_type, jobs.business_unit, jobs.status,
+    jobs.job_creation_date, jobs.job_schedule_date, jobs.job_completion_date,
+    jobs.assigned_technician, jobs.sold_by_technician, jobs.booked_by,
+    jobs.campaign_name, jobs.campaign_category, jobs.call_campaign,
+    jobs.jobs_subtotal, jobs.job_total, jobs.estimate_sales_subtotal,
+    jobs.invoice_id, jobs.total_hours_worked, jobs.priority, jobs.survey_score,
+    jobs.estimate_count, jobs.is_opportunity, jobs.is_converted, jobs.primary_technician,
+    jobs.location_id, jobs.business_unit_id, jobs.dispatched_by, jobs.summary, jobs.member_status, jobs.tags,
+    jobs.is_warranty, jobs.is_recall, jobs.is_zero_dollar
+) IS DISTINCT FROM (
+    EXCLUDED.customer_id, EXCLUDED.job_type, EXCLUDED.business_unit, EXCLUDED.status,
+    EXCLUDED.job_creation_date, EXCLUDED.job_schedule_date, EXCLUDED.job_completion_date,
+    EXCLUDED.assigned_technician, EXCLUDED.sold_by_technician, EXCLUDED.booked_by,
+    EXCLUDED.campaign_name, EXCLUDED.campaign_category, EXCLUDED.call_campaign,
+    EXCLUDED.jobs_subtotal, EXCLUDED.job_total, EXCLUDED.estimate_sales_subtotal,
+    EXCLUDED.invoice_id, EXCLUDED.total_hours_worked, EXCLUDED.priority, EXCLUDED.survey_score,
+    EXCLUDED.estimate_count, EXCLUDED.is_opportunity, EXCLUDED.is_converted, EXCLUDED.primary_technician,
+    EXCLUDED.location_id, EXCLUDED.business_unit_id, EXCLUDED.dispatched_by, EXCLUDED.summary, EXCLUDED.member_status, EXCLUDED.tags,
+    EXCLUDED.is_warranty, EXCLUDED.is_recall, EXCLUDED.is_zero_dollar
+)
+RETURNING (xmax = 0)::boolean AS inserted;
 
 -- name: GetJobsForTechnicianProcessing :many
 SELECT 
@@ -28,4 +83,58 @@ SELECT
     primary_technician,
     job_completion_date
 FROM jobs
+WHERE import_batch_id = $1;
+
+-- name: GetExistingJobIDs :many
+SELECT id FROM jobs
+WHERE id = ANY(@job_ids::text[]);
+
+-- name: GetJobIDsForBatch :many
+-- Jobs last inserted or changed by a batch.
+SELECT id FROM jobs WHERE last_import_batch_id = $1;
+
+-- name: GetAllJobIDs :many
+SELECT id FROM jobs;
+
+-- name: GetJobsForTechnicianProcessingByIDs :many
+SELECT 
+    id, 
+    assigned_technician, 
+    sold_by_technician, 
+    primary_technician,
+    job_completion_date
+FROM jobs
+WHERE id = ANY(@job_ids::text[]);
+
+-- name: GetCustomerIDsForBatch :many
+-- Customers of jobs last changed by a batch, before and after the change.
+SELECT customer_id FROM jobs WHERE last_import_batch_id = $1
+UNION
+SELECT (previous->>'customer_id')::bigint FROM batch_snapshots
+WHERE import_batch_id = $1 AND record_type = 'jobs';
+
+-- name: CountInvoicesBlockingBatchDelete :one
+-- Invoices from other batches attached to jobs that deleting the batch would remove.
+SELECT COUNT(*) FROM invoices i
+JOIN jobs j ON j.id = i.job_id
+WHERE j.last_import_batch_id = $1
+AND j.import_batch_id = $1
+AND i.last_import_batch_id <> $1;
+
+-- name: DeleteJobsCreatedByBatch :execrows
+-- Deletes jobs a batch created and no later batch changed. Job metrics and
+-- technician links cascade.
+DELETE FROM jobs
+WHERE import_batch_id = $1 AND last_import_batch_id = $1;
+
+-- name: ReleaseJobsFromBatch :execrows
+-- Jobs still marked as changed by a batch after restoring snapshots were
+-- overwritten before snapshots existed; they keep their current values.
+UPDATE jobs SET last_import_batch_id = import_batch_id
+WHERE last_import_batch_id = $1 AND import_batch_id <> $1;
+
+-- name: ReassignJobsImportBatch :exec
+-- Jobs first imported by a batch but changed since count as imported by
+-- the batch that last changed them.
+UPDATE jobs SET import_batch_id = last_import_batch_id
 WHERE import_batch_id = $1;
\ No newline at end of file
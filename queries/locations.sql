@@ -0,0 +1,55 @@
+-- name: UpsertLocation :one
+-- The address and customer follow the latest import; job dates are merged
+-- with LEAST/GREATEST like customers.
+INSERT INTO locations (
+    id, customer_id, city, state, zip,
+    first_job_date, last_job_date
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE SET
+    customer_id = EXCLUDED.customer_id,
+    city = EXCLUDED.city,
+    state = EXCLUDED.state,
+    zip = EXCLUDED.zip,
+    first_job_date = LEAST(locations.first_job_date, EXCLUDED.first_job_date),
+    last_job_date = GREATEST(locations.last_job_date, EXCLUDED.last_job_date),
+    updated_at = NOW()
+WHERE (
+    locations.customer_id, locations.city, locations.state, locations.zip,
+    locations.first_job_date, locations.last_job_date
+) IS DISTINCT FROM (
+    EXCLUDED.customer_id, EXCLUDED.city, EXCLUDED.state, EXCLUDED.zip,
+    LEAST(locations.first_job_date, EXCLUDED.first_job_date),
+    GREATEST(locations.last_job_date, EXCLUDED.last_job_date)
+)
+RETURNING (xmax = 0)::boolean AS inserted;
+
+-- name: GetLocationIDsForJobs :many
+SELECT DISTINCT location_id::bigint AS location_id
+FROM jobs
+WHERE id = ANY(@job_ids::text[]) AND location_id IS NOT NULL;
+
+-- name: RefreshLocations :exec
+-- Recomputes job dates and the owning customer from the jobs that remain.
+-- The owner is the customer on the most recent job, so a customer whose
+-- jobs were all deleted no longer owns the site.
+UPDATE locations l SET
+    customer_id = d.customer_id,
+    first_job_date = d.first_job_date,
+    last_job_date = d.last_job_date,
+    updated_at = NOW()
+FROM (
+    SELECT DISTINCT ON (j.location_id)
+        j.location_id,
+        j.customer_id,
+        MIN(j.job_completion_date) OVER (PARTITION BY j.location_id) AS first_job_date,
+        MAX(j.job_completion_date) OVER (PARTITION BY j.location_id) AS last_job_date
+    FROM jobs j
+    WHERE j.location_id = ANY(@location_ids::bigint[])
+    ORDER BY j.location_id, j.job_completion_date DESC NULLS LAST
+) d
+WHERE l.id = d.location_id;
+
+-- name: DeleteLocationsWithoutJobs :execrows
+DELETE FROM locations l
+WHERE l.id = ANY(@location_ids::bigint[])
+AND NOT EXISTS (SELECT 1 FROM jobs j WHERE j.location_id = l.id);
\ No newline at end of file
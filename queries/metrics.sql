@@ -50,4 +50,42 @@ SELECT
     gross_margin_pct,
     invoice_count,
     has_adjustment
-FROM job_metrics;
\ No newline at end of file
+FROM job_metrics;
+
+-- name: GetJobsForMetricsByIDs :many
+SELECT 
+    id,
+    status,
+    jobs_subtotal,
+    total_hours_worked
+FROM jobs
+WHERE id = ANY(@job_ids::text[]);
+
+-- name: GetInvoicesForMetricsByJobIDs :many
+SELECT 
+    id,
+    job_id,
+    total,
+    costs_total,
+    is_adjustment
+FROM invoices
+WHERE job_id = ANY(@job_ids::text[]);
+
+-- name: GetJobTechniciansForJobs :many
+SELECT 
+    job_id,
+    technician_id,
+    role
+FROM job_technicians
+WHERE job_id = ANY(@job_ids::text[]);
+
+-- name: GetJobsForTechnicianMetricsByIDs :many
+SELECT 
+    id,
+    status,
+    jobs_subtotal,
+    estimate_sales_subtotal,
+    total_hours_worked,
+    COALESCE(estimate_count, 0) as estimate_count
+FROM jobs
+WHERE id = ANY(@job_ids::text[]);
\ No newline at end of file
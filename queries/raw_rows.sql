@@ -0,0 +1,45 @@
+-- name: CreateRawJobRows :exec
+INSERT INTO raw_job_rows (import_batch_id, line_number, record)
+SELECT @import_batch_id::bigint, r.line_number, r.record::jsonb
+FROM unnest(@line_numbers::integer[], @records::text[]) AS r(line_number, record);
+
+-- name: CreateRawInvoiceRows :exec
+INSERT INTO raw_invoice_rows (import_batch_id, line_number, record)
+SELECT @import_batch_id::bigint, r.line_number, r.record::jsonb
+FROM unnest(@line_numbers::integer[], @records::text[]) AS r(line_number, record);
+
+-- name: ListRawJobRows :many
+-- Pages through a batch's raw job rows in file order
+SELECT line_number, record FROM raw_job_rows
+WHERE import_batch_id = @import_batch_id AND line_number > @after_line
+ORDER BY line_number
+LIMIT @row_limit;
+
+-- name: ListRawInvoiceRows :many
+-- Pages through a batch's raw invoice rows in file order
+SELECT line_number, record FROM raw_invoice_rows
+WHERE import_batch_id = @import_batch_id AND line_number > @after_line
+ORDER BY line_number
+LIMIT @row_limit;
+
+-- name: GetLastRawJobLine :one
+SELECT COALESCE(MAX(line_number), 0)::integer FROM raw_job_rows
+WHERE import_batch_id = $1;
+
+-- name: GetLastRawInvoiceLine :one
+SELECT COALESCE(MAX(line_number), 0)::integer FROM raw_invoice_rows
+WHERE import_batch_id = $1;
+
+-- name: ListStagedBatchIDs :many
+-- Batches with raw rows to rebuild from, oldest first
+SELECT import_batch_id FROM raw_job_rows
+UNION
+SELECT import_batch_id FROM raw_invoice_rows
+ORDER BY import_batch_id;
+
+-- name: CountUnstagedBatches :one
+-- Successful batches imported before raw rows were staged
+SELECT COUNT(*) FROM import_batches b
+WHERE b.status = 'success'
+AND NOT EXISTS (SELECT 1 FROM raw_job_rows r WHERE r.import_batch_id = b.id)
+AND NOT EXISTS (SELECT 1 FROM raw_invoice_rows r WHERE r.import_batch_id = b.id);
\ No newline at end of file
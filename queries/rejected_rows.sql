@@ -0,0 +1,21 @@
+-- name: CreateRejectedRow :exec
+INSERT INTO rejected_rows (
+    import_batch_id, report_type, row_number,
+    column_name, raw_value, error_message,
+    record_key, raw_record
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListRejectedRows :many
+-- Rows from a batch that are still waiting for a fix
+SELECT * FROM rejected_rows
+WHERE import_batch_id = $1 AND resolved_at IS NULL
+ORDER BY report_type, row_number;
+
+-- name: ResolveRejectedRows :execrows
+-- Marks rows as resolved once a fixed version with the same key was re-ingested
+UPDATE rejected_rows
+SET resolved_at = NOW()
+WHERE import_batch_id = @import_batch_id
+  AND report_type = @report_type
+  AND resolved_at IS NULL
+  AND record_key = ANY(@record_keys::text[]);
\ No newline at end of file
@@ -5,7 +5,7 @@ ON CONFLICT (name) DO UPDATE SET
     first_seen_date = LEAST(technicians.first_seen_date, EXCLUDED.first_seen_date),
     last_seen_date = GREATEST(technicians.last_seen_date, EXCLUDED.last_seen_date),
     updated_at = NOW()
-RETURNING *;
+RETURNING id, (xmax = 0)::boolean AS inserted;
 
 -- name: GetTechnicianByName :one
 SELECT * FROM technicians WHERE name = $1;
@@ -29,7 +29,7 @@ SELECT
     tm.avg_margin_pct
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_sold > 0 OR tm.jobs_serviced > 0
+WHERE t.active AND (tm.jobs_sold > 0 OR tm.jobs_serviced > 0)
 ORDER BY tm.total_sales DESC;
 
 -- name: GetTopTechniciansBySales :many
@@ -42,7 +42,7 @@ SELECT
     tm.avg_margin_pct
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_sold > 0
+WHERE t.active AND tm.jobs_sold > 0
 ORDER BY tm.avg_sale DESC
 LIMIT $1;
 
@@ -55,7 +55,7 @@ SELECT
     tm.avg_sale
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.opportunities >= 5  -- minimum sample size
+WHERE t.active AND tm.opportunities >= 5  -- minimum sample size
 ORDER BY tm.conversion_rate DESC
 LIMIT $1;
 
@@ -68,6 +68,120 @@ SELECT
     tm.avg_estimates_per_job
 FROM technicians t
 JOIN technician_metrics tm ON t.id = tm.technician_id
-WHERE tm.jobs_serviced > 0
+WHERE t.active AND tm.jobs_serviced > 0
 ORDER BY tm.avg_hours_per_job ASC
-LIMIT $1;
\ No newline at end of file
+LIMIT $1;
+
+-- name: DeleteJobTechniciansForJobs :many
+-- Returns the technician of every removed link, whose metrics may change.
+DELETE FROM job_technicians
+WHERE job_id = ANY(@job_ids::text[])
+RETURNING technician_id;
+
+-- name: GetTechnicianIDsForJobs :many
+-- Technicians credited with any of the jobs: linked to them, or named on one
+-- of their estimates. Estimates without a technician credit the job's
+-- sold_by technician, who is linked.
+SELECT technician_id FROM job_technicians WHERE job_id = ANY(@job_ids::text[])
+UNION
+SELECT technician_id FROM estimates
+WHERE job_id = ANY(@job_ids::text[]) AND technician_id IS NOT NULL;
+
+-- name: GetTechnician :one
+SELECT * FROM technicians WHERE id = $1;
+
+-- name: ListTechnicians :many
+-- Every technician with the other spellings mapped onto them and how many
+-- jobs they're linked to.
+SELECT
+    t.id,
+    t.name,
+    t.active,
+    t.team,
+    t.termination_date,
+    t.first_seen_date,
+    t.last_seen_date,
+    COALESCE((
+        SELECT string_agg(a.alias, ', ' ORDER BY a.alias)
+        FROM technician_aliases a
+        WHERE a.technician_id = t.id AND a.alias <> t.name
+    ), '')::text AS aliases,
+    (SELECT COUNT(DISTINCT jt.job_id) FROM job_technicians jt WHERE jt.technician_id = t.id) AS job_count
+FROM technicians t
+ORDER BY t.name;
+
+-- name: GetTechnicianIDByAlias :one
+SELECT technician_id FROM technician_aliases WHERE alias_key = $1;
+
+-- name: CreateTechnicianAlias :exec
+-- Keeps the existing mapping when the alias is already known.
+INSERT INTO technician_aliases (alias_key, technician_id, alias)
+VALUES ($1, $2, $3)
+ON CONFLICT (alias_key) DO NOTHING;
+
+-- name: UpdateTechnicianSeenDates :exec
+UPDATE technicians SET
+    first_seen_date = LEAST(first_seen_date, @first_seen_date),
+    last_seen_date = GREATEST(last_seen_date, @last_seen_date),
+    updated_at = NOW()
+WHERE id = @id;
+
+-- name: RenameTechnician :exec
+UPDATE technicians SET name = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetTechnicianActive :exec
+UPDATE technicians SET active = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CopyJobTechnicians :execrows
+-- Links the merge target to every job/role the merged technician has;
+-- links the target already has are skipped.
+INSERT INTO job_technicians (job_id, technician_id, role)
+SELECT job_id, @into_id, role
+FROM job_technicians
+WHERE technician_id = @from_id
+ON CONFLICT (job_id, technician_id, role) DO NOTHING;
+
+-- name: ReassignTechnicianEstimates :exec
+UPDATE estimates SET technician_id = @into_id, updated_at = NOW()
+WHERE technician_id = @from_id;
+
+-- name: ReassignTechnicianTimesheetEntries :exec
+UPDATE timesheet_entries SET technician_id = @into_id
+WHERE technician_id = @from_id;
+
+-- name: ReassignTechnicianAliases :exec
+UPDATE technician_aliases SET technician_id = @into_id
+WHERE technician_id = @from_id;
+
+-- name: MergeTechnicianSeenDates :exec
+UPDATE technicians t SET
+    first_seen_date = LEAST(t.first_seen_date, f.first_seen_date),
+    last_seen_date = GREATEST(t.last_seen_date, f.last_seen_date),
+    updated_at = NOW()
+FROM technicians f
+WHERE t.id = @into_id AND f.id = @from_id;
+
+-- name: DeleteTechnician :exec
+-- Job links and metrics cascade with the technician.
+DELETE FROM technicians WHERE id = $1;
+
+-- name: GetTechnicianIDByEmployeeID :one
+SELECT id FROM technicians WHERE employee_id = $1;
+
+-- name: UpdateTechnicianRoster :execrows
+-- The roster is authoritative: blank cells clear the field, e.g. a rehire's
+-- termination date. Returns 0 when nothing changed.
+UPDATE technicians SET
+    employee_id = @employee_id,
+    team = @team,
+    manager = @manager,
+    hire_date = @hire_date,
+    termination_date = @termination_date,
+    hourly_cost = @hourly_cost,
+    roster_filename = @roster_filename,
+    updated_at = NOW()
+WHERE id = @id
+  AND (employee_id, team, manager, hire_date, termination_date, hourly_cost)
+      IS DISTINCT FROM (@employee_id, @team, @manager, @hire_date, @termination_date, @hourly_cost);
\ No newline at end of file
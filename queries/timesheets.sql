@@ -0,0 +1,38 @@
+-- name: GetTimesheetImportByHash :one
+SELECT * FROM timesheet_imports
+WHERE file_hash = $1;
+
+-- name: CreateTimesheetImport :one
+INSERT INTO timesheet_imports (filename, file_hash)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: UpdateTimesheetImportRowCount :exec
+UPDATE timesheet_imports
+SET row_count = $2
+WHERE id = $1;
+
+-- name: InsertTimesheetEntry :exec
+INSERT INTO timesheet_entries (
+    timesheet_import_id, job_id, technician, technician_id, work_date,
+    hours, pay_rate, burden_rate, labor_cost
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- name: DeleteReplacedTimesheetEntries :execrows
+-- A re-exported timesheet replaces what earlier imports recorded for the same
+-- job and day. work_dates holds 'YYYY-MM-DD' or '' for entries without a date.
+DELETE FROM timesheet_entries e
+USING unnest(@job_ids::text[], @work_dates::text[]) AS k(job_id, work_date)
+WHERE e.job_id = k.job_id
+AND COALESCE(e.work_date::text, '') = k.work_date
+AND e.timesheet_import_id <> @timesheet_import_id;
+
+-- name: GetJobLaborCostsByJobIDs :many
+SELECT
+    job_id,
+    SUM(labor_cost) AS labor_costs
+FROM timesheet_entries
+WHERE job_id = ANY(@job_ids::text[])
+GROUP BY job_id;
\ No newline at end of file